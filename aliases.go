@@ -0,0 +1,175 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// https://msdn.microsoft.com/en-us/library/windows/desktop/bb762494(v=vs.85).aspx
+// https://msdn.microsoft.com/en-us/library/windows/desktop/bb776911(v=vs.85).aspx
+var csidls = []struct {
+	Name   string
+	Value  int
+	Folder string
+}{
+	{"CSIDL_DESKTOP", 0x0000, "Desktop"},
+	{"CSIDL_INTERNET", 0x0001, "InternetFolder"},
+	{"CSIDL_PROGRAMS", 0x0002, "Programs"},
+	{"CSIDL_CONTROLS", 0x0003, "ControlPanelFolder"},
+	{"CSIDL_PRINTERS", 0x0004, "PrintersFolder"},
+	{"CSIDL_PERSONAL", 0x0005, "Documents"},
+	{"CSIDL_FAVORITES", 0x0006, "Favorites"},
+	{"CSIDL_STARTUP", 0x0007, "Startup"},
+	{"CSIDL_RECENT", 0x0008, "Recent"},
+	{"CSIDL_SENDTO", 0x0009, "SendTo"},
+	{"CSIDL_BITBUCKET", 0x000A, "RecycleBinFolder"},
+	{"CSIDL_STARTMENU", 0x000B, "StartMenu"},
+	{"CSIDL_MYDOCUMENTS", 0x000C, "Documents"},
+	{"CSIDL_MYMUSIC", 0x000D, "Music"},
+	{"CSIDL_MYVIDEO", 0x000E, "Videos"},
+	{"CSIDL_DESKTOPDIRECTORY", 0x0010, "Desktop"},
+	{"CSIDL_DRIVES", 0x0011, "ComputerFolder"},
+	{"CSIDL_NETWORK", 0x0012, "NetworkFolder"},
+	{"CSIDL_NETHOOD", 0x0013, "NetHood"},
+	{"CSIDL_FONTS", 0x0014, "Fonts"},
+	{"CSIDL_TEMPLATES", 0x0015, "Templates"},
+	{"CSIDL_COMMON_STARTMENU", 0x0016, "CommonStartMenu"},
+	{"CSIDL_COMMON_PROGRAMS", 0x0017, "CommonPrograms"},
+	{"CSIDL_COMMON_STARTUP", 0x0018, "CommonStartup"},
+	{"CSIDL_COMMON_DESKTOPDIRECTORY", 0x0019, "PublicDesktop"},
+	{"CSIDL_APPDATA", 0x001A, "RoamingAppData"},
+	{"CSIDL_PRINTHOOD", 0x001B, "PrintHood"},
+	{"CSIDL_LOCAL_APPDATA", 0x001C, "LocalAppData"},
+	{"CSIDL_ALTSTARTUP", 0x001D, "Startup"},
+	{"CSIDL_COMMON_ALTSTARTUP", 0x001E, "CommonStartup"},
+	{"CSIDL_COMMON_FAVORITES", 0x001F, "Favorites"},
+	{"CSIDL_INTERNET_CACHE", 0x0020, "InternetCache"},
+	{"CSIDL_COOKIES", 0x0021, "Cookies"},
+	{"CSIDL_HISTORY", 0x0022, "History"},
+	{"CSIDL_COMMON_APPDATA", 0x0023, "ProgramData"},
+	{"CSIDL_WINDOWS", 0x0024, "Windows"},
+	{"CSIDL_SYSTEM", 0x0025, "System"},
+	{"CSIDL_PROGRAM_FILES", 0x0026, "ProgramFiles"},
+	{"CSIDL_MYPICTURES", 0x0027, "Pictures"},
+	{"CSIDL_PROFILE", 0x0028, "Profile"},
+	{"CSIDL_SYSTEMX86", 0x0029, "SystemX86"},
+	{"CSIDL_PROGRAM_FILESX86", 0x002A, "ProgramFilesX86"},
+	{"CSIDL_PROGRAM_FILES_COMMON", 0x002B, "ProgramFilesCommon"},
+	{"CSIDL_PROGRAM_FILES_COMMONX86", 0x002C, "ProgramFilesCommonX86"},
+	{"CSIDL_COMMON_TEMPLATES", 0x002D, "CommonTemplates"},
+	{"CSIDL_COMMON_DOCUMENTS", 0x002E, "PublicDocuments"},
+	{"CSIDL_COMMON_ADMINTOOLS", 0x002F, "CommonAdminTools"},
+	{"CSIDL_ADMINTOOLS", 0x0030, "AdminTools"},
+	{"CSIDL_CONNECTIONS", 0x0031, "ConnectionsFolder"},
+	{"CSIDL_COMMON_MUSIC", 0x0035, "PublicMusic"},
+	{"CSIDL_COMMON_PICTURES", 0x0036, "PublicPictures"},
+	{"CSIDL_COMMON_VIDEO", 0x0037, "PublicVideos"},
+	{"CSIDL_RESOURCES", 0x0038, "ResourceDir"},
+	{"CSIDL_RESOURCES_LOCALIZED", 0x0039, "LocalizedResourcesDir"},
+	{"CSIDL_COMMON_OEM_LINKS", 0x003A, "CommonOEMLinks"},
+	{"CSIDL_CDBURN_AREA", 0x003B, "CDBurning"},
+	{"CSIDL_COMPUTERSNEARME", 0x003D, "NetworkFolder"},
+}
+
+// Environment variables (as in %APPDATA%) and the legacy value names used
+// under the "User Shell Folders" and "Shell Folders" registry keys.
+var nameAliases = []struct {
+	Name   string
+	Folder string
+}{
+	{"ALLUSERSPROFILE", "ProgramData"},
+	{"APPDATA", "RoamingAppData"},
+	{"COMMONPROGRAMFILES", "ProgramFilesCommon"},
+	{"COMMONPROGRAMFILES(X86)", "ProgramFilesCommonX86"},
+	{"COMMONPROGRAMW6432", "ProgramFilesCommonX64"},
+	{"LOCALAPPDATA", "LocalAppData"},
+	{"PROGRAMDATA", "ProgramData"},
+	{"PROGRAMFILES", "ProgramFiles"},
+	{"PROGRAMFILES(X86)", "ProgramFilesX86"},
+	{"PROGRAMW6432", "ProgramFilesX64"},
+	{"PUBLIC", "Public"},
+	{"SYSTEMROOT", "Windows"},
+	{"USERPROFILE", "Profile"},
+	{"WINDIR", "Windows"},
+
+	{"AppData", "RoamingAppData"},
+	{"Cache", "InternetCache"},
+	{"Common Administrative Tools", "CommonAdminTools"},
+	{"Common AppData", "ProgramData"},
+	{"Common Desktop", "PublicDesktop"},
+	{"Common Documents", "PublicDocuments"},
+	{"Common Programs", "CommonPrograms"},
+	{"Common Start Menu", "CommonStartMenu"},
+	{"Common Startup", "CommonStartup"},
+	{"Common Templates", "CommonTemplates"},
+	{"CommonMusic", "PublicMusic"},
+	{"CommonPictures", "PublicPictures"},
+	{"CommonVideo", "PublicVideos"},
+	{"Administrative Tools", "AdminTools"},
+	{"CD Burning", "CDBurning"},
+	{"Local AppData", "LocalAppData"},
+	{"My Music", "Music"},
+	{"My Pictures", "Pictures"},
+	{"My Video", "Videos"},
+	{"OEM Links", "CommonOEMLinks"},
+	{"Personal", "Documents"},
+	{"Start Menu", "StartMenu"},
+}
+
+// lookupAlias returns the knownfolders name for a CSIDL name, CSIDL value
+// (decimal or 0x prefixed hex), environment variable or legacy registry value
+// name. Aliases are matched case insensitively, as Windows does for all of
+// these.
+func lookupAlias(name string) (folder string, ok bool) {
+	if value, ok := parseCSIDL(name); ok {
+		for _, c := range csidls {
+			if c.Value == value {
+				return c.Folder, true
+			}
+		}
+		return "", false
+	}
+	for _, c := range csidls {
+		if strings.EqualFold(c.Name, name) {
+			return c.Folder, true
+		}
+	}
+	for _, a := range nameAliases {
+		if strings.EqualFold(a.Name, name) {
+			return a.Folder, true
+		}
+	}
+	trimmed := strings.TrimPrefix(name, "FOLDERID_")
+	for _, a := range knownfolderAliases {
+		if a.Name == trimmed {
+			return a.Folder, true
+		}
+	}
+	return "", false
+}
+
+// parseCSIDL parses a CSIDL value, in decimal, or hex with a 0x prefix.
+// Leading zeros don't make it octal, as they would for strconv with base 0.
+func parseCSIDL(s string) (value int, ok bool) {
+	base := 10
+	if len(s) > 2 && strings.EqualFold(s[:2], "0x") {
+		s, base = s[2:], 16
+	}
+	v, err := strconv.ParseUint(s, base, 16)
+	return int(v), err == nil
+}
+
+// resolveFolder returns the knownfolders name for the given folder name,
+// FOLDERID_ constant name, GUID or alias.
+func resolveFolder(name string) (folder string, ok bool) {
+	if knownfolders[name] != nil {
+		return name, true
+	}
+	if trimmed := strings.TrimPrefix(name, "FOLDERID_"); knownfolders[trimmed] != nil {
+		return trimmed, true
+	}
+	if folder, ok := folderByGUID(name); ok {
+		return folder, true
+	}
+	return lookupAlias(name)
+}
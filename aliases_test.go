@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestLookupAlias(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Want string
+	}{
+		{"CSIDL_PERSONAL", "Documents"},
+		{"csidl_mymusic", "Music"},
+		{"5", "Documents"},
+		{"005", "Documents"},
+		{"026", "RoamingAppData"},
+		{"0x1A", "RoamingAppData"},
+		{"0X1a", "RoamingAppData"},
+		{"0x001c", "LocalAppData"},
+		{"APPDATA", "RoamingAppData"},
+		{"userprofile", "Profile"},
+		{"ProgramFiles(x86)", "ProgramFilesX86"},
+		{"Personal", "Documents"},
+		{"my video", "Videos"},
+		{"FOLDERID_OneDrive", "SkyDrive"},
+		{"OneDrive", "SkyDrive"},
+		// not CSIDL values
+		{"0x", ""},
+		{"0xZZ", ""},
+		{"0b101", ""},
+		{"0o5", ""},
+		{"1_0", ""},
+		{"+5", ""},
+		{"65536", ""},
+		// a value without a CSIDL
+		{"0x0F", ""},
+		{"NoSuchAlias", ""},
+	} {
+		got, ok := lookupAlias(test.Name)
+		if got != test.Want || ok != (test.Want != "") {
+			t.Errorf("lookupAlias(%q) is %q, %v, want %q", test.Name, got, ok, test.Want)
+		}
+	}
+}
+
+func TestResolveFolder(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Want string
+	}{
+		{"Documents", "Documents"},
+		{"FOLDERID_Documents", "Documents"},
+		{"{FDD39AD0-238F-46AF-ADB4-6C85480369C7}", "Documents"},
+		{"fdd39ad0-238f-46af-adb4-6c85480369c7", "Documents"},
+		{"CSIDL_APPDATA", "RoamingAppData"},
+		{"26", "RoamingAppData"},
+		{"LOCALAPPDATA", "LocalAppData"},
+		{"FOLDERID_NoSuchFolder", ""},
+	} {
+		got, ok := resolveFolder(test.Name)
+		if got != test.Want || ok != (test.Want != "") {
+			t.Errorf("resolveFolder(%q) is %q, %v, want %q", test.Name, got, ok, test.Want)
+		}
+	}
+}
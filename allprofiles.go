@@ -0,0 +1,370 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// userShellFoldersKey is the key of a user hive under which the locations of
+// the user's known folders are stored, as REG_EXPAND_SZ values. Per user
+// folders with a CSIDL are stored under their legacy name, e.g. "Personal"
+// for Documents, and others under their KNOWNFOLDERID.
+const userShellFoldersKey = `Software\Microsoft\Windows\CurrentVersion\Explorer\User Shell Folders`
+
+// defaultProfileName is the name --all-profiles reports the Default profile
+// by, which is also what --include and --exclude patterns match it with.
+const defaultProfileName = "Default"
+
+// ReadDefaultProfile returns the Default profile, which new profiles are
+// copied from, as recorded in the ProfileList key of software. It has no
+// SID.
+func ReadDefaultProfile(software RegistryKey) (Profile, error) {
+	list, err := software.OpenKey(profileListKey)
+	if err != nil {
+		return Profile{}, err
+	}
+	defer list.Close()
+	path, err := stringValue(list, "Default")
+	if err != nil {
+		return Profile{}, err
+	}
+	return Profile{Account: defaultProfileName, Path: path}, nil
+}
+
+// ProfileName returns the name a profile is reported by: its account if
+// known, otherwise its SID.
+func ProfileName(p Profile) string {
+	if p.Account != "" {
+		return p.Account
+	}
+	return p.SID
+}
+
+// ProfileUsername returns the username of the profile owner, for
+// {{.Username}} in manifests: the name part of the account if known,
+// otherwise the name of the profile directory.
+func ProfileUsername(p Profile) string {
+	name := p.Account
+	if name == "" {
+		name = p.Path
+	}
+	return name[strings.LastIndexAny(name, `\/`)+1:]
+}
+
+// ProfileFilter selects profiles by patterns matching their SID, account,
+// username or profile path, case insensitively, where * matches any text
+// and ? any single character.
+type ProfileFilter struct {
+	// Include, if not empty, are the patterns one of which a profile must
+	// match to be selected
+	Include []string
+	// Exclude are patterns which a selected profile must not match
+	Exclude []string
+}
+
+func (f ProfileFilter) match(patterns []string, p Profile) string {
+	for _, pattern := range patterns {
+		for _, s := range []string{p.SID, p.Account, ProfileUsername(p), p.Path} {
+			if s != "" && matchWildcard(pattern, s) {
+				return pattern
+			}
+		}
+	}
+	return ""
+}
+
+// matchWildcard reports whether s matches pattern, case insensitively. Unlike
+// path.Match, backslashes aren't escapes, since they separate the parts of
+// account names and paths.
+func matchWildcard(pattern, s string) bool {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.Replace(expr, `\*`, ".*", -1)
+	expr = strings.Replace(expr, `\?`, ".", -1)
+	return regexp.MustCompile("(?is)^" + expr + "$").MatchString(s)
+}
+
+// SkippedProfile is a profile that --all-profiles doesn't apply a manifest
+// to, and why.
+type SkippedProfile struct {
+	Profile Profile
+	Reason  string
+}
+
+// SelectProfiles returns those of profiles that a manifest should be applied
+// to: profiles of user accounts which filter selects, which aren't backup or
+// temporary profiles. The others are returned as skipped, with the reason.
+func SelectProfiles(profiles []Profile, filter ProfileFilter) (selected []Profile, skipped []SkippedProfile) {
+	for _, p := range profiles {
+		reason := ""
+		switch {
+		case p.SID != "" && wellKnownAccounts[profileSID(p.SID)] != "":
+			reason = "service account"
+		case p.SID != "" && !strings.HasPrefix(p.SID, "S-1-5-21-"):
+			reason = "not a user account"
+		case p.SID != profileSID(p.SID) || p.State&0x20000 != 0:
+			// PROFILE_THIS_IS_BAK
+			reason = "backup copy of a profile entry"
+		case p.State&0x800 != 0:
+			// PROFILE_TEMP_ASSIGNED
+			reason = "temporary profile"
+		case p.Path == "":
+			reason = "no ProfileImagePath"
+		case len(filter.Include) > 0 && filter.match(filter.Include, p) == "":
+			reason = "not included"
+		default:
+			if pattern := filter.match(filter.Exclude, p); pattern != "" {
+				reason = fmt.Sprintf(`excluded by "%v"`, pattern)
+			}
+		}
+		if reason != "" {
+			skipped = append(skipped, SkippedProfile{Profile: p, Reason: reason})
+			continue
+		}
+		selected = append(selected, p)
+	}
+	return
+}
+
+// ProfileHive returns the path of the user hive file of p, with environment
+// variables expanded by getenv.
+func ProfileHive(p Profile, getenv func(string) string) string {
+	return strings.TrimRight(expandWindowsEnv(p.Path, getenv), `\`) + `\NTUSER.DAT`
+}
+
+var windowsEnvPattern = regexp.MustCompile(`%([^%]+)%`)
+
+// expandWindowsEnv expands %VARIABLE% references in s with getenv. Unknown
+// variables are left as they are, as ExpandEnvironmentStrings does.
+func expandWindowsEnv(s string, getenv func(string) string) string {
+	return windowsEnvPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		if value := getenv(ref[1 : len(ref)-1]); value != "" {
+			return value
+		}
+		return ref
+	})
+}
+
+// userShellFolderNames returns the User Shell Folders value name for each
+// of folders, which must all be per user folders described in
+// descriptions.
+func userShellFolderNames(folders []string, descriptions []FolderDescription) (map[string]string, error) {
+	names := map[string]string{}
+	for _, folder := range folders {
+		d, ok := describeFolder(folder, descriptions)
+		if !ok {
+			return nil, fmt.Errorf("Folder %v is not described in the registry", folder)
+		}
+		if d.Category != KF_CATEGORY_PERUSER {
+			return nil, fmt.Errorf("Folder %v is a %v folder, not a per user one, so can't be set for each profile", folder, d.CategoryName())
+		}
+		names[folder] = shellFolderValueName(folder, d)
+	}
+	return names, nil
+}
+
+// describeFolder returns the description of folder in descriptions, or if
+// it's a built-in folder missing from them (e.g. because the hive is from an
+// older version of Windows), how Windows 10 describes it.
+func describeFolder(folder string, descriptions []FolderDescription) (FolderDescription, bool) {
+	for _, d := range descriptions {
+		if d.GUID == *knownfolders[folder] {
+			return d, true
+		}
+	}
+	if d, ok := knownfolderDescriptions[folder]; ok {
+		return FolderDescription{GUID: *knownfolders[folder], Name: d.Name, Category: d.Category}, true
+	}
+	return FolderDescription{}, false
+}
+
+// shellFolderValueName returns the name of the User Shell Folders value
+// which has the location of folder, described by d: its legacy name if it
+// has a CSIDL, otherwise its KNOWNFOLDERID.
+func shellFolderValueName(folder string, d FolderDescription) string {
+	for _, c := range csidls {
+		if c.Folder == folder {
+			return d.Name
+		}
+	}
+	return d.GUID.String()
+}
+
+// ProfilePlan is what applying a manifest to a profile will set.
+type ProfilePlan struct {
+	Profile Profile
+	// Hive is the user hive file of the profile
+	Hive    string
+	Entries []ManifestEntry
+	// Values are the values to set under userShellFoldersKey
+	Values []RegistryValue
+}
+
+// PlanProfiles renders manifest for each of profiles. The locations of any
+// folders the manifest refers to, other than those it sets, are read from
+// the profile's own user hive, as returned by openHive, which returns nil
+// if it isn't available. Profiles the manifest can't be rendered for are
+// returned as skipped.
+func PlanProfiles(profiles []Profile, manifest *Manifest, descriptions []FolderDescription, getenv func(string) string, openHive func(p Profile, file string) RegistryKey) (plans []ProfilePlan, skipped []SkippedProfile, err error) {
+	entries, err := manifest.Entries()
+	if err != nil {
+		return nil, nil, err
+	}
+	folders := make([]string, len(entries))
+	for i, entry := range entries {
+		folders[i] = entry.Folder
+	}
+	names, err := userShellFolderNames(folders, descriptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, p := range profiles {
+		plan := ProfilePlan{Profile: p, Hive: ProfileHive(p, getenv)}
+		hive := openHive(p, plan.Hive)
+		plan.Entries, err = manifest.Render(TemplateData{Username: ProfileUsername(p)})
+		if err == nil {
+			plan.Entries, err = manifest.Expand(plan.Entries, profileFolderLookup(p, hive, descriptions))
+		}
+		if hive != nil {
+			hive.Close()
+		}
+		if err != nil {
+			// keep to a line per profile
+			reason := strings.Replace(err.Error(), "\n", " ", -1)
+			skipped = append(skipped, SkippedProfile{Profile: p, Reason: reason})
+			continue
+		}
+		for _, entry := range plan.Entries {
+			value := stringRegistryValue(names[entry.Folder], entry.Location)
+			value.Type = REG_EXPAND_SZ
+			plan.Values = append(plan.Values, value)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, skipped, nil
+}
+
+// profileFolderLookup returns a FolderLookup for the folders of profile p,
+// with user hive hive (or nil): the profile folder is the profile path,
+// and other folders are located by the User Shell Folders values of the
+// hive.
+func profileFolderLookup(p Profile, hive RegistryKey, descriptions []FolderDescription) FolderLookup {
+	return func(folder string) (string, error) {
+		if folder == "Profile" {
+			return p.Path, nil
+		}
+		if hive == nil {
+			return "", fmt.Errorf("The user hive of the profile can't be read")
+		}
+		names, err := userShellFolderNames([]string{folder}, descriptions)
+		if err != nil {
+			return "", err
+		}
+		key, err := hive.OpenKey(userShellFoldersKey)
+		if err != nil {
+			return "", err
+		}
+		defer key.Close()
+		location, err := stringValue(key, names[folder])
+		if err != nil {
+			return "", err
+		}
+		return expandWindowsEnv(location, func(name string) string {
+			if strings.EqualFold(name, "USERPROFILE") {
+				return p.Path
+			}
+			return ""
+		}), nil
+	}
+}
+
+// WriteProfilePlans writes what applying a manifest to each profile would
+// do, followed by the profiles that are skipped and why.
+func WriteProfilePlans(w io.Writer, plans []ProfilePlan, skipped []SkippedProfile) error {
+	for _, plan := range plans {
+		source := plan.Hive
+		if plan.Profile.Loaded != nil && *plan.Profile.Loaded {
+			source = `HKEY_USERS\` + plan.Profile.SID
+		}
+		fmt.Fprintf(w, "%v (%v):\n", ProfileName(plan.Profile), source)
+		for i, entry := range plan.Entries {
+			fmt.Fprintf(w, "  %v=%v (%v)\n", entry.Folder, entry.Location, plan.Values[i].Name)
+		}
+	}
+	return WriteSkippedProfiles(w, skipped)
+}
+
+// WriteSkippedProfiles writes a line for each skipped profile, with the
+// reason it was skipped.
+func WriteSkippedProfiles(w io.Writer, skipped []SkippedProfile) error {
+	for _, s := range skipped {
+		if _, err := fmt.Fprintf(w, "%v: SKIPPED: %v\n", ProfileName(s.Profile), s.Reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyProfilePlans sets the folders of each plan with apply, via the
+// backend that open returns for its profile, continuing past failures, and
+// returns the outcome for each profile. close is called once all its folders
+// are set, and any error it returns is reported as the profile's, unless
+// apply failed.
+func ApplyProfilePlans(plans []ProfilePlan, open func(plan ProfilePlan) (backend Backend, close func() error, err error), apply func(plan ProfilePlan, backend Backend) ([]FolderResult, error)) []UserResult {
+	results := make([]UserResult, len(plans))
+	for i, plan := range plans {
+		results[i].Username = ProfileName(plan.Profile)
+		backend, close, err := open(plan)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Folders, err = apply(plan, backend)
+		if closeErr := close(); err == nil {
+			err = closeErr
+		}
+		results[i].Err = err
+	}
+	return results
+}
+
+// ProfileBackend gets and sets the per user folders of a profile, which
+// needn't be logged on, as the User Shell Folders values of its user hive.
+// Open opens the hive, or returns nil if it can't be read, and SetValues sets
+// values under userShellFoldersKey in it.
+type ProfileBackend struct {
+	Profile      Profile
+	Descriptions []FolderDescription
+	Open         func() RegistryKey
+	SetValues    func(values []RegistryValue) error
+}
+
+func (b *ProfileBackend) GetFolder(folder string) (string, error) {
+	hive := b.Open()
+	if hive != nil {
+		defer hive.Close()
+	}
+	return profileFolderLookup(b.Profile, hive, b.Descriptions)(folder)
+}
+
+func (b *ProfileBackend) SetFolder(folder, location string) error {
+	names, err := userShellFolderNames([]string{folder}, b.Descriptions)
+	if err != nil {
+		return err
+	}
+	value := stringRegistryValue(names[folder], location)
+	value.Type = REG_EXPAND_SZ
+	return b.SetValues([]RegistryValue{value})
+}
+
+// openProfileHiveFile opens the user hive file of a profile which isn't
+// loaded, or returns nil if the file can't be read, e.g. because it is in
+// use, or the path is of another machine.
+func openProfileHiveFile(file string) RegistryKey {
+	hive, err := openHiveRoot(file)
+	if err != nil {
+		return nil
+	}
+	return hive
+}
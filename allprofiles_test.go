@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testSoftwareHive returns a SOFTWARE hive with the Default profile and those
+// of SYSTEM, alice, bob, a backup of bob's and a temporary one, and the
+// descriptions of a few built-in folders.
+func testSoftwareHive(t *testing.T) RegistryKey {
+	file := writeTestHive(t, filepath.Join(t.TempDir(), "SOFTWARE"), testKey{
+		"Microsoft": testKey{
+			"Windows NT": testKey{"CurrentVersion": testKey{"ProfileList": testKey{
+				"Default":                 expandString(`%SystemDrive%\Users\Default`),
+				"S-1-5-18":                testKey{"ProfileImagePath": expandString(`%systemroot%\system32\config\systemprofile`), "State": uint32(0)},
+				"S-1-5-21-1-2-3-1001":     testKey{"ProfileImagePath": expandString(`C:\Users\alice`), "State": uint32(0)},
+				"S-1-5-21-1-2-3-1002":     testKey{"ProfileImagePath": expandString(`C:\Users\bob`), "State": uint32(0)},
+				"S-1-5-21-1-2-3-1002.bak": testKey{"ProfileImagePath": expandString(`C:\Users\bob`), "State": uint32(0)},
+				"S-1-5-21-1-2-3-1003":     testKey{"ProfileImagePath": expandString(`C:\Users\TEMP`), "State": uint32(0x800)},
+			}}},
+			"Windows": testKey{"CurrentVersion": testKey{"Explorer": testKey{"FolderDescriptions": testKey{
+				"{FDD39AD0-238F-46AF-ADB4-6C85480369C7}": testKey{"Name": "Personal", "Category": uint32(KF_CATEGORY_PERUSER)},
+				"{4BD8D571-6D19-48D3-BE97-422220080E43}": testKey{"Name": "My Music", "Category": uint32(KF_CATEGORY_PERUSER)},
+				"{18989B1D-99B5-455B-841C-AB7C74E4DDFC}": testKey{"Name": "My Video", "Category": uint32(KF_CATEGORY_PERUSER)},
+				"{374DE290-123F-4565-9164-39C4925E467B}": testKey{"Name": "Downloads", "Category": uint32(KF_CATEGORY_PERUSER)},
+				"{ED4824AF-DCE4-45A8-81E2-FC7965083634}": testKey{"Name": "Common Documents", "Category": uint32(KF_CATEGORY_COMMON)},
+				"{5E6C858F-0E22-4760-9AFE-EA3317B67173}": testKey{"Name": "Profile", "Category": uint32(KF_CATEGORY_FIXED)},
+			}}}},
+		},
+	})
+	hive, err := OpenHive(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hive.Root()
+}
+
+// testUserHive returns the file of a user hive with the location of Videos.
+func testUserHive(t *testing.T, videos string) string {
+	return writeTestHive(t, filepath.Join(t.TempDir(), "NTUSER.DAT"), testKey{
+		"Software": testKey{"Microsoft": testKey{"Windows": testKey{"CurrentVersion": testKey{"Explorer": testKey{
+			"User Shell Folders": testKey{"My Video": expandString(videos)},
+		}}}}},
+	})
+}
+
+func TestPlanProfiles(t *testing.T) {
+	software := testSoftwareHive(t)
+	profiles, err := ReadProfileList(software)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultProfile, err := ReadDefaultProfile(software)
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptions, err := ReadFolderDescriptions(software)
+	if err != nil {
+		t.Fatal(err)
+	}
+	selected, skipped := SelectProfiles(append([]Profile{defaultProfile}, profiles...), ProfileFilter{})
+	hives := map[string]string{
+		defaultProfileName: testUserHive(t, `%USERPROFILE%\Videos`),
+		"alice":            testUserHive(t, `E:\Videos\alice`),
+	}
+	openHive := func(p Profile, file string) RegistryKey {
+		hive := hives[ProfileUsername(p)]
+		if hive == "" {
+			return nil
+		}
+		return openProfileHiveFile(hive)
+	}
+	getenv := func(name string) string {
+		if name == "SystemDrive" {
+			return "C:"
+		}
+		return ""
+	}
+	manifest := &Manifest{Folders: map[string]string{
+		"Documents": `D:\{{.Username}}\Docs`,
+		"Downloads": `{Documents}\dl`,
+		"Music":     `{Videos}\Music`,
+	}}
+	plans, unplanned, err := PlanProfiles(selected, manifest, descriptions, getenv, openHive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := WriteProfilePlans(&b, plans, append(skipped, unplanned...)); err != nil {
+		t.Fatal(err)
+	}
+	want := `Default (C:\Users\Default\NTUSER.DAT):
+  Documents=D:\Default\Docs (Personal)
+  Downloads=D:\Default\Docs\dl ({374DE290-123F-4565-9164-39C4925E467B})
+  Music=%SystemDrive%\Users\Default\Videos\Music (My Music)
+S-1-5-21-1-2-3-1001 (C:\Users\alice\NTUSER.DAT):
+  Documents=D:\alice\Docs (Personal)
+  Downloads=D:\alice\Docs\dl ({374DE290-123F-4565-9164-39C4925E467B})
+  Music=E:\Videos\alice\Music (My Music)
+NT AUTHORITY\SYSTEM: SKIPPED: service account
+S-1-5-21-1-2-3-1002.bak: SKIPPED: backup copy of a profile entry
+S-1-5-21-1-2-3-1003: SKIPPED: temporary profile
+S-1-5-21-1-2-3-1002: SKIPPED: Could not retrieve location of Videos (via Music -> Videos): The user hive of the profile can't be read
+`
+	if b.String() != want {
+		t.Errorf("Plans:\n%v\nwant:\n%v", b.String(), want)
+	}
+	for _, plan := range plans {
+		for _, value := range plan.Values {
+			if value.Type != REG_EXPAND_SZ {
+				t.Errorf("Value %v of %v has type %v, want REG_EXPAND_SZ", value.Name, ProfileName(plan.Profile), value.Type)
+			}
+		}
+	}
+
+	// common folders can't be set per profile
+	manifest = &Manifest{Folders: map[string]string{"PublicDocuments": `D:\Public`}}
+	if _, _, err := PlanProfiles(selected, manifest, descriptions, getenv, openHive); err == nil || !strings.Contains(err.Error(), "not a per user one") {
+		t.Errorf("Planning a common folder returned %v", err)
+	}
+}
+
+func TestSelectProfiles(t *testing.T) {
+	profiles := []Profile{
+		{Account: defaultProfileName, Path: `C:\Users\Default`},
+		{SID: "S-1-5-21-1-2-3-1001", Account: `HOST\alice`, Path: `C:\Users\alice`},
+		{SID: "S-1-5-21-1-2-3-1002", Path: `C:\Users\task_1`},
+		{SID: "S-1-5-21-1-2-3-1003", Path: `C:\Users\task_2`},
+	}
+	for _, test := range []struct {
+		Filter   ProfileFilter
+		Selected []string
+	}{
+		{ProfileFilter{}, []string{defaultProfileName, `HOST\alice`, "S-1-5-21-1-2-3-1002", "S-1-5-21-1-2-3-1003"}},
+		{ProfileFilter{Include: []string{"task_*"}}, []string{"S-1-5-21-1-2-3-1002", "S-1-5-21-1-2-3-1003"}},
+		{ProfileFilter{Exclude: []string{"Default", "*-1003"}}, []string{`HOST\alice`, "S-1-5-21-1-2-3-1002"}},
+		{ProfileFilter{Include: []string{`host\ALICE`, `C:\Users\task_?`}, Exclude: []string{"task_2"}}, []string{`HOST\alice`, "S-1-5-21-1-2-3-1002"}},
+	} {
+		selected, _ := SelectProfiles(profiles, test.Filter)
+		names := []string{}
+		for _, p := range selected {
+			names = append(names, ProfileName(p))
+		}
+		if !reflect.DeepEqual(names, test.Selected) {
+			t.Errorf("%+v selected %q, want %q", test.Filter, names, test.Selected)
+		}
+	}
+}
+
+func TestProfileBackend(t *testing.T) {
+	descriptions, err := ReadFolderDescriptions(testSoftwareHive(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var set []RegistryValue
+	backend := &ProfileBackend{
+		Profile:      Profile{SID: "S-1-5-21-1-2-3-1001", Path: `C:\Users\alice`},
+		Descriptions: descriptions,
+		Open:         func() RegistryKey { return openProfileHiveFile(testUserHive(t, `%USERPROFILE%\Videos`)) },
+		SetValues: func(values []RegistryValue) error {
+			set = append(set, values...)
+			return nil
+		},
+	}
+	for folder, want := range map[string]string{"Videos": `C:\Users\alice\Videos`, "Profile": `C:\Users\alice`} {
+		if location, err := backend.GetFolder(folder); err != nil || location != want {
+			t.Errorf("GetFolder(%v) returned %q, %v, want %q", folder, location, err, want)
+		}
+	}
+	if err := backend.SetFolder("Documents", `D:\%USERNAME%\Docs`); err != nil {
+		t.Fatal(err)
+	}
+	want := stringRegistryValue("Personal", `D:\%USERNAME%\Docs`)
+	want.Type = REG_EXPAND_SZ
+	if !reflect.DeepEqual(set, []RegistryValue{want}) {
+		t.Errorf("SetFolder set %+v, want %+v", set, want)
+	}
+	if err := backend.SetFolder("PublicDocuments", `D:\Public`); err == nil {
+		t.Errorf("Setting a common folder succeeded")
+	}
+}
+
+func TestApplyProfilePlans(t *testing.T) {
+	entries := []ManifestEntry{{Folder: "Documents", Location: `D:\Docs`}, {Folder: "Music", Location: `D:\Music`}}
+	plans := []ProfilePlan{
+		{Profile: Profile{Account: "alice"}, Entries: entries},
+		{Profile: Profile{Account: "bob"}, Entries: entries},
+		{Profile: Profile{Account: "carol"}, Entries: entries},
+		{Profile: Profile{Account: "dave"}, Entries: entries},
+	}
+	backends := map[string]*fakeBackend{"alice": newFakeBackend(nil), "carol": newFakeBackend(nil), "dave": newFakeBackend(nil)}
+	backends["alice"].Errors["Music"] = errors.New("Access is denied.")
+	dir := t.TempDir()
+	// dave has an interrupted apply
+	if _, err := BeginJournal(journalFile(dir, "dave"), "apply", "dave", "-u dave", backends["dave"], entries); err != nil {
+		t.Fatal(err)
+	}
+	journaled := []string{}
+	backends["carol"].OnSet = func(folder, location string) {
+		if _, err := LoadJournal(journalFile(dir, "carol")); err != nil {
+			t.Errorf("Set %v for carol without a journal: %v", folder, err)
+		}
+		journaled = append(journaled, folder)
+	}
+	closed := []string{}
+	results := ApplyProfilePlans(plans, func(plan ProfilePlan) (Backend, func() error, error) {
+		name := plan.Profile.Account
+		if backends[name] == nil {
+			return nil, nil, errors.New("Could not load the user hive")
+		}
+		return backends[name], func() error {
+			closed = append(closed, name)
+			if name == "carol" {
+				return errors.New("Could not unload the user hive")
+			}
+			return nil
+		}, nil
+	}, func(plan ProfilePlan, backend Backend) ([]FolderResult, error) {
+		scope := plan.Profile.Account
+		return ApplyJournaled(journalFile(dir, scope), scope, "-u "+scope, nil, backend, plan.Entries)
+	})
+	var b bytes.Buffer
+	if failed := WriteApplyReport(&b, results); failed != 4 {
+		t.Errorf("%v profiles failed, want 4", failed)
+	}
+	want := `alice: FAILED
+  Music=D:\Music: Access is denied.
+bob: FAILED: Could not load the user hive
+carol: FAILED: Could not unload the user hive
+dave: FAILED: apply for dave, started `
+	if !strings.HasPrefix(b.String(), want) || !strings.Contains(b.String(), "To recover, run: knownfolder recover --rollback|--complete -u dave\n") {
+		t.Errorf("Report:\n%v\nwant:\n%v...", b.String(), want)
+	}
+	if !reflect.DeepEqual(closed, []string{"alice", "carol", "dave"}) {
+		t.Errorf("Closed the backends of %q, want alice, carol and dave", closed)
+	}
+	if !reflect.DeepEqual(journaled, []string{"Documents", "Music"}) {
+		t.Errorf("Journaled %q for carol", journaled)
+	}
+	if len(backends["dave"].Calls) != 0 {
+		t.Errorf("Set %q for dave, despite the interrupted apply", backends["dave"].Calls)
+	}
+	if journals, err := IncompleteJournals(dir); err != nil || len(journals) != 1 || journals[0].Scope != "dave" {
+		t.Errorf("Journals left are %+v (%v), want only dave's", journals, err)
+	}
+	if calls := backends["carol"].Calls; !reflect.DeepEqual(calls, []string{`Documents=D:\Docs`, `Music=D:\Music`}) {
+		t.Errorf("Set %q for carol", calls)
+	}
+}
+
+func TestDescribeFolder(t *testing.T) {
+	registered := FolderDescription{GUID: *knownfolders["Music"], Name: "Music", Category: KF_CATEGORY_COMMON}
+	for _, test := range []struct {
+		Folder string
+		Want   FolderDescription
+		OK     bool
+	}{
+		// the registry wins
+		{"Music", registered, true},
+		// built-in folders missing from the registry fall back to the table
+		{"Videos", FolderDescription{GUID: *knownfolders["Videos"], Name: "My Video", Category: KF_CATEGORY_PERUSER}, true},
+		{"ProgramData", FolderDescription{GUID: *knownfolders["ProgramData"], Name: "Common AppData", Category: KF_CATEGORY_FIXED}, true},
+	} {
+		got, ok := describeFolder(test.Folder, []FolderDescription{registered})
+		if got != test.Want || ok != test.OK {
+			t.Errorf("describeFolder(%v) returned %+v, %v, want %+v, %v", test.Folder, got, ok, test.Want, test.OK)
+		}
+	}
+	unregistered := GUID{0x01234567, 0x89AB, 0xCDEF, [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}
+	knownfolders["Unregistered"] = &unregistered
+	defer delete(knownfolders, "Unregistered")
+	if got, ok := describeFolder("Unregistered", nil); ok {
+		t.Errorf("describeFolder(Unregistered) returned %+v", got)
+	}
+}
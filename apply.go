@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// FolderResult is the outcome of setting a single folder.
+type FolderResult struct {
+	Folder   string
+	Location string
+	Err      error
+}
+
+// ApplyEntries sets each of the given folder locations via backend,
+// continuing past any failures.
+func ApplyEntries(backend Backend, entries []ManifestEntry) (results []FolderResult) {
+	for _, entry := range entries {
+		results = append(results, FolderResult{
+			Folder:   entry.Folder,
+			Location: entry.Location,
+			Err:      backend.SetFolder(entry.Folder, entry.Location),
+		})
+	}
+	return
+}
+
+// UserCredentials is a row of a users CSV file.
+type UserCredentials struct {
+	Username string
+	Password string
+}
+
+// LoadUsers reads a CSV file of usernames and passwords, one user per row,
+// optionally with a "username,password" header row. Lines starting with # are
+// ignored.
+func LoadUsers(file string) ([]UserCredentials, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+	users := []UserCredentials{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Could not read users file %v:\n%v", file, err)
+		}
+		if len(users) == 0 && strings.EqualFold(record[0], "username") && strings.EqualFold(record[1], "password") {
+			continue
+		}
+		users = append(users, UserCredentials{Username: record[0], Password: record[1]})
+	}
+	return users, nil
+}
+
+// UserResult is the outcome of applying a manifest for a single user. Err is
+// set if the manifest could not be applied at all, e.g. because the user
+// could not be logged on.
+type UserResult struct {
+	Username string
+	Folders  []FolderResult
+	Err      error
+}
+
+// Failed reports whether anything went wrong for this user.
+func (r UserResult) Failed() bool {
+	if r.Err != nil {
+		return true
+	}
+	for _, f := range r.Folders {
+		if f.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Logon returns a Backend for the given user, and a function to log them off
+// again once it is no longer needed.
+type Logon func(user UserCredentials) (backend Backend, logoff func(), err error)
+
+// UserApply sets the folder locations entries for user via backend, e.g.
+// with ApplyJournaled, returning the outcome for each folder, or an error if
+// they couldn't be set at all.
+type UserApply func(user UserCredentials, backend Backend, entries []ManifestEntry) ([]FolderResult, error)
+
+// ApplyToUsers applies manifest, rendered separately for each user, with
+// apply, using up to workers concurrent logons. Failures for one user don't
+// affect the others. Results are returned in the same order as users.
+func ApplyToUsers(users []UserCredentials, manifest *Manifest, workers int, logon Logon, apply UserApply) []UserResult {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]UserResult, len(users))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = applyToUser(users[i], manifest, logon, apply)
+			}
+		}()
+	}
+	for i := range users {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func applyToUser(user UserCredentials, manifest *Manifest, logon Logon, apply UserApply) UserResult {
+	result := UserResult{Username: user.Username}
+	entries, err := manifest.Render(TemplateData{Username: user.Username})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	backend, logoff, err := logon(user)
+	if err != nil {
+		result.Err = fmt.Errorf("Could not log on: %v", err)
+		return result
+	}
+	defer logoff()
+	entries, err = manifest.Expand(entries, backend.GetFolder)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Folders, result.Err = apply(user, backend, entries)
+	return result
+}
+
+// WriteApplyReport writes a summary line per user, followed by a line per
+// folder that could not be set, and returns the number of users with
+// failures.
+func WriteApplyReport(w io.Writer, results []UserResult) (failed int) {
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			fmt.Fprintf(w, "%v: FAILED: %v\n", r.Username, r.Err)
+		case r.Failed():
+			fmt.Fprintf(w, "%v: FAILED\n", r.Username)
+		default:
+			fmt.Fprintf(w, "%v: OK (%v folders set)\n", r.Username, len(r.Folders))
+		}
+		for _, f := range r.Folders {
+			if f.Err != nil {
+				fmt.Fprintf(w, "  %v=%v: %v\n", f.Folder, f.Location, f.Err)
+			}
+		}
+		if r.Failed() {
+			failed++
+		}
+	}
+	return
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestApplyToUsers(t *testing.T) {
+	users := []UserCredentials{{"alice", "a"}, {"bob", "wrong"}, {"carol", "c"}, {"dave", "d"}}
+	manifest := &Manifest{Folders: map[string]string{
+		"Documents": `D:\{{.Username}}\Docs`,
+		"Music":     `{Documents}\Music`,
+	}}
+	var mutex sync.Mutex
+	loggedOff := []string{}
+	logon := func(user UserCredentials) (Backend, func(), error) {
+		if user.Password != user.Username[:1] {
+			return nil, nil, errors.New("The user name or password is incorrect.")
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		backend := newFakeBackend(nil)
+		if user.Username == "carol" {
+			backend.Errors["Music"] = errors.New("Access is denied.")
+		}
+		return backend, func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+			loggedOff = append(loggedOff, user.Username)
+		}, nil
+	}
+	applied := []string{}
+	apply := func(user UserCredentials, backend Backend, entries []ManifestEntry) ([]FolderResult, error) {
+		mutex.Lock()
+		applied = append(applied, user.Username)
+		mutex.Unlock()
+		if user.Username == "dave" {
+			return nil, errors.New("dave has an interrupted apply")
+		}
+		return ApplyEntries(backend, entries), nil
+	}
+	results := ApplyToUsers(users, manifest, 2, logon, apply)
+	want := []UserResult{
+		{Username: "alice", Folders: []FolderResult{{"Documents", `D:\alice\Docs`, nil}, {"Music", `D:\alice\Docs\Music`, nil}}},
+		{Username: "bob", Err: errors.New("Could not log on: The user name or password is incorrect.")},
+		{Username: "carol", Folders: []FolderResult{{"Documents", `D:\carol\Docs`, nil}, {"Music", `D:\carol\Docs\Music`, errors.New("Access is denied.")}}},
+		{Username: "dave", Err: errors.New("dave has an interrupted apply")},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Results are %+v, want %+v", results, want)
+	}
+	sort.Strings(applied)
+	sort.Strings(loggedOff)
+	if !reflect.DeepEqual(applied, []string{"alice", "carol", "dave"}) || !reflect.DeepEqual(loggedOff, applied) {
+		t.Errorf("Applied for %q and logged off %q, want alice, carol and dave", applied, loggedOff)
+	}
+}
+
+func TestLoadUsers(t *testing.T) {
+	dir := t.TempDir()
+	for _, test := range []struct {
+		CSV  string
+		Want []UserCredentials
+		Err  string
+	}{
+		{
+			CSV:  "username,password\n# a comment\nalice, secret\n\"bob\",\"p,w\"\n",
+			Want: []UserCredentials{{"alice", "secret"}, {"bob", "p,w"}},
+		},
+		{
+			CSV:  "Username,Password\n",
+			Want: []UserCredentials{},
+		},
+		{
+			CSV: "alice,secret,extra\n",
+			Err: "wrong number of fields",
+		},
+	} {
+		file := filepath.Join(dir, "users.csv")
+		if err := ioutil.WriteFile(file, []byte(test.CSV), 0644); err != nil {
+			t.Fatal(err)
+		}
+		users, err := LoadUsers(file)
+		switch {
+		case test.Err != "":
+			if err == nil || !strings.Contains(err.Error(), test.Err) {
+				t.Errorf("Loading %q returned %v, want %v", test.CSV, err, test.Err)
+			}
+		case err != nil:
+			t.Errorf("Loading %q: %v", test.CSV, err)
+		case !reflect.DeepEqual(users, test.Want):
+			t.Errorf("Loading %q returned %+v, want %+v", test.CSV, users, test.Want)
+		}
+	}
+}
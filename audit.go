@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+// AuditRecord is a single line of the audit log.
+type AuditRecord struct {
+	Time     time.Time `json:"time"`
+	OSUser   string    `json:"osUser"`
+	Scope    string    `json:"scope"`
+	Folder   string    `json:"folder"`
+	GUID     string    `json:"guid"`
+	Old      string    `json:"old"`
+	New      string    `json:"new"`
+	Result   string    `json:"result"`
+	Error    string    `json:"error,omitempty"`
+	Version  string    `json:"version"`
+	Command  string    `json:"command"`
+	Hostname string    `json:"hostname"`
+}
+
+// AuditLog appends AuditRecords to a JSON Lines file. Each record is written
+// with a single write to a file opened in append mode, which both Windows and
+// POSIX systems perform atomically with respect to other appending writers,
+// so that concurrent knownfolder processes can share the same log.
+type AuditLog struct {
+	File string
+	// Command is recorded against every record, e.g. "set" or "enforce".
+	Command string
+	mutex   sync.Mutex
+}
+
+func (a *AuditLog) Write(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	f, err := os.OpenFile(a.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(line)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// AuditedBackend records every SetFolder call made through it, successful or
+// not, in Log. Scope describes the target of Backend, e.g. "default user".
+type AuditedBackend struct {
+	Backend
+	Log   *AuditLog
+	Scope string
+}
+
+func (b *AuditedBackend) SetFolder(folder, location string) error {
+	old, err := b.Backend.GetFolder(folder)
+	if err != nil {
+		old = ""
+	}
+	err = b.Backend.SetFolder(folder, location)
+	if logErr := b.Log.Record(b.Scope, folder, old, location, err); logErr != nil {
+		if err != nil {
+			return fmt.Errorf("%v\nCould not write audit log %v: %v", err, b.Log.File, logErr)
+		}
+		return fmt.Errorf("Folder %v was set to %v, but could not write audit log %v: %v", folder, location, b.Log.File, logErr)
+	}
+	return err
+}
+
+// Record writes a record of the change of folder in scope from old to
+// location, which failed with err, unless it is nil.
+func (a *AuditLog) Record(scope, folder, old, location string, err error) error {
+	record := AuditRecord{
+		Time:    time.Now().UTC(),
+		OSUser:  currentUsername(),
+		Scope:   scope,
+		Folder:  folder,
+		GUID:    knownfolders[folder].String(),
+		Old:     old,
+		New:     location,
+		Result:  "success",
+		Version: version,
+		Command: a.Command,
+	}
+	record.Hostname, _ = os.Hostname()
+	if err != nil {
+		record.Result = "failure"
+		record.Error = err.Error()
+	}
+	return a.Write(record)
+}
+
+func currentUsername() string {
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return u.Username
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// readAuditLog returns the records of the audit log file, failing the test
+// unless every line is a whole JSON record.
+func readAuditLog(t *testing.T, file string) []AuditRecord {
+	t.Helper()
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records := []AuditRecord{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Line %v of the audit log is not a JSON record: %v\n%s", len(records)+1, err, scanner.Bytes())
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestAuditLogConcurrentWrites(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit.log")
+	// separate AuditLogs don't share a mutex, like separate processes
+	logs := []*AuditLog{{File: file, Command: "set"}, {File: file, Command: "enforce"}}
+	const writers, records = 8, 50
+	// long enough that a line couldn't be written in one go unless appends
+	// are atomic
+	location := `D:\` + strings.Repeat("x", 4096)
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < records; i++ {
+				err := logs[w%len(logs)].Record(fmt.Sprintf("user %v", w), "Documents", "", location, nil)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	got := readAuditLog(t, file)
+	if len(got) != writers*records {
+		t.Fatalf("Audit log has %v records, want %v", len(got), writers*records)
+	}
+	counts := map[string]int{}
+	for _, record := range got {
+		if record.New != location {
+			t.Fatalf("Record of %v has a corrupt location", record.Scope)
+		}
+		counts[record.Scope]++
+	}
+	for w := 0; w < writers; w++ {
+		if n := counts[fmt.Sprintf("user %v", w)]; n != records {
+			t.Errorf("Audit log has %v records of user %v, want %v", n, w, records)
+		}
+	}
+}
+
+func TestAuditedBackend(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit.log")
+	fake := newFakeBackend(map[string]string{"Documents": `C:\Users\pete\Documents`})
+	fake.Errors["Music"] = errors.New("Access is denied.")
+	backend := &AuditedBackend{Backend: fake, Log: &AuditLog{File: file, Command: "apply"}, Scope: "user pete"}
+	if err := backend.SetFolder("Documents", `D:\Docs`); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.SetFolder("Music", `D:\Music`); err == nil || err.Error() != "Access is denied." {
+		t.Errorf("Setting Music returned %v", err)
+	}
+	records := readAuditLog(t, file)
+	if len(records) != 2 {
+		t.Fatalf("Audit log has %v records, want 2", len(records))
+	}
+	for i, want := range []AuditRecord{
+		{Scope: "user pete", Folder: "Documents", GUID: knownfolders["Documents"].String(), Old: `C:\Users\pete\Documents`, New: `D:\Docs`, Result: "success", Command: "apply", Version: version},
+		{Scope: "user pete", Folder: "Music", GUID: knownfolders["Music"].String(), New: `D:\Music`, Result: "failure", Error: "Access is denied.", Command: "apply", Version: version},
+	} {
+		got := records[i]
+		if got.Time.IsZero() {
+			t.Errorf("Record %v has no time", i+1)
+		}
+		got.Time, got.OSUser, got.Hostname = want.Time, "", ""
+		if got != want {
+			t.Errorf("Record %v is %+v, want %+v", i+1, got, want)
+		}
+	}
+}
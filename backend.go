@@ -0,0 +1,42 @@
+package main
+
+import "fmt"
+
+// Backend gets and sets known folder locations for a single target, such as
+// the user running knownfolder, or the default user profile. Folder names are
+// always knownfolders names, never aliases.
+type Backend interface {
+	GetFolder(folder string) (location string, err error)
+	SetFolder(folder, location string) (err error)
+}
+
+// Folder is the JSON representation of a known folder. Location is omitted
+// when the folder location has not been queried.
+type Folder struct {
+	Name     string `json:"name"`
+	GUID     string `json:"guid"`
+	Location string `json:"location,omitempty"`
+}
+
+// UnknownFolderError is returned when a folder name (or alias) does not
+// match any entry in knownfolders.
+type UnknownFolderError string
+
+func (e UnknownFolderError) Error() string {
+	return fmt.Sprintf(`Unknown folder "%v"`, string(e))
+}
+
+// LookupFolder resolves the given folder name or alias, and returns its
+// location according to backend.
+func LookupFolder(backend Backend, name string) (folder Folder, err error) {
+	canonical, ok := resolveFolder(name)
+	if !ok {
+		return Folder{}, UnknownFolderError(name)
+	}
+	folder = Folder{
+		Name: canonical,
+		GUID: knownfolders[canonical].String(),
+	}
+	folder.Location, err = backend.GetFolder(canonical)
+	return
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The completion scripts all call back into the hidden __complete command, so
+// that the candidates offered always match the binary that is installed:
+//
+//	knownfolder __complete N WORD1 ... WORDN [CURRENT]
+//
+// where WORD1 ... WORDN are the words already on the command line after the
+// program name, and CURRENT is the (possibly omitted, if empty) word being
+// completed. Candidates are written to standard out, one per line. The count
+// is passed explicitly since some shells drop empty arguments.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for knownfolder
+# Install with: knownfolder completion bash > /etc/bash_completion.d/knownfolder
+
+_knownfolder() {
+    local IFS=$'\n'
+    COMPREPLY=($(knownfolder __complete $((COMP_CWORD - 1)) "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _knownfolder knownfolder knownfolder.exe
+`,
+	"zsh": `#compdef knownfolder knownfolder.exe
+# zsh completion for knownfolder
+# Install with: knownfolder completion zsh > "${fpath[1]}/_knownfolder"
+
+_knownfolder() {
+    local -a candidates
+    candidates=("${(@f)$(knownfolder __complete $((CURRENT - 2)) "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    compadd -Q -- "${candidates[@]}"
+}
+
+if [ "$funcstack[1]" = "_knownfolder" ]; then
+    _knownfolder "$@"
+else
+    compdef _knownfolder knownfolder knownfolder.exe
+fi
+`,
+	"fish": `# fish completion for knownfolder
+# Install with: knownfolder completion fish > ~/.config/fish/completions/knownfolder.fish
+
+function __knownfolder_complete
+    set -l words (commandline -opc)
+    knownfolder __complete (math (count $words) - 1) $words[2..-1] (commandline -ct) 2>/dev/null
+end
+
+complete -c knownfolder -c knownfolder.exe -f -a '(__knownfolder_complete)'
+`,
+	"powershell": `# PowerShell completion for knownfolder
+# Install with: knownfolder completion powershell | Out-String | Invoke-Expression
+
+Register-ArgumentCompleter -Native -CommandName 'knownfolder', 'knownfolder.exe' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -ne '') {
+        $words = @($words | Select-Object -SkipLast 1)
+    }
+    & knownfolder __complete $words.Count @words $wordToComplete 2>$null | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`,
+}
+
+func CompletionScript(shell string) (string, error) {
+	script, ok := completionScripts[shell]
+	if !ok {
+		return "", fmt.Errorf(`Unsupported shell "%v" - must be one of bash, zsh, fish, powershell`, shell)
+	}
+	return script, nil
+}
+
+// Complete implements the hidden __complete command; args are the arguments
+// that follow "__complete" on the command line, and folders the values that
+// FOLDER may take.
+func Complete(args []string, folders []string) (candidates []string, err error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("Missing word count")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 || n > len(args)-1 {
+		return nil, fmt.Errorf(`Invalid word count "%v"`, args[0])
+	}
+	words := args[1 : n+1]
+	current := ""
+	if len(args) > n+1 {
+		current = args[n+1]
+	}
+	for _, c := range completionCandidates(words, folders) {
+		if strings.HasPrefix(c, current) {
+			candidates = append(candidates, c)
+		}
+	}
+	return
+}
+
+// usagePattern is a pattern of the Usage section of the usage string, e.g.
+// "knownfolder library show [-d|-u USERNAME -p PASSWORD] LIBRARY", from which
+// the commands, options and arguments that are completed are derived.
+type usagePattern struct {
+	// Command is the command and any subcommand, e.g. ["library", "show"],
+	// and is empty for patterns of options alone, e.g. "knownfolder --version"
+	Command []string
+	// Options are the options the pattern accepts, e.g. "-d" and "-u"
+	Options []string
+	// Arguments are the names of its positional arguments, e.g. "LIBRARY"
+	Arguments []string
+	// Excludes are the options which can't be given with each option, since
+	// they are alternatives of the same group, e.g. "-d" and "--wine-prefix"
+	// for "-u" in [-d|-u USERNAME -p PASSWORD|--wine-prefix PREFIX]
+	Excludes map[string][]string
+}
+
+// usageGrammar is what the usage string says about the command line.
+type usageGrammar struct {
+	Patterns []usagePattern
+	// ValueOptions are the options which take a value, e.g. "--audit-log",
+	// which is then not an argument
+	ValueOptions map[string]bool
+	// Repeatable are the options which can be given more than once
+	Repeatable map[string]bool
+}
+
+// usageToken matches the tokens of a usage pattern: brackets, parentheses,
+// "|" and "...", options, argument names and commands.
+var usageToken = regexp.MustCompile(`\.\.\.|[][()|]|--?[A-Za-z][\w-]*|[A-Z][A-Z0-9_]*|[a-z][\w-]*`)
+
+// parseUsage parses the Usage section of usage, where each pattern starts
+// with "knownfolder" and may continue on the lines that follow.
+func parseUsage(usage string) usageGrammar {
+	g := usageGrammar{ValueOptions: map[string]bool{}, Repeatable: map[string]bool{}}
+	lines := []string{}
+	inUsage := false
+	for _, line := range strings.Split(usage, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "Usage:":
+			inUsage = true
+		case !inUsage:
+		case line == "":
+			inUsage = false
+		case strings.HasPrefix(line, "knownfolder ") || len(lines) == 0:
+			lines = append(lines, strings.TrimPrefix(line, "knownfolder "))
+		default:
+			lines[len(lines)-1] += " " + line
+		}
+	}
+	for _, line := range lines {
+		tokens := usageToken.FindAllString(line, -1)
+		commands := [][]string{{}}
+		if len(tokens) > 0 && isUsageCommand(tokens[0]) {
+			commands[0] = []string{tokens[0]}
+			tokens = tokens[1:]
+			switch {
+			case len(tokens) > 0 && isUsageCommand(tokens[0]):
+				commands[0] = append(commands[0], tokens[0])
+				tokens = tokens[1:]
+			case len(tokens) > 1 && tokens[0] == "(" && isUsageCommand(tokens[1]):
+				// alternative subcommands, e.g. (add-location|remove-location)
+				command := commands[0][0]
+				commands = nil
+				for tokens = tokens[1:]; len(tokens) > 0 && tokens[0] != ")"; tokens = tokens[1:] {
+					if tokens[0] != "|" {
+						commands = append(commands, []string{command, tokens[0]})
+					}
+				}
+			}
+		}
+		pattern := usagePattern{Excludes: map[string][]string{}}
+		option := ""
+		// groups are the alternatives of each enclosing [...] or (...), in
+		// which each option is recorded, and so in every enclosing group
+		groups := [][][]string{}
+		for i, token := range tokens {
+			switch {
+			case token == "[" || token == "(":
+				groups = append(groups, [][]string{{}})
+			case token == "|" && len(groups) > 0:
+				groups[len(groups)-1] = append(groups[len(groups)-1], []string{})
+			case (token == "]" || token == ")") && len(groups) > 0:
+				alternatives := groups[len(groups)-1]
+				groups = groups[:len(groups)-1]
+				for a, options := range alternatives {
+					for b, others := range alternatives {
+						if a == b {
+							continue
+						}
+						for _, o := range options {
+							pattern.Excludes[o] = append(pattern.Excludes[o], others...)
+						}
+					}
+				}
+			case strings.HasPrefix(token, "-"):
+				option = token
+				pattern.Options = append(pattern.Options, token)
+				for _, group := range groups {
+					group[len(group)-1] = append(group[len(group)-1], token)
+				}
+				if i+1 < len(tokens) && isUsageArgument(tokens[i+1]) {
+					g.ValueOptions[token] = true
+				}
+			case isUsageArgument(token):
+				if i == 0 || !g.ValueOptions[tokens[i-1]] {
+					pattern.Arguments = append(pattern.Arguments, token)
+				}
+			case token == "..." && option != "":
+				g.Repeatable[option] = true
+			}
+		}
+		for _, command := range commands {
+			p := pattern
+			p.Command = command
+			g.Patterns = append(g.Patterns, p)
+		}
+	}
+	return g
+}
+
+func isUsageCommand(token string) bool {
+	return token[0] >= 'a' && token[0] <= 'z'
+}
+
+func isUsageArgument(token string) bool {
+	return token[0] >= 'A' && token[0] <= 'Z'
+}
+
+// completionCandidates returns the candidates for the word following words,
+// which are the words on the command line after the program name. Commands,
+// subcommands and options are those of the matching patterns of the usage
+// string, and are offered until the first argument is given. The values of
+// options and arguments are only completed for FOLDER, LIBRARY and SHELL,
+// since the rest are either file names, which are left to the shell's own
+// file name completion, or can't be completed.
+func completionCandidates(words []string, folders []string) []string {
+	g := parseUsage(usage)
+	candidates := []string{}
+	seen := map[string]bool{}
+	add := func(candidate string) {
+		if !seen[candidate] {
+			seen[candidate] = true
+			candidates = append(candidates, candidate)
+		}
+	}
+	if len(words) == 0 {
+		for _, p := range g.Patterns {
+			if len(p.Command) > 0 {
+				add(p.Command[0])
+			}
+		}
+		for _, p := range g.Patterns {
+			if len(p.Command) == 0 {
+				for _, option := range p.Options {
+					add(option)
+				}
+			}
+		}
+		return candidates
+	}
+	matching := []usagePattern{}
+	subcommands := false
+	for _, p := range g.Patterns {
+		if len(p.Command) > 0 && p.Command[0] == words[0] {
+			matching = append(matching, p)
+			subcommands = subcommands || len(p.Command) > 1
+		}
+	}
+	rest := words[1:]
+	if subcommands {
+		if len(rest) == 0 {
+			for _, p := range matching {
+				add(p.Command[1])
+			}
+			return candidates
+		}
+		patterns := matching
+		matching = nil
+		for _, p := range patterns {
+			if len(p.Command) > 1 && p.Command[1] == rest[0] {
+				matching = append(matching, p)
+			}
+		}
+		rest = rest[1:]
+	}
+	used := map[string]bool{}
+	arguments := 0
+	for i := 0; i < len(rest); i++ {
+		switch {
+		case g.ValueOptions[rest[i]] && i == len(rest)-1:
+			// the value of an option is being completed
+			return nil
+		case g.ValueOptions[rest[i]]:
+			used[rest[i]] = true
+			i++
+		case strings.HasPrefix(rest[i], "-"):
+			used[rest[i]] = true
+		default:
+			arguments++
+		}
+	}
+	patterns := matching
+	matching = nil
+	for _, p := range patterns {
+		if hasOptions(p, used) {
+			matching = append(matching, p)
+		}
+	}
+	if arguments == 0 {
+		for _, p := range matching {
+			excluded := map[string]bool{}
+			for option := range used {
+				for _, other := range p.Excludes[option] {
+					excluded[other] = true
+				}
+			}
+			for _, option := range p.Options {
+				if (!used[option] || g.Repeatable[option]) && !excluded[option] {
+					add(option)
+				}
+			}
+		}
+	}
+	for _, p := range matching {
+		if arguments < len(p.Arguments) {
+			for _, value := range argumentCandidates(p.Arguments[arguments], folders) {
+				add(value)
+			}
+		}
+	}
+	return candidates
+}
+
+// hasOptions reports whether p accepts every one of options.
+func hasOptions(p usagePattern, options map[string]bool) bool {
+	accepted := map[string]bool{}
+	for _, option := range p.Options {
+		accepted[option] = true
+	}
+	for option := range options {
+		if !accepted[option] {
+			return false
+		}
+	}
+	return true
+}
+
+// argumentCandidates returns the values that the argument called name may
+// take, if they can be completed.
+func argumentCandidates(name string, folders []string) []string {
+	values := []string{}
+	switch name {
+	case "FOLDER":
+		values = append(values, folders...)
+	case "LIBRARY":
+		for _, folder := range folders {
+			if strings.HasSuffix(folder, "Library") {
+				values = append(values, folder)
+			}
+		}
+	case "SHELL":
+		for shell := range completionScripts {
+			values = append(values, shell)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompletionScripts(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		script, err := CompletionScript(shell)
+		if err != nil {
+			t.Fatalf("%v: %v", shell, err)
+		}
+		checkGolden(t, "completion."+shell, []byte(script))
+	}
+	if _, err := CompletionScript("tcsh"); err == nil {
+		t.Error("Expected an error for an unsupported shell")
+	}
+}
+
+func TestCompletionCandidates(t *testing.T) {
+	folders := []string{"Profile", "MusicLibrary", "Documents", "DocumentsLibrary"}
+	for _, test := range []struct {
+		Words []string
+		Want  []string
+	}{
+		{nil, []string{"set", "get", "list", "completion", "serve", "apply", "recover", "enforce", "snapshot", "diff", "export", "import", "users", "register", "unregister", "resolve", "tree", "library", "-h", "--help", "--version"}},
+		{[]string{"get"}, []string{"-d", "-u", "-p", "--wine-prefix", "--image-root", "--profile", "Documents", "DocumentsLibrary", "MusicLibrary", "Profile"}},
+		{[]string{"get", "-u"}, nil},
+		{[]string{"get", "-u", "bob", "-p", "secret"}, []string{"Documents", "DocumentsLibrary", "MusicLibrary", "Profile"}},
+		{[]string{"get", "-d"}, []string{"Documents", "DocumentsLibrary", "MusicLibrary", "Profile"}},
+		{[]string{"recover", "--rollback"}, []string{"-d", "-u", "-p", "--wine-prefix", "--audit-log", "--hooks", "--lock-timeout", "--no-wait", "--image-root", "--profile", "--force"}},
+		{[]string{"recover", "--rollback", "--no-wait"}, []string{"-d", "-u", "-p", "--wine-prefix", "--audit-log", "--hooks", "--image-root", "--profile", "--force"}},
+		{[]string{"apply", "--all-profiles", "--image-root", "C:\\mnt"}, []string{"--include", "--exclude", "--force", "--dry-run", "--audit-log", "--hooks", "--lock-timeout", "--no-wait"}},
+		{[]string{"apply", "--all-profiles", "--force"}, []string{"--include", "--exclude", "--image-root", "--dry-run", "--audit-log", "--hooks", "--lock-timeout", "--no-wait"}},
+		{[]string{"enforce", "--once"}, []string{"-d", "--wine-prefix", "--audit-log", "--lock-timeout", "--no-wait"}},
+		{[]string{"get", "Documents"}, []string{}},
+		{[]string{"set", "-d", "Documents"}, []string{}},
+		{[]string{"unregister"}, []string{"--dry-run", "Documents", "DocumentsLibrary", "MusicLibrary", "Profile"}},
+		{[]string{"apply", "--users"}, nil},
+		{[]string{"apply", "--users", "users.csv"}, []string{"--workers", "--audit-log", "--hooks", "--lock-timeout", "--no-wait"}},
+		{[]string{"apply", "--users", "users.csv", "--workers", "8"}, []string{"--audit-log", "--hooks", "--lock-timeout", "--no-wait"}},
+		{[]string{"apply", "--all-profiles", "--include", "S-1-5-21-*"}, []string{"--include", "--exclude", "--software-hive", "--image-root", "--force", "--dry-run", "--audit-log", "--hooks", "--lock-timeout", "--no-wait"}},
+		{[]string{"serve"}, []string{"-d", "--listen", "--token-file", "--tls-cert", "--tls-key", "--audit-log", "--lock-timeout", "--no-wait"}},
+		{[]string{"export"}, []string{"--format", "-d", "--pass", "--arch", "--exe", "--merge"}},
+		{[]string{"import"}, []string{"--format", "--user-map", "--audit-log"}},
+		{[]string{"register", "--name", "Acme"}, []string{"--guid", "--category", "--parent", "--relative-path", "--parsing-name", "--precreate", "--dry-run"}},
+		{[]string{"library"}, []string{"show", "add-location", "remove-location", "set-default-save"}},
+		{[]string{"library", "show"}, []string{"-d", "-u", "-p", "DocumentsLibrary", "MusicLibrary"}},
+		{[]string{"library", "set-default-save", "MusicLibrary"}, []string{}},
+		{[]string{"tree", "--software-hive", "SOFTWARE"}, []string{"-d", "-u", "-p", "Documents", "DocumentsLibrary", "MusicLibrary", "Profile"}},
+		{[]string{"completion"}, []string{"bash", "fish", "powershell", "zsh"}},
+		{[]string{"frobnicate"}, []string{}},
+	} {
+		got := completionCandidates(test.Words, folders)
+		if len(got) == 0 && len(test.Want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, test.Want) {
+			t.Errorf("Candidates for %q:\ngot  %q\nwant %q", strings.Join(test.Words, " "), got, test.Want)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	folders := []string{"Documents", "Downloads", "Desktop"}
+	for _, test := range []struct {
+		Args []string
+		Want []string
+		Err  bool
+	}{
+		{[]string{"1", "get", "Do"}, []string{"Documents", "Downloads"}, false},
+		{[]string{"1", "get"}, []string{"-d", "-u", "-p", "--wine-prefix", "--image-root", "--profile", "Desktop", "Documents", "Downloads"}, false},
+		{[]string{"0", "se"}, []string{"set", "serve"}, false},
+		{[]string{"2", "set", "-d", "--n"}, []string{"--no-wait"}, false},
+		{[]string{}, nil, true},
+		{[]string{"x"}, nil, true},
+		{[]string{"3", "get"}, nil, true},
+	} {
+		got, err := Complete(test.Args, folders)
+		if (err != nil) != test.Err {
+			t.Errorf("Complete(%q) returned error %v", test.Args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.Want) {
+			t.Errorf("Complete(%q):\ngot  %q\nwant %q", test.Args, got, test.Want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+// desktop.ini sections and keys written for redirected folders. Explorer
+// shows a folder with a [.ShellClassInfo] section in desktop.ini with the
+// given name and icon, provided the folder is marked read only or system,
+// and desktop.ini is hidden and system, see
+// https://docs.microsoft.com/en-us/windows/desktop/shell/how-to-customize-folders-with-desktop-ini
+// The [KnownFolder] section isn't used by Explorer, but records which known
+// folder the directory is the location of.
+const (
+	shellClassInfoSection = ".ShellClassInfo"
+	knownFolderSection    = "KnownFolder"
+)
+
+// DesktopINI is what knownfolder writes to the desktop.ini file of a known
+// folder's location.
+type DesktopINI struct {
+	GUID GUID
+	// LocalizedResourceName and IconResource are resource references, which
+	// are left out of desktop.ini if they are empty
+	LocalizedResourceName string
+	IconResource          string
+}
+
+// NewDesktopINI returns the desktop.ini settings for the folder described
+// by d.
+func NewDesktopINI(d FolderDescription) DesktopINI {
+	return DesktopINI{
+		GUID:                  d.GUID,
+		LocalizedResourceName: d.LocalizedName,
+		IconResource:          d.Icon,
+	}
+}
+
+// Merge returns the content of existing, the current desktop.ini file (nil if
+// there isn't one), with the settings of d added or updated. All other
+// sections and keys are kept, and the file keeps its encoding. A new file is
+// written in UTF-16, as Explorer itself does, so that any names can be used.
+func (d DesktopINI) Merge(existing []byte) []byte {
+	var ini *iniFile
+	if existing == nil {
+		ini = &iniFile{Encoding: encodingUTF16LE, Newline: "\r\n", Sections: []*iniSection{{}}}
+	} else {
+		ini = parseINI(existing)
+	}
+	if d.LocalizedResourceName != "" {
+		ini.set(shellClassInfoSection, "LocalizedResourceName", d.LocalizedResourceName)
+	}
+	if d.IconResource != "" {
+		ini.set(shellClassInfoSection, "IconResource", d.IconResource)
+	}
+	ini.set(knownFolderSection, "FolderID", d.GUID.String())
+	return ini.bytes()
+}
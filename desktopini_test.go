@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestDesktopINIMerge(t *testing.T) {
+	documents := *knownfolders["Documents"]
+	full := DesktopINI{
+		GUID:                  documents,
+		LocalizedResourceName: "@%SystemRoot%\\system32\\shell32.dll,-21770",
+		IconResource:          "%SystemRoot%\\system32\\imageres.dll,-112",
+	}
+	const created = "[.ShellClassInfo]\r\n" +
+		"LocalizedResourceName=@%SystemRoot%\\system32\\shell32.dll,-21770\r\n" +
+		"IconResource=%SystemRoot%\\system32\\imageres.dll,-112\r\n" +
+		"\r\n" +
+		"[KnownFolder]\r\n" +
+		"FolderID={FDD39AD0-238F-46AF-ADB4-6C85480369C7}\r\n"
+	for _, test := range []struct {
+		Name     string
+		INI      DesktopINI
+		Existing []byte
+		Want     []byte
+	}{
+		{
+			Name: "new file",
+			INI:  full,
+			Want: utf16Text(created, binary.LittleEndian, true),
+		},
+		{
+			Name: "new file without resources",
+			INI:  DesktopINI{GUID: documents},
+			Want: utf16Text("[KnownFolder]\r\nFolderID={FDD39AD0-238F-46AF-ADB4-6C85480369C7}\r\n", binary.LittleEndian, true),
+		},
+		{
+			Name:     "update UTF-16BE file",
+			INI:      full,
+			Existing: utf16Text("[.ShellClassInfo]\r\nIconResource=old.dll,-1\r\nInfoTip=Mine\r\n[ViewState]\r\nMode=4\r\n", binary.BigEndian, true),
+			Want: utf16Text("[.ShellClassInfo]\r\n"+
+				"IconResource=%SystemRoot%\\system32\\imageres.dll,-112\r\n"+
+				"InfoTip=Mine\r\n"+
+				"LocalizedResourceName=@%SystemRoot%\\system32\\shell32.dll,-21770\r\n"+
+				"[ViewState]\r\n"+
+				"Mode=4\r\n"+
+				"\r\n"+
+				"[KnownFolder]\r\n"+
+				"FolderID={FDD39AD0-238F-46AF-ADB4-6C85480369C7}\r\n", binary.BigEndian, true),
+		},
+		{
+			Name:     "update UTF-8 file",
+			INI:      DesktopINI{GUID: documents},
+			Existing: []byte("[KnownFolder]\nfolderid={4BD8D571-6D19-48D3-BE97-422220080E43}\n"),
+			Want:     []byte("[KnownFolder]\nFolderID={FDD39AD0-238F-46AF-ADB4-6C85480369C7}\n"),
+		},
+		{
+			Name:     "merging again changes nothing",
+			INI:      full,
+			Existing: utf16Text(created, binary.LittleEndian, true),
+			Want:     utf16Text(created, binary.LittleEndian, true),
+		},
+	} {
+		if got := test.INI.Merge(test.Existing); !bytes.Equal(got, test.Want) {
+			text, _ := decodeText(got)
+			t.Errorf("%v: merged file is %q", test.Name, text)
+		}
+	}
+}
+
+func TestNewDesktopINI(t *testing.T) {
+	d := FolderDescription{GUID: *knownfolders["Music"], Name: "My Music", LocalizedName: "@shell32.dll,-21790", Icon: "imageres.dll,-108"}
+	want := DesktopINI{GUID: *knownfolders["Music"], LocalizedResourceName: "@shell32.dll,-21790", IconResource: "imageres.dll,-108"}
+	if got := NewDesktopINI(d); got != want {
+		t.Errorf("NewDesktopINI returned %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// minRetryDelay is the delay before retrying a failed reconciliation, which
+// doubles with each consecutive failure, up to the regular interval.
+const minRetryDelay = 10 * time.Second
+
+// Clock abstracts the passing of time, so that Enforcer can be driven without
+// real delays.
+type Clock interface {
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Enforcer periodically compares the folder locations reported by Backend
+// with those in Manifest, rendered for the user Username, and sets any that
+// have drifted.
+type Enforcer struct {
+	Backend  Backend
+	Manifest *Manifest
+	Username string
+	Interval time.Duration
+	Clock    Clock
+	// Jitter returns a pseudo-random number in [0.0, 1.0), e.g. rand.Float64
+	Jitter func() float64
+	// Lock, if set, is called before each reconciliation to lock the target
+	// against other knownfolder processes, and returns a function which
+	// unlocks it again
+	Lock func() (unlock func(), err error)
+}
+
+// NewEnforcer returns an Enforcer using the real clock.
+func NewEnforcer(backend Backend, manifest *Manifest, username string, interval time.Duration) *Enforcer {
+	return &Enforcer{
+		Backend:  backend,
+		Manifest: manifest,
+		Username: username,
+		Interval: interval,
+		Clock:    realClock{},
+		Jitter:   rand.Float64,
+	}
+}
+
+// Reconcile makes a single pass over the manifest, correcting any folders
+// whose location differs from the manifest. Every folder is attempted, even
+// if an earlier one fails; corrected is the number of folders that were
+// successfully reset.
+func (e *Enforcer) Reconcile() (corrected int, err error) {
+	if e.Lock != nil {
+		unlock, err := e.Lock()
+		if err != nil {
+			return 0, err
+		}
+		defer unlock()
+	}
+	differences, err := DiffManifest(e.Backend, e.Manifest, e.Username)
+	if err != nil {
+		return 0, err
+	}
+	failed := []string{}
+	for _, d := range differences {
+		current := d.Current
+		if d.Err != nil {
+			current = fmt.Sprintf("<%v>", d.Err)
+		}
+		if setErr := e.Backend.SetFolder(d.Folder, d.Location); setErr != nil {
+			log.Printf("Could not correct %v from %v to %v: %v", d.Folder, current, d.Location, setErr)
+			failed = append(failed, d.Folder)
+			continue
+		}
+		log.Printf("Corrected %v from %v to %v", d.Folder, current, d.Location)
+		corrected++
+	}
+	if len(failed) > 0 {
+		err = fmt.Errorf("Could not correct %v", strings.Join(failed, ", "))
+	}
+	return
+}
+
+// Run reconciles immediately, and then repeatedly until stop is closed.
+func (e *Enforcer) Run(stop <-chan struct{}) {
+	failures := 0
+	for {
+		if _, err := e.Reconcile(); err != nil {
+			failures++
+			log.Printf("%v", err)
+		} else {
+			failures = 0
+		}
+		delay := e.nextDelay(failures)
+		select {
+		case <-stop:
+			return
+		case <-e.Clock.After(delay):
+		}
+	}
+}
+
+// nextDelay returns the time to wait before the next reconciliation. A
+// failure is retried sooner than the interval, after minRetryDelay, doubled
+// for each further consecutive failure, with up to 25% jitter either way so
+// that many hosts failing for the same reason don't retry in lockstep, but
+// never later than the interval.
+func (e *Enforcer) nextDelay(failures int) time.Duration {
+	if failures == 0 {
+		return e.Interval
+	}
+	delay := minRetryDelay
+	for i := 1; i < failures && delay < e.Interval; i++ {
+		delay *= 2
+	}
+	delay = time.Duration(float64(delay) * (0.75 + e.Jitter()/2))
+	if delay > e.Interval {
+		delay = e.Interval
+	}
+	return delay
+}
+
+// samePath reports whether two Windows paths refer to the same location,
+// ignoring case and trailing path separators.
+func samePath(a, b string) bool {
+	return strings.EqualFold(strings.TrimRight(a, `\/`), strings.TrimRight(b, `\/`))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose timers only fire when the test says so. Each
+// call of After sends the delay asked for on Delays, and the timer fires on
+// the next send on Fire.
+type fakeClock struct {
+	Delays chan time.Duration
+	Fire   chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{Delays: make(chan time.Duration), Fire: make(chan time.Time)}
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.Delays <- d
+	return c.Fire
+}
+
+func TestEnforcerRun(t *testing.T) {
+	backend := newFakeBackend(map[string]string{"Documents": `C:\Users\pete\Documents`, "Music": `D:\Music`})
+	backend.Errors["Documents"] = errors.New("Access is denied.")
+	manifest := &Manifest{Folders: map[string]string{"Documents": `D:\Docs`, "Music": `D:\Music`}}
+	clock := newFakeClock()
+	enforcer := NewEnforcer(backend, manifest, "pete", time.Minute)
+	enforcer.Clock = clock
+	enforcer.Jitter = func() float64 { return 0.5 }
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		enforcer.Run(stop)
+		close(stopped)
+	}()
+	// failures are retried after 10s, doubling up to the interval
+	for i, want := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute} {
+		if got := <-clock.Delays; got != want {
+			t.Fatalf("Delay after %v failures is %v, want %v", i+1, got, want)
+		}
+		if i == 4 {
+			delete(backend.Errors, "Documents")
+		}
+		clock.Fire <- time.Now()
+	}
+	if got := <-clock.Delays; got != time.Minute {
+		t.Fatalf("Delay after success is %v, want the interval", got)
+	}
+	if location := backend.Folders["Documents"]; location != `D:\Docs` {
+		t.Errorf("Documents is %v, want it corrected to D:\\Docs", location)
+	}
+	clock.Fire <- time.Now()
+	if got := <-clock.Delays; got != time.Minute {
+		t.Fatalf("Delay after success is %v, want the interval", got)
+	}
+	close(stop)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return once stopped")
+	}
+	// Music never drifted, and Documents was only set until it succeeded
+	if len(backend.Calls) != 6 {
+		t.Errorf("Backend was called to set %v, want Documents 6 times", backend.Calls)
+	}
+}
+
+func TestEnforcerNextDelay(t *testing.T) {
+	for _, test := range []struct {
+		Interval time.Duration
+		Failures int
+		Jitter   float64
+		Want     time.Duration
+	}{
+		{5 * time.Minute, 0, 0, 5 * time.Minute},
+		{5 * time.Minute, 1, 0, 7500 * time.Millisecond},
+		{5 * time.Minute, 1, 0.999999, 12499995 * time.Microsecond},
+		{5 * time.Minute, 3, 0.5, 40 * time.Second},
+		{5 * time.Minute, 5, 0.5, 160 * time.Second},
+		{5 * time.Minute, 6, 0.5, 5 * time.Minute},
+		{5 * time.Minute, 100, 0.999999, 5 * time.Minute},
+		{5 * time.Second, 1, 0.5, 5 * time.Second},
+	} {
+		e := &Enforcer{Interval: test.Interval, Jitter: func() float64 { return test.Jitter }}
+		if got := e.nextDelay(test.Failures); got != test.Want {
+			t.Errorf("nextDelay(%v) with interval %v and jitter %v is %v, want %v", test.Failures, test.Interval, test.Jitter, got, test.Want)
+		}
+	}
+}
+
+func TestEnforcerReconcileLock(t *testing.T) {
+	backend := newFakeBackend(nil)
+	enforcer := NewEnforcer(backend, &Manifest{Folders: map[string]string{"Documents": `D:\Docs`}}, "pete", time.Minute)
+	locked := errors.New("Locked")
+	enforcer.Lock = func() (func(), error) { return nil, locked }
+	if _, err := enforcer.Reconcile(); err != locked || len(backend.Calls) > 0 {
+		t.Errorf("Reconcile returned %v and set %v while the target was locked", err, backend.Calls)
+	}
+	unlocked := false
+	enforcer.Lock = func() (func(), error) { return func() { unlocked = true }, nil }
+	if corrected, err := enforcer.Reconcile(); corrected != 1 || err != nil || !unlocked {
+		t.Errorf("Reconcile corrected %v, returned %v, and unlocked %v", corrected, err, unlocked)
+	}
+}
+
+func TestEnforcerReconcileRendersUsername(t *testing.T) {
+	backend := newFakeBackend(map[string]string{"Documents": `D:\pete\Docs`, "Music": `C:\Users\pete\Music`})
+	manifest := &Manifest{Folders: map[string]string{"Documents": `D:\{{.Username}}\Docs`, "Music": `D:\{{.Username}}\Music`}}
+	corrected, err := NewEnforcer(backend, manifest, "pete", time.Minute).Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corrected != 1 || !reflect.DeepEqual(backend.Calls, []string{`Music=D:\pete\Music`}) {
+		t.Errorf("Corrected %v folders, with %q", corrected, backend.Calls)
+	}
+}
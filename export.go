@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// ExportFormats maps each format supported by the export command to the
+// function which generates it.
+var ExportFormats = map[string]func(w io.Writer, entries []ManifestEntry, options ExportOptions) error{
+	"powershell": ExportPowerShell,
+	"unattend":   ExportUnattend,
+}
+
+// ExportOptions holds the settings common to all export formats.
+type ExportOptions struct {
+	// Manifest is the name of the manifest file the export was generated from.
+	Manifest string
+	// DefaultUser is true if folders should be set for the default user
+	// profile, rather than the user running the generated script.
+	DefaultUser bool
+	// Pass is the unattend configuration pass to run commands in.
+	Pass string
+	// Architecture is the unattend component processorArchitecture.
+	Architecture string
+	// Executable is the path of knownfolder to run from unattend commands.
+	Executable string
+	// MergeFile, if set, is an existing answer file to merge unattend
+	// commands into.
+	MergeFile string
+}
+
+// powershellUsername is what {{.Username}} of a manifest is rendered as for a
+// PowerShell script for the user running it, which the script replaces with
+// their username.
+const powershellUsername = "\x00USERNAME\x00"
+
+// ExportUsername returns the username to render manifest locations for
+// before exporting them in format with options. Scripts and answer files for
+// the default user set the folders of "Default", as "knownfolder apply -d"
+// does, while the user who runs a PowerShell script isn't known until it
+// runs.
+func ExportUsername(format string, options ExportOptions) string {
+	if format == "powershell" && !options.DefaultUser {
+		return powershellUsername
+	}
+	return "Default"
+}
+
+var powershellTemplate = template.Must(template.New("powershell").Funcs(template.FuncMap{
+	"comment":  powershellComment,
+	"quote":    powershellQuote,
+	"location": powershellLocation,
+	"guid":     func(folder string) string { return knownfolders[folder].String() },
+}).Parse(`# Generated by knownfolder {{.Version}} from {{comment .Manifest}} - do not edit.
+#
+# Sets known folder locations for the {{if .DefaultUser}}default user profile{{else}}user running this script{{end}},
+# reporting each folder that is changed. Exits with exit code 1 if any folder
+# could not be set.
+
+#Requires -Version 3.0
+$ErrorActionPreference = 'Stop'
+
+if (-not ('Knownfolder.Shell' -as [type])) {
+    Add-Type -TypeDefinition @'
+using System;
+using System.Runtime.InteropServices;
+
+namespace Knownfolder
+{
+    public static class Shell
+    {
+        // https://msdn.microsoft.com/en-us/library/windows/desktop/bb762188(v=vs.85).aspx
+        [DllImport("shell32.dll")]
+        private static extern int SHGetKnownFolderPath(
+            [MarshalAs(UnmanagedType.LPStruct)] Guid rfid, uint dwFlags, IntPtr hToken, out IntPtr pszPath);
+
+        // https://msdn.microsoft.com/en-us/library/windows/desktop/bb762249(v=vs.85).aspx
+        [DllImport("shell32.dll")]
+        private static extern int SHSetKnownFolderPath(
+            [MarshalAs(UnmanagedType.LPStruct)] Guid rfid, uint dwFlags, IntPtr hToken,
+            [MarshalAs(UnmanagedType.LPWStr)] string pszPath);
+
+        public static string GetFolder(Guid rfid, IntPtr hToken)
+        {
+            IntPtr path;
+            int hr = SHGetKnownFolderPath(rfid, 0, hToken, out path);
+            if (hr != 0)
+            {
+                Marshal.ThrowExceptionForHR(hr);
+            }
+            try
+            {
+                return Marshal.PtrToStringUni(path);
+            }
+            finally
+            {
+                Marshal.FreeCoTaskMem(path);
+            }
+        }
+
+        public static void SetFolder(Guid rfid, IntPtr hToken, string path)
+        {
+            int hr = SHSetKnownFolderPath(rfid, 0, hToken, path);
+            if (hr != 0)
+            {
+                Marshal.ThrowExceptionForHR(hr);
+            }
+        }
+    }
+}
+'@
+}
+
+{{if .DefaultUser}}# -1 is the token which represents the default user profile
+$token = [IntPtr](-1){{else}}$token = [IntPtr]::Zero{{end}}
+
+$folders = @(
+{{- range $i, $entry := .Entries}}{{if $i}},{{end}}
+    @{ Name = {{quote $entry.Folder}}; Id = [Guid]{{quote (guid $entry.Folder)}}; Path = {{location $entry.Location}} }
+{{- end}}
+)
+
+$failed = 0
+foreach ($folder in $folders) {
+    try {
+        $current = [Knownfolder.Shell]::GetFolder($folder.Id, $token)
+    } catch {
+        $current = $null
+    }
+    # PowerShell string comparison is case insensitive, as are Windows paths
+    if ($current -ne $null -and $current.TrimEnd('\') -eq $folder.Path.TrimEnd('\')) {
+        Write-Output ('{0} is already {1}' -f $folder.Name, $folder.Path)
+        continue
+    }
+    try {
+        [Knownfolder.Shell]::SetFolder($folder.Id, $token, $folder.Path)
+        Write-Output ('Changed {0} from {1} to {2}' -f $folder.Name, $current, $folder.Path)
+    } catch {
+        Write-Warning ('Could not set {0} to {1}: {2}' -f $folder.Name, $folder.Path, $_.Exception.Message)
+        $failed++
+    }
+}
+if ($failed -gt 0) {
+    exit 1
+}
+`))
+
+// ExportPowerShell writes a self-contained PowerShell script which sets the
+// given folder locations via the shell API, in the same way as
+// "knownfolder set" does.
+func ExportPowerShell(w io.Writer, entries []ManifestEntry, options ExportOptions) error {
+	return powershellTemplate.Execute(w, struct {
+		ExportOptions
+		Version string
+		Entries []ManifestEntry
+	}{
+		ExportOptions: options,
+		Version:       version,
+		Entries:       entries,
+	})
+}
+
+// powershellQuote returns s as a PowerShell single quoted string literal.
+// Inside single quotes nothing is expanded, and the only character needing
+// escaping is the quote itself, which is escaped by doubling it. PowerShell
+// also accepts the typographic single quotes U+2018 to U+201B as quotes, so
+// they must be doubled too, or a path containing one could end the string.
+func powershellQuote(s string) string {
+	var b strings.Builder
+	b.WriteRune('\'')
+	for _, r := range s {
+		switch r {
+		case '\'', '‘', '’', '‚', '‛':
+			b.WriteRune(r)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteRune('\'')
+	return b.String()
+}
+
+// powershellComment returns s with any control characters, and the Unicode
+// line and paragraph separators, replaced by "?", so that it stays within
+// the line comment it is written into, rather than ending it and becoming
+// part of the script.
+func powershellComment(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '\u2028' || r == '\u2029' {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+// powershellLocation returns location as a PowerShell expression, which
+// evaluates to it with any powershellUsername replaced by the username of
+// the user running the script.
+func powershellLocation(location string) string {
+	parts := strings.Split(location, powershellUsername)
+	if len(parts) == 1 {
+		return powershellQuote(location)
+	}
+	terms := []string{}
+	for i, part := range parts {
+		if i > 0 {
+			terms = append(terms, "$env:USERNAME")
+		}
+		if part != "" {
+			terms = append(terms, powershellQuote(part))
+		}
+	}
+	return "(" + strings.Join(terms, " + ") + ")"
+}
+
+// Export writes the manifest entries in the given format.
+func Export(w io.Writer, format string, entries []ManifestEntry, options ExportOptions) error {
+	export, ok := ExportFormats[format]
+	if !ok {
+		return fmt.Errorf(`Unsupported export format "%v"`, format)
+	}
+	return export(w, entries, options)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// exportEntries are manifest entries with locations that need quoting.
+var exportEntries = []ManifestEntry{
+	{Folder: "Documents", Location: `D:\Users\O'Brien\Documents`},
+	{Folder: "LocalAppData", Location: `D:\AppData\Local`},
+	{Folder: "Music", Location: `D:\Music $HOME ` + "`n" + ` "live"`},
+}
+
+// pinVersion sets the version generated files record for the rest of the
+// test, so that golden files don't change with every release.
+func pinVersion(t *testing.T) {
+	v := version
+	version = "0.0.0-test"
+	t.Cleanup(func() { version = v })
+}
+
+func TestExportPowerShell(t *testing.T) {
+	pinVersion(t)
+	for _, test := range []struct {
+		Golden  string
+		Options ExportOptions
+	}{
+		{"export.ps1", ExportOptions{Manifest: "folders.yaml"}},
+		{"export-default-user.ps1", ExportOptions{Manifest: "folders.yaml", DefaultUser: true}},
+	} {
+		var b bytes.Buffer
+		if err := Export(&b, "powershell", exportEntries, test.Options); err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, test.Golden, b.Bytes())
+	}
+}
+
+func TestPowerShellQuote(t *testing.T) {
+	for _, test := range []struct {
+		Path   string
+		Quoted string
+	}{
+		{``, `''`},
+		{`C:\Users\pete`, `'C:\Users\pete'`},
+		{`D:\O'Brien`, `'D:\O''Brien'`},
+		{`''`, `''''''`},
+		// PowerShell treats U+2018 to U+201B as single quotes too
+		{"D:\\\u2018left", "'D:\\\u2018\u2018left'"},
+		{"D:\\right\u2019", "'D:\\right\u2019\u2019'"},
+		{"D:\\low\u201a", "'D:\\low\u201a\u201a'"},
+		{"D:\\reversed\u201b", "'D:\\reversed\u201b\u201b'"},
+		// nothing is expanded inside single quotes
+		{`D:\$env:USERNAME\$(whoami)`, `'D:\$env:USERNAME\$(whoami)'`},
+		{"D:\\tick`n`'", "'D:\\tick`n`'''"},
+		{`D:\"double"`, `'D:\"double"'`},
+		// double quotes, including typographic ones, don't end single
+		// quoted strings
+		{"D:\\\u201cdouble\u201d", "'D:\\\u201cdouble\u201d'"},
+		{`D:\Müller\文档`, `'D:\Müller\文档'`},
+		{"D:\\a\nb", "'D:\\a\nb'"},
+	} {
+		if quoted := powershellQuote(test.Path); quoted != test.Quoted {
+			t.Errorf("powershellQuote(%q) = %q, want %q", test.Path, quoted, test.Quoted)
+		}
+	}
+}
+
+func TestPowerShellComment(t *testing.T) {
+	for _, test := range []struct {
+		Manifest string
+		Want     string
+	}{
+		{`C:\manifests\folders.yaml`, `C:\manifests\folders.yaml`},
+		{`D:\Müller\文档.yaml`, `D:\Müller\文档.yaml`},
+		{"a\nRemove-Item C:\\ -Recurse\n#.yaml", "a?Remove-Item C:\\ -Recurse?#.yaml"},
+		{"a\r\nb\tc\x00d\u0085e\u2028f\u2029g.yaml", "a??b?c?d?e?f?g.yaml"},
+	} {
+		if got := powershellComment(test.Manifest); got != test.Want {
+			t.Errorf("powershellComment(%q) = %q, want %q", test.Manifest, got, test.Want)
+		}
+	}
+	var b bytes.Buffer
+	if err := Export(&b, "powershell", exportEntries, ExportOptions{Manifest: "x\nWrite-Output pwned\n.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(b.String(), "\n") {
+		if strings.Contains(line, "pwned") && !strings.HasPrefix(line, "#") {
+			t.Errorf("Manifest name escaped its comment as %q", line)
+		}
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	var b bytes.Buffer
+	if err := Export(&b, "batch", exportEntries, ExportOptions{}); err == nil {
+		t.Errorf("Exporting an unknown format succeeded")
+	}
+}
+
+func TestExportUsername(t *testing.T) {
+	manifest := &Manifest{Folders: map[string]string{"LocalAppData": `D:\tasks\{{.Username}}\AppData\{{.Username}}`}}
+	for _, test := range []struct {
+		Format  string
+		Options ExportOptions
+		Want    string
+	}{
+		{"powershell", ExportOptions{}, `Path = ('D:\tasks\' + $env:USERNAME + '\AppData\' + $env:USERNAME) }`},
+		{"powershell", ExportOptions{DefaultUser: true}, `Path = 'D:\tasks\Default\AppData\Default' }`},
+		{"unattend", ExportOptions{Pass: "specialize", Architecture: "amd64", Executable: "knownfolder.exe"}, `set -d LocalAppData D:\tasks\Default\AppData\Default<`},
+	} {
+		entries, err := manifest.Render(TemplateData{Username: ExportUsername(test.Format, test.Options)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if err := Export(&b, test.Format, entries, test.Options); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(b.String(), test.Want) {
+			t.Errorf("%v export with %+v doesn't contain %v:\n%v", test.Format, test.Options, test.Want, b.String())
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// replaceFile writes data to file by way of a temporary file alongside it,
+// so that the file is only replaced once data has been written in full.
+func replaceFile(file string, data []byte) error {
+	temp, err := os.Create(filepath.Join(filepath.Dir(file), "."+filepath.Base(file)+".tmp"))
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(data)
+	if err == nil {
+		// make sure the new content is on disk before it replaces the old
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+	return err
+}
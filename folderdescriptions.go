@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// folderDescriptionsKey is the key of the SOFTWARE hive under which every
+// known folder, built-in or registered by an application, is described in a
+// subkey named after its KNOWNFOLDERID.
+const folderDescriptionsKey = `Microsoft\Windows\CurrentVersion\Explorer\FolderDescriptions`
+
+// KF_CATEGORY values
+const (
+	KF_CATEGORY_VIRTUAL = 1
+	KF_CATEGORY_FIXED   = 2
+	KF_CATEGORY_COMMON  = 3
+	KF_CATEGORY_PERUSER = 4
+)
+
+var folderCategories = map[uint32]string{
+	KF_CATEGORY_VIRTUAL: "virtual",
+	KF_CATEGORY_FIXED:   "fixed",
+	KF_CATEGORY_COMMON:  "common",
+	KF_CATEGORY_PERUSER: "peruser",
+}
+
+// FolderDescription is a known folder registered under FolderDescriptions.
+type FolderDescription struct {
+	GUID     GUID
+	Name     string
+	Category uint32
+	// Parent is the KNOWNFOLDERID of the folder RelativePath is relative
+	// to, or "" if the folder has no parent.
+	Parent       string
+	RelativePath string
+	// LocalizedName and Icon are resource references for the display name
+	// and icon of the folder, e.g. "@%SystemRoot%\system32\shell32.dll,-21770"
+	LocalizedName string
+	Icon          string
+}
+
+// CategoryName returns the name of d's category, e.g. "peruser".
+func (d *FolderDescription) CategoryName() string {
+	if name, ok := folderCategories[d.Category]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown (%v)", d.Category)
+}
+
+// discoveredFolders are the folders merged into knownfolders by
+// MergeFolderDescriptions, by knownfolders name.
+var discoveredFolders = map[string]*FolderDescription{}
+
+// ReadFolderDescriptions returns the known folders registered under the
+// FolderDescriptions key of software, the root of a SOFTWARE hive, sorted by
+// name. Entries that are not named after a GUID or have no Name are skipped.
+func ReadFolderDescriptions(software RegistryKey) ([]FolderDescription, error) {
+	key, err := software.OpenKey(folderDescriptionsKey)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Close()
+	guids, err := key.SubkeyNames()
+	if err != nil {
+		return nil, err
+	}
+	descriptions := []FolderDescription{}
+	for _, g := range guids {
+		guid, err := ParseGUID(g)
+		if err != nil {
+			continue
+		}
+		d, err := readFolderDescription(key, g)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read folder description %v:\n%v", g, err)
+		}
+		if d.Name == "" {
+			continue
+		}
+		d.GUID = guid
+		descriptions = append(descriptions, d)
+	}
+	sort.Slice(descriptions, func(i, j int) bool {
+		if descriptions[i].Name != descriptions[j].Name {
+			return descriptions[i].Name < descriptions[j].Name
+		}
+		return descriptions[i].GUID.String() < descriptions[j].GUID.String()
+	})
+	return descriptions, nil
+}
+
+func readFolderDescription(folders RegistryKey, guid string) (d FolderDescription, err error) {
+	key, err := folders.OpenKey(guid)
+	if err != nil {
+		return
+	}
+	defer key.Close()
+	for _, v := range []struct {
+		Name  string
+		Value *string
+	}{
+		{"Name", &d.Name},
+		{"ParentFolder", &d.Parent},
+		{"RelativePath", &d.RelativePath},
+		{"LocalizedName", &d.LocalizedName},
+		{"Icon", &d.Icon},
+	} {
+		*v.Value, err = stringValue(key, v.Name)
+		if err != nil && !isRegistryNotFound(err) {
+			return
+		}
+	}
+	d.Category, err = dwordValue(key, "Category")
+	if err != nil && !isRegistryNotFound(err) {
+		return
+	}
+	return d, nil
+}
+
+// MergeFolderDescriptions adds those of descriptions that are not built in
+// to knownfolders (and discoveredFolders), so that they can be used like any
+// other folder. A description whose name is already taken, by a built-in
+// folder, an alias or another description, is not added under that name, and
+// a message describing the clash is returned instead; it can still be used
+// by its GUID.
+func MergeFolderDescriptions(descriptions []FolderDescription) (clashes []string) {
+	for i := range descriptions {
+		d := &descriptions[i]
+		guid := d.GUID.String()
+		if _, known := folderByGUID(guid); known {
+			continue
+		}
+		if existing, taken := resolveFolder(d.Name); taken {
+			what := "built-in folder"
+			if discoveredFolders[existing] != nil {
+				what = "registered folder"
+			}
+			clashes = append(clashes, fmt.Sprintf(`Registered folder "%v" %v has the same name as %v %v, so can only be used by its GUID`, d.Name, guid, what, existing))
+			knownfolders[guid] = &d.GUID
+			discoveredFolders[guid] = d
+			continue
+		}
+		knownfolders[d.Name] = &d.GUID
+		discoveredFolders[d.Name] = d
+	}
+	return
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// restoreKnownFolders puts knownfolders and discoveredFolders back as they
+// are now when the test finishes, for tests which merge folders into them.
+func restoreKnownFolders(t *testing.T) {
+	known := map[string]*GUID{}
+	for name, guid := range knownfolders {
+		known[name] = guid
+	}
+	discovered := discoveredFolders
+	discoveredFolders = map[string]*FolderDescription{}
+	t.Cleanup(func() {
+		knownfolders, discoveredFolders = known, discovered
+	})
+}
+
+// testGUID parses the GUID s, which must be valid.
+func testGUID(t *testing.T, s string) GUID {
+	t.Helper()
+	guid, err := ParseGUID(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return guid
+}
+
+func TestReadFolderDescriptions(t *testing.T) {
+	file := writeTestHive(t, filepath.Join(t.TempDir(), "SOFTWARE"), testKey{
+		"Microsoft": testKey{"Windows": testKey{"CurrentVersion": testKey{"Explorer": testKey{"FolderDescriptions": testKey{
+			"{FDD39AD0-238F-46AF-ADB4-6C85480369C7}": testKey{
+				"Name":          "Personal",
+				"Category":      uint32(KF_CATEGORY_PERUSER),
+				"LocalizedName": "@%SystemRoot%\\system32\\shell32.dll,-21770",
+				"Icon":          expandString(`%SystemRoot%\system32\imageres.dll,-112`),
+			},
+			"{0ddd015d-b06c-45d5-8c4c-f59713854639}": testKey{
+				"Name":         "AcmeData",
+				"Category":     uint32(KF_CATEGORY_PERUSER),
+				"ParentFolder": "{FDD39AD0-238F-46AF-ADB4-6C85480369C7}",
+				"RelativePath": `Acme\Data`,
+			},
+			// partially described, and not folders at all
+			"{1A2B3C4D-0000-0000-0000-000000000001}": testKey{"Name": "NoCategory"},
+			"{1A2B3C4D-0000-0000-0000-000000000002}": testKey{"Category": uint32(KF_CATEGORY_COMMON)},
+			"NotAGUID":                               testKey{"Name": "NotAFolder", "Category": uint32(KF_CATEGORY_COMMON)},
+		}}}}},
+	})
+	software, err := openHiveRoot(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer software.Close()
+	descriptions, err := ReadFolderDescriptions(software)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FolderDescription{
+		{
+			GUID:         testGUID(t, "{0DDD015D-B06C-45D5-8C4C-F59713854639}"),
+			Name:         "AcmeData",
+			Category:     KF_CATEGORY_PERUSER,
+			Parent:       "{FDD39AD0-238F-46AF-ADB4-6C85480369C7}",
+			RelativePath: `Acme\Data`,
+		},
+		{GUID: testGUID(t, "{1A2B3C4D-0000-0000-0000-000000000001}"), Name: "NoCategory"},
+		{
+			GUID:          *knownfolders["Documents"],
+			Name:          "Personal",
+			Category:      KF_CATEGORY_PERUSER,
+			LocalizedName: "@%SystemRoot%\\system32\\shell32.dll,-21770",
+			Icon:          `%SystemRoot%\system32\imageres.dll,-112`,
+		},
+	}
+	if !reflect.DeepEqual(descriptions, want) {
+		t.Errorf("Read\n%+v\nwant\n%+v", descriptions, want)
+	}
+	if name := descriptions[1].CategoryName(); name != "unknown (0)" {
+		t.Errorf("Category of a folder without one is %q", name)
+	}
+}
+
+func TestReadFolderDescriptionsErrors(t *testing.T) {
+	file := writeTestHive(t, filepath.Join(t.TempDir(), "SOFTWARE"), testKey{
+		"Microsoft": testKey{"Windows": testKey{"CurrentVersion": testKey{"Explorer": testKey{"FolderDescriptions": testKey{
+			"{1A2B3C4D-0000-0000-0000-000000000001}": testKey{"Name": "Acme", "Category": "peruser"},
+		}}}}},
+	})
+	software, err := openHiveRoot(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer software.Close()
+	if _, err := ReadFolderDescriptions(software); err == nil || !strings.Contains(err.Error(), "Could not read folder description {1A2B3C4D-0000-0000-0000-000000000001}") {
+		t.Errorf("Reading a description with a string Category returned %v", err)
+	}
+}
+
+func TestMergeFolderDescriptions(t *testing.T) {
+	restoreKnownFolders(t)
+	acme := FolderDescription{GUID: testGUID(t, "{1A2B3C4D-0000-0000-0000-000000000001}"), Name: "AcmeData", Category: KF_CATEGORY_PERUSER}
+	descriptions := []FolderDescription{
+		// built in, so already known
+		{GUID: *knownfolders["Documents"], Name: "Personal", Category: KF_CATEGORY_PERUSER},
+		acme,
+		// registered under a built-in name, an alias and another
+		// registered folder's name
+		{GUID: testGUID(t, "{1A2B3C4D-0000-0000-0000-000000000002}"), Name: "Documents", Category: KF_CATEGORY_PERUSER},
+		{GUID: testGUID(t, "{1A2B3C4D-0000-0000-0000-000000000003}"), Name: "Personal", Category: KF_CATEGORY_PERUSER},
+		{GUID: testGUID(t, "{1A2B3C4D-0000-0000-0000-000000000004}"), Name: "acmedata", Category: KF_CATEGORY_COMMON},
+	}
+	clashes := MergeFolderDescriptions(descriptions)
+	want := []string{
+		`Registered folder "Documents" {1A2B3C4D-0000-0000-0000-000000000002} has the same name as built-in folder Documents, so can only be used by its GUID`,
+		`Registered folder "Personal" {1A2B3C4D-0000-0000-0000-000000000003} has the same name as built-in folder Documents, so can only be used by its GUID`,
+	}
+	if !reflect.DeepEqual(clashes, want) {
+		t.Errorf("Clashes are\n%q\nwant\n%q", clashes, want)
+	}
+	if knownfolders["Documents"].String() != "{FDD39AD0-238F-46AF-ADB4-6C85480369C7}" || discoveredFolders["Documents"] != nil {
+		t.Error("A registered folder replaced the built-in Documents")
+	}
+	for _, test := range []struct {
+		Name string
+		GUID string
+	}{
+		{"AcmeData", "{1A2B3C4D-0000-0000-0000-000000000001}"},
+		{"{1A2B3C4D-0000-0000-0000-000000000002}", "{1A2B3C4D-0000-0000-0000-000000000002}"},
+		{"{1A2B3C4D-0000-0000-0000-000000000003}", "{1A2B3C4D-0000-0000-0000-000000000003}"},
+	} {
+		folder, ok := resolveFolder(test.Name)
+		if !ok || knownfolders[folder].String() != test.GUID || discoveredFolders[folder] == nil {
+			t.Errorf("%v resolves to %q (%v), and discovered %+v, want %v", test.Name, folder, ok, discoveredFolders[folder], test.GUID)
+		}
+	}
+	if d := discoveredFolders["AcmeData"]; d == nil || *d != acme {
+		t.Errorf("AcmeData was discovered as %+v, want %+v", d, acme)
+	}
+	// folder names are case sensitive, so a differently cased name isn't a
+	// clash, unlike aliases
+	if d := discoveredFolders["acmedata"]; d == nil || d.Category != KF_CATEGORY_COMMON {
+		t.Errorf("acmedata was discovered as %+v", d)
+	}
+	// merging again, e.g. from the same hive, changes nothing
+	if clashes := MergeFolderDescriptions(descriptions); len(clashes) != 0 {
+		t.Errorf("Merging again clashed: %q", clashes)
+	}
+}
+
+func TestMergeFolderDescriptionsDuplicateNames(t *testing.T) {
+	restoreKnownFolders(t)
+	clashes := MergeFolderDescriptions([]FolderDescription{
+		{GUID: testGUID(t, "{1A2B3C4D-0000-0000-0000-000000000001}"), Name: "AcmeData", Category: KF_CATEGORY_PERUSER},
+		{GUID: testGUID(t, "{1A2B3C4D-0000-0000-0000-000000000002}"), Name: "AcmeData", Category: KF_CATEGORY_COMMON},
+	})
+	want := []string{`Registered folder "AcmeData" {1A2B3C4D-0000-0000-0000-000000000002} has the same name as registered folder AcmeData, so can only be used by its GUID`}
+	if !reflect.DeepEqual(clashes, want) {
+		t.Errorf("Clashes are\n%q\nwant\n%q", clashes, want)
+	}
+	if knownfolders["AcmeData"].String() != "{1A2B3C4D-0000-0000-0000-000000000001}" {
+		t.Errorf("AcmeData is %v, want the first registered", knownfolders["AcmeData"])
+	}
+}
@@ -0,0 +1,409 @@
+//
+// KnownFolders.h
+//
+// The DEFINE_KNOWN_FOLDER lines of the Windows SDK KnownFolders.h, which
+// gen/knownfolders generates the knownfolders table from. To pick up new
+// folders, replace this file with a newer copy of the SDK header (or add the
+// new lines to it) and run "go generate".
+//
+
+#ifndef DEFINE_KNOWN_FOLDER
+#define DEFINE_KNOWN_FOLDER(name, l, w1, w2, b1, b2, b3, b4, b5, b6, b7, b8) \
+    EXTERN_C const GUID DECLSPEC_SELECTANY name \
+            = { l, w1, w2, { b1, b2,  b3,  b4,  b5,  b6,  b7,  b8 } }
+#endif // DEFINE_KNOWN_FOLDER
+
+// {008CA0B1-55B4-4C56-B8A8-4DE4B299D3BE}
+DEFINE_KNOWN_FOLDER(FOLDERID_AccountPictures,              0x008CA0B1, 0x55B4, 0x4C56, 0xB8, 0xA8, 0x4D, 0xE4, 0xB2, 0x99, 0xD3, 0xBE);
+
+// {DE61D971-5EBC-4F02-A3A9-6C82895E5C04}
+DEFINE_KNOWN_FOLDER(FOLDERID_AddNewPrograms,               0xDE61D971, 0x5EBC, 0x4F02, 0xA3, 0xA9, 0x6C, 0x82, 0x89, 0x5E, 0x5C, 0x04);
+
+// {724EF170-A42D-4FEF-9F26-B60E846FBA4F}
+DEFINE_KNOWN_FOLDER(FOLDERID_AdminTools,                   0x724EF170, 0xA42D, 0x4FEF, 0x9F, 0x26, 0xB6, 0x0E, 0x84, 0x6F, 0xBA, 0x4F);
+
+// {B2C5E279-7ADD-439F-B28C-C41FE1BBF672}
+DEFINE_KNOWN_FOLDER(FOLDERID_AppDataDesktop,               0xB2C5E279, 0x7ADD, 0x439F, 0xB2, 0x8C, 0xC4, 0x1F, 0xE1, 0xBB, 0xF6, 0x72);
+
+// {7BE16610-1F7F-44AC-BFF0-83E15F2FFCA1}
+DEFINE_KNOWN_FOLDER(FOLDERID_AppDataDocuments,             0x7BE16610, 0x1F7F, 0x44AC, 0xBF, 0xF0, 0x83, 0xE1, 0x5F, 0x2F, 0xFC, 0xA1);
+
+// {7CFBEFBC-DE1F-45AA-B843-A542AC536CC9}
+DEFINE_KNOWN_FOLDER(FOLDERID_AppDataFavorites,             0x7CFBEFBC, 0xDE1F, 0x45AA, 0xB8, 0x43, 0xA5, 0x42, 0xAC, 0x53, 0x6C, 0xC9);
+
+// {559D40A3-A036-40FA-AF61-84CB430A4D34}
+DEFINE_KNOWN_FOLDER(FOLDERID_AppDataProgramData,           0x559D40A3, 0xA036, 0x40FA, 0xAF, 0x61, 0x84, 0xCB, 0x43, 0x0A, 0x4D, 0x34);
+
+// {A3918781-E5F2-4890-B3D9-A7E54332328C}
+DEFINE_KNOWN_FOLDER(FOLDERID_ApplicationShortcuts,         0xA3918781, 0xE5F2, 0x4890, 0xB3, 0xD9, 0xA7, 0xE5, 0x43, 0x32, 0x32, 0x8C);
+
+// {1E87508D-89C2-42F0-8A7E-645A0F50CA58}
+DEFINE_KNOWN_FOLDER(FOLDERID_AppsFolder,                   0x1E87508D, 0x89C2, 0x42F0, 0x8A, 0x7E, 0x64, 0x5A, 0x0F, 0x50, 0xCA, 0x58);
+
+// {A305CE99-F527-492B-8B1A-7E76FA98D6E4}
+DEFINE_KNOWN_FOLDER(FOLDERID_AppUpdates,                   0xA305CE99, 0xF527, 0x492B, 0x8B, 0x1A, 0x7E, 0x76, 0xFA, 0x98, 0xD6, 0xE4);
+
+// {AB5FB87B-7CE2-4F83-915D-550846C9537B}
+DEFINE_KNOWN_FOLDER(FOLDERID_CameraRoll,                   0xAB5FB87B, 0x7CE2, 0x4F83, 0x91, 0x5D, 0x55, 0x08, 0x46, 0xC9, 0x53, 0x7B);
+
+// {9E52AB10-F80D-49DF-ACB8-4330F5687855}
+DEFINE_KNOWN_FOLDER(FOLDERID_CDBurning,                    0x9E52AB10, 0xF80D, 0x49DF, 0xAC, 0xB8, 0x43, 0x30, 0xF5, 0x68, 0x78, 0x55);
+
+// {DF7266AC-9274-4867-8D55-3BD661DE872D}
+DEFINE_KNOWN_FOLDER(FOLDERID_ChangeRemovePrograms,         0xDF7266AC, 0x9274, 0x4867, 0x8D, 0x55, 0x3B, 0xD6, 0x61, 0xDE, 0x87, 0x2D);
+
+// {D0384E7D-BAC3-4797-8F14-CBA229B392B5}
+DEFINE_KNOWN_FOLDER(FOLDERID_CommonAdminTools,             0xD0384E7D, 0xBAC3, 0x4797, 0x8F, 0x14, 0xCB, 0xA2, 0x29, 0xB3, 0x92, 0xB5);
+
+// {C1BAE2D0-10DF-4334-BEDD-7AA20B227A9D}
+DEFINE_KNOWN_FOLDER(FOLDERID_CommonOEMLinks,               0xC1BAE2D0, 0x10DF, 0x4334, 0xBE, 0xDD, 0x7A, 0xA2, 0x0B, 0x22, 0x7A, 0x9D);
+
+// {0139D44E-6AFE-49F2-8690-3DAFCAE6FFB8}
+DEFINE_KNOWN_FOLDER(FOLDERID_CommonPrograms,               0x0139D44E, 0x6AFE, 0x49F2, 0x86, 0x90, 0x3D, 0xAF, 0xCA, 0xE6, 0xFF, 0xB8);
+
+// {A4115719-D62E-491D-AA7C-E74B8BE3B067}
+DEFINE_KNOWN_FOLDER(FOLDERID_CommonStartMenu,              0xA4115719, 0xD62E, 0x491D, 0xAA, 0x7C, 0xE7, 0x4B, 0x8B, 0xE3, 0xB0, 0x67);
+
+// {82A5EA35-D9CD-47C5-9629-E15D2F714E6E}
+DEFINE_KNOWN_FOLDER(FOLDERID_CommonStartup,                0x82A5EA35, 0xD9CD, 0x47C5, 0x96, 0x29, 0xE1, 0x5D, 0x2F, 0x71, 0x4E, 0x6E);
+
+// {B94237E7-57AC-4347-9151-B08C6C32D1F7}
+DEFINE_KNOWN_FOLDER(FOLDERID_CommonTemplates,              0xB94237E7, 0x57AC, 0x4347, 0x91, 0x51, 0xB0, 0x8C, 0x6C, 0x32, 0xD1, 0xF7);
+
+// {0AC0837C-BBF8-452A-850D-79D08E667CA7}
+DEFINE_KNOWN_FOLDER(FOLDERID_ComputerFolder,               0x0AC0837C, 0xBBF8, 0x452A, 0x85, 0x0D, 0x79, 0xD0, 0x8E, 0x66, 0x7C, 0xA7);
+
+// {4BFEFB45-347D-4006-A5BE-AC0CB0567192}
+DEFINE_KNOWN_FOLDER(FOLDERID_ConflictFolder,               0x4BFEFB45, 0x347D, 0x4006, 0xA5, 0xBE, 0xAC, 0x0C, 0xB0, 0x56, 0x71, 0x92);
+
+// {6F0CD92B-2E97-45D1-88FF-B0D186B8DEDD}
+DEFINE_KNOWN_FOLDER(FOLDERID_ConnectionsFolder,            0x6F0CD92B, 0x2E97, 0x45D1, 0x88, 0xFF, 0xB0, 0xD1, 0x86, 0xB8, 0xDE, 0xDD);
+
+// {56784854-C6CB-462B-8169-88E350ACB882}
+DEFINE_KNOWN_FOLDER(FOLDERID_Contacts,                     0x56784854, 0xC6CB, 0x462B, 0x81, 0x69, 0x88, 0xE3, 0x50, 0xAC, 0xB8, 0x82);
+
+// {82A74AEB-AEB4-465C-A014-D097EE346D63}
+DEFINE_KNOWN_FOLDER(FOLDERID_ControlPanelFolder,           0x82A74AEB, 0xAEB4, 0x465C, 0xA0, 0x14, 0xD0, 0x97, 0xEE, 0x34, 0x6D, 0x63);
+
+// {2B0F765D-C0E9-4171-908E-08A611B84FF6}
+DEFINE_KNOWN_FOLDER(FOLDERID_Cookies,                      0x2B0F765D, 0xC0E9, 0x4171, 0x90, 0x8E, 0x08, 0xA6, 0x11, 0xB8, 0x4F, 0xF6);
+
+// {B4BFCC3A-DB2C-424C-B029-7FE99A87C641}
+DEFINE_KNOWN_FOLDER(FOLDERID_Desktop,                      0xB4BFCC3A, 0xDB2C, 0x424C, 0xB0, 0x29, 0x7F, 0xE9, 0x9A, 0x87, 0xC6, 0x41);
+
+// {5CE4A5E9-E4EB-479D-B89F-130C02886155}
+DEFINE_KNOWN_FOLDER(FOLDERID_DeviceMetadataStore,          0x5CE4A5E9, 0xE4EB, 0x479D, 0xB8, 0x9F, 0x13, 0x0C, 0x02, 0x88, 0x61, 0x55);
+
+// {FDD39AD0-238F-46AF-ADB4-6C85480369C7}
+DEFINE_KNOWN_FOLDER(FOLDERID_Documents,                    0xFDD39AD0, 0x238F, 0x46AF, 0xAD, 0xB4, 0x6C, 0x85, 0x48, 0x03, 0x69, 0xC7);
+
+// {7B0DB17D-9CD2-4A93-9733-46CC89022E7C}
+DEFINE_KNOWN_FOLDER(FOLDERID_DocumentsLibrary,             0x7B0DB17D, 0x9CD2, 0x4A93, 0x97, 0x33, 0x46, 0xCC, 0x89, 0x02, 0x2E, 0x7C);
+
+// {374DE290-123F-4565-9164-39C4925E467B}
+DEFINE_KNOWN_FOLDER(FOLDERID_Downloads,                    0x374DE290, 0x123F, 0x4565, 0x91, 0x64, 0x39, 0xC4, 0x92, 0x5E, 0x46, 0x7B);
+
+// {1777F761-68AD-4D8A-87BD-30B759FA33DD}
+DEFINE_KNOWN_FOLDER(FOLDERID_Favorites,                    0x1777F761, 0x68AD, 0x4D8A, 0x87, 0xBD, 0x30, 0xB7, 0x59, 0xFA, 0x33, 0xDD);
+
+// {FD228CB7-AE11-4AE3-864C-16F3910AB8FE}
+DEFINE_KNOWN_FOLDER(FOLDERID_Fonts,                        0xFD228CB7, 0xAE11, 0x4AE3, 0x86, 0x4C, 0x16, 0xF3, 0x91, 0x0A, 0xB8, 0xFE);
+
+// {CAC52C1A-B53D-4EDC-92D7-6B2E8AC19434}
+DEFINE_KNOWN_FOLDER(FOLDERID_Games,                        0xCAC52C1A, 0xB53D, 0x4EDC, 0x92, 0xD7, 0x6B, 0x2E, 0x8A, 0xC1, 0x94, 0x34);
+
+// {054FAE61-4DD8-4787-80B6-090220C4B700}
+DEFINE_KNOWN_FOLDER(FOLDERID_GameTasks,                    0x054FAE61, 0x4DD8, 0x4787, 0x80, 0xB6, 0x09, 0x02, 0x20, 0xC4, 0xB7, 0x00);
+
+// {D9DC8A3B-B784-432E-A781-5A1130A75963}
+DEFINE_KNOWN_FOLDER(FOLDERID_History,                      0xD9DC8A3B, 0xB784, 0x432E, 0xA7, 0x81, 0x5A, 0x11, 0x30, 0xA7, 0x59, 0x63);
+
+// {52528A6B-B9E3-4ADD-B60D-588C2DBA842D}
+DEFINE_KNOWN_FOLDER(FOLDERID_HomeGroup,                    0x52528A6B, 0xB9E3, 0x4ADD, 0xB6, 0x0D, 0x58, 0x8C, 0x2D, 0xBA, 0x84, 0x2D);
+
+// {9B74B6A3-0DFD-4F11-9E78-5F7800F2E772}
+DEFINE_KNOWN_FOLDER(FOLDERID_HomeGroupCurrentUser,         0x9B74B6A3, 0x0DFD, 0x4F11, 0x9E, 0x78, 0x5F, 0x78, 0x00, 0xF2, 0xE7, 0x72);
+
+// {BCB5256F-79F6-4CEE-B725-DC34E402FD46}
+DEFINE_KNOWN_FOLDER(FOLDERID_ImplicitAppShortcuts,         0xBCB5256F, 0x79F6, 0x4CEE, 0xB7, 0x25, 0xDC, 0x34, 0xE4, 0x02, 0xFD, 0x46);
+
+// {352481E8-33BE-4251-BA85-6007CAEDCF9D}
+DEFINE_KNOWN_FOLDER(FOLDERID_InternetCache,                0x352481E8, 0x33BE, 0x4251, 0xBA, 0x85, 0x60, 0x07, 0xCA, 0xED, 0xCF, 0x9D);
+
+// {4D9F7874-4E0C-4904-967B-40B0D20C3E4B}
+DEFINE_KNOWN_FOLDER(FOLDERID_InternetFolder,               0x4D9F7874, 0x4E0C, 0x4904, 0x96, 0x7B, 0x40, 0xB0, 0xD2, 0x0C, 0x3E, 0x4B);
+
+// {1B3EA5DC-B587-4786-B4EF-BD1DC332AEAE}
+DEFINE_KNOWN_FOLDER(FOLDERID_Libraries,                    0x1B3EA5DC, 0xB587, 0x4786, 0xB4, 0xEF, 0xBD, 0x1D, 0xC3, 0x32, 0xAE, 0xAE);
+
+// {BFB9D5E0-C6A9-404C-B2B2-AE6DB6AF4968}
+DEFINE_KNOWN_FOLDER(FOLDERID_Links,                        0xBFB9D5E0, 0xC6A9, 0x404C, 0xB2, 0xB2, 0xAE, 0x6D, 0xB6, 0xAF, 0x49, 0x68);
+
+// {F1B32785-6FBA-4FCF-9D55-7B8E7F157091}
+DEFINE_KNOWN_FOLDER(FOLDERID_LocalAppData,                 0xF1B32785, 0x6FBA, 0x4FCF, 0x9D, 0x55, 0x7B, 0x8E, 0x7F, 0x15, 0x70, 0x91);
+
+// {A520A1A4-1780-4FF6-BD18-167343C5AF16}
+DEFINE_KNOWN_FOLDER(FOLDERID_LocalAppDataLow,              0xA520A1A4, 0x1780, 0x4FF6, 0xBD, 0x18, 0x16, 0x73, 0x43, 0xC5, 0xAF, 0x16);
+
+// {F42EE2D3-909F-4907-8871-4C22FC0BF756}
+DEFINE_KNOWN_FOLDER(FOLDERID_LocalDocuments,               0xF42EE2D3, 0x909F, 0x4907, 0x88, 0x71, 0x4C, 0x22, 0xFC, 0x0B, 0xF7, 0x56);
+
+// {7D83EE9B-2244-4E70-B1F5-5393042AF1E4}
+DEFINE_KNOWN_FOLDER(FOLDERID_LocalDownloads,               0x7D83EE9B, 0x2244, 0x4E70, 0xB1, 0xF5, 0x53, 0x93, 0x04, 0x2A, 0xF1, 0xE4);
+
+// {2A00375E-224C-49DE-B8D1-440DF7EF3DDC}
+DEFINE_KNOWN_FOLDER(FOLDERID_LocalizedResourcesDir,        0x2A00375E, 0x224C, 0x49DE, 0xB8, 0xD1, 0x44, 0x0D, 0xF7, 0xEF, 0x3D, 0xDC);
+
+// {A0C69A99-21C8-4671-8703-7934162FCF1D}
+DEFINE_KNOWN_FOLDER(FOLDERID_LocalMusic,                   0xA0C69A99, 0x21C8, 0x4671, 0x87, 0x03, 0x79, 0x34, 0x16, 0x2F, 0xCF, 0x1D);
+
+// {0DDD015D-B06C-45D5-8C4C-F59713854639}
+DEFINE_KNOWN_FOLDER(FOLDERID_LocalPictures,                0x0DDD015D, 0xB06C, 0x45D5, 0x8C, 0x4C, 0xF5, 0x97, 0x13, 0x85, 0x46, 0x39);
+
+// {35286A68-3C57-41A1-BBB1-0EAE73D76C95}
+DEFINE_KNOWN_FOLDER(FOLDERID_LocalVideos,                  0x35286A68, 0x3C57, 0x41A1, 0xBB, 0xB1, 0x0E, 0xAE, 0x73, 0xD7, 0x6C, 0x95);
+
+// {4BD8D571-6D19-48D3-BE97-422220080E43}
+DEFINE_KNOWN_FOLDER(FOLDERID_Music,                        0x4BD8D571, 0x6D19, 0x48D3, 0xBE, 0x97, 0x42, 0x22, 0x20, 0x08, 0x0E, 0x43);
+
+// {2112AB0A-C86A-4FFE-A368-0DE96E47012E}
+DEFINE_KNOWN_FOLDER(FOLDERID_MusicLibrary,                 0x2112AB0A, 0xC86A, 0x4FFE, 0xA3, 0x68, 0x0D, 0xE9, 0x6E, 0x47, 0x01, 0x2E);
+
+// {C5ABBF53-E17F-4121-8900-86626FC2C973}
+DEFINE_KNOWN_FOLDER(FOLDERID_NetHood,                      0xC5ABBF53, 0xE17F, 0x4121, 0x89, 0x00, 0x86, 0x62, 0x6F, 0xC2, 0xC9, 0x73);
+
+// {D20BEEC4-5CA8-4905-AE3B-BF251EA09B53}
+DEFINE_KNOWN_FOLDER(FOLDERID_NetworkFolder,                0xD20BEEC4, 0x5CA8, 0x4905, 0xAE, 0x3B, 0xBF, 0x25, 0x1E, 0xA0, 0x9B, 0x53);
+
+// {31C0DD25-9439-4F12-BF41-7FF4EDA38722}
+DEFINE_KNOWN_FOLDER(FOLDERID_Objects3D,                    0x31C0DD25, 0x9439, 0x4F12, 0xBF, 0x41, 0x7F, 0xF4, 0xED, 0xA3, 0x87, 0x22);
+
+// {2C36C0AA-5812-4B87-BFD0-4CD0DFB19B39}
+DEFINE_KNOWN_FOLDER(FOLDERID_OriginalImages,               0x2C36C0AA, 0x5812, 0x4B87, 0xBF, 0xD0, 0x4C, 0xD0, 0xDF, 0xB1, 0x9B, 0x39);
+
+// {69D2CF90-FC33-4FB7-9A0C-EBB0F0FCB43C}
+DEFINE_KNOWN_FOLDER(FOLDERID_PhotoAlbums,                  0x69D2CF90, 0xFC33, 0x4FB7, 0x9A, 0x0C, 0xEB, 0xB0, 0xF0, 0xFC, 0xB4, 0x3C);
+
+// {33E28130-4E1E-4676-835A-98395C3BC3BB}
+DEFINE_KNOWN_FOLDER(FOLDERID_Pictures,                     0x33E28130, 0x4E1E, 0x4676, 0x83, 0x5A, 0x98, 0x39, 0x5C, 0x3B, 0xC3, 0xBB);
+
+// {A990AE9F-A03B-4E80-94BC-9912D7504104}
+DEFINE_KNOWN_FOLDER(FOLDERID_PicturesLibrary,              0xA990AE9F, 0xA03B, 0x4E80, 0x94, 0xBC, 0x99, 0x12, 0xD7, 0x50, 0x41, 0x04);
+
+// {DE92C1C7-837F-4F69-A3BB-86E631204A23}
+DEFINE_KNOWN_FOLDER(FOLDERID_Playlists,                    0xDE92C1C7, 0x837F, 0x4F69, 0xA3, 0xBB, 0x86, 0xE6, 0x31, 0x20, 0x4A, 0x23);
+
+// {76FC4E2D-D6AD-4519-A663-37BD56068185}
+DEFINE_KNOWN_FOLDER(FOLDERID_PrintersFolder,               0x76FC4E2D, 0xD6AD, 0x4519, 0xA6, 0x63, 0x37, 0xBD, 0x56, 0x06, 0x81, 0x85);
+
+// {9274BD8D-CFD1-41C3-B35E-B13F55A758F4}
+DEFINE_KNOWN_FOLDER(FOLDERID_PrintHood,                    0x9274BD8D, 0xCFD1, 0x41C3, 0xB3, 0x5E, 0xB1, 0x3F, 0x55, 0xA7, 0x58, 0xF4);
+
+// {5E6C858F-0E22-4760-9AFE-EA3317B67173}
+DEFINE_KNOWN_FOLDER(FOLDERID_Profile,                      0x5E6C858F, 0x0E22, 0x4760, 0x9A, 0xFE, 0xEA, 0x33, 0x17, 0xB6, 0x71, 0x73);
+
+// {62AB5D82-FDC1-4DC3-A9DD-070D1D495D97}
+DEFINE_KNOWN_FOLDER(FOLDERID_ProgramData,                  0x62AB5D82, 0xFDC1, 0x4DC3, 0xA9, 0xDD, 0x07, 0x0D, 0x1D, 0x49, 0x5D, 0x97);
+
+// {905E63B6-C1BF-494E-B29C-65B732D3D21A}
+DEFINE_KNOWN_FOLDER(FOLDERID_ProgramFiles,                 0x905E63B6, 0xC1BF, 0x494E, 0xB2, 0x9C, 0x65, 0xB7, 0x32, 0xD3, 0xD2, 0x1A);
+
+// {F7F1ED05-9F6D-47A2-AAAE-29D317C6F066}
+DEFINE_KNOWN_FOLDER(FOLDERID_ProgramFilesCommon,           0xF7F1ED05, 0x9F6D, 0x47A2, 0xAA, 0xAE, 0x29, 0xD3, 0x17, 0xC6, 0xF0, 0x66);
+
+// {6365D5A7-0F0D-45E5-87F6-0DA56B6A4F7D}
+DEFINE_KNOWN_FOLDER(FOLDERID_ProgramFilesCommonX64,        0x6365D5A7, 0x0F0D, 0x45E5, 0x87, 0xF6, 0x0D, 0xA5, 0x6B, 0x6A, 0x4F, 0x7D);
+
+// {DE974D24-D9C6-4D3E-BF91-F4455120B917}
+DEFINE_KNOWN_FOLDER(FOLDERID_ProgramFilesCommonX86,        0xDE974D24, 0xD9C6, 0x4D3E, 0xBF, 0x91, 0xF4, 0x45, 0x51, 0x20, 0xB9, 0x17);
+
+// {6D809377-6AF0-444B-8957-A3773F02200E}
+DEFINE_KNOWN_FOLDER(FOLDERID_ProgramFilesX64,              0x6D809377, 0x6AF0, 0x444B, 0x89, 0x57, 0xA3, 0x77, 0x3F, 0x02, 0x20, 0x0E);
+
+// {7C5A40EF-A0FB-4BFC-874A-C0F2E0B9FA8E}
+DEFINE_KNOWN_FOLDER(FOLDERID_ProgramFilesX86,              0x7C5A40EF, 0xA0FB, 0x4BFC, 0x87, 0x4A, 0xC0, 0xF2, 0xE0, 0xB9, 0xFA, 0x8E);
+
+// {A77F5D77-2E2B-44C3-A6A2-ABA601054A51}
+DEFINE_KNOWN_FOLDER(FOLDERID_Programs,                     0xA77F5D77, 0x2E2B, 0x44C3, 0xA6, 0xA2, 0xAB, 0xA6, 0x01, 0x05, 0x4A, 0x51);
+
+// {DFDF76A2-C82A-4D63-906A-5644AC457385}
+DEFINE_KNOWN_FOLDER(FOLDERID_Public,                       0xDFDF76A2, 0xC82A, 0x4D63, 0x90, 0x6A, 0x56, 0x44, 0xAC, 0x45, 0x73, 0x85);
+
+// {C4AA340D-F20F-4863-AFEF-F87EF2E6BA25}
+DEFINE_KNOWN_FOLDER(FOLDERID_PublicDesktop,                0xC4AA340D, 0xF20F, 0x4863, 0xAF, 0xEF, 0xF8, 0x7E, 0xF2, 0xE6, 0xBA, 0x25);
+
+// {ED4824AF-DCE4-45A8-81E2-FC7965083634}
+DEFINE_KNOWN_FOLDER(FOLDERID_PublicDocuments,              0xED4824AF, 0xDCE4, 0x45A8, 0x81, 0xE2, 0xFC, 0x79, 0x65, 0x08, 0x36, 0x34);
+
+// {3D644C9B-1FB8-4F30-9B45-F670235F79C0}
+DEFINE_KNOWN_FOLDER(FOLDERID_PublicDownloads,              0x3D644C9B, 0x1FB8, 0x4F30, 0x9B, 0x45, 0xF6, 0x70, 0x23, 0x5F, 0x79, 0xC0);
+
+// {DEBF2536-E1A8-4C59-B6A2-414586476AEA}
+DEFINE_KNOWN_FOLDER(FOLDERID_PublicGameTasks,              0xDEBF2536, 0xE1A8, 0x4C59, 0xB6, 0xA2, 0x41, 0x45, 0x86, 0x47, 0x6A, 0xEA);
+
+// {48DAF80B-E6CF-4F4E-B800-0E69D84EE384}
+DEFINE_KNOWN_FOLDER(FOLDERID_PublicLibraries,              0x48DAF80B, 0xE6CF, 0x4F4E, 0xB8, 0x00, 0x0E, 0x69, 0xD8, 0x4E, 0xE3, 0x84);
+
+// {3214FAB5-9757-4298-BB61-92A9DEAA44FF}
+DEFINE_KNOWN_FOLDER(FOLDERID_PublicMusic,                  0x3214FAB5, 0x9757, 0x4298, 0xBB, 0x61, 0x92, 0xA9, 0xDE, 0xAA, 0x44, 0xFF);
+
+// {B6EBFB86-6907-413C-9AF7-4FC2ABF07CC5}
+DEFINE_KNOWN_FOLDER(FOLDERID_PublicPictures,               0xB6EBFB86, 0x6907, 0x413C, 0x9A, 0xF7, 0x4F, 0xC2, 0xAB, 0xF0, 0x7C, 0xC5);
+
+// {E555AB60-153B-4D17-9F04-A5FE99FC15EC}
+DEFINE_KNOWN_FOLDER(FOLDERID_PublicRingtones,              0xE555AB60, 0x153B, 0x4D17, 0x9F, 0x04, 0xA5, 0xFE, 0x99, 0xFC, 0x15, 0xEC);
+
+// {0482AF6C-08F1-4C34-8C90-E17EC98B1E17}
+DEFINE_KNOWN_FOLDER(FOLDERID_PublicUserTiles,              0x0482AF6C, 0x08F1, 0x4C34, 0x8C, 0x90, 0xE1, 0x7E, 0xC9, 0x8B, 0x1E, 0x17);
+
+// {2400183A-6185-49FB-A2D8-4A392A602BA3}
+DEFINE_KNOWN_FOLDER(FOLDERID_PublicVideos,                 0x2400183A, 0x6185, 0x49FB, 0xA2, 0xD8, 0x4A, 0x39, 0x2A, 0x60, 0x2B, 0xA3);
+
+// {52A4F021-7B75-48A9-9F6B-4B87A210BC8F}
+DEFINE_KNOWN_FOLDER(FOLDERID_QuickLaunch,                  0x52A4F021, 0x7B75, 0x48A9, 0x9F, 0x6B, 0x4B, 0x87, 0xA2, 0x10, 0xBC, 0x8F);
+
+// {AE50C081-EBD2-438A-8655-8A092E34987A}
+DEFINE_KNOWN_FOLDER(FOLDERID_Recent,                       0xAE50C081, 0xEBD2, 0x438A, 0x86, 0x55, 0x8A, 0x09, 0x2E, 0x34, 0x98, 0x7A);
+
+// {1A6FDBA2-F42D-4358-A798-B74D745926C5}
+DEFINE_KNOWN_FOLDER(FOLDERID_RecordedTVLibrary,            0x1A6FDBA2, 0xF42D, 0x4358, 0xA7, 0x98, 0xB7, 0x4D, 0x74, 0x59, 0x26, 0xC5);
+
+// {B7534046-3ECB-4C18-BE4E-64CD4CB7D6AC}
+DEFINE_KNOWN_FOLDER(FOLDERID_RecycleBinFolder,             0xB7534046, 0x3ECB, 0x4C18, 0xBE, 0x4E, 0x64, 0xCD, 0x4C, 0xB7, 0xD6, 0xAC);
+
+// {8AD10C31-2ADB-4296-A8F7-E4701232C972}
+DEFINE_KNOWN_FOLDER(FOLDERID_ResourceDir,                  0x8AD10C31, 0x2ADB, 0x4296, 0xA8, 0xF7, 0xE4, 0x70, 0x12, 0x32, 0xC9, 0x72);
+
+// {C870044B-F49E-4126-A9C3-B52A1FF411E8}
+DEFINE_KNOWN_FOLDER(FOLDERID_Ringtones,                    0xC870044B, 0xF49E, 0x4126, 0xA9, 0xC3, 0xB5, 0x2A, 0x1F, 0xF4, 0x11, 0xE8);
+
+// {AAA8D5A5-F1D6-4259-BAA8-78E7EF60835E}
+DEFINE_KNOWN_FOLDER(FOLDERID_RoamedTileImages,             0xAAA8D5A5, 0xF1D6, 0x4259, 0xBA, 0xA8, 0x78, 0xE7, 0xEF, 0x60, 0x83, 0x5E);
+
+// {3EB685DB-65F9-4CF6-A03A-E3EF65729F3D}
+DEFINE_KNOWN_FOLDER(FOLDERID_RoamingAppData,               0x3EB685DB, 0x65F9, 0x4CF6, 0xA0, 0x3A, 0xE3, 0xEF, 0x65, 0x72, 0x9F, 0x3D);
+
+// {00BCFC5A-ED94-4E48-96A1-3F6217F21990}
+DEFINE_KNOWN_FOLDER(FOLDERID_RoamingTiles,                 0x00BCFC5A, 0xED94, 0x4E48, 0x96, 0xA1, 0x3F, 0x62, 0x17, 0xF2, 0x19, 0x90);
+
+// {B250C668-F57D-4EE1-A63C-290EE7D1AA1F}
+DEFINE_KNOWN_FOLDER(FOLDERID_SampleMusic,                  0xB250C668, 0xF57D, 0x4EE1, 0xA6, 0x3C, 0x29, 0x0E, 0xE7, 0xD1, 0xAA, 0x1F);
+
+// {C4900540-2379-4C75-844B-64E6FAF8716B}
+DEFINE_KNOWN_FOLDER(FOLDERID_SamplePictures,               0xC4900540, 0x2379, 0x4C75, 0x84, 0x4B, 0x64, 0xE6, 0xFA, 0xF8, 0x71, 0x6B);
+
+// {15CA69B3-30EE-49C1-ACE1-6B5EC372AFB5}
+DEFINE_KNOWN_FOLDER(FOLDERID_SamplePlaylists,              0x15CA69B3, 0x30EE, 0x49C1, 0xAC, 0xE1, 0x6B, 0x5E, 0xC3, 0x72, 0xAF, 0xB5);
+
+// {859EAD94-2E85-48AD-A71A-0969CB56A6CD}
+DEFINE_KNOWN_FOLDER(FOLDERID_SampleVideos,                 0x859EAD94, 0x2E85, 0x48AD, 0xA7, 0x1A, 0x09, 0x69, 0xCB, 0x56, 0xA6, 0xCD);
+
+// {4C5C32FF-BB9D-43B0-B5B4-2D72E54EAAA4}
+DEFINE_KNOWN_FOLDER(FOLDERID_SavedGames,                   0x4C5C32FF, 0xBB9D, 0x43B0, 0xB5, 0xB4, 0x2D, 0x72, 0xE5, 0x4E, 0xAA, 0xA4);
+
+// {3B193882-D3AD-4EAB-965A-69829D1FB59F}
+DEFINE_KNOWN_FOLDER(FOLDERID_SavedPictures,                0x3B193882, 0xD3AD, 0x4EAB, 0x96, 0x5A, 0x69, 0x82, 0x9D, 0x1F, 0xB5, 0x9F);
+
+// {E25B5812-BE88-4BD9-94B0-29233477B6C3}
+DEFINE_KNOWN_FOLDER(FOLDERID_SavedPicturesLibrary,         0xE25B5812, 0xBE88, 0x4BD9, 0x94, 0xB0, 0x29, 0x23, 0x34, 0x77, 0xB6, 0xC3);
+
+// {7D1D3A04-DEBB-4115-95CF-2F29DA2920DA}
+DEFINE_KNOWN_FOLDER(FOLDERID_SavedSearches,                0x7D1D3A04, 0xDEBB, 0x4115, 0x95, 0xCF, 0x2F, 0x29, 0xDA, 0x29, 0x20, 0xDA);
+
+// {B7BEDE81-DF94-4682-A7D8-57A52620B86F}
+DEFINE_KNOWN_FOLDER(FOLDERID_Screenshots,                  0xB7BEDE81, 0xDF94, 0x4682, 0xA7, 0xD8, 0x57, 0xA5, 0x26, 0x20, 0xB8, 0x6F);
+
+// {EE32E446-31CA-4ABA-814F-A5EBD2FD6D5E}
+DEFINE_KNOWN_FOLDER(FOLDERID_SEARCH_CSC,                   0xEE32E446, 0x31CA, 0x4ABA, 0x81, 0x4F, 0xA5, 0xEB, 0xD2, 0xFD, 0x6D, 0x5E);
+
+// {98EC0E18-2098-4D44-8644-66979315A281}
+DEFINE_KNOWN_FOLDER(FOLDERID_SEARCH_MAPI,                  0x98EC0E18, 0x2098, 0x4D44, 0x86, 0x44, 0x66, 0x97, 0x93, 0x15, 0xA2, 0x81);
+
+// {0D4C3DB6-03A3-462F-A0E6-08924C41B5D4}
+DEFINE_KNOWN_FOLDER(FOLDERID_SearchHistory,                0x0D4C3DB6, 0x03A3, 0x462F, 0xA0, 0xE6, 0x08, 0x92, 0x4C, 0x41, 0xB5, 0xD4);
+
+// {190337D1-B8CA-4121-A639-6D472D16972A}
+DEFINE_KNOWN_FOLDER(FOLDERID_SearchHome,                   0x190337D1, 0xB8CA, 0x4121, 0xA6, 0x39, 0x6D, 0x47, 0x2D, 0x16, 0x97, 0x2A);
+
+// {7E636BFE-DFA9-4D5E-B456-D7B39851D8A9}
+DEFINE_KNOWN_FOLDER(FOLDERID_SearchTemplates,              0x7E636BFE, 0xDFA9, 0x4D5E, 0xB4, 0x56, 0xD7, 0xB3, 0x98, 0x51, 0xD8, 0xA9);
+
+// {8983036C-27C0-404B-8F08-102D10DCFD74}
+DEFINE_KNOWN_FOLDER(FOLDERID_SendTo,                       0x8983036C, 0x27C0, 0x404B, 0x8F, 0x08, 0x10, 0x2D, 0x10, 0xDC, 0xFD, 0x74);
+
+// {7B396E54-9EC5-4300-BE0A-2482EBAE1A26}
+DEFINE_KNOWN_FOLDER(FOLDERID_SidebarDefaultParts,          0x7B396E54, 0x9EC5, 0x4300, 0xBE, 0x0A, 0x24, 0x82, 0xEB, 0xAE, 0x1A, 0x26);
+
+// {A75D362E-50FC-4FB7-AC2C-A8BEAA314493}
+DEFINE_KNOWN_FOLDER(FOLDERID_SidebarParts,                 0xA75D362E, 0x50FC, 0x4FB7, 0xAC, 0x2C, 0xA8, 0xBE, 0xAA, 0x31, 0x44, 0x93);
+
+#if (NTDDI_VERSION >= NTDDI_WINBLUE)
+// {A52BBA46-E9E1-435F-B3D9-28DAA648C0F6}
+DEFINE_KNOWN_FOLDER(FOLDERID_SkyDrive,                     0xa52bba46, 0xe9e1, 0x435f, 0xb3, 0xd9, 0x28, 0xda, 0xa6, 0x48, 0xc0, 0xf6);
+
+// {767E6811-49CB-4273-87C2-20F355E1085B}
+DEFINE_KNOWN_FOLDER(FOLDERID_SkyDriveCameraRoll,           0x767e6811, 0x49cb, 0x4273, 0x87, 0xc2, 0x20, 0xf3, 0x55, 0xe1, 0x08, 0x5b);
+
+// {24D89E24-2F19-4534-9DDE-6A6671FBB8FE}
+DEFINE_KNOWN_FOLDER(FOLDERID_SkyDriveDocuments,            0x24d89e24, 0x2f19, 0x4534, 0x9d, 0xde, 0x6a, 0x66, 0x71, 0xfb, 0xb8, 0xfe);
+
+// {C3F2459E-80D6-45DC-BFEF-1F769F2BE730}
+DEFINE_KNOWN_FOLDER(FOLDERID_SkyDriveMusic,                0xc3f2459e, 0x80d6, 0x45dc, 0xbf, 0xef, 0x1f, 0x76, 0x9f, 0x2b, 0xe7, 0x30);
+
+// {339719B5-8C47-4894-94C2-D8F77ADD44A6}
+DEFINE_KNOWN_FOLDER(FOLDERID_SkyDrivePictures,             0x339719b5, 0x8c47, 0x4894, 0x94, 0xc2, 0xd8, 0xf7, 0x7a, 0xdd, 0x44, 0xa6);
+
+// {A52BBA46-E9E1-435F-B3D9-28DAA648C0F6}
+DEFINE_KNOWN_FOLDER(FOLDERID_OneDrive,                     0xa52bba46, 0xe9e1, 0x435f, 0xb3, 0xd9, 0x28, 0xda, 0xa6, 0x48, 0xc0, 0xf6);
+#endif // (NTDDI_VERSION >= NTDDI_WINBLUE)
+
+// {625B53C3-AB48-4EC1-BA1F-A1EF4146FC19}
+DEFINE_KNOWN_FOLDER(FOLDERID_StartMenu,                    0x625B53C3, 0xAB48, 0x4EC1, 0xBA, 0x1F, 0xA1, 0xEF, 0x41, 0x46, 0xFC, 0x19);
+
+// {B97D20BB-F46A-4C97-BA10-5E3608430854}
+DEFINE_KNOWN_FOLDER(FOLDERID_Startup,                      0xB97D20BB, 0xF46A, 0x4C97, 0xBA, 0x10, 0x5E, 0x36, 0x08, 0x43, 0x08, 0x54);
+
+// {43668BF8-C14E-49B2-97C9-747784D784B7}
+DEFINE_KNOWN_FOLDER(FOLDERID_SyncManagerFolder,            0x43668BF8, 0xC14E, 0x49B2, 0x97, 0xC9, 0x74, 0x77, 0x84, 0xD7, 0x84, 0xB7);
+
+// {289A9A43-BE44-4057-A41B-587A76D7E7F9}
+DEFINE_KNOWN_FOLDER(FOLDERID_SyncResultsFolder,            0x289A9A43, 0xBE44, 0x4057, 0xA4, 0x1B, 0x58, 0x7A, 0x76, 0xD7, 0xE7, 0xF9);
+
+// {0F214138-B1D3-4A90-BBA9-27CBC0C5389A}
+DEFINE_KNOWN_FOLDER(FOLDERID_SyncSetupFolder,              0x0F214138, 0xB1D3, 0x4A90, 0xBB, 0xA9, 0x27, 0xCB, 0xC0, 0xC5, 0x38, 0x9A);
+
+// {1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}
+DEFINE_KNOWN_FOLDER(FOLDERID_System,                       0x1AC14E77, 0x02E7, 0x4E5D, 0xB7, 0x44, 0x2E, 0xB1, 0xAE, 0x51, 0x98, 0xB7);
+
+// {D65231B0-B2F1-4857-A4CE-A8E7C6EA7D27}
+DEFINE_KNOWN_FOLDER(FOLDERID_SystemX86,                    0xD65231B0, 0xB2F1, 0x4857, 0xA4, 0xCE, 0xA8, 0xE7, 0xC6, 0xEA, 0x7D, 0x27);
+
+// {A63293E8-664E-48DB-A079-DF759E0509F7}
+DEFINE_KNOWN_FOLDER(FOLDERID_Templates,                    0xA63293E8, 0x664E, 0x48DB, 0xA0, 0x79, 0xDF, 0x75, 0x9E, 0x05, 0x09, 0xF7);
+
+// {9E3995AB-1F9C-4F13-B827-48B24B6C7174}
+DEFINE_KNOWN_FOLDER(FOLDERID_UserPinned,                   0x9E3995AB, 0x1F9C, 0x4F13, 0xB8, 0x27, 0x48, 0xB2, 0x4B, 0x6C, 0x71, 0x74);
+
+// {0762D272-C50A-4BB0-A382-697DCD729B80}
+DEFINE_KNOWN_FOLDER(FOLDERID_UserProfiles,                 0x0762D272, 0xC50A, 0x4BB0, 0xA3, 0x82, 0x69, 0x7D, 0xCD, 0x72, 0x9B, 0x80);
+
+// {5CD7AEE2-2219-4A67-B85D-6C9CE15660CB}
+DEFINE_KNOWN_FOLDER(FOLDERID_UserProgramFiles,             0x5CD7AEE2, 0x2219, 0x4A67, 0xB8, 0x5D, 0x6C, 0x9C, 0xE1, 0x56, 0x60, 0xCB);
+
+// {BCBD3057-CA5C-4622-B42D-BC56DB0AE516}
+DEFINE_KNOWN_FOLDER(FOLDERID_UserProgramFilesCommon,       0xBCBD3057, 0xCA5C, 0x4622, 0xB4, 0x2D, 0xBC, 0x56, 0xDB, 0x0A, 0xE5, 0x16);
+
+// {F3CE0F7C-4901-4ACC-8648-D5D44B04EF8F}
+DEFINE_KNOWN_FOLDER(FOLDERID_UsersFiles,                   0xF3CE0F7C, 0x4901, 0x4ACC, 0x86, 0x48, 0xD5, 0xD4, 0x4B, 0x04, 0xEF, 0x8F);
+
+// {A302545D-DEFF-464B-ABE8-61C8648D939B}
+DEFINE_KNOWN_FOLDER(FOLDERID_UsersLibraries,               0xA302545D, 0xDEFF, 0x464B, 0xAB, 0xE8, 0x61, 0xC8, 0x64, 0x8D, 0x93, 0x9B);
+
+// {18989B1D-99B5-455B-841C-AB7C74E4DDFC}
+DEFINE_KNOWN_FOLDER(FOLDERID_Videos,                       0x18989B1D, 0x99B5, 0x455B, 0x84, 0x1C, 0xAB, 0x7C, 0x74, 0xE4, 0xDD, 0xFC);
+
+// {491E922F-5643-4AF4-A7EB-4E7A138D8174}
+DEFINE_KNOWN_FOLDER(FOLDERID_VideosLibrary,                0x491E922F, 0x5643, 0x4AF4, 0xA7, 0xEB, 0x4E, 0x7A, 0x13, 0x8D, 0x81, 0x74);
+
+// {F38BF404-1D43-42F2-9305-67DE0B28FC23}
+DEFINE_KNOWN_FOLDER(FOLDERID_Windows,                      0xF38BF404, 0x1D43, 0x42F2, 0x93, 0x05, 0x67, 0xDE, 0x0B, 0x28, 0xFC, 0x23);
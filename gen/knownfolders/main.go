@@ -0,0 +1,276 @@
+// Command knownfolders generates the knownfolders table from the
+// DEFINE_KNOWN_FOLDER lines of the Windows SDK KnownFolders.h header, and the
+// knownfolderDescriptions table from FolderDescriptions.csv, since the header
+// has nothing but GUIDs. It is run by "go generate" in the repository root;
+// with -check, it instead exits with a non-zero exit code if the generated
+// file is not up to date.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	definePattern  = regexp.MustCompile(`^\s*DEFINE_KNOWN_FOLDER\(\s*FOLDERID_(\w+)\s*,((?:\s*0[xX][0-9A-Fa-f]+\s*,?){11})\)\s*;`)
+	guidComment    = regexp.MustCompile(`^\s*//\s*\{([0-9A-Fa-f-]{36})\}\s*$`)
+	commentPattern = regexp.MustCompile(`^\s*//\s?(.*)$`)
+)
+
+// folder is a single DEFINE_KNOWN_FOLDER line of the header.
+type folder struct {
+	Name string
+	// GUID in registry format, e.g. {F1B32785-6FBA-4FCF-9D55-7B8E7F157091}
+	GUID   string
+	Values [11]uint64
+	// Comment holds any comment lines directly above the definition, apart
+	// from the usual one repeating the GUID.
+	Comment []string
+	Line    int
+	// Alias is the name of the folder first defined with the same GUID, if
+	// any.
+	Alias string
+}
+
+// description is a row of FolderDescriptions.csv.
+type description struct {
+	// Name is the Name value of the folder's FolderDescriptions key
+	Name string
+	// Category is the name of a KF_CATEGORY_ constant
+	Category string
+	Line     int
+}
+
+// categories are the Category values of FolderDescriptions.csv, and the
+// constants they are generated as.
+var categories = map[string]string{
+	"VIRTUAL": "KF_CATEGORY_VIRTUAL",
+	"FIXED":   "KF_CATEGORY_FIXED",
+	"COMMON":  "KF_CATEGORY_COMMON",
+	"PERUSER": "KF_CATEGORY_PERUSER",
+}
+
+func main() {
+	header := flag.String("header", "gen/knownfolders/KnownFolders.h", "the KnownFolders.h header to read")
+	descriptionsFile := flag.String("descriptions", "gen/knownfolders/FolderDescriptions.csv", "the names and categories of the folders")
+	out := flag.String("out", "knownfolders_table.go", "the Go file to generate")
+	check := flag.Bool("check", false, "check that the generated file is up to date, rather than writing it")
+	flag.Parse()
+
+	f, err := os.Open(*header)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	folders, err := parseHeader(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("%v: %v", *header, err)
+	}
+	f, err = os.Open(*descriptionsFile)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	descriptions, err := parseDescriptions(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("%v: %v", *descriptionsFile, err)
+	}
+	source, err := generate(folders, descriptions)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if *check {
+		existing, err := ioutil.ReadFile(*out)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if !bytes.Equal(existing, source) {
+			log.Fatalf("%v is out of date with %v or %v, please run go generate", *out, *header, *descriptionsFile)
+		}
+		return
+	}
+	err = ioutil.WriteFile(*out, source, 0644)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+// parseHeader returns the folders defined in r, checking each against the
+// GUID comment above it, if there is one.
+func parseHeader(r io.Reader) ([]folder, error) {
+	folders := []folder{}
+	comment := []string{}
+	guid := ""
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if m := guidComment.FindStringSubmatch(text); m != nil {
+			guid = "{" + strings.ToUpper(m[1]) + "}"
+			continue
+		}
+		if m := commentPattern.FindStringSubmatch(text); m != nil {
+			comment = append(comment, m[1])
+			continue
+		}
+		m := definePattern.FindStringSubmatch(text)
+		if m == nil {
+			if strings.Contains(text, "DEFINE_KNOWN_FOLDER(FOLDERID_") {
+				return nil, fmt.Errorf("line %v: could not parse %q", line, text)
+			}
+			comment = comment[:0]
+			guid = ""
+			continue
+		}
+		f := folder{Name: m[1], Line: line}
+		for i, v := range strings.Split(m[2], ",")[:11] {
+			f.Values[i], _ = strconv.ParseUint(strings.TrimSpace(v), 0, 32)
+		}
+		f.GUID = fmt.Sprintf("{%08X-%04X-%04X-%02X%02X-%02X%02X%02X%02X%02X%02X}",
+			f.Values[0], f.Values[1], f.Values[2], f.Values[3], f.Values[4],
+			f.Values[5], f.Values[6], f.Values[7], f.Values[8], f.Values[9], f.Values[10])
+		if guid != "" && guid != f.GUID {
+			return nil, fmt.Errorf("line %v: FOLDERID_%v is %v, but the comment above it says %v", line, f.Name, f.GUID, guid)
+		}
+		f.Comment = append([]string{}, comment...)
+		folders = append(folders, f)
+		comment = comment[:0]
+		guid = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(folders) == 0 {
+		return nil, fmt.Errorf("no DEFINE_KNOWN_FOLDER lines found")
+	}
+	return folders, nil
+}
+
+// parseDescriptions returns the rows of r, a CSV file with a header row and
+// the columns constant, name and category, by folder name.
+func parseDescriptions(r io.Reader) (map[string]description, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 3
+	descriptions := map[string]description{}
+	header := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if header {
+			header = false
+			continue
+		}
+		if !strings.HasPrefix(record[0], "FOLDERID_") {
+			return nil, fmt.Errorf("line %v: %q is not a FOLDERID_ constant", line, record[0])
+		}
+		name := strings.TrimPrefix(record[0], "FOLDERID_")
+		if d, ok := descriptions[name]; ok {
+			return nil, fmt.Errorf("line %v: %v is already described on line %v", line, record[0], d.Line)
+		}
+		if record[1] == "" {
+			return nil, fmt.Errorf("line %v: %v has no name", line, record[0])
+		}
+		if _, ok := categories[record[2]]; !ok {
+			return nil, fmt.Errorf("line %v: %v has unknown category %q", line, record[0], record[2])
+		}
+		descriptions[name] = description{Name: record[1], Category: record[2], Line: line}
+	}
+	return descriptions, nil
+}
+
+// generate returns the formatted Go source of the knownfolders table. Folders
+// with the same GUID as one defined earlier in the header (e.g. OneDrive,
+// which replaced SkyDrive) go in knownfolderAliases rather than the table, so
+// that every GUID has a single name. Every folder of the table must be in
+// descriptions, and nothing else.
+func generate(folders []folder, descriptions map[string]description) ([]byte, error) {
+	sort.SliceStable(folders, func(i, j int) bool { return folders[i].Line < folders[j].Line })
+	names := map[string]bool{}
+	byGUID := map[string]string{}
+	table := []folder{}
+	aliases := []folder{}
+	for _, f := range folders {
+		if names[f.Name] {
+			return nil, fmt.Errorf("FOLDERID_%v is defined twice", f.Name)
+		}
+		names[f.Name] = true
+		if first, ok := byGUID[f.GUID]; ok {
+			f.Alias = first
+			aliases = append(aliases, f)
+			continue
+		}
+		byGUID[f.GUID] = f.Name
+		table = append(table, f)
+	}
+	byName := func(list []folder) func(i, j int) bool {
+		return func(i, j int) bool { return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name) }
+	}
+	sort.Slice(table, byName(table))
+	sort.Slice(aliases, byName(aliases))
+	for _, f := range table {
+		if _, ok := descriptions[f.Name]; !ok {
+			return nil, fmt.Errorf("FOLDERID_%v is not described", f.Name)
+		}
+	}
+	for _, a := range aliases {
+		if d, ok := descriptions[a.Name]; ok {
+			return nil, fmt.Errorf("line %v: FOLDERID_%v is an alias of FOLDERID_%v, so has its description", d.Line, a.Name, a.Alias)
+		}
+	}
+	for name, d := range descriptions {
+		if !names[name] {
+			return nil, fmt.Errorf("line %v: FOLDERID_%v is not defined", d.Line, name)
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprint(&b, "// Code generated by gen/knownfolders from KnownFolders.h; DO NOT EDIT.\n\n")
+	fmt.Fprint(&b, "package main\n\n")
+	fmt.Fprint(&b, "// knownfolders maps the names of the FOLDERID_ constants of the Windows SDK,\n")
+	fmt.Fprint(&b, "// without the prefix, to their KNOWNFOLDERIDs. See\n")
+	fmt.Fprint(&b, "// https://msdn.microsoft.com/en-us/library/windows/desktop/dd378457(v=vs.85).aspx\n")
+	fmt.Fprint(&b, "var knownfolders = map[string]*GUID{\n")
+	for _, f := range table {
+		for _, c := range f.Comment {
+			fmt.Fprintf(&b, "// %v\n", c)
+		}
+		v := f.Values
+		fmt.Fprintf(&b, "%q: {0x%08X, 0x%04X, 0x%04X, [8]byte{0x%02X, 0x%02X, 0x%02X, 0x%02X, 0x%02X, 0x%02X, 0x%02X, 0x%02X}}, // %v\n",
+			f.Name, v[0], v[1], v[2], v[3], v[4], v[5], v[6], v[7], v[8], v[9], v[10], f.GUID)
+	}
+	fmt.Fprint(&b, "}\n\n")
+	fmt.Fprint(&b, "// knownfolderAliases are FOLDERID_ constants (without the prefix) with the same\n")
+	fmt.Fprint(&b, "// KNOWNFOLDERID as a folder in knownfolders.\n")
+	fmt.Fprint(&b, "var knownfolderAliases = []struct {\n\tName   string\n\tFolder string\n}{\n")
+	for _, a := range aliases {
+		fmt.Fprintf(&b, "{%q, %q},\n", a.Name, a.Alias)
+	}
+	fmt.Fprint(&b, "}\n\n")
+	fmt.Fprint(&b, "// knownfolderDescriptions are the Names and Categories of the folders in\n")
+	fmt.Fprint(&b, "// knownfolders, as described in the registry by Windows 10.\n")
+	fmt.Fprint(&b, "var knownfolderDescriptions = map[string]struct {\n\tName     string\n\tCategory uint32\n}{\n")
+	for _, f := range table {
+		d := descriptions[f.Name]
+		fmt.Fprintf(&b, "%q: {%q, %v},\n", f.Name, d.Name, categories[d.Category])
+	}
+	fmt.Fprint(&b, "}\n")
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// parse returns the folders and descriptions of header and csv, failing the
+// test if either can't be parsed.
+func parse(t *testing.T, header, csv string) ([]folder, map[string]description) {
+	t.Helper()
+	folders, err := parseHeader(strings.NewReader(header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptions, err := parseDescriptions(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return folders, descriptions
+}
+
+func TestTableIsCurrent(t *testing.T) {
+	f, err := os.Open("KnownFolders.h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	folders, err := parseHeader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := os.Open("FolderDescriptions.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	descriptions, err := parseDescriptions(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := generate(folders, descriptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing, err := ioutil.ReadFile("../../knownfolders_table.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(existing, source) {
+		t.Error("knownfolders_table.go is out of date, please run go generate")
+	}
+	if !bytes.Contains(source, []byte(`{"OneDrive", "SkyDrive"},`)) {
+		t.Error("OneDrive is not generated as an alias of SkyDrive")
+	}
+}
+
+const testHeader = `// {A52BBA46-E9E1-435F-B3D9-28DAA648C0F6}
+DEFINE_KNOWN_FOLDER(FOLDERID_SkyDrive, 0xa52bba46, 0xe9e1, 0x435f, 0xb3, 0xd9, 0x28, 0xda, 0xa6, 0x48, 0xc0, 0xf6);
+
+// Where the user's documents are
+// {FDD39AD0-238F-46AF-ADB4-6C85480369C7}
+DEFINE_KNOWN_FOLDER(FOLDERID_Documents, 0xFDD39AD0, 0x238F, 0x46AF, 0xAD, 0xB4, 0x6C, 0x85, 0x48, 0x03, 0x69, 0xC7);
+
+DEFINE_KNOWN_FOLDER(FOLDERID_OneDrive, 0xa52bba46, 0xe9e1, 0x435f, 0xb3, 0xd9, 0x28, 0xda, 0xa6, 0x48, 0xc0, 0xf6);
+`
+
+const testDescriptions = `# comment
+constant,name,category
+FOLDERID_Documents,Personal,PERUSER
+FOLDERID_SkyDrive,OneDrive,PERUSER
+`
+
+func TestGenerate(t *testing.T) {
+	source, err := generate(parse(t, testHeader, testDescriptions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"// Where the user's documents are\n\t\"Documents\": {0xFDD39AD0, 0x238F, 0x46AF, [8]byte{0xAD, 0xB4, 0x6C, 0x85, 0x48, 0x03, 0x69, 0xC7}}, // {FDD39AD0-238F-46AF-ADB4-6C85480369C7}\n",
+		"\t\"SkyDrive\":  {0xA52BBA46, 0xE9E1, 0x435F, [8]byte{0xB3, 0xD9, 0x28, 0xDA, 0xA6, 0x48, 0xC0, 0xF6}}, // {A52BBA46-E9E1-435F-B3D9-28DAA648C0F6}\n",
+		"\t{\"OneDrive\", \"SkyDrive\"},\n",
+		"\t\"Documents\": {\"Personal\", KF_CATEGORY_PERUSER},\n",
+		"\t\"SkyDrive\":  {\"OneDrive\", KF_CATEGORY_PERUSER},\n",
+	} {
+		if !strings.Contains(string(source), want) {
+			t.Errorf("Generated source doesn't contain %q:\n%s", want, source)
+		}
+	}
+	if strings.Contains(string(source), "\"OneDrive\": {0x") {
+		t.Errorf("Alias OneDrive is in the table:\n%s", source)
+	}
+}
+
+func TestParseHeaderErrors(t *testing.T) {
+	for _, test := range []struct {
+		Header string
+		Err    string
+	}{
+		{
+			Header: "// {FDD39AD0-238F-46AF-ADB4-6C85480369C8}\nDEFINE_KNOWN_FOLDER(FOLDERID_Documents, 0xFDD39AD0, 0x238F, 0x46AF, 0xAD, 0xB4, 0x6C, 0x85, 0x48, 0x03, 0x69, 0xC7);\n",
+			Err:    "line 2: FOLDERID_Documents is {FDD39AD0-238F-46AF-ADB4-6C85480369C7}, but the comment above it says {FDD39AD0-238F-46AF-ADB4-6C85480369C8}",
+		},
+		{
+			Header: "DEFINE_KNOWN_FOLDER(FOLDERID_Documents, 0xFDD39AD0, 0x238F, 0x46AF, 0xAD, 0xB4, 0x6C, 0x85, 0x48, 0x03, 0x69);\n",
+			Err:    `line 1: could not parse "DEFINE_KNOWN_FOLDER(FOLDERID_Documents, 0xFDD39AD0, 0x238F, 0x46AF, 0xAD, 0xB4, 0x6C, 0x85, 0x48, 0x03, 0x69);"`,
+		},
+		{
+			Header: "#pragma once\n",
+			Err:    "no DEFINE_KNOWN_FOLDER lines found",
+		},
+	} {
+		_, err := parseHeader(strings.NewReader(test.Header))
+		if err == nil || err.Error() != test.Err {
+			t.Errorf("Parsing %q returned %v, want %v", test.Header, err, test.Err)
+		}
+	}
+}
+
+func TestParseDescriptionsErrors(t *testing.T) {
+	for _, test := range []struct {
+		CSV string
+		Err string
+	}{
+		{
+			CSV: "constant,name,category\nDocuments,Personal,PERUSER\n",
+			Err: `line 2: "Documents" is not a FOLDERID_ constant`,
+		},
+		{
+			CSV: "constant,name,category\nFOLDERID_Documents,Personal,PERUSER\nFOLDERID_Documents,Documents,PERUSER\n",
+			Err: "line 3: FOLDERID_Documents is already described on line 2",
+		},
+		{
+			CSV: "constant,name,category\nFOLDERID_Documents,,PERUSER\n",
+			Err: "line 2: FOLDERID_Documents has no name",
+		},
+		{
+			CSV: "constant,name,category\nFOLDERID_Documents,Personal,USER\n",
+			Err: `line 2: FOLDERID_Documents has unknown category "USER"`,
+		},
+	} {
+		_, err := parseDescriptions(strings.NewReader(test.CSV))
+		if err == nil || err.Error() != test.Err {
+			t.Errorf("Parsing %q returned %v, want %v", test.CSV, err, test.Err)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, test := range []struct {
+		Descriptions string
+		Err          string
+	}{
+		{
+			Descriptions: "constant,name,category\nFOLDERID_Documents,Personal,PERUSER\n",
+			Err:          "FOLDERID_SkyDrive is not described",
+		},
+		{
+			Descriptions: testDescriptions + "FOLDERID_OneDrive,OneDrive,PERUSER\n",
+			Err:          "line 5: FOLDERID_OneDrive is an alias of FOLDERID_SkyDrive, so has its description",
+		},
+		{
+			Descriptions: testDescriptions + "FOLDERID_Music,My Music,PERUSER\n",
+			Err:          "line 5: FOLDERID_Music is not defined",
+		},
+	} {
+		_, err := generate(parse(t, testHeader, test.Descriptions))
+		if err == nil || err.Error() != test.Err {
+			t.Errorf("Generating with %q returned %v, want %v", test.Descriptions, err, test.Err)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// everyoneSID is the well known SID of the Everyone group, which Folder
+// Redirection uses for targets that apply to all users ("Basic" redirection).
+const everyoneSID = "S-1-1-0"
+
+// fdeployFollowParent is the [FolderStatus] flag of a folder which follows
+// its parent, i.e. is redirected to a subfolder of wherever its parent is
+// redirected to, e.g. Pictures following Documents, rather than having
+// targets of its own.
+const fdeployFollowParent = 0x4
+
+// fdeployParents are the folders that the folders which can follow their
+// parent follow.
+var fdeployParents = map[string]string{
+	"Music":    "Documents",
+	"Pictures": "Documents",
+	"Videos":   "Documents",
+}
+
+// fdeployLegacyNames maps the folder names used in pre-Vista fdeploy.ini
+// files to knownfolders names.
+var fdeployLegacyNames = map[string]string{
+	"application data": "RoamingAppData",
+	"desktop":          "Desktop",
+	"my documents":     "Documents",
+	"my pictures":      "Pictures",
+	"start menu":       "StartMenu",
+}
+
+// GPOUserMap determines which of the per-SID redirection targets of a Group
+// Policy apply to the user a manifest is being imported for.
+type GPOUserMap struct {
+	// SIDs of the user and the groups they belong to, most specific first.
+	// The Everyone group is always implicitly included last.
+	SIDs []string `yaml:"sids"`
+	// Username, if set, replaces %USERNAME% in redirection targets.
+	Username string `yaml:"username"`
+}
+
+// LoadGPOUserMap reads a YAML user mapping file, e.g.
+//
+//	sids:
+//	  - S-1-5-21-1004336348-1177238915-682003330-1105
+//	username: task_1
+func LoadGPOUserMap(file string) (*GPOUserMap, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	userMap := new(GPOUserMap)
+	err = yaml.UnmarshalStrict(data, userMap)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse user map %v:\n%v", file, err)
+	}
+	return userMap, nil
+}
+
+// FindFdeploy returns the Folder Redirection configuration file to import
+// from path, which may be the file itself, or a directory such as a GPO
+// backup, which is searched for fdeploy1.ini (Windows Vista and later) or,
+// failing that, fdeploy.ini.
+func FindFdeploy(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return path, nil
+	}
+	for _, name := range []string{"fdeploy1.ini", "fdeploy.ini"} {
+		matches := []string{}
+		err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && strings.EqualFold(info.Name(), name) {
+				matches = append(matches, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], nil
+		default:
+			return "", fmt.Errorf("Found %v files named %v under %v, please specify which to import:\n  %v", len(matches), name, path, strings.Join(matches, "\n  "))
+		}
+	}
+	return "", fmt.Errorf("No fdeploy1.ini or fdeploy.ini found under %v", path)
+}
+
+// ImportFdeploy converts a Folder Redirection configuration file into a
+// manifest. The [FolderStatus] section lists the redirected folders, keyed by
+// KNOWNFOLDERID (or by name, in fdeploy.ini), and each folder's own section
+// maps SIDs to redirection targets. The target for the first SID in userMap
+// (or Everyone) which the folder has a target for is used; folders with no
+// applicable target are logged and skipped. The value of each folder in
+// [FolderStatus] is its redirection flags, of which only
+// fdeployFollowParent affects the location: such a folder is imported as a
+// subfolder of its parent, if its parent is imported. The rest, e.g. whether
+// the user is granted exclusive rights, or the contents are moved, are
+// ignored.
+func ImportFdeploy(file string, userMap *GPOUserMap) (*Manifest, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	ini := parseINI(data)
+	status := ini.section("FolderStatus")
+	if status == nil {
+		return nil, fmt.Errorf("No [FolderStatus] section in %v", file)
+	}
+	sids := []string{}
+	username := ""
+	if userMap != nil {
+		sids = append(sids, userMap.SIDs...)
+		username = userMap.Username
+	}
+	sids = append(sids, everyoneSID)
+
+	manifest := &Manifest{Folders: map[string]string{}}
+	followers := map[string]string{}
+	for _, entry := range status.entries() {
+		folder, ok := fdeployFolder(entry.Key)
+		if !ok {
+			log.Printf("Skipping unknown folder %v", entry.Key)
+			continue
+		}
+		flags, err := strconv.ParseUint(strings.TrimSpace(entry.Value), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid status %q of %v in %v", entry.Value, entry.Key, file)
+		}
+		if flags&fdeployFollowParent != 0 {
+			// the subfolder is named as the folder is in fdeploy.ini,
+			// e.g. "My Pictures", or after the known folder
+			subfolder := folder
+			if _, legacy := fdeployLegacyNames[strings.ToLower(entry.Key)]; legacy {
+				subfolder = entry.Key
+			}
+			followers[folder] = subfolder
+			continue
+		}
+		targets := ini.section(entry.Key)
+		if targets == nil {
+			log.Printf("Skipping %v: no redirection targets configured", folder)
+			continue
+		}
+		target := ""
+		for _, sid := range sids {
+			for _, t := range targets.entries() {
+				if strings.EqualFold(t.Key, sid) {
+					target = t.Value
+					break
+				}
+			}
+			if target != "" {
+				break
+			}
+		}
+		if target == "" {
+			log.Printf("Skipping %v: no redirection target for any of %v", folder, strings.Join(sids, ", "))
+			continue
+		}
+		if username != "" {
+			target = expandUsername(target, username)
+		}
+		manifest.Folders[folder] = target
+	}
+	for folder, subfolder := range followers {
+		parent, ok := fdeployParents[folder]
+		if !ok || manifest.Folders[parent] == "" {
+			log.Printf("Skipping %v: it follows its parent, which isn't redirected", folder)
+			continue
+		}
+		manifest.Folders[folder] = "{" + parent + `}\` + subfolder
+	}
+	return manifest, nil
+}
+
+var usernameVariable = regexp.MustCompile(`(?i)%USERNAME%`)
+
+func expandUsername(target, username string) string {
+	return usernameVariable.ReplaceAllLiteralString(target, username)
+}
+
+func fdeployFolder(key string) (string, bool) {
+	if folder, ok := folderByGUID(key); ok {
+		return folder, true
+	}
+	if folder, ok := fdeployLegacyNames[strings.ToLower(key)]; ok {
+		return folder, true
+	}
+	return resolveFolder(key)
+}
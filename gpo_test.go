@@ -0,0 +1,191 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testGPO is a GPO backup whose fdeploy1.ini, which is UTF-16 encoded as
+// Windows writes it, redirects Documents and Desktop for Everyone, Documents
+// differently for a group, and RoamingAppData only for another group, with
+// Pictures and Music following Documents.
+var testGPO = filepath.Join("testdata", "gpo", "{31B2F340-016D-11D2-945F-00C04FB984F9}")
+
+func TestImportFdeploy(t *testing.T) {
+	file, err := FindFdeploy(testGPO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		Name    string
+		File    string
+		UserMap *GPOUserMap
+		Want    map[string]string
+	}{
+		{
+			Name: "Everyone",
+			File: file,
+			Want: map[string]string{
+				"Documents": `\\fileserver\home\%USERNAME%\Documents`,
+				"Desktop":   `\\fileserver\home\%username%\Desktop`,
+				"Pictures":  `{Documents}\Pictures`,
+				"Music":     `{Documents}\Music`,
+			},
+		},
+		{
+			Name: "group before Everyone",
+			File: file,
+			UserMap: &GPOUserMap{
+				SIDs:     []string{"S-1-5-21-1004336348-1177238915-682003330-1105", "S-1-5-21-1004336348-1177238915-682003330-513"},
+				Username: "task_1",
+			},
+			Want: map[string]string{
+				"Documents": `\\fileserver\staff\task_1\Documents`,
+				"Desktop":   `\\fileserver\home\task_1\Desktop`,
+				"Pictures":  `{Documents}\Pictures`,
+				"Music":     `{Documents}\Music`,
+			},
+		},
+		{
+			Name:    "group only target",
+			File:    file,
+			UserMap: &GPOUserMap{SIDs: []string{"S-1-5-21-1004336348-1177238915-682003330-512"}, Username: "admin"},
+			Want: map[string]string{
+				"Documents":      `\\fileserver\home\admin\Documents`,
+				"Desktop":        `\\fileserver\home\admin\Desktop`,
+				"Pictures":       `{Documents}\Pictures`,
+				"Music":          `{Documents}\Music`,
+				"RoamingAppData": `\\fileserver\admins\admin\AppData`,
+			},
+		},
+		{
+			Name:    "legacy fdeploy.ini",
+			File:    filepath.Join("testdata", "fdeploy.ini"),
+			UserMap: &GPOUserMap{Username: "task_1"},
+			Want: map[string]string{
+				"Documents": `\\server\users\task_1\My Documents`,
+				"Desktop":   `\\server\users\task_1\Desktop`,
+				"Pictures":  `{Documents}\My Pictures`,
+			},
+		},
+	} {
+		manifest, err := ImportFdeploy(test.File, test.UserMap)
+		if err != nil {
+			t.Errorf("%v: %v", test.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(manifest.Folders, test.Want) {
+			t.Errorf("%v: imported %q, want %q", test.Name, manifest.Folders, test.Want)
+		}
+	}
+}
+
+func TestImportFdeployErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, test := range []struct {
+		INI  string
+		Want map[string]string
+		Err  string
+	}{
+		{INI: "[{FDD39AD0-238F-46AF-ADB4-6C85480369C7}]\ns-1-1-0=D:\\Docs\n", Err: "No [FolderStatus] section"},
+		{INI: "[FolderStatus]\n{FDD39AD0-238F-46AF-ADB4-6C85480369C7}=yes\n", Err: `Invalid status "yes"`},
+		// a follower whose parent isn't redirected is skipped
+		{INI: "[FolderStatus]\n{33E28130-4E1E-4676-835A-98395C3BC3BB}=4\n", Want: map[string]string{}},
+		// as is a folder that can't follow a parent
+		{
+			INI:  "[FolderStatus]\nMy Documents=11\nDesktop=4\n[My Documents]\ns-1-1-0=D:\\Docs\n",
+			Want: map[string]string{"Documents": `D:\Docs`},
+		},
+	} {
+		file := filepath.Join(dir, "fdeploy1.ini")
+		if err := ioutil.WriteFile(file, []byte(test.INI), 0644); err != nil {
+			t.Fatal(err)
+		}
+		manifest, err := ImportFdeploy(file, nil)
+		switch {
+		case test.Err != "":
+			if err == nil || !strings.Contains(err.Error(), test.Err) {
+				t.Errorf("Importing %q returned %v, want %v", test.INI, err, test.Err)
+			}
+		case err != nil:
+			t.Errorf("Importing %q: %v", test.INI, err)
+		case !reflect.DeepEqual(manifest.Folders, test.Want):
+			t.Errorf("Importing %q returned %q, want %q", test.INI, manifest.Folders, test.Want)
+		}
+	}
+}
+
+func TestFindFdeploy(t *testing.T) {
+	// writeFiles creates the given files, relative to a new directory,
+	// which it returns
+	writeFiles := func(files ...string) string {
+		dir := t.TempDir()
+		for _, file := range files {
+			path := filepath.Join(dir, file)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(path, []byte("[FolderStatus]\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+	for _, test := range []struct {
+		Name  string
+		Files []string
+		Want  string
+		Err   string
+	}{
+		{"GPO backup", []string{"GPO/User/Documents & Settings/fdeploy1.ini", "GPO/Machine/registry.pol"}, "GPO/User/Documents & Settings/fdeploy1.ini", ""},
+		{"fdeploy1.ini first", []string{"a/fdeploy.ini", "b/FDEPLOY1.INI"}, "b/FDEPLOY1.INI", ""},
+		{"legacy", []string{"User/fdeploy.ini"}, "User/fdeploy.ini", ""},
+		{"ambiguous", []string{"a/fdeploy1.ini", "b/fdeploy1.ini"}, "", "Found 2 files named fdeploy1.ini"},
+		{"none", []string{"GPO/Machine/registry.pol"}, "", "No fdeploy1.ini or fdeploy.ini found"},
+	} {
+		dir := writeFiles(test.Files...)
+		got, err := FindFdeploy(dir)
+		switch {
+		case test.Err != "":
+			if err == nil || !strings.Contains(err.Error(), test.Err) {
+				t.Errorf("%v: returned %v, want %v", test.Name, err, test.Err)
+			}
+		case err != nil:
+			t.Errorf("%v: %v", test.Name, err)
+		case got != filepath.Join(dir, filepath.FromSlash(test.Want)):
+			t.Errorf("%v: found %v, want %v", test.Name, got, test.Want)
+		}
+	}
+	file := filepath.Join("testdata", "fdeploy.ini")
+	if got, err := FindFdeploy(file); err != nil || got != file {
+		t.Errorf("FindFdeploy of a file returned %v (%v), want the file", got, err)
+	}
+	if _, err := FindFdeploy(filepath.Join("testdata", "no-such-gpo")); !os.IsNotExist(err) {
+		t.Errorf("FindFdeploy of a missing path returned %v", err)
+	}
+}
+
+func TestLoadGPOUserMap(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "users.yaml")
+	if err := ioutil.WriteFile(file, []byte("sids:\n  - S-1-5-21-1-2-3-1105\nusername: task_1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	userMap, err := LoadGPOUserMap(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&GPOUserMap{SIDs: []string{"S-1-5-21-1-2-3-1105"}, Username: "task_1"}); !reflect.DeepEqual(userMap, want) {
+		t.Errorf("Loaded %+v, want %+v", userMap, want)
+	}
+	if err := ioutil.WriteFile(file, []byte("sid: S-1-5-21-1-2-3-1105\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGPOUserMap(file); err == nil || !strings.Contains(err.Error(), "Could not parse user map") {
+		t.Errorf("Loading a user map with an unknown key returned %v", err)
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Hive is a registry hive file, such as SOFTWARE or NTUSER.DAT, read
+// directly rather than through the Windows registry API, so that the hives of
+// offline images can be inspected (and values set, see SetValue) on any
+// platform. Only the primary file is read: changes still in the transaction
+// logs (.LOG1/.LOG2) of a hive that was not cleanly unloaded are not seen.
+//
+// See https://github.com/msuhanov/regf/blob/master/Windows%20registry%20file%20format%20specification.md
+// for the file format.
+type Hive struct {
+	File string
+	data []byte
+	root uint32
+	// spareOffset and spareSize are the unallocated space at the end of the
+	// last hive bin added by SetValue
+	spareOffset uint32
+	spareSize   int
+}
+
+const (
+	// cell offsets are relative to the first hive bin, which follows the
+	// base block
+	hiveBinsOffset = 0x1000
+
+	keyCompressedName   = 0x0020
+	valueCompressedName = 0x0001
+
+	// values with more data than this are stored in "db" segments
+	bigDataSegmentSize = 16344
+
+	// no cell, e.g. for a key with no subkeys
+	noCell = 0xFFFFFFFF
+)
+
+var le = binary.LittleEndian
+
+// OpenHive reads the hive file at the given path.
+func OpenHive(file string) (*Hive, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < hiveBinsOffset || string(data[:4]) != "regf" {
+		return nil, fmt.Errorf("%v is not a registry hive file", file)
+	}
+	h := &Hive{File: file, data: data, root: le.Uint32(data[0x24:])}
+	if _, err := h.key(h.root, ""); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Root returns the root key of the hive.
+func (h *Hive) Root() RegistryKey {
+	k, err := h.key(h.root, "")
+	if err != nil {
+		// the hive is closed, since OpenHive checked the root
+		return brokenKey{err}
+	}
+	return k
+}
+
+// brokenKey is a key which can't be read, failing with err.
+type brokenKey struct {
+	err error
+}
+
+func (k brokenKey) OpenKey(path string) (RegistryKey, error) {
+	return nil, k.err
+}
+
+func (k brokenKey) SubkeyNames() ([]string, error) {
+	return nil, k.err
+}
+
+func (k brokenKey) Value(name string) (uint32, []byte, error) {
+	return 0, nil, k.err
+}
+
+func (k brokenKey) Close() error {
+	return nil
+}
+
+// Close releases the data of the hive, discarding any changes which haven't
+// been saved. Neither the hive nor its keys can be used afterwards.
+func (h *Hive) Close() error {
+	h.data = nil
+	return nil
+}
+
+// closingRootKey is the root key of a hive which is only needed for as long
+// as the key, so closing the key closes the hive.
+type closingRootKey struct {
+	*hiveKey
+}
+
+func (k closingRootKey) Close() error {
+	return k.hive.Close()
+}
+
+// ClosingRoot returns the root key of the hive, which closes the hive when
+// it is closed.
+func (h *Hive) ClosingRoot() RegistryKey {
+	k, err := h.key(h.root, "")
+	if err != nil {
+		return brokenKey{err}
+	}
+	return closingRootKey{k}
+}
+
+// openHiveRoot reads the hive file at the given path, returning its root
+// key, which must be closed once it is no longer needed.
+func openHiveRoot(file string) (RegistryKey, error) {
+	h, err := OpenHive(file)
+	if err != nil {
+		return nil, err
+	}
+	return h.ClosingRoot(), nil
+}
+
+func (h *Hive) corrupt(offset uint32, format string, args ...interface{}) error {
+	return fmt.Errorf("Registry hive %v is corrupt at cell 0x%x: %v", h.File, offset, fmt.Sprintf(format, args...))
+}
+
+// cell returns the data of the allocated cell at the given offset.
+func (h *Hive) cell(offset uint32) ([]byte, error) {
+	if h.data == nil {
+		return nil, fmt.Errorf("Registry hive %v is closed", h.File)
+	}
+	start := int64(hiveBinsOffset) + int64(offset)
+	if start+4 > int64(len(h.data)) {
+		return nil, h.corrupt(offset, "offset out of range")
+	}
+	size := -int64(int32(le.Uint32(h.data[start:])))
+	if size < 4 || start+size > int64(len(h.data)) {
+		return nil, h.corrupt(offset, "cell is unallocated, or has an invalid size")
+	}
+	return h.data[start+4 : start+size], nil
+}
+
+// signedCell returns the data of the cell at the given offset, checking that
+// it starts with signature and is at least minSize bytes long.
+func (h *Hive) signedCell(offset uint32, signature string, minSize int) ([]byte, error) {
+	c, err := h.cell(offset)
+	if err != nil {
+		return nil, err
+	}
+	if len(c) < minSize || string(c[:2]) != signature {
+		return nil, h.corrupt(offset, "expected %v cell", signature)
+	}
+	return c, nil
+}
+
+type hiveKey struct {
+	hive   *Hive
+	offset uint32
+	nk     []byte
+	path   string
+}
+
+func (h *Hive) key(offset uint32, path string) (*hiveKey, error) {
+	nk, err := h.signedCell(offset, "nk", 0x4C)
+	if err != nil {
+		return nil, err
+	}
+	if 0x4C+int(le.Uint16(nk[0x48:])) > len(nk) {
+		return nil, h.corrupt(offset, "key name out of range")
+	}
+	return &hiveKey{hive: h, offset: offset, nk: nk, path: path}, nil
+}
+
+func (k *hiveKey) name() string {
+	n := int(le.Uint16(k.nk[0x48:]))
+	return hiveString(k.nk[0x4C:0x4C+n], le.Uint16(k.nk[0x02:])&keyCompressedName != 0)
+}
+
+// hiveString decodes a key or value name, which is stored as Latin-1 if
+// compressed, otherwise UTF-16LE.
+func hiveString(data []byte, compressed bool) string {
+	if !compressed {
+		return decodeUTF16(data, le)
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// subkeys returns the offsets of the key's subkeys.
+func (k *hiveKey) subkeys() ([]uint32, error) {
+	if le.Uint32(k.nk[0x14:]) == 0 || le.Uint32(k.nk[0x1C:]) == noCell {
+		return nil, nil
+	}
+	return k.hive.subkeyList(le.Uint32(k.nk[0x1C:]), 0)
+}
+
+func (h *Hive) subkeyList(offset uint32, depth int) ([]uint32, error) {
+	c, err := h.cell(offset)
+	if err != nil {
+		return nil, err
+	}
+	if len(c) < 4 {
+		return nil, h.corrupt(offset, "subkey list too short")
+	}
+	count := int(le.Uint16(c[2:]))
+	var stride int
+	switch string(c[:2]) {
+	case "lf", "lh":
+		// offset followed by a name hint or hash
+		stride = 8
+	case "li", "ri":
+		stride = 4
+	default:
+		return nil, h.corrupt(offset, "expected subkey list")
+	}
+	if 4+count*stride > len(c) {
+		return nil, h.corrupt(offset, "subkey list out of range")
+	}
+	offsets := make([]uint32, 0, count)
+	for i := 0; i < count; i++ {
+		o := le.Uint32(c[4+i*stride:])
+		if string(c[:2]) != "ri" {
+			offsets = append(offsets, o)
+			continue
+		}
+		// an index root, listing other subkey lists
+		if depth > 0 {
+			return nil, h.corrupt(offset, "nested index root")
+		}
+		sub, err := h.subkeyList(o, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, sub...)
+	}
+	return offsets, nil
+}
+
+func (k *hiveKey) OpenKey(path string) (RegistryKey, error) {
+	current := k
+	for _, name := range strings.Split(path, `\`) {
+		if name == "" {
+			continue
+		}
+		offsets, err := current.subkeys()
+		if err != nil {
+			return nil, err
+		}
+		var next *hiveKey
+		for _, o := range offsets {
+			sub, err := k.hive.key(o, current.path+`\`+name)
+			if err != nil {
+				return nil, err
+			}
+			if strings.EqualFold(sub.name(), name) {
+				sub.path = current.path + `\` + sub.name()
+				next = sub
+				break
+			}
+		}
+		if next == nil {
+			return nil, RegistryNotFoundError(current.path + `\` + name)
+		}
+		current = next
+	}
+	return current, nil
+}
+
+func (k *hiveKey) SubkeyNames() ([]string, error) {
+	offsets, err := k.subkeys()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(offsets))
+	for _, o := range offsets {
+		sub, err := k.hive.key(o, "")
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, sub.name())
+	}
+	return names, nil
+}
+
+func (k *hiveKey) Value(name string) (valueType uint32, data []byte, err error) {
+	count := int(le.Uint32(k.nk[0x24:]))
+	if count == 0 {
+		return 0, nil, RegistryNotFoundError(k.path + `\` + name)
+	}
+	listOffset := le.Uint32(k.nk[0x28:])
+	list, err := k.hive.cell(listOffset)
+	if err != nil {
+		return 0, nil, err
+	}
+	if count*4 > len(list) {
+		return 0, nil, k.hive.corrupt(listOffset, "value list out of range")
+	}
+	for i := 0; i < count; i++ {
+		offset := le.Uint32(list[i*4:])
+		vk, err := k.hive.signedCell(offset, "vk", 0x14)
+		if err != nil {
+			return 0, nil, err
+		}
+		n := int(le.Uint16(vk[0x02:]))
+		if 0x14+n > len(vk) {
+			return 0, nil, k.hive.corrupt(offset, "value name out of range")
+		}
+		if !strings.EqualFold(hiveString(vk[0x14:0x14+n], le.Uint16(vk[0x10:])&valueCompressedName != 0), name) {
+			continue
+		}
+		data, err := k.hive.valueData(offset, vk)
+		return le.Uint32(vk[0x0C:]), data, err
+	}
+	return 0, nil, RegistryNotFoundError(k.path + `\` + name)
+}
+
+func (h *Hive) valueData(offset uint32, vk []byte) ([]byte, error) {
+	size := le.Uint32(vk[0x04:])
+	if size&0x80000000 != 0 {
+		// up to 4 bytes of data, stored in place of the data offset
+		size &^= 0x80000000
+		if size > 4 {
+			return nil, h.corrupt(offset, "resident value data too large")
+		}
+		return vk[0x08 : 0x08+size], nil
+	}
+	if size == 0 {
+		return []byte{}, nil
+	}
+	c, err := h.cell(le.Uint32(vk[0x08:]))
+	if err != nil {
+		return nil, err
+	}
+	if size > bigDataSegmentSize && len(c) >= 8 && string(c[:2]) == "db" {
+		return h.bigData(le.Uint32(vk[0x08:]), c, size)
+	}
+	if int64(size) > int64(len(c)) {
+		return nil, h.corrupt(offset, "value data out of range")
+	}
+	return c[:size], nil
+}
+
+func (h *Hive) bigData(offset uint32, db []byte, size uint32) ([]byte, error) {
+	count := int(le.Uint16(db[2:]))
+	list, err := h.cell(le.Uint32(db[4:]))
+	if err != nil {
+		return nil, err
+	}
+	if count*4 > len(list) {
+		return nil, h.corrupt(offset, "segment list out of range")
+	}
+	data := make([]byte, 0, size)
+	for i := 0; i < count && uint32(len(data)) < size; i++ {
+		segment, err := h.cell(le.Uint32(list[i*4:]))
+		if err != nil {
+			return nil, err
+		}
+		if len(segment) > bigDataSegmentSize {
+			segment = segment[:bigDataSegmentSize]
+		}
+		data = append(data, segment...)
+	}
+	if uint32(len(data)) < size {
+		return nil, h.corrupt(offset, "value data out of range")
+	}
+	return data[:size], nil
+}
+
+func (k *hiveKey) Close() error {
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testHive returns a hive written by writeTestHive, with values of each
+// type and data that is and isn't stored in the value cell.
+func testHive(t *testing.T) *Hive {
+	t.Helper()
+	file := writeTestHive(t, filepath.Join(t.TempDir(), "NTUSER.DAT"), testKey{
+		"Environment": testKey{
+			"TEMP":  expandString(`%USERPROFILE%\AppData\Local\Temp`),
+			"Count": uint32(7),
+			"Short": "a",
+		},
+		"Software": testKey{
+			"Microsoft": testKey{},
+			"Wine":      testKey{},
+		},
+	})
+	hive, err := OpenHive(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hive
+}
+
+func TestHiveRead(t *testing.T) {
+	hive := testHive(t)
+	names, err := hive.Root().SubkeyNames()
+	if err != nil || !reflect.DeepEqual(names, []string{"Environment", "Software"}) {
+		t.Errorf("Root has subkeys %q, %v", names, err)
+	}
+	software, err := hive.Root().OpenKey(`SOFTWARE\`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names, err := software.SubkeyNames(); err != nil || !reflect.DeepEqual(names, []string{"Microsoft", "Wine"}) {
+		t.Errorf("Software has subkeys %q, %v", names, err)
+	}
+	for _, test := range []struct {
+		Name string
+		Type uint32
+		Data []byte
+	}{
+		{"temp", REG_EXPAND_SZ, utf16Bytes(`%USERPROFILE%\AppData\Local\Temp`)},
+		{"Count", REG_DWORD, []byte{7, 0, 0, 0}},
+		// resident data, stored in the value cell
+		{"Short", REG_SZ, utf16Bytes("a")},
+	} {
+		key, err := hive.Root().OpenKey("environment")
+		if err != nil {
+			t.Fatal(err)
+		}
+		valueType, data, err := key.Value(test.Name)
+		if err != nil || valueType != test.Type || !bytes.Equal(data, test.Data) {
+			t.Errorf("Value %v is %v % X, %v", test.Name, valueType, data, err)
+		}
+	}
+	if _, err := hive.Root().OpenKey(`Software\Missing\Key`); err == nil || err.Error() != RegistryNotFoundError(`\Software\Missing`).Error() {
+		t.Errorf("Opening missing key returned %v", err)
+	}
+	if _, _, err := software.Value("Missing"); err == nil || err.Error() != RegistryNotFoundError(`\Software\Missing`).Error() {
+		t.Errorf("Reading missing value returned %v", err)
+	}
+}
+
+func TestOpenHiveErrors(t *testing.T) {
+	dir := t.TempDir()
+	notHive := filepath.Join(dir, "not-a-hive")
+	if err := ioutil.WriteFile(notHive, bytes.Repeat([]byte{0}, 0x2000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenHive(notHive); err == nil || err.Error() != notHive+" is not a registry hive file" {
+		t.Errorf("Opening a file which isn't a hive returned %v", err)
+	}
+	hive := testHive(t)
+	data, err := ioutil.ReadFile(hive.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// point the root at the end of the hive
+	le.PutUint32(data[0x24:], uint32(len(data)))
+	corrupt := filepath.Join(dir, "corrupt")
+	if err := ioutil.WriteFile(corrupt, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenHive(corrupt); err == nil {
+		t.Error("Opened a hive whose root is out of range")
+	}
+	if _, err := OpenHive(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Opened a missing hive")
+	}
+}
+
+func TestHiveClose(t *testing.T) {
+	root, err := openHiveRoot(testHive(t).File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	environment, err := root.OpenKey("Environment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// closing a subkey leaves the hive open
+	environment.Close()
+	if _, err := root.OpenKey(`Software\Wine`); err != nil {
+		t.Errorf("Could not open a key after closing a subkey: %v", err)
+	}
+	if err := root.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := root.OpenKey("Environment"); err == nil {
+		t.Error("Opened a key of a closed hive")
+	}
+}
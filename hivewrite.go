@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	// base block fields
+	hivePrimarySequence   = 0x04
+	hiveSecondarySequence = 0x08
+	hiveTimestamp         = 0x0C
+	hiveBinsSize          = 0x28
+	hiveChecksum          = 0x1FC
+
+	hiveBinHeaderSize = 0x20
+	hiveBinAlignment  = 0x1000
+)
+
+// Dirty returns why the hive has changes that are only in its transaction
+// logs, which Windows would apply when it next loads the hive, or "" if it
+// has none: either the sequence numbers of the hive differ, since it wasn't
+// cleanly unloaded, or a .LOG1/.LOG2 file next to it has log entries which
+// are at least as new as the hive.
+func (h *Hive) Dirty() string {
+	sequence := le.Uint32(h.data[hiveSecondarySequence:])
+	if le.Uint32(h.data[hivePrimarySequence:]) != sequence {
+		return "it was not cleanly unloaded"
+	}
+	// logs are matched case insensitively, since Windows names them e.g.
+	// ntuser.dat.LOG1 next to NTUSER.DAT
+	entries, _ := ioutil.ReadDir(filepath.Dir(h.File))
+	for _, e := range entries {
+		name := e.Name()
+		if !isHiveLog(filepath.Base(h.File), name) {
+			continue
+		}
+		log, err := ioutil.ReadFile(filepath.Join(filepath.Dir(h.File), name))
+		if err != nil {
+			continue
+		}
+		// log entries follow a base block of 0x200 bytes, and have their
+		// sequence number at 0x0C
+		if len(log) >= 0x210 && string(log[0x200:0x204]) == "HvLE" && le.Uint32(log[0x20C:]) >= sequence {
+			return fmt.Sprintf("%v has changes which haven't been written to it", filepath.Join(filepath.Dir(h.File), name))
+		}
+	}
+	return ""
+}
+
+// isHiveLog returns whether name is a transaction log of the hive file
+// named hive.
+func isHiveLog(hive, name string) bool {
+	for _, ext := range []string{".LOG1", ".LOG2", ".LOG"} {
+		if strings.EqualFold(name, hive+ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetValue sets the named value of the key at path, which must exist, to
+// data of the given type, adding the value if there isn't one. now is
+// recorded as the time the key and the hive were last written. New cells are
+// allocated in hive bins added to the end of the hive, and replaced cells are
+// freed. Call Save to write the hive back to its file.
+func (h *Hive) SetValue(path, name string, valueType uint32, data []byte, now time.Time) error {
+	key, err := h.Root().OpenKey(path)
+	if err != nil {
+		return err
+	}
+	nkOffset := key.(*hiveKey).offset
+	if len(data) > bigDataSegmentSize {
+		return fmt.Errorf("Value %v of %v is too large to set offline", name, path)
+	}
+	vkOffset, found, err := h.findValue(key.(*hiveKey), name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		vkOffset, err = h.addValue(nkOffset, name)
+		if err != nil {
+			return err
+		}
+	}
+	vk, _ := h.cell(vkOffset)
+	if size := le.Uint32(vk[0x04:]); size&0x80000000 == 0 && size > 0 && size <= bigDataSegmentSize {
+		h.free(le.Uint32(vk[0x08:]))
+	}
+	// up to 4 bytes of data are stored in place of the data offset
+	size, dataOffset := uint32(len(data))|0x80000000, uint32(0)
+	if len(data) > 4 {
+		size, dataOffset = uint32(len(data)), h.allocate(len(data))
+		c, _ := h.cell(dataOffset)
+		copy(c, data)
+	} else {
+		resident := make([]byte, 4)
+		copy(resident, data)
+		dataOffset = le.Uint32(resident)
+	}
+	vk, _ = h.cell(vkOffset)
+	le.PutUint32(vk[0x04:], size)
+	le.PutUint32(vk[0x08:], dataOffset)
+	le.PutUint32(vk[0x0C:], valueType)
+	nk, _ := h.cell(nkOffset)
+	if uint32(len(data)) > le.Uint32(nk[0x40:]) {
+		le.PutUint32(nk[0x40:], uint32(len(data)))
+	}
+	le.PutUint64(nk[0x04:], fileTime(now))
+	h.written(now)
+	return nil
+}
+
+// findValue returns the offset of the named value of k.
+func (h *Hive) findValue(k *hiveKey, name string) (offset uint32, found bool, err error) {
+	if _, _, err := k.Value(name); err != nil {
+		if _, notFound := err.(RegistryNotFoundError); notFound {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	// Value has checked the list and the value cells
+	list, _ := h.cell(le.Uint32(k.nk[0x28:]))
+	for i := 0; i < int(le.Uint32(k.nk[0x24:])); i++ {
+		offset := le.Uint32(list[i*4:])
+		vk, _ := h.cell(offset)
+		n := int(le.Uint16(vk[0x02:]))
+		if strings.EqualFold(hiveString(vk[0x14:0x14+n], le.Uint16(vk[0x10:])&valueCompressedName != 0), name) {
+			return offset, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// addValue adds a value with no data to the key at nkOffset, and returns
+// its offset.
+func (h *Hive) addValue(nkOffset uint32, name string) (uint32, error) {
+	encoded, compressed := encodeHiveString(name)
+	vkOffset := h.allocate(0x14 + len(encoded))
+	vk, _ := h.cell(vkOffset)
+	copy(vk, "vk")
+	le.PutUint16(vk[0x02:], uint16(len(encoded)))
+	if compressed {
+		le.PutUint16(vk[0x10:], valueCompressedName)
+	}
+	copy(vk[0x14:], encoded)
+	nk, _ := h.cell(nkOffset)
+	count := int(le.Uint32(nk[0x24:]))
+	oldList := le.Uint32(nk[0x28:])
+	listOffset := h.allocate(4 * (count + 1))
+	list, _ := h.cell(listOffset)
+	if count > 0 {
+		old, err := h.cell(oldList)
+		if err != nil {
+			return 0, err
+		}
+		copy(list, old[:4*count])
+		h.free(oldList)
+	}
+	le.PutUint32(list[4*count:], vkOffset)
+	nk, _ = h.cell(nkOffset)
+	le.PutUint32(nk[0x24:], uint32(count+1))
+	le.PutUint32(nk[0x28:], listOffset)
+	// the largest value name length is that of the name in UTF-16
+	if n := uint32(2 * len(utf16.Encode([]rune(name)))); n > le.Uint32(nk[0x3C:]) {
+		le.PutUint32(nk[0x3C:], n)
+	}
+	return vkOffset, nil
+}
+
+// encodeHiveString encodes a key or value name as Latin-1 if possible,
+// otherwise UTF-16LE.
+func encodeHiveString(s string) (data []byte, compressed bool) {
+	for _, r := range s {
+		if r > 0xFF {
+			units := utf16.Encode([]rune(s))
+			data = make([]byte, 2*len(units))
+			for i, u := range units {
+				le.PutUint16(data[2*i:], u)
+			}
+			return data, false
+		}
+	}
+	for _, r := range s {
+		data = append(data, byte(r))
+	}
+	return data, true
+}
+
+// allocate returns the offset of a new cell with room for size bytes of
+// data, in the spare space of the last hive bin added, or a new one.
+func (h *Hive) allocate(size int) uint32 {
+	// cells include their size, and are a multiple of 8 bytes
+	size = (size + 4 + 7) &^ 7
+	if h.spareSize < size {
+		h.addBin(size)
+	}
+	offset := h.spareOffset
+	start := hiveBinsOffset + int(offset)
+	for i := start; i < start+size; i++ {
+		h.data[i] = 0
+	}
+	le.PutUint32(h.data[start:], uint32(-int32(size)))
+	h.spareOffset += uint32(size)
+	h.spareSize -= size
+	if h.spareSize > 0 {
+		le.PutUint32(h.data[start+size:], uint32(h.spareSize))
+	}
+	return offset
+}
+
+// addBin adds a hive bin to the end of the hive, with room for a cell of at
+// least size bytes, which becomes the spare space that cells are allocated
+// from.
+func (h *Hive) addBin(size int) {
+	binsSize := le.Uint32(h.data[hiveBinsSize:])
+	binSize := (hiveBinHeaderSize + size + hiveBinAlignment - 1) &^ (hiveBinAlignment - 1)
+	// anything after the last hive bin isn't part of the hive
+	h.data = append(h.data[:hiveBinsOffset+binsSize], make([]byte, binSize)...)
+	bin := h.data[hiveBinsOffset+binsSize:]
+	copy(bin, "hbin")
+	le.PutUint32(bin[0x04:], binsSize)
+	le.PutUint32(bin[0x08:], uint32(binSize))
+	le.PutUint32(h.data[hiveBinsSize:], binsSize+uint32(binSize))
+	h.spareOffset = binsSize + hiveBinHeaderSize
+	h.spareSize = binSize - hiveBinHeaderSize
+	le.PutUint32(bin[hiveBinHeaderSize:], uint32(h.spareSize))
+}
+
+// free marks the cell at offset as unallocated.
+func (h *Hive) free(offset uint32) {
+	start := hiveBinsOffset + int64(offset)
+	if start+4 > int64(len(h.data)) {
+		return
+	}
+	if size := int32(le.Uint32(h.data[start:])); size < 0 {
+		le.PutUint32(h.data[start:], uint32(-size))
+	}
+}
+
+// written updates the base block for a change made at now: both sequence
+// numbers are incremented, so that the hive is consistent, and the checksum
+// recalculated.
+func (h *Hive) written(now time.Time) {
+	sequence := le.Uint32(h.data[hivePrimarySequence:]) + 1
+	le.PutUint32(h.data[hivePrimarySequence:], sequence)
+	le.PutUint32(h.data[hiveSecondarySequence:], sequence)
+	le.PutUint64(h.data[hiveTimestamp:], fileTime(now))
+	var checksum uint32
+	for i := 0; i < hiveChecksum; i += 4 {
+		checksum ^= le.Uint32(h.data[i:])
+	}
+	switch checksum {
+	case 0:
+		checksum = 1
+	case 0xFFFFFFFF:
+		checksum = 0xFFFFFFFE
+	}
+	le.PutUint32(h.data[hiveChecksum:], checksum)
+}
+
+// Save writes the hive back to its file.
+func (h *Hive) Save() error {
+	return replaceFile(h.File, h.data)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHiveSetValue(t *testing.T) {
+	now := time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)
+	long := strings.Repeat(`D:\Very long path\`, 300)
+	for _, test := range []struct {
+		Name  string
+		Key   string
+		Value string
+		Type  uint32
+		Data  []byte
+	}{
+		{"replace non-resident data", "Environment", "TEMP", REG_EXPAND_SZ, utf16Bytes(`D:\Temp`)},
+		{"replace with resident data", "Environment", "temp", REG_DWORD, []byte{1, 2, 3, 4}},
+		{"replace resident data", "Environment", "Count", REG_DWORD, []byte{9, 0, 0, 0}},
+		{"replace with empty data", "Environment", "TEMP", REG_SZ, []byte{}},
+		{"add value", "Environment", "Personal", REG_EXPAND_SZ, utf16Bytes(`%USERPROFILE%\Docs`)},
+		{"add value with Unicode name", "Environment", "Müsic 🎵", REG_SZ, utf16Bytes("x")},
+		{"add value to key without values", `Software\Wine`, "Version", REG_SZ, utf16Bytes("win10")},
+		{"add long value", `Software\Microsoft`, "Long", REG_SZ, utf16Bytes(long)},
+	} {
+		hive := testHive(t)
+		if err := hive.SetValue(test.Key, test.Value, test.Type, test.Data, now); err != nil {
+			t.Errorf("%v: %v", test.Name, err)
+			continue
+		}
+		if err := hive.Save(); err != nil {
+			t.Fatal(err)
+		}
+		reopened, err := OpenHive(hive.File)
+		if err != nil {
+			t.Fatalf("%v: %v", test.Name, err)
+		}
+		key, err := reopened.Root().OpenKey(test.Key)
+		if err != nil {
+			t.Fatalf("%v: %v", test.Name, err)
+		}
+		valueType, data, err := key.Value(test.Value)
+		if err != nil || valueType != test.Type || !bytes.Equal(data, test.Data) {
+			t.Errorf("%v: value reads back as %v % X, %v", test.Name, valueType, data, err)
+		}
+		// the rest of the hive is intact
+		if _, data, err := key.Value("Short"); test.Key == "Environment" && (err != nil || !bytes.Equal(data, utf16Bytes("a"))) {
+			t.Errorf("%v: other value reads back as % X, %v", test.Name, data, err)
+		}
+		if names, err := reopened.Root().SubkeyNames(); err != nil || len(names) != 2 {
+			t.Errorf("%v: root has subkeys %q, %v", test.Name, names, err)
+		}
+		if reason := reopened.Dirty(); reason != "" {
+			t.Errorf("%v: written hive is dirty: %v", test.Name, reason)
+		}
+		checkHiveBaseBlock(t, reopened, now)
+	}
+}
+
+// checkHiveBaseBlock checks that the base block of hive was updated as
+// Windows would for a write at now.
+func checkHiveBaseBlock(t *testing.T, hive *Hive, now time.Time) {
+	t.Helper()
+	if primary := le.Uint32(hive.data[hivePrimarySequence:]); primary != 2 {
+		t.Errorf("Primary sequence number is %v, want 2", primary)
+	}
+	if stamp := le.Uint64(hive.data[hiveTimestamp:]); stamp != fileTime(now) {
+		t.Errorf("Timestamp is %x, want %x", stamp, fileTime(now))
+	}
+	var checksum uint32
+	for i := 0; i < hiveChecksum; i += 4 {
+		checksum ^= le.Uint32(hive.data[i:])
+	}
+	if stored := le.Uint32(hive.data[hiveChecksum:]); stored != checksum {
+		t.Errorf("Checksum is %x, want %x", stored, checksum)
+	}
+	if size := le.Uint32(hive.data[hiveBinsSize:]); int(size)+hiveBinsOffset != len(hive.data) || size%hiveBinAlignment != 0 {
+		t.Errorf("Hive bins size %x doesn't match file size %x", size, len(hive.data))
+	}
+}
+
+func TestHiveSetValueRepeatedly(t *testing.T) {
+	hive := testHive(t)
+	now := time.Now()
+	for i := 0; i < 200; i++ {
+		data := utf16Bytes(strings.Repeat("x", i))
+		if err := hive.SetValue("Environment", "TEMP", REG_SZ, data, now); err != nil {
+			t.Fatal(err)
+		}
+		if err := hive.SetValue("Environment", "Personal", REG_SZ, data, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := hive.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenHive(hive.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := reopened.Root().OpenKey("Environment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"TEMP", "Personal"} {
+		if _, data, err := key.Value(name); err != nil || !bytes.Equal(data, utf16Bytes(strings.Repeat("x", 199))) {
+			t.Errorf("%v reads back as %q, %v", name, decodeUTF16(data, le), err)
+		}
+	}
+}
+
+func TestHiveSetValueErrors(t *testing.T) {
+	hive := testHive(t)
+	if err := hive.SetValue(`Software\Missing`, "x", REG_SZ, nil, time.Now()); err == nil {
+		t.Error("Set a value of a missing key")
+	}
+	if err := hive.SetValue("Environment", "x", REG_BINARY, make([]byte, bigDataSegmentSize+1), time.Now()); err == nil || err.Error() != `Value x of Environment is too large to set offline` {
+		t.Errorf("Setting a big data value returned %v", err)
+	}
+}
+
+func TestHiveDirty(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		// Secondary is the secondary sequence number of the hive, whose
+		// primary one is 1
+		Secondary uint32
+		// Log is the name and sequence number of a log entry written next
+		// to the hive, if any
+		Log      string
+		Sequence uint32
+		Want     string
+	}{
+		{Name: "clean", Secondary: 1},
+		{Name: "not unloaded", Secondary: 0, Want: "it was not cleanly unloaded"},
+		{Name: "old log", Secondary: 1, Log: "ntuser.dat.LOG1", Sequence: 0},
+		{Name: "new log", Secondary: 1, Log: "ntuser.dat.LOG2", Sequence: 1, Want: "ntuser.dat.LOG2 has changes which haven't been written to it"},
+		{Name: "other file's log", Secondary: 1, Log: "SOFTWARE.LOG1", Sequence: 5},
+	} {
+		hive := testHive(t)
+		le.PutUint32(hive.data[hiveSecondarySequence:], test.Secondary)
+		dir := filepath.Dir(hive.File)
+		if test.Log != "" {
+			log := make([]byte, 0x210)
+			copy(log[0x200:], "HvLE")
+			le.PutUint32(log[0x20C:], test.Sequence)
+			if err := ioutil.WriteFile(filepath.Join(dir, test.Log), log, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want := test.Want
+		if strings.Contains(want, "LOG") {
+			want = filepath.Join(dir, want)
+		}
+		if got := hive.Dirty(); got != want {
+			t.Errorf("%v: hive is dirty because %q, want %q", test.Name, got, want)
+		}
+	}
+}
+
+func TestEncodeHiveString(t *testing.T) {
+	for _, test := range []struct {
+		Name       string
+		Data       []byte
+		Compressed bool
+	}{
+		{"Personal", []byte("Personal"), true},
+		{"Café", []byte{'C', 'a', 'f', 0xE9}, true},
+		{"日本", []byte{0xE5, 0x65, 0x2C, 0x67}, false},
+	} {
+		data, compressed := encodeHiveString(test.Name)
+		if !bytes.Equal(data, test.Data) || compressed != test.Compressed {
+			t.Errorf("%q is encoded as % X, %v", test.Name, data, compressed)
+		}
+		if got := hiveString(data, compressed); got != test.Name {
+			t.Errorf("%q is decoded as %q", test.Name, got)
+		}
+	}
+}
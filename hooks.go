@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// defaultHookTimeout is how long a hook may run if the hooks file doesn't
+// say otherwise.
+const defaultHookTimeout = time.Minute
+
+// Hook events
+const (
+	hookPreChange  = "pre-change"
+	hookPostChange = "post-change"
+	hookPreApply   = "pre-apply"
+	hookPostApply  = "post-apply"
+)
+
+// Hooks are commands run before and after each folder change, and around a
+// whole apply, as configured in a YAML hooks file, e.g.
+//
+//	timeout: 2m
+//	pre-change:
+//	  - command: ['C:\hooks\stop-service.cmd', 'Indexer']
+//	    folders: [LocalAppData]
+//	post-change:
+//	  - command: ['powershell.exe', '-File', 'C:\hooks\fix-acls.ps1']
+//	    timeout: 5m
+//
+// Each hook is given a HookEvent as JSON on stdin, and as KNOWNFOLDER_*
+// environment variables, and its output goes to stderr. A pre-change or
+// pre-apply hook which fails, i.e. exits non-zero or times out, vetoes the
+// change or apply; the failure of a post hook is reported as an error, even
+// though the change has been made.
+type Hooks struct {
+	// Timeout is how long each hook may run, unless it has its own, e.g.
+	// "30s". It is killed once it runs for longer.
+	Timeout    string `yaml:"timeout,omitempty"`
+	PreChange  []Hook `yaml:"pre-change,omitempty"`
+	PostChange []Hook `yaml:"post-change,omitempty"`
+	PreApply   []Hook `yaml:"pre-apply,omitempty"`
+	PostApply  []Hook `yaml:"post-apply,omitempty"`
+}
+
+// HookRunner runs the hooks for an event, like Hooks.Run.
+type HookRunner interface {
+	Run(e HookEvent) error
+}
+
+// Hook is a command to run for a hook event.
+type Hook struct {
+	// Command is the executable and its arguments. Scripts need their
+	// interpreter, e.g. ['sh', 'hook.sh'], unless they can be executed
+	// directly.
+	Command []string `yaml:"command"`
+	// Folders, if given, are the only folders the hook is run for; an apply
+	// hook is run if any of them is changed
+	Folders []string `yaml:"folders,omitempty"`
+	Timeout string   `yaml:"timeout,omitempty"`
+}
+
+// HookEvent is what hooks are told about the change they are run for.
+// Folder, GUID, Old and New are those of the folder for change events, and
+// Changes are every folder of the manifest for apply events. Result and
+// Error are only set for post events.
+type HookEvent struct {
+	Event string `json:"event"`
+	// Target is the scope of the change, e.g. "default user", as in the
+	// audit log, and Username the name of the user concerned, if known
+	Target   string       `json:"target"`
+	Username string       `json:"username,omitempty"`
+	Folder   string       `json:"folder,omitempty"`
+	GUID     string       `json:"guid,omitempty"`
+	Old      string       `json:"old,omitempty"`
+	New      string       `json:"new,omitempty"`
+	Manifest string       `json:"manifest,omitempty"`
+	Changes  []HookChange `json:"changes,omitempty"`
+	Result   string       `json:"result,omitempty"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// HookChange is a folder change of an apply.
+type HookChange struct {
+	Folder string `json:"folder"`
+	GUID   string `json:"guid"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new"`
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// LoadHooks reads the YAML hooks file at the given path.
+func LoadHooks(file string) (*Hooks, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	h := new(Hooks)
+	err = yaml.UnmarshalStrict(data, h)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse hooks file %v:\n%v", file, err)
+	}
+	if err = h.validate(); err != nil {
+		return nil, fmt.Errorf("Invalid hooks file %v:\n%v", file, err)
+	}
+	return h, nil
+}
+
+// validate checks the timeouts and commands of every hook, and resolves
+// their folders to knownfolders names.
+func (h *Hooks) validate() error {
+	if _, err := parseHookTimeout(h.Timeout, defaultHookTimeout); err != nil {
+		return err
+	}
+	for event, hooks := range map[string][]Hook{
+		hookPreChange:  h.PreChange,
+		hookPostChange: h.PostChange,
+		hookPreApply:   h.PreApply,
+		hookPostApply:  h.PostApply,
+	} {
+		for i := range hooks {
+			hook := &hooks[i]
+			if len(hook.Command) == 0 || hook.Command[0] == "" {
+				return fmt.Errorf("No command given for %v hook %v", event, i+1)
+			}
+			if _, err := parseHookTimeout(hook.Timeout, 0); err != nil {
+				return err
+			}
+			for j, name := range hook.Folders {
+				folder, ok := resolveFolder(name)
+				if !ok {
+					return UnknownFolderError(name)
+				}
+				hook.Folders[j] = folder
+			}
+		}
+	}
+	return nil
+}
+
+// parseHookTimeout parses a timeout of a hooks file, which is fallback if
+// not given.
+func parseHookTimeout(timeout string, fallback time.Duration) (time.Duration, error) {
+	if timeout == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf(`Invalid timeout "%v"`, timeout)
+	}
+	return d, nil
+}
+
+// hooks returns the hooks configured for event.
+func (h *Hooks) hooks(event string) []Hook {
+	switch event {
+	case hookPreChange:
+		return h.PreChange
+	case hookPostChange:
+		return h.PostChange
+	case hookPreApply:
+		return h.PreApply
+	case hookPostApply:
+		return h.PostApply
+	}
+	return nil
+}
+
+// Run runs the hooks configured for e.Event which apply to its folders, in
+// order. Pre hooks stop at the first which fails, and return its error, while
+// every post hook is run, and the errors of any that fail returned together.
+func (h *Hooks) Run(e HookEvent) error {
+	failures := []string{}
+	for _, hook := range h.hooks(e.Event) {
+		if !hook.appliesTo(e) {
+			continue
+		}
+		timeout, _ := parseHookTimeout(h.Timeout, defaultHookTimeout)
+		timeout, _ = parseHookTimeout(hook.Timeout, timeout)
+		err := hook.run(e, timeout)
+		if err == nil {
+			continue
+		}
+		if e.Event == hookPreChange || e.Event == hookPreApply {
+			return err
+		}
+		failures = append(failures, err.Error())
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%v", strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// appliesTo reports whether the hook is run for e, given its folders.
+func (hook Hook) appliesTo(e HookEvent) bool {
+	if len(hook.Folders) == 0 {
+		return true
+	}
+	for _, folder := range hook.Folders {
+		if folder == e.Folder {
+			return true
+		}
+		for _, c := range e.Changes {
+			if folder == c.Folder {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// run runs the hook for e, killing it if it runs for longer than timeout.
+func (hook Hook) run(e HookEvent, timeout time.Duration) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	// stdout is knownfolder's own output, e.g. of the folders set, and
+	// passing files rather than buffers means a hook's background processes
+	// can't keep knownfolder waiting for its output once it is killed
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), e.environment()...)
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("The %v hook %v was killed after running for longer than %v", e.Event, hook.Command[0], timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("The %v hook %v failed: %v", e.Event, hook.Command[0], err)
+	}
+	return nil
+}
+
+// environment returns the environment variables that hooks are given for e.
+func (e HookEvent) environment() []string {
+	env := []string{
+		"KNOWNFOLDER_HOOK_EVENT=" + e.Event,
+		"KNOWNFOLDER_TARGET=" + e.Target,
+		"KNOWNFOLDER_USERNAME=" + e.Username,
+	}
+	for _, v := range []struct {
+		Name  string
+		Value string
+	}{
+		{"KNOWNFOLDER_FOLDER", e.Folder},
+		{"KNOWNFOLDER_GUID", e.GUID},
+		{"KNOWNFOLDER_OLD_LOCATION", e.Old},
+		{"KNOWNFOLDER_NEW_LOCATION", e.New},
+		{"KNOWNFOLDER_MANIFEST", e.Manifest},
+		{"KNOWNFOLDER_RESULT", e.Result},
+		{"KNOWNFOLDER_ERROR", e.Error},
+	} {
+		if v.Value != "" {
+			env = append(env, v.Name+"="+v.Value)
+		}
+	}
+	return env
+}
+
+// HookedBackend runs Hooks around every SetFolder call made through it. A
+// failing pre-change hook vetoes the change. Target and Username are passed
+// to the hooks.
+type HookedBackend struct {
+	Backend
+	Hooks    HookRunner
+	Target   string
+	Username string
+}
+
+func (b *HookedBackend) SetFolder(folder, location string) error {
+	old, err := b.Backend.GetFolder(folder)
+	if err != nil {
+		old = ""
+	}
+	e := HookEvent{
+		Event:    hookPreChange,
+		Target:   b.Target,
+		Username: b.Username,
+		Folder:   folder,
+		GUID:     knownfolders[folder].String(),
+		Old:      old,
+		New:      location,
+	}
+	if err := b.Hooks.Run(e); err != nil {
+		return fmt.Errorf("Folder %v was not changed, since:\n%v", folder, err)
+	}
+	err = b.Backend.SetFolder(folder, location)
+	e.Event, e.Result = hookPostChange, "success"
+	if err != nil {
+		e.Result, e.Error = "failure", err.Error()
+	}
+	if hookErr := b.Hooks.Run(e); hookErr != nil {
+		if err != nil {
+			return fmt.Errorf("%v\n%v", err, hookErr)
+		}
+		return fmt.Errorf("Folder %v was set to %v, but:\n%v", folder, location, hookErr)
+	}
+	return err
+}
+
+// ApplyHooks are the hooks run before and after an apply, for the user
+// Username, of the manifest file Manifest.
+type ApplyHooks struct {
+	Hooks    HookRunner
+	Username string
+	Manifest string
+}
+
+// applyHookEvent returns the HookEvent of an apply of manifest, whose
+// changes are those of journal, with the outcome of each in results if the
+// apply has been made.
+func applyHookEvent(event, target, username, manifest string, journal *Journal, results []FolderResult) HookEvent {
+	e := HookEvent{Event: event, Target: target, Username: username, Manifest: manifest}
+	failed := false
+	for _, c := range journal.Changes {
+		change := HookChange{Folder: c.Folder, GUID: c.GUID, Old: c.Old, New: c.New}
+		for _, r := range results {
+			if r.Folder != c.Folder {
+				continue
+			}
+			change.Result = "success"
+			if r.Err != nil {
+				change.Result, change.Error = "failure", r.Err.Error()
+				failed = true
+			}
+		}
+		e.Changes = append(e.Changes, change)
+	}
+	if results != nil {
+		e.Result = "success"
+		if failed {
+			e.Result = "failure"
+		}
+	}
+	return e
+}
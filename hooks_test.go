@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// hookLog is a HookRunner which records the events it is run for, and the
+// folders set in between, as lines like "pre-change user bob Documents". It
+// fails the events in Fail, keyed by event and folder, e.g. "pre-apply" or
+// "pre-change Music", and calls OnRun, if set, before each.
+type hookLog struct {
+	mutex   sync.Mutex
+	Entries []string
+	Events  []HookEvent
+	Fail    map[string]error
+	OnRun   func(e HookEvent)
+}
+
+func (l *hookLog) add(entry ...string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	fields := []string{}
+	for _, field := range entry {
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+	l.Entries = append(l.Entries, strings.Join(fields, " "))
+}
+
+func (l *hookLog) Run(e HookEvent) error {
+	if l.OnRun != nil {
+		l.OnRun(e)
+	}
+	l.add(e.Event, e.Target, e.Folder, e.Result)
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.Events = append(l.Events, e)
+	return l.Fail[strings.TrimSpace(e.Event+" "+e.Folder)]
+}
+
+// hookedTestBackend returns a fakeBackend with the given folders, whose sets
+// are recorded in log, and a HookedBackend running log's hooks around them
+// for the user username.
+func hookedTestBackend(log *hookLog, target, username string, folders map[string]string) (*fakeBackend, Backend) {
+	backend := newFakeBackend(folders)
+	backend.OnSet = func(folder, location string) { log.add("set", folder) }
+	return backend, &HookedBackend{Backend: backend, Hooks: log, Target: target, Username: username}
+}
+
+func TestApplyJournaledHooks(t *testing.T) {
+	entries := []ManifestEntry{{"Documents", `D:\Docs`}, {"Music", `D:\Music`}}
+	for _, test := range []struct {
+		Name    string
+		Fail    map[string]error
+		Errors  map[string]error
+		Want    []string
+		Err     string
+		Applied bool
+	}{
+		{
+			Name: "success",
+			Want: []string{
+				"pre-apply user bob",
+				"pre-change user bob Documents", "set Documents", "post-change user bob Documents success",
+				"pre-change user bob Music", "set Music", "post-change user bob Music success",
+				"post-apply user bob success",
+			},
+			Applied: true,
+		},
+		{
+			Name:   "failed change",
+			Errors: map[string]error{"Music": errors.New("Access is denied.")},
+			Want: []string{
+				"pre-apply user bob",
+				"pre-change user bob Documents", "set Documents", "post-change user bob Documents success",
+				"pre-change user bob Music", "set Music", "post-change user bob Music failure",
+				"post-apply user bob failure",
+			},
+			Applied: true,
+		},
+		{
+			Name: "pre-apply veto",
+			Fail: map[string]error{"pre-apply": errors.New("The pre-apply hook stop.cmd failed: exit status 1")},
+			Want: []string{"pre-apply user bob"},
+			Err:  "Did not apply C:\\manifest.yaml, since:\nThe pre-apply hook stop.cmd failed: exit status 1",
+		},
+		{
+			Name: "pre-change veto",
+			Fail: map[string]error{"pre-change Documents": errors.New("The pre-change hook stop.cmd failed: exit status 1")},
+			Want: []string{
+				"pre-apply user bob",
+				"pre-change user bob Documents",
+				"pre-change user bob Music", "set Music", "post-change user bob Music success",
+				"post-apply user bob failure",
+			},
+			Applied: true,
+		},
+		{
+			Name: "post-apply failure",
+			Fail: map[string]error{"post-apply": errors.New("The post-apply hook notify.cmd failed: exit status 2")},
+			Want: []string{
+				"pre-apply user bob",
+				"pre-change user bob Documents", "set Documents", "post-change user bob Documents success",
+				"pre-change user bob Music", "set Music", "post-change user bob Music success",
+				"post-apply user bob success",
+			},
+			Err:     "The post-apply hook notify.cmd failed: exit status 2",
+			Applied: true,
+		},
+	} {
+		file := journalFile(t.TempDir(), "user bob")
+		log := &hookLog{Fail: test.Fail}
+		log.OnRun = func(e HookEvent) {
+			_, err := os.Stat(file)
+			if journaled := err == nil; journaled != (e.Event != hookPostApply) {
+				t.Errorf("%v: %v hooks run with journal %v", test.Name, e.Event, err)
+			}
+		}
+		backend, hooked := hookedTestBackend(log, "user bob", "bob", map[string]string{"Documents": `C:\Docs`})
+		for folder, err := range test.Errors {
+			backend.Errors[folder] = err
+		}
+		hooks := &ApplyHooks{Hooks: log, Username: "bob", Manifest: `C:\manifest.yaml`}
+		results, err := ApplyJournaled(file, "user bob", "-u bob", hooks, hooked, entries)
+		switch {
+		case test.Err == "" && err != nil:
+			t.Errorf("%v: %v", test.Name, err)
+		case test.Err != "" && (err == nil || err.Error() != test.Err):
+			t.Errorf("%v: returned %v, want %v", test.Name, err, test.Err)
+		}
+		if applied := results != nil; applied != test.Applied {
+			t.Errorf("%v: returned results %+v", test.Name, results)
+		}
+		if !reflect.DeepEqual(log.Entries, test.Want) {
+			t.Errorf("%v: ran\n%q\nwant\n%q", test.Name, log.Entries, test.Want)
+		}
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("%v: journal remains: %v", test.Name, err)
+		}
+	}
+}
+
+func TestApplyHookEvents(t *testing.T) {
+	log := &hookLog{}
+	backend, hooked := hookedTestBackend(log, "user bob", "bob", map[string]string{"Documents": `C:\Docs`})
+	backend.Errors["Music"] = errors.New("Access is denied.")
+	hooks := &ApplyHooks{Hooks: log, Username: "bob", Manifest: `C:\manifest.yaml`}
+	entries := []ManifestEntry{{"Documents", `D:\Docs`}, {"Music", `D:\Music`}}
+	if _, err := ApplyJournaled(journalFile(t.TempDir(), "user bob"), "user bob", "-u bob", hooks, hooked, entries); err != nil {
+		t.Fatal(err)
+	}
+	pre, post := log.Events[0], log.Events[len(log.Events)-1]
+	documents := HookChange{Folder: "Documents", GUID: knownfolders["Documents"].String(), Old: `C:\Docs`, New: `D:\Docs`}
+	music := HookChange{Folder: "Music", GUID: knownfolders["Music"].String(), New: `D:\Music`}
+	want := HookEvent{Event: hookPreApply, Target: "user bob", Username: "bob", Manifest: `C:\manifest.yaml`, Changes: []HookChange{documents, music}}
+	if !reflect.DeepEqual(pre, want) {
+		t.Errorf("pre-apply event is %+v, want %+v", pre, want)
+	}
+	documents.Result = "success"
+	music.Result, music.Error = "failure", "Access is denied."
+	want.Event, want.Result, want.Changes = hookPostApply, "failure", []HookChange{documents, music}
+	if !reflect.DeepEqual(post, want) {
+		t.Errorf("post-apply event is %+v, want %+v", post, want)
+	}
+	change := log.Events[2]
+	wantChange := HookEvent{Event: hookPostChange, Target: "user bob", Username: "bob", Folder: "Documents",
+		GUID: documents.GUID, Old: `C:\Docs`, New: `D:\Docs`, Result: "success"}
+	if !reflect.DeepEqual(change, wantChange) {
+		t.Errorf("post-change event is %+v, want %+v", change, wantChange)
+	}
+}
+
+func TestApplyToUsersHooks(t *testing.T) {
+	dir := t.TempDir()
+	users := []UserCredentials{{"alice", "a"}, {"bob", "b"}}
+	manifest := &Manifest{Folders: map[string]string{"Documents": `D:\{{.Username}}\Docs`}}
+	log := &hookLog{Fail: map[string]error{}}
+	log.OnRun = func(e HookEvent) {
+		if e.Event == hookPreApply && e.Username == "bob" {
+			log.Fail["pre-apply"] = errors.New("The pre-apply hook stop.cmd failed: exit status 1")
+		}
+	}
+	logon := func(user UserCredentials) (Backend, func(), error) {
+		_, backend := hookedTestBackend(log, "user "+user.Username, user.Username, nil)
+		return backend, func() { log.add("logoff", user.Username) }, nil
+	}
+	apply := func(user UserCredentials, backend Backend, entries []ManifestEntry) ([]FolderResult, error) {
+		scope := "user " + user.Username
+		hooks := &ApplyHooks{Hooks: log, Username: user.Username, Manifest: `C:\manifest.yaml`}
+		return ApplyJournaled(journalFile(dir, scope), scope, "-u "+user.Username, hooks, backend, entries)
+	}
+	results := ApplyToUsers(users, manifest, 1, logon, apply)
+	want := []string{
+		"pre-apply user alice",
+		"pre-change user alice Documents", "set Documents", "post-change user alice Documents success",
+		"post-apply user alice success",
+		"logoff alice",
+		"pre-apply user bob",
+		"logoff bob",
+	}
+	if !reflect.DeepEqual(log.Entries, want) {
+		t.Errorf("Ran\n%q\nwant\n%q", log.Entries, want)
+	}
+	if results[0].Failed() || results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "Did not apply") {
+		t.Errorf("Results are %+v", results)
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// imageSoftwareHive is the path of the SOFTWARE hive in a Windows image.
+const imageSoftwareHive = `Windows\System32\config\SOFTWARE`
+
+// commonShellFoldersKey is the key of the SOFTWARE hive under which the
+// locations of common known folders are stored, as userShellFoldersKey is
+// for per user folders in user hives.
+const commonShellFoldersKey = `Microsoft\Windows\CurrentVersion\Explorer\User Shell Folders`
+
+// WindowsImage is an offline Windows installation, such as a VHD or WIM
+// mounted at Root, whose registry hives are read and written directly, on
+// any platform. Paths in the image are on its system drive, e.g. C:, which is
+// mapped to Root.
+type WindowsImage struct {
+	Root     string
+	Software *Hive
+	// Force allows hives to be written even though they have changes that
+	// are only in their transaction logs, see Hive.Dirty
+	Force       bool
+	systemRoot  string
+	systemDrive string
+}
+
+// OpenWindowsImage opens the Windows image mounted at root, by reading its
+// SOFTWARE hive.
+func OpenWindowsImage(root string) (*WindowsImage, error) {
+	i := &WindowsImage{Root: root, systemRoot: `C:\Windows`, systemDrive: "C:"}
+	software, err := OpenHive(i.hostPath(strings.Split(imageSoftwareHive, `\`)))
+	if err != nil {
+		return nil, fmt.Errorf("Could not read the SOFTWARE hive of the Windows image at %v:\n%v", root, err)
+	}
+	i.Software = software
+	if key, err := software.Root().OpenKey(`Microsoft\Windows NT\CurrentVersion`); err == nil {
+		if systemRoot, err := stringValue(key, "SystemRoot"); err == nil && len(systemRoot) >= 2 && systemRoot[1] == ':' {
+			i.systemRoot = systemRoot
+			i.systemDrive = strings.ToUpper(systemRoot[:2])
+		}
+	}
+	return i, nil
+}
+
+// Close closes the SOFTWARE hive of the image.
+func (i *WindowsImage) Close() error {
+	return i.Software.Close()
+}
+
+// Profiles returns the Default profile, followed by the profiles in the
+// ProfileList of the image.
+func (i *WindowsImage) Profiles() ([]Profile, error) {
+	defaultProfile, err := ReadDefaultProfile(i.Software.Root())
+	if err != nil {
+		// as created by Windows setup
+		defaultProfile = Profile{Account: defaultProfileName, Path: `%SystemDrive%\Users\Default`}
+	}
+	profiles, err := ReadProfileList(i.Software.Root())
+	if err != nil {
+		return nil, err
+	}
+	return append([]Profile{defaultProfile}, profiles...), nil
+}
+
+// FindProfile returns the profile of the image matching pattern, as for
+// --include, or the Default profile if pattern is "".
+func (i *WindowsImage) FindProfile(pattern string) (Profile, error) {
+	if pattern == "" {
+		pattern = defaultProfileName
+	}
+	profiles, err := i.Profiles()
+	if err != nil {
+		return Profile{}, err
+	}
+	matches := []Profile{}
+	for _, p := range profiles {
+		if (ProfileFilter{}).match([]string{pattern}, p) != "" {
+			matches = append(matches, p)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Profile{}, fmt.Errorf(`No profile of the image matches "%v"`, pattern)
+	case 1:
+		return matches[0], nil
+	}
+	names := make([]string, len(matches))
+	for j, p := range matches {
+		names[j] = ProfileName(p)
+	}
+	return Profile{}, fmt.Errorf(`More than one profile of the image matches "%v": %v`, pattern, strings.Join(names, ", "))
+}
+
+// Environment returns a function which looks up the environment variables
+// that locations in the image may refer to, as Windows would set them for
+// the owner of profile p.
+func (i *WindowsImage) Environment(p Profile) func(string) string {
+	variables := map[string]string{
+		"SYSTEMDRIVE": i.systemDrive,
+		"SYSTEMROOT":  i.systemRoot,
+		"WINDIR":      i.systemRoot,
+	}
+	getenv := func(name string) string { return variables[strings.ToUpper(name)] }
+	if list, err := i.Software.Root().OpenKey(profileListKey); err == nil {
+		if programData, err := stringValue(list, "ProgramData"); err == nil {
+			variables["PROGRAMDATA"] = expandWindowsEnv(programData, getenv)
+			variables["ALLUSERSPROFILE"] = variables["PROGRAMDATA"]
+		}
+		if public, err := stringValue(list, "Public"); err == nil {
+			variables["PUBLIC"] = expandWindowsEnv(public, getenv)
+		}
+	}
+	if p.Path != "" {
+		variables["USERPROFILE"] = expandWindowsEnv(p.Path, getenv)
+		variables["USERNAME"] = ProfileUsername(p)
+	}
+	return getenv
+}
+
+// HostPath returns where path, a path on the system drive of the image, is
+// on the host.
+func (i *WindowsImage) HostPath(path string) (string, error) {
+	elements := splitWindowsPath(normalizeWindowsPath(path))
+	if len(elements) == 0 || elements[0] != i.systemDrive {
+		return "", fmt.Errorf("%v is not on the system drive (%v) of the Windows image", path, i.systemDrive)
+	}
+	return i.hostPath(elements[1:]), nil
+}
+
+// hostPath returns the path on the host of the given path elements, relative
+// to the image root. Each is matched case insensitively to an existing file
+// or directory, as Windows would, since images are usually mounted case
+// sensitively; any which don't exist are kept as they are.
+func (i *WindowsImage) hostPath(elements []string) string {
+	host := i.Root
+	for _, e := range elements {
+		name := e
+		if entries, err := ioutil.ReadDir(host); err == nil {
+			for _, entry := range entries {
+				if strings.EqualFold(entry.Name(), e) {
+					name = entry.Name()
+					break
+				}
+			}
+		}
+		host = filepath.Join(host, name)
+	}
+	return host
+}
+
+// WindowsPath returns the path in the image of path on the host, which must
+// be below the image root.
+func (i *WindowsImage) WindowsPath(path string) (string, error) {
+	relative, err := filepath.Rel(i.Root, path)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%v is not in the Windows image at %v", path, i.Root)
+	}
+	if relative == "." {
+		relative = ""
+	}
+	return i.systemDrive + `\` + strings.Replace(filepath.ToSlash(relative), "/", `\`, -1), nil
+}
+
+// OpenProfileHive opens the user hive of p, or returns nil if it can't be
+// read.
+func (i *WindowsImage) OpenProfileHive(p Profile) RegistryKey {
+	file, err := i.HostPath(ProfileHive(p, i.Environment(p)))
+	if err != nil {
+		return nil
+	}
+	return openProfileHiveFile(file)
+}
+
+// SetValues sets values under key in the hive file at the given path in the
+// image, as of now. Hives with changes that are only in their transaction
+// logs are refused, unless Force is set, since Windows would apply those
+// changes over the new values.
+func (i *WindowsImage) SetValues(file, key string, values []RegistryValue, now time.Time) error {
+	host, err := i.HostPath(file)
+	if err != nil {
+		return err
+	}
+	hive := i.Software
+	if host != hive.File {
+		hive, err = OpenHive(host)
+		if err != nil {
+			return err
+		}
+		defer hive.Close()
+	}
+	if dirty := hive.Dirty(); dirty != "" && !i.Force {
+		return fmt.Errorf("Refusing to write %v, since %v; use --force to write it anyway", hive.File, dirty)
+	}
+	for _, v := range values {
+		if err := hive.SetValue(key, v.Name, v.Type, v.Data, now); err != nil {
+			return err
+		}
+	}
+	return hive.Save()
+}
+
+// ImageBackend gets and sets the known folder locations of a profile of a
+// Windows image. Per user folders are stored in the profile's user hive, and
+// common folders in the SOFTWARE hive.
+type ImageBackend struct {
+	Image        *WindowsImage
+	Profile      Profile
+	Descriptions []FolderDescription
+	// Now returns the time that changed hives are recorded as written at,
+	// time.Now if nil
+	Now func() time.Time
+}
+
+// NewImageBackend returns an ImageBackend for the profile of the image at
+// root which matches pattern, see WindowsImage.FindProfile.
+func NewImageBackend(root, pattern string, force bool) (*ImageBackend, error) {
+	image, err := OpenWindowsImage(root)
+	if err != nil {
+		return nil, err
+	}
+	image.Force = force
+	profile, err := image.FindProfile(pattern)
+	if err != nil {
+		image.Close()
+		return nil, err
+	}
+	descriptions, err := ReadFolderDescriptions(image.Software.Root())
+	if err != nil {
+		image.Close()
+		return nil, err
+	}
+	return &ImageBackend{Image: image, Profile: profile, Descriptions: descriptions}, nil
+}
+
+// Close closes the image.
+func (b *ImageBackend) Close() error {
+	return b.Image.Close()
+}
+
+// value returns the hive file (as a path in the image), key and value name
+// which the location of folder is stored in.
+func (b *ImageBackend) value(folder string) (file, key, name string, err error) {
+	d, ok := describeFolder(folder, b.Descriptions)
+	if !ok {
+		return "", "", "", fmt.Errorf("Folder %v is not described in the registry of the image", folder)
+	}
+	switch d.Category {
+	case KF_CATEGORY_PERUSER:
+		file = ProfileHive(b.Profile, b.Image.Environment(b.Profile))
+		key = userShellFoldersKey
+	case KF_CATEGORY_COMMON:
+		file = b.Image.systemRoot + `\System32\config\SOFTWARE`
+		key = commonShellFoldersKey
+	default:
+		return "", "", "", fmt.Errorf("Folder %v is a %v folder, whose location isn't stored in the registry", folder, d.CategoryName())
+	}
+	return file, key, shellFolderValueName(folder, d), nil
+}
+
+func (b *ImageBackend) GetFolder(folder string) (string, error) {
+	env := b.Image.Environment(b.Profile)
+	if folder == "Profile" {
+		return env("USERPROFILE"), nil
+	}
+	file, key, name, err := b.value(folder)
+	if err != nil {
+		return "", err
+	}
+	var hive RegistryKey
+	if key == commonShellFoldersKey {
+		hive = b.Image.Software.Root()
+	} else if hive = b.Image.OpenProfileHive(b.Profile); hive == nil {
+		return "", fmt.Errorf("Could not read the user hive %v", file)
+	}
+	defer hive.Close()
+	k, err := hive.OpenKey(key)
+	if err != nil {
+		return "", err
+	}
+	location, err := stringValue(k, name)
+	if err != nil {
+		return "", err
+	}
+	return expandWindowsEnv(location, env), nil
+}
+
+// SetFolder sets the location of folder, which may also be given as a path
+// on the host below the image root.
+func (b *ImageBackend) SetFolder(folder, location string) error {
+	if strings.HasPrefix(location, "/") {
+		var err error
+		location, err = b.Image.WindowsPath(location)
+		if err != nil {
+			return err
+		}
+	}
+	file, key, name, err := b.value(folder)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if b.Now != nil {
+		now = b.Now()
+	}
+	value := stringRegistryValue(name, location)
+	value.Type = REG_EXPAND_SZ
+	return b.Image.SetValues(file, key, []RegistryValue{value}, now)
+}
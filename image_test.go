@@ -0,0 +1,216 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testImage returns the root of a Windows image with the Default profile and
+// alice's, where alice's Documents are redirected to D:\alice\Docs.
+func testImage(t *testing.T) string {
+	root := t.TempDir()
+	for _, dir := range []string{`Windows/System32/config`, `Users/Default`, `Users/alice`} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestHive(t, filepath.Join(root, "Windows", "System32", "config", "SOFTWARE"), testKey{
+		"Microsoft": testKey{
+			"Windows NT": testKey{"CurrentVersion": testKey{
+				"SystemRoot": `C:\Windows`,
+				"ProfileList": testKey{
+					"Default":             expandString(`%SystemDrive%\Users\Default`),
+					"Public":              expandString(`%SystemDrive%\Users\Public`),
+					"S-1-5-21-1-2-3-1001": testKey{"ProfileImagePath": expandString(`C:\Users\alice`), "State": uint32(0)},
+				},
+			}},
+			"Windows": testKey{"CurrentVersion": testKey{"Explorer": testKey{
+				"FolderDescriptions": testKey{
+					"{FDD39AD0-238F-46AF-ADB4-6C85480369C7}": testKey{
+						"Name":         "Personal",
+						"Category":     uint32(KF_CATEGORY_PERUSER),
+						"ParentFolder": "{5E6C858F-0E22-4760-9AFE-EA3317B67173}",
+						"RelativePath": "Documents",
+					},
+					"{374DE290-123F-4565-9164-39C4925E467B}": testKey{
+						"Name":         "Downloads",
+						"Category":     uint32(KF_CATEGORY_PERUSER),
+						"ParentFolder": "{5E6C858F-0E22-4760-9AFE-EA3317B67173}",
+						"RelativePath": "Downloads",
+					},
+					"{ED4824AF-DCE4-45A8-81E2-FC7965083634}": testKey{"Name": "Common Documents", "Category": uint32(KF_CATEGORY_COMMON)},
+					"{5E6C858F-0E22-4760-9AFE-EA3317B67173}": testKey{"Name": "Profile", "Category": uint32(KF_CATEGORY_FIXED)},
+				},
+				"User Shell Folders": testKey{"Common Documents": expandString(`%PUBLIC%\Documents`)},
+			}}},
+		},
+	})
+	writeTestHive(t, filepath.Join(root, "Users", "Default", "NTUSER.DAT"), testKey{
+		"Software": testKey{"Microsoft": testKey{"Windows": testKey{"CurrentVersion": testKey{"Explorer": testKey{
+			"User Shell Folders": testKey{
+				"Personal":                               expandString(`%USERPROFILE%\Documents`),
+				"{374DE290-123F-4565-9164-39C4925E467B}": expandString(`%USERPROFILE%\Downloads`),
+			},
+		}}}}},
+	})
+	writeTestHive(t, filepath.Join(root, "Users", "alice", "NTUSER.DAT"), testKey{
+		"Software": testKey{"Microsoft": testKey{"Windows": testKey{"CurrentVersion": testKey{"Explorer": testKey{
+			"User Shell Folders": testKey{
+				"Personal":                               expandString(`D:\alice\Docs`),
+				"{374DE290-123F-4565-9164-39C4925E467B}": expandString(`%USERPROFILE%\Downloads`),
+			},
+		}}}}},
+	})
+	return root
+}
+
+// newTestImageBackend returns an ImageBackend for the profile of the image
+// at root matching pattern, which is closed when the test finishes.
+func newTestImageBackend(t *testing.T, root, pattern string) *ImageBackend {
+	t.Helper()
+	b, err := NewImageBackend(root, pattern, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+func TestImageBackendGetFolder(t *testing.T) {
+	root := testImage(t)
+	for _, test := range []struct {
+		Profile string
+		Folder  string
+		Want    string
+	}{
+		{"", "Documents", `C:\Users\Default\Documents`},
+		{"", "Profile", `C:\Users\Default`},
+		{"alice", "Documents", `D:\alice\Docs`},
+		{"alice", "Downloads", `C:\Users\alice\Downloads`},
+		{"alice", "PublicDocuments", `C:\Users\Public\Documents`},
+	} {
+		got, err := newTestImageBackend(t, root, test.Profile).GetFolder(test.Folder)
+		if err != nil {
+			t.Errorf("%v of %q: %v", test.Folder, test.Profile, err)
+			continue
+		}
+		if got != test.Want {
+			t.Errorf("%v of %q is %v, want %v", test.Folder, test.Profile, got, test.Want)
+		}
+	}
+}
+
+func TestImageBackendSetFolder(t *testing.T) {
+	root := testImage(t)
+	b := newTestImageBackend(t, root, "alice")
+	b.Now = func() time.Time { return time.Unix(1600000000, 0) }
+	locations := map[string]string{
+		"Downloads":       `E:\dl`,
+		"Documents":       `C:\Data\alice`,
+		"PublicDocuments": `D:\Public`,
+	}
+	if strings.HasPrefix(root, "/") {
+		// given as a path on the host below the image root
+		locations["Documents"] = root + "/Data/alice"
+	}
+	for folder, location := range locations {
+		if err := b.SetFolder(folder, location); err != nil {
+			t.Fatalf("Could not set %v: %v", folder, err)
+		}
+	}
+	// read back from the hive files, with a fresh image
+	for _, test := range []struct {
+		Profile string
+		Folder  string
+		Want    string
+	}{
+		{"alice", "Downloads", `E:\dl`},
+		{"alice", "Documents", `C:\Data\alice`},
+		{"alice", "PublicDocuments", `D:\Public`},
+		{"", "Downloads", `C:\Users\Default\Downloads`},
+	} {
+		got, err := newTestImageBackend(t, root, test.Profile).GetFolder(test.Folder)
+		if err != nil || got != test.Want {
+			t.Errorf("%v of %q is %q (%v), want %q", test.Folder, test.Profile, got, err, test.Want)
+		}
+	}
+}
+
+func TestImageBackendErrors(t *testing.T) {
+	root := testImage(t)
+	b := newTestImageBackend(t, root, "alice")
+	for _, test := range []struct {
+		Name string
+		Err  error
+		Want string
+	}{
+		{"get unset", getError(b, "Music"), `"\Software\Microsoft\Windows\CurrentVersion\Explorer\User Shell Folders\My Music" does not exist`},
+		{"get virtual", getError(b, "ControlPanelFolder"), "Folder ControlPanelFolder is a virtual folder, whose location isn't stored in the registry"},
+		{"set fixed", b.SetFolder("Profile", `D:\alice`), "Folder Profile is a fixed folder, whose location isn't stored in the registry"},
+		{"set outside image", b.SetFolder("Downloads", "/elsewhere/dl"), "/elsewhere/dl is not in the Windows image at"},
+	} {
+		if test.Err == nil || !strings.Contains(test.Err.Error(), test.Want) {
+			t.Errorf("%v: returned %v, want %v", test.Name, test.Err, test.Want)
+		}
+	}
+	if _, err := NewImageBackend(root, "bob", false); err == nil {
+		t.Error("Expected an error for a profile that isn't in the image")
+	}
+	if _, err := NewImageBackend(t.TempDir(), "", false); err == nil || !strings.Contains(err.Error(), "Could not read the SOFTWARE hive") {
+		t.Errorf("Opening a directory without an image returned %v", err)
+	}
+}
+
+// getError returns the error getting the location of folder from b.
+func getError(b Backend, folder string) error {
+	_, err := b.GetFolder(folder)
+	return err
+}
+
+func TestImageBackendClose(t *testing.T) {
+	b, err := NewImageBackend(testImage(t), "alice", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if b.Image.Software.data != nil {
+		t.Error("Closing the backend didn't release the SOFTWARE hive")
+	}
+	if _, err := b.GetFolder("PublicDocuments"); err == nil {
+		t.Error("Got a common folder from a closed image")
+	}
+}
+
+func TestOfflineBackendClose(t *testing.T) {
+	backend, close, ok, err := offlineBackend(map[string]interface{}{"--image-root": testImage(t), "--profile": "alice"})
+	if err != nil || !ok {
+		t.Fatalf("offlineBackend returned ok %v, error %v", ok, err)
+	}
+	close()
+	if backend.(*ImageBackend).Image.Software.data != nil {
+		t.Error("close didn't close the image")
+	}
+	if _, _, ok, _ := offlineBackend(map[string]interface{}{}); ok {
+		t.Error("offlineBackend returned a backend without --wine-prefix or --image-root")
+	}
+}
+
+func TestSoftwareKeyOfImage(t *testing.T) {
+	software, err := softwareKey(map[string]interface{}{"--image-root": testImage(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptions, err := ReadFolderDescriptions(software)
+	if err != nil || len(descriptions) != 4 {
+		t.Errorf("Read %v folder descriptions (%v), want 4", len(descriptions), err)
+	}
+	software.Close()
+	if _, err := software.OpenKey(`Microsoft`); err == nil {
+		t.Error("Opened a key of a closed SOFTWARE hive")
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+)
+
+// textEncoding identifies how a text file we read was encoded, so that it can
+// be written back the same way.
+type textEncoding int
+
+const (
+	encodingUTF8 textEncoding = iota
+	encodingUTF8BOM
+	encodingUTF16LE
+	encodingUTF16BE
+)
+
+// decodeText decodes the content of a Windows text file. Files with a byte
+// order mark are decoded accordingly; files without one are assumed to be
+// UTF-16LE if they start with an ASCII character followed by a zero byte,
+// otherwise UTF-8.
+func decodeText(data []byte) (string, textEncoding) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), encodingUTF8BOM
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], binary.LittleEndian), encodingUTF16LE
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], binary.BigEndian), encodingUTF16BE
+	case len(data) >= 2 && data[0] != 0 && data[1] == 0:
+		return decodeUTF16(data, binary.LittleEndian), encodingUTF16LE
+	}
+	return string(data), encodingUTF8
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// encodeText encodes text in the given encoding, with a byte order mark
+// for any encoding other than plain UTF-8.
+func encodeText(text string, encoding textEncoding) []byte {
+	var order binary.ByteOrder
+	switch encoding {
+	case encodingUTF8:
+		return []byte(text)
+	case encodingUTF8BOM:
+		return append([]byte{0xEF, 0xBB, 0xBF}, text...)
+	case encodingUTF16LE:
+		order = binary.LittleEndian
+	case encodingUTF16BE:
+		order = binary.BigEndian
+	}
+	units := utf16.Encode([]rune(text))
+	data := make([]byte, 2*len(units)+2)
+	order.PutUint16(data, 0xFEFF)
+	for i, u := range units {
+		order.PutUint16(data[2*i+2:], u)
+	}
+	return data
+}
+
+// iniFile is a Windows INI file. Keys and section names are matched case
+// insensitively, as GetPrivateProfileString does.
+type iniFile struct {
+	Sections []*iniSection
+	Encoding textEncoding
+	// Newline is the line separator, "\r\n" unless the file used "\n"
+	Newline string
+}
+
+type iniSection struct {
+	// Name is "" for any lines before the first section header
+	Name string
+	// Header is the section header line as written, e.g. "[Name]"
+	Header string
+	Lines  []iniLine
+}
+
+// iniLine is a single line of an INI file. Key is "" for blank lines and
+// comments, which are kept verbatim in Raw.
+type iniLine struct {
+	Key   string
+	Value string
+	Raw   string
+}
+
+func parseINI(data []byte) *iniFile {
+	text, encoding := decodeText(data)
+	ini := &iniFile{Encoding: encoding, Newline: "\r\n"}
+	if strings.Contains(text, "\n") && !strings.Contains(text, "\r\n") {
+		ini.Newline = "\n"
+	}
+	section := &iniSection{}
+	ini.Sections = append(ini.Sections, section)
+	for _, raw := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n") {
+		line := strings.TrimSpace(raw)
+		switch {
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = &iniSection{Name: strings.TrimSpace(line[1 : len(line)-1]), Header: raw}
+			ini.Sections = append(ini.Sections, section)
+		case line == "" || strings.HasPrefix(line, ";") || !strings.Contains(line, "="):
+			section.Lines = append(section.Lines, iniLine{Raw: raw})
+		default:
+			i := strings.Index(line, "=")
+			section.Lines = append(section.Lines, iniLine{
+				Key:   strings.TrimSpace(line[:i]),
+				Value: strings.TrimSpace(line[i+1:]),
+				Raw:   raw,
+			})
+		}
+	}
+	return ini
+}
+
+// section returns the first section with the given name, or nil.
+func (ini *iniFile) section(name string) *iniSection {
+	for _, s := range ini.Sections {
+		if strings.EqualFold(s.Name, name) {
+			return s
+		}
+	}
+	return nil
+}
+
+// entries returns the key/value lines of the section, in file order.
+func (s *iniSection) entries() (entries []iniLine) {
+	for _, line := range s.Lines {
+		if line.Key != "" {
+			entries = append(entries, line)
+		}
+	}
+	return
+}
+
+// set sets the value of key in the named section, replacing the first
+// existing line for the key, or otherwise adding it after the last entry of
+// the section. The section is added at the end of the file if there is
+// none. Everything else in the file is left as it is.
+func (ini *iniFile) set(name, key, value string) {
+	line := iniLine{Key: key, Value: value, Raw: key + "=" + value}
+	section := ini.section(name)
+	if section == nil {
+		section = &iniSection{Name: name, Header: "[" + name + "]"}
+		// keep any blank lines which end the file at the end
+		last := ini.Sections[len(ini.Sections)-1]
+		i := len(last.Lines)
+		for i > 0 && strings.TrimSpace(last.Lines[i-1].Raw) == "" {
+			i--
+		}
+		trailing := append([]iniLine{}, last.Lines[i:]...)
+		last.Lines = last.Lines[:i]
+		if len(trailing) == 0 {
+			// the file doesn't end with a newline, so start a new line
+			trailing = []iniLine{{}}
+		}
+		if i > 0 || len(ini.Sections) > 1 {
+			// separate the new section from the one before
+			last.Lines = append(last.Lines, iniLine{})
+		}
+		section.Lines = trailing
+		ini.Sections = append(ini.Sections, section)
+	}
+	insert := 0
+	for i, l := range section.Lines {
+		if strings.EqualFold(l.Key, key) {
+			section.Lines[i] = line
+			return
+		}
+		if l.Key != "" {
+			insert = i + 1
+		}
+	}
+	section.Lines = append(section.Lines[:insert], append([]iniLine{line}, section.Lines[insert:]...)...)
+}
+
+// bytes returns the content of the INI file, in the encoding it was read in.
+func (ini *iniFile) bytes() []byte {
+	lines := []string{}
+	for i, section := range ini.Sections {
+		if i > 0 {
+			lines = append(lines, section.Header)
+		}
+		for _, line := range section.Lines {
+			lines = append(lines, line.Raw)
+		}
+	}
+	return encodeText(strings.Join(lines, ini.Newline), ini.Encoding)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+// utf16Text returns text encoded as UTF-16 in the given byte order, with a
+// byte order mark if bom is set.
+func utf16Text(text string, order binary.ByteOrder, bom bool) []byte {
+	units := utf16.Encode([]rune(text))
+	if bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	data := make([]byte, 2*len(units))
+	for i, u := range units {
+		order.PutUint16(data[2*i:], u)
+	}
+	return data
+}
+
+func TestDecodeText(t *testing.T) {
+	const text = "[.ShellClassInfo]\r\nLocalizedResourceName=Dokumente 📄\r\n"
+	for _, test := range []struct {
+		Name     string
+		Data     []byte
+		Encoding textEncoding
+		// Written is how the text is encoded again, if not as Data
+		Written []byte
+	}{
+		{Name: "UTF-8", Data: []byte(text), Encoding: encodingUTF8},
+		{Name: "UTF-8 with BOM", Data: append([]byte{0xEF, 0xBB, 0xBF}, text...), Encoding: encodingUTF8BOM},
+		{Name: "UTF-16LE with BOM", Data: utf16Text(text, binary.LittleEndian, true), Encoding: encodingUTF16LE},
+		{Name: "UTF-16BE with BOM", Data: utf16Text(text, binary.BigEndian, true), Encoding: encodingUTF16BE},
+		{
+			Name:     "UTF-16LE without BOM",
+			Data:     utf16Text(text, binary.LittleEndian, false),
+			Encoding: encodingUTF16LE,
+			Written:  utf16Text(text, binary.LittleEndian, true),
+		},
+	} {
+		got, encoding := decodeText(test.Data)
+		if got != text || encoding != test.Encoding {
+			t.Errorf("%v: decoded as %q, %v, want %q, %v", test.Name, got, encoding, text, test.Encoding)
+		}
+		want := test.Written
+		if want == nil {
+			want = test.Data
+		}
+		if encoded := encodeText(got, encoding); !bytes.Equal(encoded, want) {
+			t.Errorf("%v: encoded as % X, want % X", test.Name, encoded, want)
+		}
+	}
+	if got, encoding := decodeText(nil); got != "" || encoding != encodingUTF8 {
+		t.Errorf("Empty file decoded as %q, %v", got, encoding)
+	}
+}
+
+func TestINIRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Data []byte
+	}{
+		{"CRLF", []byte("; comment\r\n[a]\r\nx = 1\r\n\r\n[ b ]\r\ny=2\r\nnot a key\r\n")},
+		{"LF", []byte("[a]\nx=1\n")},
+		{"no final newline", []byte("[a]\r\nx=1")},
+		{"empty", []byte{}},
+		{"UTF-8 with BOM", append([]byte{0xEF, 0xBB, 0xBF}, "[a]\r\nx=é\r\n"...)},
+		{"UTF-16LE", utf16Text("[a]\r\nx=Müsik 🎵\r\n", binary.LittleEndian, true)},
+		{"UTF-16BE", utf16Text("[a]\r\nx=Müsik 🎵\r\n", binary.BigEndian, true)},
+	} {
+		if got := parseINI(test.Data).bytes(); !bytes.Equal(got, test.Data) {
+			t.Errorf("%v: written back as %q, want %q", test.Name, got, test.Data)
+		}
+	}
+}
+
+func TestParseINI(t *testing.T) {
+	ini := parseINI([]byte("top=0\r\n[A]\r\n; x=comment\r\nx = 1 \r\n[ B ]\r\ny=a=b\r\n[a]\r\nz=3\r\n"))
+	if ini.Newline != "\r\n" || ini.Encoding != encodingUTF8 {
+		t.Errorf("File has newline %q and encoding %v", ini.Newline, ini.Encoding)
+	}
+	for _, test := range []struct {
+		Section string
+		Want    []iniLine
+	}{
+		{"", []iniLine{{"top", "0", "top=0"}}},
+		{"a", []iniLine{{"x", "1", "x = 1 "}}},
+		{"b", []iniLine{{"y", "a=b", "y=a=b"}}},
+	} {
+		section := ini.section(test.Section)
+		if section == nil {
+			t.Errorf("No section %q", test.Section)
+			continue
+		}
+		got := section.entries()
+		if len(got) != len(test.Want) {
+			t.Errorf("Section %q has entries %+v, want %+v", test.Section, got, test.Want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.Want[i] {
+				t.Errorf("Section %q has entries %+v, want %+v", test.Section, got, test.Want)
+			}
+		}
+	}
+	if ini.section("C") != nil {
+		t.Error("Found missing section C")
+	}
+}
+
+func TestINISet(t *testing.T) {
+	for _, test := range []struct {
+		Name    string
+		Content string
+		Section string
+		Key     string
+		Value   string
+		Want    string
+	}{
+		{
+			Name:    "replace",
+			Content: "[A]\r\nKey=old\r\nkey=second\r\n",
+			Section: "a", Key: "KEY", Value: "new",
+			Want: "[A]\r\nKEY=new\r\nkey=second\r\n",
+		},
+		{
+			Name:    "add after last entry",
+			Content: "[A]\r\nx=1\r\n; comment\r\n\r\n[B]\r\n",
+			Section: "A", Key: "y", Value: "2",
+			Want: "[A]\r\nx=1\r\ny=2\r\n; comment\r\n\r\n[B]\r\n",
+		},
+		{
+			Name:    "add to empty section",
+			Content: "[A]\r\n; comment\r\n",
+			Section: "A", Key: "y", Value: "2",
+			Want: "[A]\r\ny=2\r\n; comment\r\n",
+		},
+		{
+			Name:    "new section",
+			Content: "[A]\r\nx=1\r\n",
+			Section: "B", Key: "y", Value: "2",
+			Want: "[A]\r\nx=1\r\n\r\n[B]\r\ny=2\r\n",
+		},
+		{
+			Name:    "new section after blank lines",
+			Content: "[A]\nx=1\n\n\n",
+			Section: "B", Key: "y", Value: "2",
+			Want: "[A]\nx=1\n\n[B]\ny=2\n\n\n",
+		},
+		{
+			Name:    "new section without final newline",
+			Content: "[A]\r\nx=1",
+			Section: "B", Key: "y", Value: "2",
+			Want: "[A]\r\nx=1\r\n\r\n[B]\r\ny=2\r\n",
+		},
+		{
+			Name:    "new section in empty file",
+			Content: "",
+			Section: "B", Key: "y", Value: "2",
+			Want: "[B]\r\ny=2\r\n",
+		},
+	} {
+		ini := parseINI([]byte(test.Content))
+		ini.set(test.Section, test.Key, test.Value)
+		if got := string(ini.bytes()); got != test.Want {
+			t.Errorf("%v: file is %q, want %q", test.Name, got, test.Want)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// journalVersion is the version of the journal format that is written.
+// Journals of any other version are refused rather than guessed at.
+const journalVersion = 1
+
+// Statuses of a JournalChange. A change which is started but neither done nor
+// failed was interrupted while being set, so may or may not have been made.
+const (
+	changePending    = "pending"
+	changeStarted    = "started"
+	changeDone       = "done"
+	changeFailed     = "failed"
+	changeRolledBack = "rolled back"
+)
+
+// JournalChange is a folder location change planned by a journaled
+// operation, and how far it got.
+type JournalChange struct {
+	Folder string `json:"folder"`
+	GUID   string `json:"guid"`
+	// Old is the location before the change, if OldKnown; it can't be rolled
+	// back to otherwise
+	Old      string `json:"old"`
+	OldKnown bool   `json:"oldKnown"`
+	New      string `json:"new"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Journal is a write-ahead journal of a multi-folder operation such as apply:
+// every planned change and the location it replaces is written to File before
+// any folder is set, and the status of each change is updated, and synced to
+// disk, before and after it is made. If knownfolder is interrupted, e.g. by a
+// reboot, File remains, and the operation can be rolled back or completed
+// from it with "knownfolder recover". File is removed once the operation
+// finishes, whether or not every folder could be set.
+type Journal struct {
+	Version int    `json:"version"`
+	Command string `json:"command"`
+	// Scope is the target of the operation, as recorded in the audit log
+	Scope string `json:"scope"`
+	// Target is the options which select the same target for recover, e.g.
+	// "-u bob -p PASSWORD"
+	Target  string          `json:"target"`
+	PID     int             `json:"pid"`
+	Started time.Time       `json:"started"`
+	Changes []JournalChange `json:"changes"`
+	File    string          `json:"-"`
+}
+
+// IncompleteJournalError is returned when an operation is started for a
+// target which already has a journal, i.e. one that was interrupted and
+// hasn't been recovered.
+type IncompleteJournalError struct {
+	Journal *Journal
+}
+
+func (e IncompleteJournalError) Error() string {
+	return strings.TrimSpace(fmt.Sprintf("%v\nTo recover, run: knownfolder recover --rollback|--complete %v", e.Journal.Summary(), e.Journal.Target))
+}
+
+// journalFile returns the journal file in dir for operations on scope. Each
+// scope has its own, so that operations on different targets don't affect
+// each other.
+func journalFile(dir, scope string) string {
+	hash := sha256.Sum256([]byte(scope))
+	return filepath.Join(dir, fmt.Sprintf("journal-%x.json", hash[:8]))
+}
+
+// BeginJournal writes a journal to file of setting each of entries via
+// backend, recording the current location of every folder first. It fails
+// with an IncompleteJournalError if file already exists.
+func BeginJournal(file, command, scope, target string, backend Backend, entries []ManifestEntry) (*Journal, error) {
+	if existing, err := LoadJournal(file); err == nil {
+		return nil, IncompleteJournalError{existing}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	j := &Journal{
+		Version: journalVersion,
+		Command: command,
+		Scope:   scope,
+		Target:  target,
+		PID:     os.Getpid(),
+		Started: time.Now().UTC(),
+		File:    file,
+	}
+	for _, entry := range entries {
+		change := JournalChange{
+			Folder: entry.Folder,
+			GUID:   knownfolders[entry.Folder].String(),
+			New:    entry.Location,
+			Status: changePending,
+		}
+		old, err := backend.GetFolder(entry.Folder)
+		if err == nil {
+			change.Old, change.OldKnown = old, true
+		}
+		j.Changes = append(j.Changes, change)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return nil, err
+	}
+	if err := j.save(); err != nil {
+		return nil, fmt.Errorf("Could not write journal %v:\n%v", file, err)
+	}
+	return j, nil
+}
+
+// ApplyJournaled sets each of entries via backend, journaled in file for
+// scope, whose options for recover are target, see BeginJournal. The journal
+// is removed once every change has been attempted. If hooks isn't nil, its
+// pre-apply hooks are run once the journal is begun, and prevent the apply if
+// they fail, and its post-apply hooks once it is removed, whose failure is
+// returned along with the results.
+func ApplyJournaled(file, scope, target string, hooks *ApplyHooks, backend Backend, entries []ManifestEntry) ([]FolderResult, error) {
+	j, err := BeginJournal(file, "apply", scope, target, backend, entries)
+	if err != nil {
+		return nil, err
+	}
+	if hooks != nil {
+		err = hooks.Hooks.Run(applyHookEvent(hookPreApply, scope, hooks.Username, hooks.Manifest, j, nil))
+		if err != nil {
+			j.Finish()
+			return nil, fmt.Errorf("Did not apply %v, since:\n%v", hooks.Manifest, err)
+		}
+	}
+	results := j.Apply(backend)
+	failures := []string{}
+	if err := j.Finish(); err != nil {
+		failures = append(failures, fmt.Sprintf("Could not remove journal %v:\n%v", j.File, err))
+	}
+	if hooks != nil {
+		err = hooks.Hooks.Run(applyHookEvent(hookPostApply, scope, hooks.Username, hooks.Manifest, j, results))
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return results, fmt.Errorf("%v", strings.Join(failures, "\n"))
+	}
+	return results, nil
+}
+
+// LoadJournal reads the journal file at the given path.
+func LoadJournal(file string) (*Journal, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{File: file}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("Could not parse journal %v:\n%v", file, err)
+	}
+	if j.Version != journalVersion {
+		return nil, fmt.Errorf("Journal %v has version %v, but this version of knownfolder only understands version %v", file, j.Version, journalVersion)
+	}
+	return j, nil
+}
+
+// IncompleteJournals returns the journals in dir, which are those of
+// operations that were interrupted.
+func IncompleteJournals(dir string) ([]*Journal, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "journal-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	journals := []*Journal{}
+	for _, file := range files {
+		j, err := LoadJournal(file)
+		if err != nil {
+			return nil, err
+		}
+		journals = append(journals, j)
+	}
+	return journals, nil
+}
+
+// Summary describes the operation and how far it got, e.g. "apply for
+// current user, started ... by process 1234, was interrupted after 2 of 5
+// changes".
+func (j *Journal) Summary() string {
+	done := 0
+	for _, c := range j.Changes {
+		if c.Status != changePending {
+			done++
+		}
+	}
+	return fmt.Sprintf("%v for %v, started %v by process %v, was interrupted after %v of %v changes (journal %v)", j.Command, j.Scope, j.Started.Local().Format(time.RFC1123), j.PID, done, len(j.Changes), j.File)
+}
+
+// Apply sets every change that isn't done via backend, in order, which for a
+// new journal is all of them, and for an interrupted one completes it.
+// Changes are attempted even if earlier ones fail, but none are made once the
+// journal can't be written, since it would no longer reflect them.
+func (j *Journal) Apply(backend Backend) (results []FolderResult) {
+	return j.run(backend, false)
+}
+
+// Rollback sets every change that was started back to its old location via
+// backend, in reverse order. Changes whose old location is unknown fail.
+func (j *Journal) Rollback(backend Backend) (results []FolderResult) {
+	return j.run(backend, true)
+}
+
+func (j *Journal) run(backend Backend, rollback bool) (results []FolderResult) {
+	var journalErr error
+	for n := range j.Changes {
+		i := n
+		if rollback {
+			i = len(j.Changes) - 1 - n
+		}
+		c := &j.Changes[i]
+		location := c.New
+		switch {
+		case rollback && (c.Status == changePending || c.Status == changeRolledBack):
+			continue
+		case rollback:
+			location = c.Old
+		case c.Status == changeDone:
+			continue
+		}
+		result := FolderResult{Folder: c.Folder, Location: location}
+		switch {
+		case journalErr != nil:
+			result.Err = journalErr
+		case rollback && !c.OldKnown:
+			result.Err = fmt.Errorf("The location of %v before the change is unknown", c.Folder)
+		default:
+			result.Err = j.step(c, backend, location, rollback)
+			if _, ok := result.Err.(journalWriteError); ok {
+				journalErr = result.Err
+			}
+		}
+		results = append(results, result)
+	}
+	return
+}
+
+// journalWriteError is returned by step when the journal can't be written.
+type journalWriteError struct {
+	error
+}
+
+// step records that change c is started, sets its folder to location, and
+// records the outcome.
+func (j *Journal) step(c *JournalChange, backend Backend, location string, rollback bool) error {
+	c.Status, c.Error = changeStarted, ""
+	if err := j.save(); err != nil {
+		return journalWriteError{fmt.Errorf("Could not write journal %v, so did not set %v:\n%v", j.File, c.Folder, err)}
+	}
+	err := backend.SetFolder(c.Folder, location)
+	switch {
+	case err != nil:
+		c.Status, c.Error = changeFailed, err.Error()
+	case rollback:
+		c.Status = changeRolledBack
+	default:
+		c.Status = changeDone
+	}
+	if saveErr := j.save(); saveErr != nil {
+		return journalWriteError{fmt.Errorf("Could not write journal %v after setting %v:\n%v", j.File, c.Folder, saveErr)}
+	}
+	return err
+}
+
+// save writes the journal to File, and syncs it to disk.
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := replaceFile(j.File, append(data, '\n')); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(j.File))
+}
+
+// Finish removes the journal, once the operation is over.
+func (j *Journal) Finish() error {
+	err := os.Remove(j.File)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
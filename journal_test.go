@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// errCrash is panicked with by crashingBackend, to simulate knownfolder being
+// killed, e.g. by a reboot.
+var errCrash = errors.New("crash")
+
+// crashingBackend sets folders via Backend, until the call numbered Crash
+// (from 0), which crashes before setting the folder, or if After is set,
+// just after.
+type crashingBackend struct {
+	Backend
+	Crash int
+	After bool
+	calls int
+}
+
+func (b *crashingBackend) SetFolder(folder, location string) error {
+	b.calls++
+	if b.calls <= b.Crash {
+		return b.Backend.SetFolder(folder, location)
+	}
+	if b.After {
+		b.Backend.SetFolder(folder, location)
+	}
+	panic(errCrash)
+}
+
+// applyUntilCrash applies j via backend, which crashes, and returns the
+// journal as it was left on disk.
+func applyUntilCrash(t *testing.T, j *Journal, backend Backend) *Journal {
+	t.Helper()
+	func() {
+		defer func() {
+			if r := recover(); r != errCrash {
+				t.Fatalf("Apply didn't crash, but recovered %v", r)
+			}
+		}()
+		j.Apply(backend)
+	}()
+	left, err := LoadJournal(j.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return left
+}
+
+func TestJournalRecoverAfterCrash(t *testing.T) {
+	before := map[string]string{"Documents": `C:\Docs`, "Music": `C:\Music`, "Videos": `C:\Videos`}
+	entries := []ManifestEntry{{"Documents", `D:\Docs`}, {"Music", `D:\Music`}, {"Videos", `D:\Videos`}}
+	after := map[string]string{"Documents": `D:\Docs`, "Music": `D:\Music`, "Videos": `D:\Videos`}
+	for crash := 0; crash < len(entries); crash++ {
+		for _, crashAfter := range []bool{false, true} {
+			for _, rollback := range []bool{false, true} {
+				name := fmt.Sprintf("crash at %v of %v (after: %v), rollback: %v", crash+1, len(entries), crashAfter, rollback)
+				dir := t.TempDir()
+				file := journalFile(dir, "current user")
+				backend := newFakeBackend(before)
+				j, err := BeginJournal(file, "apply", "current user", "", backend, entries)
+				if err != nil {
+					t.Fatal(err)
+				}
+				left := applyUntilCrash(t, j, &crashingBackend{Backend: backend, Crash: crash, After: crashAfter})
+				statuses := []string{}
+				for _, c := range left.Changes {
+					statuses = append(statuses, c.Status)
+				}
+				wantStatuses := []string{changePending, changePending, changePending}
+				for i := 0; i < crash; i++ {
+					wantStatuses[i] = changeDone
+				}
+				wantStatuses[crash] = changeStarted
+				if !reflect.DeepEqual(statuses, wantStatuses) {
+					t.Errorf("%v: journal left with statuses %q, want %q", name, statuses, wantStatuses)
+				}
+				if summary := left.Summary(); !strings.Contains(summary, fmt.Sprintf("was interrupted after %v of 3 changes", crash+1)) {
+					t.Errorf("%v: summary is %q", name, summary)
+				}
+				_, err = BeginJournal(file, "apply", "current user", "", backend, entries)
+				if _, ok := err.(IncompleteJournalError); !ok {
+					t.Errorf("%v: apply after the crash returned %v, want an IncompleteJournalError", name, err)
+				}
+				var results []FolderResult
+				want := after
+				if rollback {
+					results, want = left.Rollback(backend), before
+				} else {
+					results = left.Apply(backend)
+				}
+				for _, result := range results {
+					if result.Err != nil {
+						t.Errorf("%v: could not recover %v: %v", name, result.Folder, result.Err)
+					}
+				}
+				if !reflect.DeepEqual(backend.Folders, want) {
+					t.Errorf("%v: folders are %v after recovering, want %v", name, backend.Folders, want)
+				}
+				// recovering again, e.g. after another crash, is harmless
+				backend.Calls = nil
+				if rollback {
+					left.Rollback(backend)
+				} else {
+					left.Apply(backend)
+				}
+				if len(backend.Calls) != 0 {
+					t.Errorf("%v: recovering twice set %q", name, backend.Calls)
+				}
+				if err := left.Finish(); err != nil {
+					t.Fatal(err)
+				}
+				if journals, err := IncompleteJournals(dir); err != nil || len(journals) != 0 {
+					t.Errorf("%v: journals %+v (%v) remain", name, journals, err)
+				}
+			}
+		}
+	}
+}
+
+func TestJournalRollbackUnknownLocation(t *testing.T) {
+	backend := newFakeBackend(map[string]string{"Documents": `C:\Docs`})
+	entries := []ManifestEntry{{"Documents", `D:\Docs`}, {"Music", `D:\Music`}}
+	j, err := BeginJournal(journalFile(t.TempDir(), "current user"), "apply", "current user", "", backend, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.Apply(backend)
+	results := j.Rollback(backend)
+	want := []FolderResult{
+		{Folder: "Music", Location: "", Err: errors.New("The location of Music before the change is unknown")},
+		{Folder: "Documents", Location: `C:\Docs`},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Rollback returned %+v, want %+v", results, want)
+	}
+}
+
+func TestJournalWriteFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "journals")
+	backend := newFakeBackend(nil)
+	entries := []ManifestEntry{{"Documents", `D:\Docs`}, {"Music", `D:\Music`}, {"Videos", `D:\Videos`}}
+	j, err := BeginJournal(journalFile(dir, "current user"), "apply", "current user", "", backend, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.OnSet = func(folder, location string) {
+		// the journal can't be written once its directory is gone
+		os.RemoveAll(dir)
+	}
+	results := j.Apply(backend)
+	if len(results) != 3 || results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "after setting Documents") {
+		t.Fatalf("Apply returned %+v, want a journal error for Documents", results)
+	}
+	for _, result := range results[1:] {
+		if result.Err != results[0].Err {
+			t.Errorf("%v failed with %v, want the journal error", result.Folder, result.Err)
+		}
+	}
+	if !reflect.DeepEqual(backend.Calls, []string{`Documents=D:\Docs`}) {
+		t.Errorf("Set %q after the journal could no longer be written", backend.Calls)
+	}
+}
+
+func TestApplyJournaled(t *testing.T) {
+	dir := t.TempDir()
+	file := journalFile(dir, "user bob")
+	backend := newFakeBackend(map[string]string{"Documents": `C:\Docs`})
+	backend.Errors["Music"] = errors.New("Access is denied.")
+	backend.OnSet = func(folder, location string) {
+		j, err := LoadJournal(file)
+		if err != nil {
+			t.Fatalf("Set %v without a journal: %v", folder, err)
+		}
+		if j.Scope != "user bob" || j.Target != "-u bob" || j.Command != "apply" {
+			t.Errorf("Journal is for %v %v (%v)", j.Command, j.Scope, j.Target)
+		}
+	}
+	entries := []ManifestEntry{{"Documents", `D:\Docs`}, {"Music", `D:\Music`}}
+	results, err := ApplyJournaled(file, "user bob", "-u bob", nil, backend, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FolderResult{{"Documents", `D:\Docs`, nil}, {"Music", `D:\Music`, errors.New("Access is denied.")}}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Results are %+v, want %+v", results, want)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("Journal remains after apply: %v", err)
+	}
+	// an interrupted apply is not overwritten
+	if _, err := BeginJournal(file, "apply", "user bob", "-u bob", backend, entries); err != nil {
+		t.Fatal(err)
+	}
+	backend.Calls = nil
+	if _, err := ApplyJournaled(file, "user bob", "-u bob", nil, backend, entries); err == nil || !strings.Contains(err.Error(), "knownfolder recover --rollback|--complete -u bob") {
+		t.Errorf("Applying over an interrupted apply returned %v", err)
+	}
+	if len(backend.Calls) != 0 {
+		t.Errorf("Set %q over an interrupted apply", backend.Calls)
+	}
+}
+
+func TestLoadJournalErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, test := range []struct {
+		Data string
+		Err  string
+	}{
+		{`{"version": 2}`, "has version 2, but this version of knownfolder only understands version 1"},
+		{`{"version": `, "Could not parse journal"},
+	} {
+		file := filepath.Join(dir, "journal.json")
+		if err := ioutil.WriteFile(file, []byte(test.Data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadJournal(file); err == nil || !strings.Contains(err.Error(), test.Err) {
+			t.Errorf("Loading %q returned %v, want %v", test.Data, err, test.Err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Package knownfolder locates the user's well known folders, such as their
+// documents or application data folders, on Windows, Linux (and other
+// freedesktop.org platforms) and macOS:
+//
+//	dir, err := knownfolder.Path(knownfolder.RoamingAppData)
+//
+// On Windows, folders are resolved with the shell API, so that redirected
+// folders are found. On Linux, they are resolved according to the XDG Base
+// Directory Specification and xdg-user-dirs. On macOS, they are resolved
+// according to Apple's File System Programming Guide.
+package knownfolder
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Folder identifies a known folder. The names match the Windows KNOWNFOLDERID
+// constants, without their FOLDERID_ prefix.
+type Folder int
+
+const (
+	// Profile is the user's home directory.
+	Profile Folder = iota
+	Desktop
+	Documents
+	Downloads
+	Music
+	Pictures
+	Videos
+	Templates
+	// Public is a folder shared with other users.
+	Public
+	// RoamingAppData is for the user's application configuration and data
+	// which should follow them between machines.
+	RoamingAppData
+	// LocalAppData is for the user's application data which is specific to
+	// this machine.
+	LocalAppData
+	// ProgramData is for application data shared by all users.
+	ProgramData
+	// Fonts is for the user's fonts on Linux and macOS, and the system fonts
+	// on Windows.
+	Fonts
+)
+
+var folderNames = []string{
+	Profile:        "Profile",
+	Desktop:        "Desktop",
+	Documents:      "Documents",
+	Downloads:      "Downloads",
+	Music:          "Music",
+	Pictures:       "Pictures",
+	Videos:         "Videos",
+	Templates:      "Templates",
+	Public:         "Public",
+	RoamingAppData: "RoamingAppData",
+	LocalAppData:   "LocalAppData",
+	ProgramData:    "ProgramData",
+	Fonts:          "Fonts",
+}
+
+func (f Folder) String() string {
+	if f < 0 || int(f) >= len(folderNames) {
+		return fmt.Sprintf("Folder(%d)", int(f))
+	}
+	return folderNames[f]
+}
+
+// UnsupportedError is returned by Path for folders with no equivalent on the
+// operating system.
+type UnsupportedError struct {
+	Folder Folder
+	OS     string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("Known folder %v has no equivalent on %v", e.Folder, e.OS)
+}
+
+// Path returns the location of the given folder for the current user. The
+// folder is not created if it does not exist.
+func Path(folder Folder) (string, error) {
+	if folder < 0 || int(folder) >= len(folderNames) {
+		return "", fmt.Errorf("Unknown folder %v", folder)
+	}
+	return path(folder)
+}
+
+func unsupported(folder Folder) error {
+	return &UnsupportedError{Folder: folder, OS: runtime.GOOS}
+}
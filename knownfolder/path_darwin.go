@@ -0,0 +1,39 @@
+package knownfolder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// darwinFolders are the locations of folders relative to the user's home
+// directory, see
+// https://developer.apple.com/library/archive/documentation/FileManagement/Conceptual/FileSystemProgrammingGuide/FileSystemOverview/FileSystemOverview.html
+var darwinFolders = map[Folder]string{
+	Profile:        "",
+	Desktop:        "Desktop",
+	Documents:      "Documents",
+	Downloads:      "Downloads",
+	Music:          "Music",
+	Pictures:       "Pictures",
+	Videos:         "Movies",
+	Public:         "Public",
+	RoamingAppData: "Library/Application Support",
+	LocalAppData:   "Library/Application Support",
+	Fonts:          "Library/Fonts",
+}
+
+func path(folder Folder) (string, error) {
+	if folder == ProgramData {
+		return "/Library/Application Support", nil
+	}
+	relative, ok := darwinFolders[folder]
+	if !ok {
+		return "", unsupported(folder)
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		return "", fmt.Errorf("$HOME is not set")
+	}
+	return filepath.Join(home, relative), nil
+}
@@ -0,0 +1,28 @@
+package knownfolder
+
+import "testing"
+
+func TestFolderString(t *testing.T) {
+	for _, test := range []struct {
+		Folder Folder
+		Want   string
+	}{
+		{Profile, "Profile"},
+		{RoamingAppData, "RoamingAppData"},
+		{Fonts, "Fonts"},
+		{Folder(-1), "Folder(-1)"},
+		{Fonts + 1, "Folder(13)"},
+	} {
+		if got := test.Folder.String(); got != test.Want {
+			t.Errorf("Folder %d is %q, want %q", int(test.Folder), got, test.Want)
+		}
+	}
+}
+
+func TestPathUnknownFolder(t *testing.T) {
+	for _, folder := range []Folder{-1, Fonts + 1} {
+		if _, err := Path(folder); err == nil {
+			t.Errorf("Path(%v) succeeded", folder)
+		}
+	}
+}
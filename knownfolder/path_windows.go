@@ -0,0 +1,71 @@
+package knownfolder
+
+import (
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// guid has the same memory layout as the Windows GUID structure.
+type guid struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+var folderIDs = map[Folder]*guid{
+	Profile:        {0x5E6C858F, 0x0E22, 0x4760, [8]byte{0x9A, 0xFE, 0xEA, 0x33, 0x17, 0xB6, 0x71, 0x73}},
+	Desktop:        {0xB4BFCC3A, 0xDB2C, 0x424C, [8]byte{0xB0, 0x29, 0x7F, 0xE9, 0x9A, 0x87, 0xC6, 0x41}},
+	Documents:      {0xFDD39AD0, 0x238F, 0x46AF, [8]byte{0xAD, 0xB4, 0x6C, 0x85, 0x48, 0x03, 0x69, 0xC7}},
+	Downloads:      {0x374DE290, 0x123F, 0x4565, [8]byte{0x91, 0x64, 0x39, 0xC4, 0x92, 0x5E, 0x46, 0x7B}},
+	Music:          {0x4BD8D571, 0x6D19, 0x48D3, [8]byte{0xBE, 0x97, 0x42, 0x22, 0x20, 0x08, 0x0E, 0x43}},
+	Pictures:       {0x33E28130, 0x4E1E, 0x4676, [8]byte{0x83, 0x5A, 0x98, 0x39, 0x5C, 0x3B, 0xC3, 0xBB}},
+	Videos:         {0x18989B1D, 0x99B5, 0x455B, [8]byte{0x84, 0x1C, 0xAB, 0x7C, 0x74, 0xE4, 0xDD, 0xFC}},
+	Templates:      {0xA63293E8, 0x664E, 0x48DB, [8]byte{0xA0, 0x79, 0xDF, 0x75, 0x9E, 0x05, 0x09, 0xF7}},
+	Public:         {0xDFDF76A2, 0xC82A, 0x4D63, [8]byte{0x90, 0x6A, 0x56, 0x44, 0xAC, 0x45, 0x73, 0x85}},
+	RoamingAppData: {0x3EB685DB, 0x65F9, 0x4CF6, [8]byte{0xA0, 0x3A, 0xE3, 0xEF, 0x65, 0x72, 0x9F, 0x3D}},
+	LocalAppData:   {0xF1B32785, 0x6FBA, 0x4FCF, [8]byte{0x9D, 0x55, 0x7B, 0x8E, 0x7F, 0x15, 0x70, 0x91}},
+	ProgramData:    {0x62AB5D82, 0xFDC1, 0x4DC3, [8]byte{0xA9, 0xDD, 0x07, 0x0D, 0x1D, 0x49, 0x5D, 0x97}},
+	Fonts:          {0xFD228CB7, 0xAE11, 0x4AE3, [8]byte{0x86, 0x4C, 0x16, 0xF3, 0x91, 0x0A, 0xB8, 0xFE}},
+}
+
+var (
+	shell32                  = syscall.NewLazyDLL("shell32.dll")
+	ole32                    = syscall.NewLazyDLL("ole32.dll")
+	procSHGetKnownFolderPath = shell32.NewProc("SHGetKnownFolderPath")
+	procCoTaskMemFree        = ole32.NewProc("CoTaskMemFree")
+)
+
+func path(folder Folder) (string, error) {
+	id, ok := folderIDs[folder]
+	if !ok {
+		return "", unsupported(folder)
+	}
+	var p *uint16
+	r0, _, _ := procSHGetKnownFolderPath.Call(
+		uintptr(unsafe.Pointer(id)),
+		0,
+		0,
+		uintptr(unsafe.Pointer(&p)),
+	)
+	// the returned path must be freed even on failure
+	defer procCoTaskMemFree.Call(uintptr(unsafe.Pointer(p)))
+	if r0 != 0 {
+		return "", syscall.Errno(r0)
+	}
+	return utf16PtrToString(p), nil
+}
+
+// utf16PtrToString returns the NUL terminated UTF-16 string at p, copied
+// into Go memory.
+func utf16PtrToString(p *uint16) string {
+	if p == nil {
+		return ""
+	}
+	s := []uint16{}
+	for ; *p != 0; p = (*uint16)(unsafe.Pointer(uintptr(unsafe.Pointer(p)) + unsafe.Sizeof(*p))) {
+		s = append(s, *p)
+	}
+	return string(utf16.Decode(s))
+}
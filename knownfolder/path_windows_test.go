@@ -0,0 +1,29 @@
+package knownfolder
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestUTF16PtrToString(t *testing.T) {
+	for _, want := range []string{"", `C:\Users\pete`, `D:\Ünïcödé\😀`} {
+		s, err := syscall.UTF16FromString(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := utf16PtrToString(&s[0]); got != want {
+			t.Errorf("utf16PtrToString returned %q, want %q", got, want)
+		}
+	}
+	if got := utf16PtrToString(nil); got != "" {
+		t.Errorf("utf16PtrToString(nil) returned %q", got)
+	}
+}
+
+func TestPath(t *testing.T) {
+	for folder := range folderIDs {
+		if _, err := Path(folder); err != nil {
+			t.Errorf("Path(%v) returned %v", folder, err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+//go:build !windows && !darwin
+// +build !windows,!darwin
+
+package knownfolder
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// xdgUserDirs are the names of the xdg-user-dirs variables for folders, e.g.
+// XDG_DESKTOP_DIR for Desktop.
+var xdgUserDirs = map[Folder]string{
+	Desktop:   "DESKTOP",
+	Documents: "DOCUMENTS",
+	Downloads: "DOWNLOAD",
+	Music:     "MUSIC",
+	Pictures:  "PICTURES",
+	Videos:    "VIDEOS",
+	Templates: "TEMPLATES",
+	Public:    "PUBLICSHARE",
+}
+
+// xdgEnvironment is what folders are resolved from: the environment
+// variables and files of the system, or a fake for testing.
+type xdgEnvironment struct {
+	Getenv   func(key string) string
+	ReadFile func(file string) ([]byte, error)
+}
+
+var system = xdgEnvironment{
+	Getenv:   os.Getenv,
+	ReadFile: ioutil.ReadFile,
+}
+
+func path(folder Folder) (string, error) {
+	return system.path(folder)
+}
+
+func (e xdgEnvironment) path(folder Folder) (string, error) {
+	home := e.Getenv("HOME")
+	if home == "" {
+		return "", fmt.Errorf("$HOME is not set")
+	}
+	switch folder {
+	case Profile:
+		return home, nil
+	case RoamingAppData:
+		return e.baseDir("XDG_CONFIG_HOME", filepath.Join(home, ".config")), nil
+	case LocalAppData:
+		return e.baseDir("XDG_DATA_HOME", filepath.Join(home, ".local", "share")), nil
+	case Fonts:
+		// the per user font directory of fontconfig
+		return filepath.Join(e.baseDir("XDG_DATA_HOME", filepath.Join(home, ".local", "share")), "fonts"), nil
+	}
+	name, ok := xdgUserDirs[folder]
+	if !ok {
+		return "", unsupported(folder)
+	}
+	return e.userDir(home, name)
+}
+
+// baseDir returns the value of the given XDG Base Directory variable, or
+// fallback if it is not set, or not an absolute path, which the
+// specification says must be ignored.
+func (e xdgEnvironment) baseDir(variable, fallback string) string {
+	if dir := e.Getenv(variable); filepath.IsAbs(dir) {
+		return dir
+	}
+	return fallback
+}
+
+// userDir returns the location of an xdg-user-dirs folder, as the
+// xdg-user-dir command does: from the user-dirs.dirs file in the config
+// directory, or else the environment. Folders that aren't configured fall
+// back to the home directory, except for the desktop, which falls back to
+// ~/Desktop.
+func (e xdgEnvironment) userDir(home, name string) (string, error) {
+	variable := "XDG_" + name + "_DIR"
+	configDir := e.baseDir("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	data, err := e.ReadFile(filepath.Join(configDir, "user-dirs.dirs"))
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if dir, ok := parseUserDirs(data, home)[variable]; ok {
+		return dir, nil
+	}
+	if dir := e.Getenv(variable); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	if name == "DESKTOP" {
+		return filepath.Join(home, "Desktop"), nil
+	}
+	return home, nil
+}
+
+// parseUserDirs parses a user-dirs.dirs file, a shell script of lines in the
+// form
+//
+//	XDG_DESKTOP_DIR="$HOME/Desktop"
+//
+// where values are either absolute, or relative to $HOME. Lines in any other
+// form are ignored, as xdg-user-dirs ignores them.
+func parseUserDirs(data []byte, home string) map[string]string {
+	dirs := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || len(parts[1]) < 2 || !strings.HasPrefix(parts[1], `"`) || !strings.HasSuffix(parts[1], `"`) {
+			continue
+		}
+		value := parts[1][1 : len(parts[1])-1]
+		switch {
+		case value == "$HOME":
+			value = home
+		case strings.HasPrefix(value, "$HOME/"):
+			value = filepath.Join(home, unescapeShell(value[len("$HOME/"):]))
+		case strings.HasPrefix(value, "/"):
+			value = unescapeShell(value)
+		default:
+			continue
+		}
+		dirs[strings.TrimSpace(parts[0])] = value
+	}
+	return dirs
+}
+
+// unescapeShell removes the backslash escapes xdg-user-dirs-update writes for
+// the characters that are special inside double quotes.
+func unescapeShell(s string) string {
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`, "\\$", "$", "\\`", "`").Replace(s)
+}
@@ -0,0 +1,99 @@
+//go:build !windows && !darwin
+// +build !windows,!darwin
+
+package knownfolder
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// fakeEnvironment returns an xdgEnvironment with the given variables and
+// files, in which every other file doesn't exist.
+func fakeEnvironment(variables, files map[string]string) xdgEnvironment {
+	return xdgEnvironment{
+		Getenv: func(key string) string { return variables[key] },
+		ReadFile: func(file string) ([]byte, error) {
+			if data, ok := files[file]; ok {
+				return []byte(data), nil
+			}
+			return nil, &os.PathError{Op: "open", Path: file, Err: os.ErrNotExist}
+		},
+	}
+}
+
+const userDirs = `# This file is written by xdg-user-dirs-update
+XDG_DESKTOP_DIR="$HOME/Schreibtisch"
+XDG_DOWNLOAD_DIR="$HOME"
+XDG_MUSIC_DIR="/srv/music"
+XDG_PICTURES_DIR="$HOME/My \"Pictures\" \$1"
+XDG_VIDEOS_DIR=relative
+XDG_TEMPLATES_DIR="Templates"
+`
+
+func TestXDGPath(t *testing.T) {
+	home := map[string]string{"HOME": "/home/pete"}
+	configured := map[string]string{
+		"HOME":              "/home/pete",
+		"XDG_CONFIG_HOME":   "/etc/pete",
+		"XDG_DATA_HOME":     "/var/pete",
+		"XDG_DOCUMENTS_DIR": "/srv/docs",
+		"XDG_MUSIC_DIR":     "/srv/env-music",
+	}
+	for _, test := range []struct {
+		Variables map[string]string
+		Files     map[string]string
+		Folder    Folder
+		Want      string
+	}{
+		{home, nil, Profile, "/home/pete"},
+		{home, nil, RoamingAppData, "/home/pete/.config"},
+		{home, nil, LocalAppData, "/home/pete/.local/share"},
+		{home, nil, Fonts, "/home/pete/.local/share/fonts"},
+		{configured, nil, RoamingAppData, "/etc/pete"},
+		{configured, nil, LocalAppData, "/var/pete"},
+		{configured, nil, Fonts, "/var/pete/fonts"},
+		// relative base directories are ignored
+		{map[string]string{"HOME": "/home/pete", "XDG_CONFIG_HOME": "config"}, nil, RoamingAppData, "/home/pete/.config"},
+		// unconfigured user dirs are the home directory, apart from the desktop
+		{home, nil, Desktop, "/home/pete/Desktop"},
+		{home, nil, Documents, "/home/pete"},
+		// the environment is used if user-dirs.dirs doesn't say
+		{configured, nil, Documents, "/srv/docs"},
+		// user-dirs.dirs is read from the config directory
+		{home, map[string]string{"/home/pete/.config/user-dirs.dirs": userDirs}, Desktop, "/home/pete/Schreibtisch"},
+		{home, map[string]string{"/home/pete/.config/user-dirs.dirs": userDirs}, Downloads, "/home/pete"},
+		{home, map[string]string{"/home/pete/.config/user-dirs.dirs": userDirs}, Music, "/srv/music"},
+		{home, map[string]string{"/home/pete/.config/user-dirs.dirs": userDirs}, Pictures, `/home/pete/My "Pictures" $1`},
+		{configured, map[string]string{"/etc/pete/user-dirs.dirs": userDirs}, Music, "/srv/music"},
+		{configured, map[string]string{"/home/pete/.config/user-dirs.dirs": userDirs}, Music, "/srv/env-music"},
+		// values that are neither absolute nor relative to $HOME are ignored
+		{home, map[string]string{"/home/pete/.config/user-dirs.dirs": userDirs}, Videos, "/home/pete"},
+		{home, map[string]string{"/home/pete/.config/user-dirs.dirs": userDirs}, Templates, "/home/pete"},
+	} {
+		got, err := fakeEnvironment(test.Variables, test.Files).path(test.Folder)
+		if err != nil {
+			t.Errorf("%v with %v: %v", test.Folder, test.Variables, err)
+			continue
+		}
+		if got != test.Want {
+			t.Errorf("%v with %v is %q, want %q", test.Folder, test.Variables, got, test.Want)
+		}
+	}
+}
+
+func TestXDGPathErrors(t *testing.T) {
+	if _, err := fakeEnvironment(nil, nil).path(Profile); err == nil || err.Error() != "$HOME is not set" {
+		t.Errorf("Path without $HOME returned %v", err)
+	}
+	var unsupported *UnsupportedError
+	if _, err := fakeEnvironment(map[string]string{"HOME": "/home/pete"}, nil).path(ProgramData); !errors.As(err, &unsupported) || unsupported.Folder != ProgramData {
+		t.Errorf("ProgramData returned %v", err)
+	}
+	e := fakeEnvironment(map[string]string{"HOME": "/home/pete"}, nil)
+	e.ReadFile = func(string) ([]byte, error) { return nil, os.ErrPermission }
+	if _, err := e.path(Documents); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Unreadable user-dirs.dirs returned %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+//go:generate go run gen/knownfolders/main.go -header gen/knownfolders/KnownFolders.h -descriptions gen/knownfolders/FolderDescriptions.csv -out knownfolders_table.go
+
+import (
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GUID has the same memory layout as the Windows GUID structure, so that it
+// can be passed directly to Windows API calls.
+type GUID struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+// String returns the GUID in registry format, e.g.
+// {F1B32785-6FBA-4FCF-9D55-7B8E7F157091}.
+func (g *GUID) String() string {
+	return fmt.Sprintf(
+		"{%08X-%04X-%04X-%02X%02X-%02X%02X%02X%02X%02X%02X}",
+		g.Data1, g.Data2, g.Data3,
+		g.Data4[0], g.Data4[1], g.Data4[2], g.Data4[3],
+		g.Data4[4], g.Data4[5], g.Data4[6], g.Data4[7],
+	)
+}
+
+var guidPattern = regexp.MustCompile(`^\{?[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}\}?$`)
+
+// ParseGUID parses a GUID in registry format, e.g.
+// {F1B32785-6FBA-4FCF-9D55-7B8E7F157091}. Braces are optional.
+func ParseGUID(s string) (guid GUID, err error) {
+	if !guidPattern.MatchString(s) {
+		return guid, fmt.Errorf(`Invalid GUID "%v"`, s)
+	}
+	hex := strings.Replace(strings.Trim(s, "{}"), "-", "", -1)
+	var b [16]byte
+	for i := range b {
+		v, _ := strconv.ParseUint(hex[2*i:2*i+2], 16, 8)
+		b[i] = byte(v)
+	}
+	guid.Data1 = binary.BigEndian.Uint32(b[0:])
+	guid.Data2 = binary.BigEndian.Uint16(b[4:])
+	guid.Data3 = binary.BigEndian.Uint16(b[6:])
+	copy(guid.Data4[:], b[8:])
+	return guid, nil
+}
+
+// folderByGUID returns the knownfolders name of the folder with the given
+// GUID, which should be in registry format, e.g.
+// {F1B32785-6FBA-4FCF-9D55-7B8E7F157091}. Braces are optional.
+func folderByGUID(guid string) (string, bool) {
+	guid = "{" + strings.Trim(guid, "{}") + "}"
+	for name, g := range knownfolders {
+		if strings.EqualFold(g.String(), guid) {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,286 @@
+// Code generated by gen/knownfolders from KnownFolders.h; DO NOT EDIT.
+
+package main
+
+// knownfolders maps the names of the FOLDERID_ constants of the Windows SDK,
+// without the prefix, to their KNOWNFOLDERIDs. See
+// https://msdn.microsoft.com/en-us/library/windows/desktop/dd378457(v=vs.85).aspx
+var knownfolders = map[string]*GUID{
+	"AccountPictures":        {0x008CA0B1, 0x55B4, 0x4C56, [8]byte{0xB8, 0xA8, 0x4D, 0xE4, 0xB2, 0x99, 0xD3, 0xBE}}, // {008CA0B1-55B4-4C56-B8A8-4DE4B299D3BE}
+	"AddNewPrograms":         {0xDE61D971, 0x5EBC, 0x4F02, [8]byte{0xA3, 0xA9, 0x6C, 0x82, 0x89, 0x5E, 0x5C, 0x04}}, // {DE61D971-5EBC-4F02-A3A9-6C82895E5C04}
+	"AdminTools":             {0x724EF170, 0xA42D, 0x4FEF, [8]byte{0x9F, 0x26, 0xB6, 0x0E, 0x84, 0x6F, 0xBA, 0x4F}}, // {724EF170-A42D-4FEF-9F26-B60E846FBA4F}
+	"AppDataDesktop":         {0xB2C5E279, 0x7ADD, 0x439F, [8]byte{0xB2, 0x8C, 0xC4, 0x1F, 0xE1, 0xBB, 0xF6, 0x72}}, // {B2C5E279-7ADD-439F-B28C-C41FE1BBF672}
+	"AppDataDocuments":       {0x7BE16610, 0x1F7F, 0x44AC, [8]byte{0xBF, 0xF0, 0x83, 0xE1, 0x5F, 0x2F, 0xFC, 0xA1}}, // {7BE16610-1F7F-44AC-BFF0-83E15F2FFCA1}
+	"AppDataFavorites":       {0x7CFBEFBC, 0xDE1F, 0x45AA, [8]byte{0xB8, 0x43, 0xA5, 0x42, 0xAC, 0x53, 0x6C, 0xC9}}, // {7CFBEFBC-DE1F-45AA-B843-A542AC536CC9}
+	"AppDataProgramData":     {0x559D40A3, 0xA036, 0x40FA, [8]byte{0xAF, 0x61, 0x84, 0xCB, 0x43, 0x0A, 0x4D, 0x34}}, // {559D40A3-A036-40FA-AF61-84CB430A4D34}
+	"ApplicationShortcuts":   {0xA3918781, 0xE5F2, 0x4890, [8]byte{0xB3, 0xD9, 0xA7, 0xE5, 0x43, 0x32, 0x32, 0x8C}}, // {A3918781-E5F2-4890-B3D9-A7E54332328C}
+	"AppsFolder":             {0x1E87508D, 0x89C2, 0x42F0, [8]byte{0x8A, 0x7E, 0x64, 0x5A, 0x0F, 0x50, 0xCA, 0x58}}, // {1E87508D-89C2-42F0-8A7E-645A0F50CA58}
+	"AppUpdates":             {0xA305CE99, 0xF527, 0x492B, [8]byte{0x8B, 0x1A, 0x7E, 0x76, 0xFA, 0x98, 0xD6, 0xE4}}, // {A305CE99-F527-492B-8B1A-7E76FA98D6E4}
+	"CameraRoll":             {0xAB5FB87B, 0x7CE2, 0x4F83, [8]byte{0x91, 0x5D, 0x55, 0x08, 0x46, 0xC9, 0x53, 0x7B}}, // {AB5FB87B-7CE2-4F83-915D-550846C9537B}
+	"CDBurning":              {0x9E52AB10, 0xF80D, 0x49DF, [8]byte{0xAC, 0xB8, 0x43, 0x30, 0xF5, 0x68, 0x78, 0x55}}, // {9E52AB10-F80D-49DF-ACB8-4330F5687855}
+	"ChangeRemovePrograms":   {0xDF7266AC, 0x9274, 0x4867, [8]byte{0x8D, 0x55, 0x3B, 0xD6, 0x61, 0xDE, 0x87, 0x2D}}, // {DF7266AC-9274-4867-8D55-3BD661DE872D}
+	"CommonAdminTools":       {0xD0384E7D, 0xBAC3, 0x4797, [8]byte{0x8F, 0x14, 0xCB, 0xA2, 0x29, 0xB3, 0x92, 0xB5}}, // {D0384E7D-BAC3-4797-8F14-CBA229B392B5}
+	"CommonOEMLinks":         {0xC1BAE2D0, 0x10DF, 0x4334, [8]byte{0xBE, 0xDD, 0x7A, 0xA2, 0x0B, 0x22, 0x7A, 0x9D}}, // {C1BAE2D0-10DF-4334-BEDD-7AA20B227A9D}
+	"CommonPrograms":         {0x0139D44E, 0x6AFE, 0x49F2, [8]byte{0x86, 0x90, 0x3D, 0xAF, 0xCA, 0xE6, 0xFF, 0xB8}}, // {0139D44E-6AFE-49F2-8690-3DAFCAE6FFB8}
+	"CommonStartMenu":        {0xA4115719, 0xD62E, 0x491D, [8]byte{0xAA, 0x7C, 0xE7, 0x4B, 0x8B, 0xE3, 0xB0, 0x67}}, // {A4115719-D62E-491D-AA7C-E74B8BE3B067}
+	"CommonStartup":          {0x82A5EA35, 0xD9CD, 0x47C5, [8]byte{0x96, 0x29, 0xE1, 0x5D, 0x2F, 0x71, 0x4E, 0x6E}}, // {82A5EA35-D9CD-47C5-9629-E15D2F714E6E}
+	"CommonTemplates":        {0xB94237E7, 0x57AC, 0x4347, [8]byte{0x91, 0x51, 0xB0, 0x8C, 0x6C, 0x32, 0xD1, 0xF7}}, // {B94237E7-57AC-4347-9151-B08C6C32D1F7}
+	"ComputerFolder":         {0x0AC0837C, 0xBBF8, 0x452A, [8]byte{0x85, 0x0D, 0x79, 0xD0, 0x8E, 0x66, 0x7C, 0xA7}}, // {0AC0837C-BBF8-452A-850D-79D08E667CA7}
+	"ConflictFolder":         {0x4BFEFB45, 0x347D, 0x4006, [8]byte{0xA5, 0xBE, 0xAC, 0x0C, 0xB0, 0x56, 0x71, 0x92}}, // {4BFEFB45-347D-4006-A5BE-AC0CB0567192}
+	"ConnectionsFolder":      {0x6F0CD92B, 0x2E97, 0x45D1, [8]byte{0x88, 0xFF, 0xB0, 0xD1, 0x86, 0xB8, 0xDE, 0xDD}}, // {6F0CD92B-2E97-45D1-88FF-B0D186B8DEDD}
+	"Contacts":               {0x56784854, 0xC6CB, 0x462B, [8]byte{0x81, 0x69, 0x88, 0xE3, 0x50, 0xAC, 0xB8, 0x82}}, // {56784854-C6CB-462B-8169-88E350ACB882}
+	"ControlPanelFolder":     {0x82A74AEB, 0xAEB4, 0x465C, [8]byte{0xA0, 0x14, 0xD0, 0x97, 0xEE, 0x34, 0x6D, 0x63}}, // {82A74AEB-AEB4-465C-A014-D097EE346D63}
+	"Cookies":                {0x2B0F765D, 0xC0E9, 0x4171, [8]byte{0x90, 0x8E, 0x08, 0xA6, 0x11, 0xB8, 0x4F, 0xF6}}, // {2B0F765D-C0E9-4171-908E-08A611B84FF6}
+	"Desktop":                {0xB4BFCC3A, 0xDB2C, 0x424C, [8]byte{0xB0, 0x29, 0x7F, 0xE9, 0x9A, 0x87, 0xC6, 0x41}}, // {B4BFCC3A-DB2C-424C-B029-7FE99A87C641}
+	"DeviceMetadataStore":    {0x5CE4A5E9, 0xE4EB, 0x479D, [8]byte{0xB8, 0x9F, 0x13, 0x0C, 0x02, 0x88, 0x61, 0x55}}, // {5CE4A5E9-E4EB-479D-B89F-130C02886155}
+	"Documents":              {0xFDD39AD0, 0x238F, 0x46AF, [8]byte{0xAD, 0xB4, 0x6C, 0x85, 0x48, 0x03, 0x69, 0xC7}}, // {FDD39AD0-238F-46AF-ADB4-6C85480369C7}
+	"DocumentsLibrary":       {0x7B0DB17D, 0x9CD2, 0x4A93, [8]byte{0x97, 0x33, 0x46, 0xCC, 0x89, 0x02, 0x2E, 0x7C}}, // {7B0DB17D-9CD2-4A93-9733-46CC89022E7C}
+	"Downloads":              {0x374DE290, 0x123F, 0x4565, [8]byte{0x91, 0x64, 0x39, 0xC4, 0x92, 0x5E, 0x46, 0x7B}}, // {374DE290-123F-4565-9164-39C4925E467B}
+	"Favorites":              {0x1777F761, 0x68AD, 0x4D8A, [8]byte{0x87, 0xBD, 0x30, 0xB7, 0x59, 0xFA, 0x33, 0xDD}}, // {1777F761-68AD-4D8A-87BD-30B759FA33DD}
+	"Fonts":                  {0xFD228CB7, 0xAE11, 0x4AE3, [8]byte{0x86, 0x4C, 0x16, 0xF3, 0x91, 0x0A, 0xB8, 0xFE}}, // {FD228CB7-AE11-4AE3-864C-16F3910AB8FE}
+	"Games":                  {0xCAC52C1A, 0xB53D, 0x4EDC, [8]byte{0x92, 0xD7, 0x6B, 0x2E, 0x8A, 0xC1, 0x94, 0x34}}, // {CAC52C1A-B53D-4EDC-92D7-6B2E8AC19434}
+	"GameTasks":              {0x054FAE61, 0x4DD8, 0x4787, [8]byte{0x80, 0xB6, 0x09, 0x02, 0x20, 0xC4, 0xB7, 0x00}}, // {054FAE61-4DD8-4787-80B6-090220C4B700}
+	"History":                {0xD9DC8A3B, 0xB784, 0x432E, [8]byte{0xA7, 0x81, 0x5A, 0x11, 0x30, 0xA7, 0x59, 0x63}}, // {D9DC8A3B-B784-432E-A781-5A1130A75963}
+	"HomeGroup":              {0x52528A6B, 0xB9E3, 0x4ADD, [8]byte{0xB6, 0x0D, 0x58, 0x8C, 0x2D, 0xBA, 0x84, 0x2D}}, // {52528A6B-B9E3-4ADD-B60D-588C2DBA842D}
+	"HomeGroupCurrentUser":   {0x9B74B6A3, 0x0DFD, 0x4F11, [8]byte{0x9E, 0x78, 0x5F, 0x78, 0x00, 0xF2, 0xE7, 0x72}}, // {9B74B6A3-0DFD-4F11-9E78-5F7800F2E772}
+	"ImplicitAppShortcuts":   {0xBCB5256F, 0x79F6, 0x4CEE, [8]byte{0xB7, 0x25, 0xDC, 0x34, 0xE4, 0x02, 0xFD, 0x46}}, // {BCB5256F-79F6-4CEE-B725-DC34E402FD46}
+	"InternetCache":          {0x352481E8, 0x33BE, 0x4251, [8]byte{0xBA, 0x85, 0x60, 0x07, 0xCA, 0xED, 0xCF, 0x9D}}, // {352481E8-33BE-4251-BA85-6007CAEDCF9D}
+	"InternetFolder":         {0x4D9F7874, 0x4E0C, 0x4904, [8]byte{0x96, 0x7B, 0x40, 0xB0, 0xD2, 0x0C, 0x3E, 0x4B}}, // {4D9F7874-4E0C-4904-967B-40B0D20C3E4B}
+	"Libraries":              {0x1B3EA5DC, 0xB587, 0x4786, [8]byte{0xB4, 0xEF, 0xBD, 0x1D, 0xC3, 0x32, 0xAE, 0xAE}}, // {1B3EA5DC-B587-4786-B4EF-BD1DC332AEAE}
+	"Links":                  {0xBFB9D5E0, 0xC6A9, 0x404C, [8]byte{0xB2, 0xB2, 0xAE, 0x6D, 0xB6, 0xAF, 0x49, 0x68}}, // {BFB9D5E0-C6A9-404C-B2B2-AE6DB6AF4968}
+	"LocalAppData":           {0xF1B32785, 0x6FBA, 0x4FCF, [8]byte{0x9D, 0x55, 0x7B, 0x8E, 0x7F, 0x15, 0x70, 0x91}}, // {F1B32785-6FBA-4FCF-9D55-7B8E7F157091}
+	"LocalAppDataLow":        {0xA520A1A4, 0x1780, 0x4FF6, [8]byte{0xBD, 0x18, 0x16, 0x73, 0x43, 0xC5, 0xAF, 0x16}}, // {A520A1A4-1780-4FF6-BD18-167343C5AF16}
+	"LocalDocuments":         {0xF42EE2D3, 0x909F, 0x4907, [8]byte{0x88, 0x71, 0x4C, 0x22, 0xFC, 0x0B, 0xF7, 0x56}}, // {F42EE2D3-909F-4907-8871-4C22FC0BF756}
+	"LocalDownloads":         {0x7D83EE9B, 0x2244, 0x4E70, [8]byte{0xB1, 0xF5, 0x53, 0x93, 0x04, 0x2A, 0xF1, 0xE4}}, // {7D83EE9B-2244-4E70-B1F5-5393042AF1E4}
+	"LocalizedResourcesDir":  {0x2A00375E, 0x224C, 0x49DE, [8]byte{0xB8, 0xD1, 0x44, 0x0D, 0xF7, 0xEF, 0x3D, 0xDC}}, // {2A00375E-224C-49DE-B8D1-440DF7EF3DDC}
+	"LocalMusic":             {0xA0C69A99, 0x21C8, 0x4671, [8]byte{0x87, 0x03, 0x79, 0x34, 0x16, 0x2F, 0xCF, 0x1D}}, // {A0C69A99-21C8-4671-8703-7934162FCF1D}
+	"LocalPictures":          {0x0DDD015D, 0xB06C, 0x45D5, [8]byte{0x8C, 0x4C, 0xF5, 0x97, 0x13, 0x85, 0x46, 0x39}}, // {0DDD015D-B06C-45D5-8C4C-F59713854639}
+	"LocalVideos":            {0x35286A68, 0x3C57, 0x41A1, [8]byte{0xBB, 0xB1, 0x0E, 0xAE, 0x73, 0xD7, 0x6C, 0x95}}, // {35286A68-3C57-41A1-BBB1-0EAE73D76C95}
+	"Music":                  {0x4BD8D571, 0x6D19, 0x48D3, [8]byte{0xBE, 0x97, 0x42, 0x22, 0x20, 0x08, 0x0E, 0x43}}, // {4BD8D571-6D19-48D3-BE97-422220080E43}
+	"MusicLibrary":           {0x2112AB0A, 0xC86A, 0x4FFE, [8]byte{0xA3, 0x68, 0x0D, 0xE9, 0x6E, 0x47, 0x01, 0x2E}}, // {2112AB0A-C86A-4FFE-A368-0DE96E47012E}
+	"NetHood":                {0xC5ABBF53, 0xE17F, 0x4121, [8]byte{0x89, 0x00, 0x86, 0x62, 0x6F, 0xC2, 0xC9, 0x73}}, // {C5ABBF53-E17F-4121-8900-86626FC2C973}
+	"NetworkFolder":          {0xD20BEEC4, 0x5CA8, 0x4905, [8]byte{0xAE, 0x3B, 0xBF, 0x25, 0x1E, 0xA0, 0x9B, 0x53}}, // {D20BEEC4-5CA8-4905-AE3B-BF251EA09B53}
+	"Objects3D":              {0x31C0DD25, 0x9439, 0x4F12, [8]byte{0xBF, 0x41, 0x7F, 0xF4, 0xED, 0xA3, 0x87, 0x22}}, // {31C0DD25-9439-4F12-BF41-7FF4EDA38722}
+	"OriginalImages":         {0x2C36C0AA, 0x5812, 0x4B87, [8]byte{0xBF, 0xD0, 0x4C, 0xD0, 0xDF, 0xB1, 0x9B, 0x39}}, // {2C36C0AA-5812-4B87-BFD0-4CD0DFB19B39}
+	"PhotoAlbums":            {0x69D2CF90, 0xFC33, 0x4FB7, [8]byte{0x9A, 0x0C, 0xEB, 0xB0, 0xF0, 0xFC, 0xB4, 0x3C}}, // {69D2CF90-FC33-4FB7-9A0C-EBB0F0FCB43C}
+	"Pictures":               {0x33E28130, 0x4E1E, 0x4676, [8]byte{0x83, 0x5A, 0x98, 0x39, 0x5C, 0x3B, 0xC3, 0xBB}}, // {33E28130-4E1E-4676-835A-98395C3BC3BB}
+	"PicturesLibrary":        {0xA990AE9F, 0xA03B, 0x4E80, [8]byte{0x94, 0xBC, 0x99, 0x12, 0xD7, 0x50, 0x41, 0x04}}, // {A990AE9F-A03B-4E80-94BC-9912D7504104}
+	"Playlists":              {0xDE92C1C7, 0x837F, 0x4F69, [8]byte{0xA3, 0xBB, 0x86, 0xE6, 0x31, 0x20, 0x4A, 0x23}}, // {DE92C1C7-837F-4F69-A3BB-86E631204A23}
+	"PrintersFolder":         {0x76FC4E2D, 0xD6AD, 0x4519, [8]byte{0xA6, 0x63, 0x37, 0xBD, 0x56, 0x06, 0x81, 0x85}}, // {76FC4E2D-D6AD-4519-A663-37BD56068185}
+	"PrintHood":              {0x9274BD8D, 0xCFD1, 0x41C3, [8]byte{0xB3, 0x5E, 0xB1, 0x3F, 0x55, 0xA7, 0x58, 0xF4}}, // {9274BD8D-CFD1-41C3-B35E-B13F55A758F4}
+	"Profile":                {0x5E6C858F, 0x0E22, 0x4760, [8]byte{0x9A, 0xFE, 0xEA, 0x33, 0x17, 0xB6, 0x71, 0x73}}, // {5E6C858F-0E22-4760-9AFE-EA3317B67173}
+	"ProgramData":            {0x62AB5D82, 0xFDC1, 0x4DC3, [8]byte{0xA9, 0xDD, 0x07, 0x0D, 0x1D, 0x49, 0x5D, 0x97}}, // {62AB5D82-FDC1-4DC3-A9DD-070D1D495D97}
+	"ProgramFiles":           {0x905E63B6, 0xC1BF, 0x494E, [8]byte{0xB2, 0x9C, 0x65, 0xB7, 0x32, 0xD3, 0xD2, 0x1A}}, // {905E63B6-C1BF-494E-B29C-65B732D3D21A}
+	"ProgramFilesCommon":     {0xF7F1ED05, 0x9F6D, 0x47A2, [8]byte{0xAA, 0xAE, 0x29, 0xD3, 0x17, 0xC6, 0xF0, 0x66}}, // {F7F1ED05-9F6D-47A2-AAAE-29D317C6F066}
+	"ProgramFilesCommonX64":  {0x6365D5A7, 0x0F0D, 0x45E5, [8]byte{0x87, 0xF6, 0x0D, 0xA5, 0x6B, 0x6A, 0x4F, 0x7D}}, // {6365D5A7-0F0D-45E5-87F6-0DA56B6A4F7D}
+	"ProgramFilesCommonX86":  {0xDE974D24, 0xD9C6, 0x4D3E, [8]byte{0xBF, 0x91, 0xF4, 0x45, 0x51, 0x20, 0xB9, 0x17}}, // {DE974D24-D9C6-4D3E-BF91-F4455120B917}
+	"ProgramFilesX64":        {0x6D809377, 0x6AF0, 0x444B, [8]byte{0x89, 0x57, 0xA3, 0x77, 0x3F, 0x02, 0x20, 0x0E}}, // {6D809377-6AF0-444B-8957-A3773F02200E}
+	"ProgramFilesX86":        {0x7C5A40EF, 0xA0FB, 0x4BFC, [8]byte{0x87, 0x4A, 0xC0, 0xF2, 0xE0, 0xB9, 0xFA, 0x8E}}, // {7C5A40EF-A0FB-4BFC-874A-C0F2E0B9FA8E}
+	"Programs":               {0xA77F5D77, 0x2E2B, 0x44C3, [8]byte{0xA6, 0xA2, 0xAB, 0xA6, 0x01, 0x05, 0x4A, 0x51}}, // {A77F5D77-2E2B-44C3-A6A2-ABA601054A51}
+	"Public":                 {0xDFDF76A2, 0xC82A, 0x4D63, [8]byte{0x90, 0x6A, 0x56, 0x44, 0xAC, 0x45, 0x73, 0x85}}, // {DFDF76A2-C82A-4D63-906A-5644AC457385}
+	"PublicDesktop":          {0xC4AA340D, 0xF20F, 0x4863, [8]byte{0xAF, 0xEF, 0xF8, 0x7E, 0xF2, 0xE6, 0xBA, 0x25}}, // {C4AA340D-F20F-4863-AFEF-F87EF2E6BA25}
+	"PublicDocuments":        {0xED4824AF, 0xDCE4, 0x45A8, [8]byte{0x81, 0xE2, 0xFC, 0x79, 0x65, 0x08, 0x36, 0x34}}, // {ED4824AF-DCE4-45A8-81E2-FC7965083634}
+	"PublicDownloads":        {0x3D644C9B, 0x1FB8, 0x4F30, [8]byte{0x9B, 0x45, 0xF6, 0x70, 0x23, 0x5F, 0x79, 0xC0}}, // {3D644C9B-1FB8-4F30-9B45-F670235F79C0}
+	"PublicGameTasks":        {0xDEBF2536, 0xE1A8, 0x4C59, [8]byte{0xB6, 0xA2, 0x41, 0x45, 0x86, 0x47, 0x6A, 0xEA}}, // {DEBF2536-E1A8-4C59-B6A2-414586476AEA}
+	"PublicLibraries":        {0x48DAF80B, 0xE6CF, 0x4F4E, [8]byte{0xB8, 0x00, 0x0E, 0x69, 0xD8, 0x4E, 0xE3, 0x84}}, // {48DAF80B-E6CF-4F4E-B800-0E69D84EE384}
+	"PublicMusic":            {0x3214FAB5, 0x9757, 0x4298, [8]byte{0xBB, 0x61, 0x92, 0xA9, 0xDE, 0xAA, 0x44, 0xFF}}, // {3214FAB5-9757-4298-BB61-92A9DEAA44FF}
+	"PublicPictures":         {0xB6EBFB86, 0x6907, 0x413C, [8]byte{0x9A, 0xF7, 0x4F, 0xC2, 0xAB, 0xF0, 0x7C, 0xC5}}, // {B6EBFB86-6907-413C-9AF7-4FC2ABF07CC5}
+	"PublicRingtones":        {0xE555AB60, 0x153B, 0x4D17, [8]byte{0x9F, 0x04, 0xA5, 0xFE, 0x99, 0xFC, 0x15, 0xEC}}, // {E555AB60-153B-4D17-9F04-A5FE99FC15EC}
+	"PublicUserTiles":        {0x0482AF6C, 0x08F1, 0x4C34, [8]byte{0x8C, 0x90, 0xE1, 0x7E, 0xC9, 0x8B, 0x1E, 0x17}}, // {0482AF6C-08F1-4C34-8C90-E17EC98B1E17}
+	"PublicVideos":           {0x2400183A, 0x6185, 0x49FB, [8]byte{0xA2, 0xD8, 0x4A, 0x39, 0x2A, 0x60, 0x2B, 0xA3}}, // {2400183A-6185-49FB-A2D8-4A392A602BA3}
+	"QuickLaunch":            {0x52A4F021, 0x7B75, 0x48A9, [8]byte{0x9F, 0x6B, 0x4B, 0x87, 0xA2, 0x10, 0xBC, 0x8F}}, // {52A4F021-7B75-48A9-9F6B-4B87A210BC8F}
+	"Recent":                 {0xAE50C081, 0xEBD2, 0x438A, [8]byte{0x86, 0x55, 0x8A, 0x09, 0x2E, 0x34, 0x98, 0x7A}}, // {AE50C081-EBD2-438A-8655-8A092E34987A}
+	"RecordedTVLibrary":      {0x1A6FDBA2, 0xF42D, 0x4358, [8]byte{0xA7, 0x98, 0xB7, 0x4D, 0x74, 0x59, 0x26, 0xC5}}, // {1A6FDBA2-F42D-4358-A798-B74D745926C5}
+	"RecycleBinFolder":       {0xB7534046, 0x3ECB, 0x4C18, [8]byte{0xBE, 0x4E, 0x64, 0xCD, 0x4C, 0xB7, 0xD6, 0xAC}}, // {B7534046-3ECB-4C18-BE4E-64CD4CB7D6AC}
+	"ResourceDir":            {0x8AD10C31, 0x2ADB, 0x4296, [8]byte{0xA8, 0xF7, 0xE4, 0x70, 0x12, 0x32, 0xC9, 0x72}}, // {8AD10C31-2ADB-4296-A8F7-E4701232C972}
+	"Ringtones":              {0xC870044B, 0xF49E, 0x4126, [8]byte{0xA9, 0xC3, 0xB5, 0x2A, 0x1F, 0xF4, 0x11, 0xE8}}, // {C870044B-F49E-4126-A9C3-B52A1FF411E8}
+	"RoamedTileImages":       {0xAAA8D5A5, 0xF1D6, 0x4259, [8]byte{0xBA, 0xA8, 0x78, 0xE7, 0xEF, 0x60, 0x83, 0x5E}}, // {AAA8D5A5-F1D6-4259-BAA8-78E7EF60835E}
+	"RoamingAppData":         {0x3EB685DB, 0x65F9, 0x4CF6, [8]byte{0xA0, 0x3A, 0xE3, 0xEF, 0x65, 0x72, 0x9F, 0x3D}}, // {3EB685DB-65F9-4CF6-A03A-E3EF65729F3D}
+	"RoamingTiles":           {0x00BCFC5A, 0xED94, 0x4E48, [8]byte{0x96, 0xA1, 0x3F, 0x62, 0x17, 0xF2, 0x19, 0x90}}, // {00BCFC5A-ED94-4E48-96A1-3F6217F21990}
+	"SampleMusic":            {0xB250C668, 0xF57D, 0x4EE1, [8]byte{0xA6, 0x3C, 0x29, 0x0E, 0xE7, 0xD1, 0xAA, 0x1F}}, // {B250C668-F57D-4EE1-A63C-290EE7D1AA1F}
+	"SamplePictures":         {0xC4900540, 0x2379, 0x4C75, [8]byte{0x84, 0x4B, 0x64, 0xE6, 0xFA, 0xF8, 0x71, 0x6B}}, // {C4900540-2379-4C75-844B-64E6FAF8716B}
+	"SamplePlaylists":        {0x15CA69B3, 0x30EE, 0x49C1, [8]byte{0xAC, 0xE1, 0x6B, 0x5E, 0xC3, 0x72, 0xAF, 0xB5}}, // {15CA69B3-30EE-49C1-ACE1-6B5EC372AFB5}
+	"SampleVideos":           {0x859EAD94, 0x2E85, 0x48AD, [8]byte{0xA7, 0x1A, 0x09, 0x69, 0xCB, 0x56, 0xA6, 0xCD}}, // {859EAD94-2E85-48AD-A71A-0969CB56A6CD}
+	"SavedGames":             {0x4C5C32FF, 0xBB9D, 0x43B0, [8]byte{0xB5, 0xB4, 0x2D, 0x72, 0xE5, 0x4E, 0xAA, 0xA4}}, // {4C5C32FF-BB9D-43B0-B5B4-2D72E54EAAA4}
+	"SavedPictures":          {0x3B193882, 0xD3AD, 0x4EAB, [8]byte{0x96, 0x5A, 0x69, 0x82, 0x9D, 0x1F, 0xB5, 0x9F}}, // {3B193882-D3AD-4EAB-965A-69829D1FB59F}
+	"SavedPicturesLibrary":   {0xE25B5812, 0xBE88, 0x4BD9, [8]byte{0x94, 0xB0, 0x29, 0x23, 0x34, 0x77, 0xB6, 0xC3}}, // {E25B5812-BE88-4BD9-94B0-29233477B6C3}
+	"SavedSearches":          {0x7D1D3A04, 0xDEBB, 0x4115, [8]byte{0x95, 0xCF, 0x2F, 0x29, 0xDA, 0x29, 0x20, 0xDA}}, // {7D1D3A04-DEBB-4115-95CF-2F29DA2920DA}
+	"Screenshots":            {0xB7BEDE81, 0xDF94, 0x4682, [8]byte{0xA7, 0xD8, 0x57, 0xA5, 0x26, 0x20, 0xB8, 0x6F}}, // {B7BEDE81-DF94-4682-A7D8-57A52620B86F}
+	"SEARCH_CSC":             {0xEE32E446, 0x31CA, 0x4ABA, [8]byte{0x81, 0x4F, 0xA5, 0xEB, 0xD2, 0xFD, 0x6D, 0x5E}}, // {EE32E446-31CA-4ABA-814F-A5EBD2FD6D5E}
+	"SEARCH_MAPI":            {0x98EC0E18, 0x2098, 0x4D44, [8]byte{0x86, 0x44, 0x66, 0x97, 0x93, 0x15, 0xA2, 0x81}}, // {98EC0E18-2098-4D44-8644-66979315A281}
+	"SearchHistory":          {0x0D4C3DB6, 0x03A3, 0x462F, [8]byte{0xA0, 0xE6, 0x08, 0x92, 0x4C, 0x41, 0xB5, 0xD4}}, // {0D4C3DB6-03A3-462F-A0E6-08924C41B5D4}
+	"SearchHome":             {0x190337D1, 0xB8CA, 0x4121, [8]byte{0xA6, 0x39, 0x6D, 0x47, 0x2D, 0x16, 0x97, 0x2A}}, // {190337D1-B8CA-4121-A639-6D472D16972A}
+	"SearchTemplates":        {0x7E636BFE, 0xDFA9, 0x4D5E, [8]byte{0xB4, 0x56, 0xD7, 0xB3, 0x98, 0x51, 0xD8, 0xA9}}, // {7E636BFE-DFA9-4D5E-B456-D7B39851D8A9}
+	"SendTo":                 {0x8983036C, 0x27C0, 0x404B, [8]byte{0x8F, 0x08, 0x10, 0x2D, 0x10, 0xDC, 0xFD, 0x74}}, // {8983036C-27C0-404B-8F08-102D10DCFD74}
+	"SidebarDefaultParts":    {0x7B396E54, 0x9EC5, 0x4300, [8]byte{0xBE, 0x0A, 0x24, 0x82, 0xEB, 0xAE, 0x1A, 0x26}}, // {7B396E54-9EC5-4300-BE0A-2482EBAE1A26}
+	"SidebarParts":           {0xA75D362E, 0x50FC, 0x4FB7, [8]byte{0xAC, 0x2C, 0xA8, 0xBE, 0xAA, 0x31, 0x44, 0x93}}, // {A75D362E-50FC-4FB7-AC2C-A8BEAA314493}
+	"SkyDrive":               {0xA52BBA46, 0xE9E1, 0x435F, [8]byte{0xB3, 0xD9, 0x28, 0xDA, 0xA6, 0x48, 0xC0, 0xF6}}, // {A52BBA46-E9E1-435F-B3D9-28DAA648C0F6}
+	"SkyDriveCameraRoll":     {0x767E6811, 0x49CB, 0x4273, [8]byte{0x87, 0xC2, 0x20, 0xF3, 0x55, 0xE1, 0x08, 0x5B}}, // {767E6811-49CB-4273-87C2-20F355E1085B}
+	"SkyDriveDocuments":      {0x24D89E24, 0x2F19, 0x4534, [8]byte{0x9D, 0xDE, 0x6A, 0x66, 0x71, 0xFB, 0xB8, 0xFE}}, // {24D89E24-2F19-4534-9DDE-6A6671FBB8FE}
+	"SkyDriveMusic":          {0xC3F2459E, 0x80D6, 0x45DC, [8]byte{0xBF, 0xEF, 0x1F, 0x76, 0x9F, 0x2B, 0xE7, 0x30}}, // {C3F2459E-80D6-45DC-BFEF-1F769F2BE730}
+	"SkyDrivePictures":       {0x339719B5, 0x8C47, 0x4894, [8]byte{0x94, 0xC2, 0xD8, 0xF7, 0x7A, 0xDD, 0x44, 0xA6}}, // {339719B5-8C47-4894-94C2-D8F77ADD44A6}
+	"StartMenu":              {0x625B53C3, 0xAB48, 0x4EC1, [8]byte{0xBA, 0x1F, 0xA1, 0xEF, 0x41, 0x46, 0xFC, 0x19}}, // {625B53C3-AB48-4EC1-BA1F-A1EF4146FC19}
+	"Startup":                {0xB97D20BB, 0xF46A, 0x4C97, [8]byte{0xBA, 0x10, 0x5E, 0x36, 0x08, 0x43, 0x08, 0x54}}, // {B97D20BB-F46A-4C97-BA10-5E3608430854}
+	"SyncManagerFolder":      {0x43668BF8, 0xC14E, 0x49B2, [8]byte{0x97, 0xC9, 0x74, 0x77, 0x84, 0xD7, 0x84, 0xB7}}, // {43668BF8-C14E-49B2-97C9-747784D784B7}
+	"SyncResultsFolder":      {0x289A9A43, 0xBE44, 0x4057, [8]byte{0xA4, 0x1B, 0x58, 0x7A, 0x76, 0xD7, 0xE7, 0xF9}}, // {289A9A43-BE44-4057-A41B-587A76D7E7F9}
+	"SyncSetupFolder":        {0x0F214138, 0xB1D3, 0x4A90, [8]byte{0xBB, 0xA9, 0x27, 0xCB, 0xC0, 0xC5, 0x38, 0x9A}}, // {0F214138-B1D3-4A90-BBA9-27CBC0C5389A}
+	"System":                 {0x1AC14E77, 0x02E7, 0x4E5D, [8]byte{0xB7, 0x44, 0x2E, 0xB1, 0xAE, 0x51, 0x98, 0xB7}}, // {1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}
+	"SystemX86":              {0xD65231B0, 0xB2F1, 0x4857, [8]byte{0xA4, 0xCE, 0xA8, 0xE7, 0xC6, 0xEA, 0x7D, 0x27}}, // {D65231B0-B2F1-4857-A4CE-A8E7C6EA7D27}
+	"Templates":              {0xA63293E8, 0x664E, 0x48DB, [8]byte{0xA0, 0x79, 0xDF, 0x75, 0x9E, 0x05, 0x09, 0xF7}}, // {A63293E8-664E-48DB-A079-DF759E0509F7}
+	"UserPinned":             {0x9E3995AB, 0x1F9C, 0x4F13, [8]byte{0xB8, 0x27, 0x48, 0xB2, 0x4B, 0x6C, 0x71, 0x74}}, // {9E3995AB-1F9C-4F13-B827-48B24B6C7174}
+	"UserProfiles":           {0x0762D272, 0xC50A, 0x4BB0, [8]byte{0xA3, 0x82, 0x69, 0x7D, 0xCD, 0x72, 0x9B, 0x80}}, // {0762D272-C50A-4BB0-A382-697DCD729B80}
+	"UserProgramFiles":       {0x5CD7AEE2, 0x2219, 0x4A67, [8]byte{0xB8, 0x5D, 0x6C, 0x9C, 0xE1, 0x56, 0x60, 0xCB}}, // {5CD7AEE2-2219-4A67-B85D-6C9CE15660CB}
+	"UserProgramFilesCommon": {0xBCBD3057, 0xCA5C, 0x4622, [8]byte{0xB4, 0x2D, 0xBC, 0x56, 0xDB, 0x0A, 0xE5, 0x16}}, // {BCBD3057-CA5C-4622-B42D-BC56DB0AE516}
+	"UsersFiles":             {0xF3CE0F7C, 0x4901, 0x4ACC, [8]byte{0x86, 0x48, 0xD5, 0xD4, 0x4B, 0x04, 0xEF, 0x8F}}, // {F3CE0F7C-4901-4ACC-8648-D5D44B04EF8F}
+	"UsersLibraries":         {0xA302545D, 0xDEFF, 0x464B, [8]byte{0xAB, 0xE8, 0x61, 0xC8, 0x64, 0x8D, 0x93, 0x9B}}, // {A302545D-DEFF-464B-ABE8-61C8648D939B}
+	"Videos":                 {0x18989B1D, 0x99B5, 0x455B, [8]byte{0x84, 0x1C, 0xAB, 0x7C, 0x74, 0xE4, 0xDD, 0xFC}}, // {18989B1D-99B5-455B-841C-AB7C74E4DDFC}
+	"VideosLibrary":          {0x491E922F, 0x5643, 0x4AF4, [8]byte{0xA7, 0xEB, 0x4E, 0x7A, 0x13, 0x8D, 0x81, 0x74}}, // {491E922F-5643-4AF4-A7EB-4E7A138D8174}
+	"Windows":                {0xF38BF404, 0x1D43, 0x42F2, [8]byte{0x93, 0x05, 0x67, 0xDE, 0x0B, 0x28, 0xFC, 0x23}}, // {F38BF404-1D43-42F2-9305-67DE0B28FC23}
+}
+
+// knownfolderAliases are FOLDERID_ constants (without the prefix) with the same
+// KNOWNFOLDERID as a folder in knownfolders.
+var knownfolderAliases = []struct {
+	Name   string
+	Folder string
+}{
+	{"OneDrive", "SkyDrive"},
+}
+
+// knownfolderDescriptions are the Names and Categories of the folders in
+// knownfolders, as described in the registry by Windows 10.
+var knownfolderDescriptions = map[string]struct {
+	Name     string
+	Category uint32
+}{
+	"AccountPictures":        {"AccountPictures", KF_CATEGORY_PERUSER},
+	"AddNewPrograms":         {"AddNewProgramsFolder", KF_CATEGORY_VIRTUAL},
+	"AdminTools":             {"Administrative Tools", KF_CATEGORY_PERUSER},
+	"AppDataDesktop":         {"AppDataDesktop", KF_CATEGORY_PERUSER},
+	"AppDataDocuments":       {"AppDataDocuments", KF_CATEGORY_PERUSER},
+	"AppDataFavorites":       {"AppDataFavorites", KF_CATEGORY_PERUSER},
+	"AppDataProgramData":     {"AppDataProgramData", KF_CATEGORY_PERUSER},
+	"ApplicationShortcuts":   {"Application Shortcuts", KF_CATEGORY_PERUSER},
+	"AppsFolder":             {"AppsFolder", KF_CATEGORY_VIRTUAL},
+	"AppUpdates":             {"AppUpdatesFolder", KF_CATEGORY_VIRTUAL},
+	"CameraRoll":             {"Camera Roll", KF_CATEGORY_PERUSER},
+	"CDBurning":              {"CD Burning", KF_CATEGORY_PERUSER},
+	"ChangeRemovePrograms":   {"ChangeRemoveProgramsFolder", KF_CATEGORY_VIRTUAL},
+	"CommonAdminTools":       {"Common Administrative Tools", KF_CATEGORY_COMMON},
+	"CommonOEMLinks":         {"OEM Links", KF_CATEGORY_COMMON},
+	"CommonPrograms":         {"Common Programs", KF_CATEGORY_COMMON},
+	"CommonStartMenu":        {"Common Start Menu", KF_CATEGORY_COMMON},
+	"CommonStartup":          {"Common Startup", KF_CATEGORY_COMMON},
+	"CommonTemplates":        {"Common Templates", KF_CATEGORY_COMMON},
+	"ComputerFolder":         {"MyComputerFolder", KF_CATEGORY_VIRTUAL},
+	"ConflictFolder":         {"ConflictFolder", KF_CATEGORY_VIRTUAL},
+	"ConnectionsFolder":      {"ConnectionsFolder", KF_CATEGORY_VIRTUAL},
+	"Contacts":               {"Contacts", KF_CATEGORY_PERUSER},
+	"ControlPanelFolder":     {"ControlPanelFolder", KF_CATEGORY_VIRTUAL},
+	"Cookies":                {"Cookies", KF_CATEGORY_PERUSER},
+	"Desktop":                {"Desktop", KF_CATEGORY_PERUSER},
+	"DeviceMetadataStore":    {"Device Metadata Store", KF_CATEGORY_COMMON},
+	"Documents":              {"Personal", KF_CATEGORY_PERUSER},
+	"DocumentsLibrary":       {"DocumentsLibrary", KF_CATEGORY_PERUSER},
+	"Downloads":              {"Downloads", KF_CATEGORY_PERUSER},
+	"Favorites":              {"Favorites", KF_CATEGORY_PERUSER},
+	"Fonts":                  {"Fonts", KF_CATEGORY_FIXED},
+	"Games":                  {"Games", KF_CATEGORY_VIRTUAL},
+	"GameTasks":              {"GameTasks", KF_CATEGORY_PERUSER},
+	"History":                {"History", KF_CATEGORY_PERUSER},
+	"HomeGroup":              {"HomeGroupFolder", KF_CATEGORY_VIRTUAL},
+	"HomeGroupCurrentUser":   {"HomeGroupCurrentUserFolder", KF_CATEGORY_VIRTUAL},
+	"ImplicitAppShortcuts":   {"ImplicitAppShortcuts", KF_CATEGORY_PERUSER},
+	"InternetCache":          {"Cache", KF_CATEGORY_PERUSER},
+	"InternetFolder":         {"InternetFolder", KF_CATEGORY_VIRTUAL},
+	"Libraries":              {"Libraries", KF_CATEGORY_PERUSER},
+	"Links":                  {"Links", KF_CATEGORY_PERUSER},
+	"LocalAppData":           {"Local AppData", KF_CATEGORY_PERUSER},
+	"LocalAppDataLow":        {"LocalAppDataLow", KF_CATEGORY_PERUSER},
+	"LocalDocuments":         {"Local Documents", KF_CATEGORY_PERUSER},
+	"LocalDownloads":         {"Local Downloads", KF_CATEGORY_PERUSER},
+	"LocalizedResourcesDir":  {"LocalizedResourcesDir", KF_CATEGORY_FIXED},
+	"LocalMusic":             {"Local Music", KF_CATEGORY_PERUSER},
+	"LocalPictures":          {"Local Pictures", KF_CATEGORY_PERUSER},
+	"LocalVideos":            {"Local Videos", KF_CATEGORY_PERUSER},
+	"Music":                  {"My Music", KF_CATEGORY_PERUSER},
+	"MusicLibrary":           {"MusicLibrary", KF_CATEGORY_PERUSER},
+	"NetHood":                {"NetHood", KF_CATEGORY_PERUSER},
+	"NetworkFolder":          {"NetworkPlacesFolder", KF_CATEGORY_VIRTUAL},
+	"Objects3D":              {"3D Objects", KF_CATEGORY_PERUSER},
+	"OriginalImages":         {"Original Images", KF_CATEGORY_PERUSER},
+	"PhotoAlbums":            {"PhotoAlbums", KF_CATEGORY_PERUSER},
+	"Pictures":               {"My Pictures", KF_CATEGORY_PERUSER},
+	"PicturesLibrary":        {"PicturesLibrary", KF_CATEGORY_PERUSER},
+	"Playlists":              {"Playlists", KF_CATEGORY_PERUSER},
+	"PrintersFolder":         {"PrintersFolder", KF_CATEGORY_VIRTUAL},
+	"PrintHood":              {"PrintHood", KF_CATEGORY_PERUSER},
+	"Profile":                {"Profile", KF_CATEGORY_FIXED},
+	"ProgramData":            {"Common AppData", KF_CATEGORY_FIXED},
+	"ProgramFiles":           {"ProgramFiles", KF_CATEGORY_FIXED},
+	"ProgramFilesCommon":     {"ProgramFilesCommon", KF_CATEGORY_FIXED},
+	"ProgramFilesCommonX64":  {"ProgramFilesCommonX64", KF_CATEGORY_FIXED},
+	"ProgramFilesCommonX86":  {"ProgramFilesCommonX86", KF_CATEGORY_FIXED},
+	"ProgramFilesX64":        {"ProgramFilesX64", KF_CATEGORY_FIXED},
+	"ProgramFilesX86":        {"ProgramFilesX86", KF_CATEGORY_FIXED},
+	"Programs":               {"Programs", KF_CATEGORY_PERUSER},
+	"Public":                 {"Public", KF_CATEGORY_FIXED},
+	"PublicDesktop":          {"Common Desktop", KF_CATEGORY_COMMON},
+	"PublicDocuments":        {"Common Documents", KF_CATEGORY_COMMON},
+	"PublicDownloads":        {"CommonDownloads", KF_CATEGORY_COMMON},
+	"PublicGameTasks":        {"PublicGameTasks", KF_CATEGORY_COMMON},
+	"PublicLibraries":        {"PublicLibraries", KF_CATEGORY_COMMON},
+	"PublicMusic":            {"CommonMusic", KF_CATEGORY_COMMON},
+	"PublicPictures":         {"CommonPictures", KF_CATEGORY_COMMON},
+	"PublicRingtones":        {"CommonRingtones", KF_CATEGORY_COMMON},
+	"PublicUserTiles":        {"Public Account Pictures", KF_CATEGORY_COMMON},
+	"PublicVideos":           {"CommonVideo", KF_CATEGORY_COMMON},
+	"QuickLaunch":            {"Quick Launch", KF_CATEGORY_PERUSER},
+	"Recent":                 {"Recent", KF_CATEGORY_PERUSER},
+	"RecordedTVLibrary":      {"RecordedTVLibrary", KF_CATEGORY_COMMON},
+	"RecycleBinFolder":       {"RecycleBinFolder", KF_CATEGORY_VIRTUAL},
+	"ResourceDir":            {"ResourceDir", KF_CATEGORY_FIXED},
+	"Ringtones":              {"Ringtones", KF_CATEGORY_PERUSER},
+	"RoamedTileImages":       {"Roamed Tile Images", KF_CATEGORY_PERUSER},
+	"RoamingAppData":         {"AppData", KF_CATEGORY_PERUSER},
+	"RoamingTiles":           {"Roaming Tiles", KF_CATEGORY_PERUSER},
+	"SampleMusic":            {"SampleMusic", KF_CATEGORY_COMMON},
+	"SamplePictures":         {"SamplePictures", KF_CATEGORY_COMMON},
+	"SamplePlaylists":        {"SamplePlaylists", KF_CATEGORY_COMMON},
+	"SampleVideos":           {"SampleVideos", KF_CATEGORY_COMMON},
+	"SavedGames":             {"SavedGames", KF_CATEGORY_PERUSER},
+	"SavedPictures":          {"SavedPictures", KF_CATEGORY_PERUSER},
+	"SavedPicturesLibrary":   {"SavedPicturesLibrary", KF_CATEGORY_PERUSER},
+	"SavedSearches":          {"Searches", KF_CATEGORY_PERUSER},
+	"Screenshots":            {"Screenshots", KF_CATEGORY_PERUSER},
+	"SEARCH_CSC":             {"CSCFolder", KF_CATEGORY_VIRTUAL},
+	"SEARCH_MAPI":            {"MAPIFolder", KF_CATEGORY_VIRTUAL},
+	"SearchHistory":          {"SearchHistoryFolder", KF_CATEGORY_PERUSER},
+	"SearchHome":             {"SearchHomeFolder", KF_CATEGORY_VIRTUAL},
+	"SearchTemplates":        {"SearchTemplatesFolder", KF_CATEGORY_PERUSER},
+	"SendTo":                 {"SendTo", KF_CATEGORY_PERUSER},
+	"SidebarDefaultParts":    {"Default Gadgets", KF_CATEGORY_COMMON},
+	"SidebarParts":           {"Gadgets", KF_CATEGORY_PERUSER},
+	"SkyDrive":               {"OneDrive", KF_CATEGORY_PERUSER},
+	"SkyDriveCameraRoll":     {"OneDriveCameraRoll", KF_CATEGORY_PERUSER},
+	"SkyDriveDocuments":      {"OneDriveDocuments", KF_CATEGORY_PERUSER},
+	"SkyDriveMusic":          {"OneDriveMusic", KF_CATEGORY_PERUSER},
+	"SkyDrivePictures":       {"OneDrivePictures", KF_CATEGORY_PERUSER},
+	"StartMenu":              {"Start Menu", KF_CATEGORY_PERUSER},
+	"Startup":                {"Startup", KF_CATEGORY_PERUSER},
+	"SyncManagerFolder":      {"SyncCenterFolder", KF_CATEGORY_VIRTUAL},
+	"SyncResultsFolder":      {"SyncResultsFolder", KF_CATEGORY_VIRTUAL},
+	"SyncSetupFolder":        {"SyncSetupFolder", KF_CATEGORY_VIRTUAL},
+	"System":                 {"System", KF_CATEGORY_FIXED},
+	"SystemX86":              {"SystemX86", KF_CATEGORY_FIXED},
+	"Templates":              {"Templates", KF_CATEGORY_PERUSER},
+	"UserPinned":             {"User Pinned", KF_CATEGORY_PERUSER},
+	"UserProfiles":           {"UserProfiles", KF_CATEGORY_FIXED},
+	"UserProgramFiles":       {"UserProgramFiles", KF_CATEGORY_PERUSER},
+	"UserProgramFilesCommon": {"UserProgramFilesCommon", KF_CATEGORY_PERUSER},
+	"UsersFiles":             {"UsersFilesFolder", KF_CATEGORY_VIRTUAL},
+	"UsersLibraries":         {"UsersLibrariesFolder", KF_CATEGORY_VIRTUAL},
+	"Videos":                 {"My Video", KF_CATEGORY_PERUSER},
+	"VideosLibrary":          {"VideosLibrary", KF_CATEGORY_PERUSER},
+	"Windows":                {"Windows", KF_CATEGORY_FIXED},
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// knownFolderURLPrefix is the prefix of library location URLs which refer to
+// a known folder by KNOWNFOLDERID, e.g.
+// knownfolder:{FDD39AD0-238F-46AF-ADB4-6C85480369C7}.
+const knownFolderURLPrefix = "knownfolder:"
+
+// Library is a Windows Library definition, read from a .library-ms file,
+// see
+// https://docs.microsoft.com/en-us/windows/desktop/shell/library-schema-entry
+// Only the locations included in the library are interpreted; the rest of
+// the file is written back as it was read.
+type Library struct {
+	doc  *xmlNode
+	root *xmlNode
+}
+
+// LibraryLocation is a location included in a Library.
+type LibraryLocation struct {
+	// URL is a file system path, or a known folder URL.
+	URL                 string
+	DefaultSave         bool
+	DefaultNonOwnerSave bool
+	node                *xmlNode
+}
+
+// Name returns the location as it is given on the command line, i.e. a
+// known folder URL as {Folder}, or otherwise the URL itself.
+func (l LibraryLocation) Name() string {
+	if strings.HasPrefix(strings.ToLower(l.URL), knownFolderURLPrefix) {
+		if folder, ok := folderByGUID(l.URL[len(knownFolderURLPrefix):]); ok {
+			return "{" + folder + "}"
+		}
+	}
+	return l.URL
+}
+
+// ParseLibrary reads a .library-ms file.
+func ParseLibrary(r io.Reader) (*Library, error) {
+	doc, err := parseXML(r)
+	if err != nil {
+		return nil, err
+	}
+	root := doc.element("libraryDescription")
+	if root == nil {
+		return nil, fmt.Errorf("Not a library description: no <libraryDescription> element")
+	}
+	return &Library{doc: doc, root: root}, nil
+}
+
+// LoadLibrary reads the .library-ms file at the given path.
+func LoadLibrary(file string) (*Library, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	library, err := ParseLibrary(f)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse library %v:\n%v", file, err)
+	}
+	return library, nil
+}
+
+// Save writes the library back to the given path, replacing the file only
+// once it has been written in full.
+func (l *Library) Save(file string) error {
+	var b bytes.Buffer
+	if err := l.Write(&b); err != nil {
+		return err
+	}
+	return replaceFile(file, b.Bytes())
+}
+
+// Write writes the library in .library-ms format.
+func (l *Library) Write(w io.Writer) error {
+	return writeXML(w, l.doc)
+}
+
+// Locations returns the locations included in the library, in order.
+func (l *Library) Locations() []LibraryLocation {
+	locations := []LibraryLocation{}
+	list := l.root.element("searchConnectorDescriptionList")
+	if list == nil {
+		return locations
+	}
+	for _, d := range list.elements("searchConnectorDescription") {
+		location := LibraryLocation{
+			DefaultSave:         d.childText("isDefaultSaveLocation") == "true",
+			DefaultNonOwnerSave: d.childText("isDefaultNonOwnerSaveLocation") == "true",
+			node:                d,
+		}
+		if simple := d.element("simpleLocation"); simple != nil {
+			location.URL = simple.childText("url")
+		}
+		locations = append(locations, location)
+	}
+	return locations
+}
+
+// LibraryURL returns the library location URL for path, which is either a
+// file system path, a known folder URL, or a known folder given as {Folder}.
+func LibraryURL(path string) (string, error) {
+	if strings.HasPrefix(path, "{") && strings.HasSuffix(path, "}") {
+		folder, ok := resolveFolder(path[1 : len(path)-1])
+		if !ok {
+			return "", UnknownFolderError(path[1 : len(path)-1])
+		}
+		return knownFolderURLPrefix + knownfolders[folder].String(), nil
+	}
+	return path, nil
+}
+
+// sameLibraryURL reports whether a and b refer to the same location.
+func sameLibraryURL(a, b string) bool {
+	knownA := strings.HasPrefix(strings.ToLower(a), knownFolderURLPrefix)
+	knownB := strings.HasPrefix(strings.ToLower(b), knownFolderURLPrefix)
+	if knownA || knownB {
+		return knownA && knownB && strings.EqualFold(strings.Trim(a[len(knownFolderURLPrefix):], "{}"), strings.Trim(b[len(knownFolderURLPrefix):], "{}"))
+	}
+	return strings.EqualFold(normalizeWindowsPath(a), normalizeWindowsPath(b))
+}
+
+func (l *Library) find(url string) (LibraryLocation, bool) {
+	for _, location := range l.Locations() {
+		if sameLibraryURL(location.URL, url) {
+			return location, true
+		}
+	}
+	return LibraryLocation{}, false
+}
+
+// AddLocation includes url in the library, after its existing locations.
+func (l *Library) AddLocation(url string) error {
+	if _, exists := l.find(url); exists {
+		return fmt.Errorf("%v is already included in the library", LibraryLocation{URL: url}.Name())
+	}
+	list := l.root.element("searchConnectorDescriptionList")
+	if list == nil {
+		list = newXMLElement("searchConnectorDescriptionList")
+		l.root.appendElement(list)
+	}
+	description := newXMLElement("searchConnectorDescription")
+	list.appendElement(description)
+	description.appendElement(newXMLTextElement("isSupported", "false"))
+	simple := newXMLElement("simpleLocation")
+	description.appendElement(simple)
+	simple.appendElement(newXMLTextElement("url", url))
+	return nil
+}
+
+// RemoveLocation removes url from the library.
+func (l *Library) RemoveLocation(url string) error {
+	location, exists := l.find(url)
+	if !exists {
+		return fmt.Errorf("%v is not included in the library", LibraryLocation{URL: url}.Name())
+	}
+	l.root.element("searchConnectorDescriptionList").removeElement(location.node)
+	return nil
+}
+
+// searchConnectorElements are the child elements of a
+// searchConnectorDescription, in the order the schema requires.
+var searchConnectorElements = []string{
+	"description",
+	"isDefaultSaveLocation",
+	"isDefaultNonOwnerSaveLocation",
+	"isSearchOnlyItem",
+	"isSupported",
+	"simpleLocation",
+}
+
+// SetDefaultSaveLocation makes url, which must be included in the library,
+// the location that files saved to the library are saved in, for its owner,
+// or with nonOwner, for other users.
+func (l *Library) SetDefaultSaveLocation(url string, nonOwner bool) error {
+	target, exists := l.find(url)
+	if !exists {
+		return fmt.Errorf("%v is not included in the library", LibraryLocation{URL: url}.Name())
+	}
+	flag := "isDefaultSaveLocation"
+	if nonOwner {
+		flag = "isDefaultNonOwnerSaveLocation"
+	}
+	for _, location := range l.Locations() {
+		if location.node == target.node {
+			continue
+		}
+		for _, e := range location.node.elements(flag) {
+			location.node.removeElement(e)
+		}
+	}
+	if e := target.node.element(flag); e != nil {
+		e.setText("true")
+		return nil
+	}
+	e := newXMLTextElement(flag, "true")
+	// insert the flag before the first element which the schema says must
+	// follow it
+	following := false
+	for _, name := range searchConnectorElements {
+		if name == flag {
+			following = true
+			continue
+		}
+		if !following {
+			continue
+		}
+		if before := target.node.element(name); before != nil {
+			target.node.insertElementBefore(e, before)
+			return nil
+		}
+	}
+	target.node.appendElement(e)
+	return nil
+}
+
+// WriteLibraryLocations writes the locations of library to w as a table.
+func WriteLibraryLocations(w io.Writer, library *Library) error {
+	t := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(t, "LOCATION\tDEFAULT SAVE\tDEFAULT NON-OWNER SAVE")
+	for _, location := range library.Locations() {
+		fmt.Fprintf(t, "%v\t%v\t%v\n", location.Name(), yesNo(location.DefaultSave), yesNo(location.DefaultNonOwnerSave))
+	}
+	return t.Flush()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
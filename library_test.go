@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testLibrary = utf8BOM + `<?xml version="1.0" encoding="UTF-8"?>
+<libraryDescription xmlns="http://schemas.microsoft.com/windows/2009/library">
+  <name>@shell32.dll,-34575</name>
+  <isLibraryPinned>true</isLibraryPinned>
+  <iconReference>imageres.dll,-1002</iconReference>
+  <searchConnectorDescriptionList>
+    <searchConnectorDescription publisher="Microsoft" product="Windows">
+      <description>@shell32.dll,-34577</description>
+      <isDefaultSaveLocation>true</isDefaultSaveLocation>
+      <isDefaultNonOwnerSaveLocation>true</isDefaultNonOwnerSaveLocation>
+      <isSupported>false</isSupported>
+      <simpleLocation>
+        <url>knownfolder:{FDD39AD0-238F-46AF-ADB4-6C85480369C7}</url>
+        <serialized>MBAAAEAFCAAA</serialized>
+      </simpleLocation>
+    </searchConnectorDescription>
+    <searchConnectorDescription>
+      <isSupported>false</isSupported>
+      <simpleLocation>
+        <url>D:\Shared Docs</url>
+      </simpleLocation>
+    </searchConnectorDescription>
+  </searchConnectorDescriptionList>
+</libraryDescription>
+`
+
+// mustParseLibrary returns the library of document, failing the test if it
+// can't be parsed.
+func mustParseLibrary(t *testing.T, document string) *Library {
+	t.Helper()
+	library, err := ParseLibrary(strings.NewReader(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return library
+}
+
+// libraryDocument returns library in .library-ms format.
+func libraryDocument(t *testing.T, library *Library) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := library.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestLibraryLocations(t *testing.T) {
+	library := mustParseLibrary(t, testLibrary)
+	var got []LibraryLocation
+	for _, location := range library.Locations() {
+		location.node = nil
+		got = append(got, location)
+	}
+	want := []LibraryLocation{
+		{URL: "knownfolder:{FDD39AD0-238F-46AF-ADB4-6C85480369C7}", DefaultSave: true, DefaultNonOwnerSave: true},
+		{URL: `D:\Shared Docs`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Locations are %+v, want %+v", got, want)
+	}
+	if got := libraryDocument(t, library); got != testLibrary {
+		t.Errorf("Library was written back as\n%v", got)
+	}
+	var b bytes.Buffer
+	if err := WriteLibraryLocations(&b, library); err != nil {
+		t.Fatal(err)
+	}
+	table := "" +
+		"LOCATION        DEFAULT SAVE  DEFAULT NON-OWNER SAVE\n" +
+		"{Documents}     yes           yes\n" +
+		"D:\\Shared Docs  no            no\n"
+	if b.String() != table {
+		t.Errorf("Table is\n%v\nwant\n%v", b.String(), table)
+	}
+}
+
+func TestLibraryEdit(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Edit func(library *Library) error
+		Want string
+	}{
+		{
+			Name: "add",
+			Edit: func(library *Library) error { return library.AddLocation(`E:\More`) },
+			Want: strings.Replace(testLibrary, "    </searchConnectorDescription>\n  </searchConnectorDescriptionList>", `    </searchConnectorDescription>
+    <searchConnectorDescription>
+      <isSupported>false</isSupported>
+      <simpleLocation>
+        <url>E:\More</url>
+      </simpleLocation>
+    </searchConnectorDescription>
+  </searchConnectorDescriptionList>`, 1),
+		},
+		{
+			Name: "remove",
+			Edit: func(library *Library) error { return library.RemoveLocation(`d:/shared docs/`) },
+			Want: strings.Replace(testLibrary, `
+    <searchConnectorDescription>
+      <isSupported>false</isSupported>
+      <simpleLocation>
+        <url>D:\Shared Docs</url>
+      </simpleLocation>
+    </searchConnectorDescription>`, "", 1),
+		},
+		{
+			Name: "remove known folder",
+			Edit: func(library *Library) error {
+				return library.RemoveLocation("knownfolder:fdd39ad0-238f-46af-adb4-6c85480369c7")
+			},
+			Want: strings.Replace(testLibrary, `
+    <searchConnectorDescription publisher="Microsoft" product="Windows">
+      <description>@shell32.dll,-34577</description>
+      <isDefaultSaveLocation>true</isDefaultSaveLocation>
+      <isDefaultNonOwnerSaveLocation>true</isDefaultNonOwnerSaveLocation>
+      <isSupported>false</isSupported>
+      <simpleLocation>
+        <url>knownfolder:{FDD39AD0-238F-46AF-ADB4-6C85480369C7}</url>
+        <serialized>MBAAAEAFCAAA</serialized>
+      </simpleLocation>
+    </searchConnectorDescription>`, "", 1),
+		},
+		{
+			Name: "set default save location",
+			Edit: func(library *Library) error { return library.SetDefaultSaveLocation(`D:\Shared Docs`, false) },
+			Want: strings.Replace(strings.Replace(testLibrary, `
+      <isDefaultSaveLocation>true</isDefaultSaveLocation>`, "", 1), `    <searchConnectorDescription>
+      <isSupported>false</isSupported>`, `    <searchConnectorDescription>
+      <isDefaultSaveLocation>true</isDefaultSaveLocation>
+      <isSupported>false</isSupported>`, 1),
+		},
+		{
+			Name: "set default non-owner save location",
+			Edit: func(library *Library) error { return library.SetDefaultSaveLocation(`D:\Shared Docs`, true) },
+			Want: strings.Replace(strings.Replace(testLibrary, `
+      <isDefaultNonOwnerSaveLocation>true</isDefaultNonOwnerSaveLocation>`, "", 1), `    <searchConnectorDescription>
+      <isSupported>false</isSupported>`, `    <searchConnectorDescription>
+      <isDefaultNonOwnerSaveLocation>true</isDefaultNonOwnerSaveLocation>
+      <isSupported>false</isSupported>`, 1),
+		},
+		{
+			Name: "set existing default save location",
+			Edit: func(library *Library) error {
+				return library.SetDefaultSaveLocation("knownfolder:{FDD39AD0-238F-46AF-ADB4-6C85480369C7}", false)
+			},
+			Want: testLibrary,
+		},
+	} {
+		library := mustParseLibrary(t, testLibrary)
+		if err := test.Edit(library); err != nil {
+			t.Errorf("%v: %v", test.Name, err)
+			continue
+		}
+		if got := libraryDocument(t, library); got != test.Want {
+			t.Errorf("%v: library is\n%v\nwant\n%v", test.Name, got, test.Want)
+		}
+	}
+}
+
+func TestLibraryEditErrors(t *testing.T) {
+	library := mustParseLibrary(t, testLibrary)
+	documents, err := LibraryURL("{Personal}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		Err  error
+		Want string
+	}{
+		{library.AddLocation(documents), "{Documents} is already included in the library"},
+		{library.AddLocation(`D:\Shared Docs\.`), `D:\Shared Docs\. is already included in the library`},
+		{library.RemoveLocation(`D:\Other`), `D:\Other is not included in the library`},
+		{library.SetDefaultSaveLocation(`D:\Other`, false), `D:\Other is not included in the library`},
+	} {
+		if test.Err == nil || test.Err.Error() != test.Want {
+			t.Errorf("Returned %v, want %v", test.Err, test.Want)
+		}
+	}
+	if got := libraryDocument(t, library); got != testLibrary {
+		t.Errorf("Failed edits changed the library to\n%v", got)
+	}
+	if _, err := ParseLibrary(strings.NewReader("<searchConnectorDescription />")); err == nil {
+		t.Error("Parsed a document which isn't a library")
+	}
+}
+
+func TestLibraryAddToEmpty(t *testing.T) {
+	library := mustParseLibrary(t, "<libraryDescription>\n\t<name>Empty</name>\n</libraryDescription>")
+	if err := library.AddLocation(`C:\x`); err != nil {
+		t.Fatal(err)
+	}
+	want := "<libraryDescription>\n\t<name>Empty</name>\n\t<searchConnectorDescriptionList>\n\t\t<searchConnectorDescription>\n\t\t\t<isSupported>false</isSupported>\n\t\t\t<simpleLocation>\n\t\t\t\t<url>C:\\x</url>\n\t\t\t</simpleLocation>\n\t\t</searchConnectorDescription>\n\t</searchConnectorDescriptionList>\n</libraryDescription>"
+	if got := libraryDocument(t, library); got != want {
+		t.Errorf("Library is\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestLibraryURL(t *testing.T) {
+	for _, test := range []struct {
+		Path string
+		Want string
+		Err  string
+	}{
+		{Path: `D:\Docs`, Want: `D:\Docs`},
+		{Path: "{Documents}", Want: "knownfolder:{FDD39AD0-238F-46AF-ADB4-6C85480369C7}"},
+		{Path: "{FOLDERID_Documents}", Want: "knownfolder:{FDD39AD0-238F-46AF-ADB4-6C85480369C7}"},
+		{Path: "{Nowhere}", Err: UnknownFolderError("Nowhere").Error()},
+	} {
+		got, err := LibraryURL(test.Path)
+		if test.Err != "" {
+			if err == nil || err.Error() != test.Err {
+				t.Errorf("LibraryURL(%v) returned %v, want %v", test.Path, err, test.Err)
+			}
+			continue
+		}
+		if err != nil || got != test.Want {
+			t.Errorf("LibraryURL(%v) returned %v, %v, want %v", test.Path, got, err, test.Want)
+		}
+	}
+}
+
+func TestSameLibraryURL(t *testing.T) {
+	for _, test := range []struct {
+		A, B string
+		Want bool
+	}{
+		{`D:\Docs`, `d:/docs/`, true},
+		{`D:\Docs`, `D:\Docs\..\Docs`, true},
+		{`D:\Docs`, `D:\Docs2`, false},
+		{"knownfolder:{FDD39AD0-238F-46AF-ADB4-6C85480369C7}", "KnownFolder:fdd39ad0-238f-46af-adb4-6c85480369c7", true},
+		{"knownfolder:{FDD39AD0-238F-46AF-ADB4-6C85480369C7}", `C:\Users\pete\Documents`, false},
+	} {
+		if got := sameLibraryURL(test.A, test.B); got != test.Want {
+			t.Errorf("sameLibraryURL(%q, %q) is %v, want %v", test.A, test.B, got, test.Want)
+		}
+	}
+}
+
+func TestLibrarySaveAndLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "Documents.library-ms")
+	if err := ioutil.WriteFile(file, []byte(testLibrary), 0644); err != nil {
+		t.Fatal(err)
+	}
+	library, err := LoadLibrary(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := library.AddLocation(`E:\More`); err != nil {
+		t.Fatal(err)
+	}
+	if err := library.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) != libraryDocument(t, library) {
+		t.Errorf("Saved library is\n%s", saved)
+	}
+	files, err := ioutil.ReadDir(filepath.Dir(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Saving left %v files behind", len(files)-1)
+	}
+	if _, err := LoadLibrary(filepath.Join(filepath.Dir(file), "missing.library-ms")); err == nil {
+		t.Error("Loaded a missing library")
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// lockPollInterval is how often AcquireLock retries a lock that is held.
+var lockPollInterval = 250 * time.Millisecond
+
+// processStarted is when knownfolder started, as recorded in lock files.
+var processStarted = time.Now()
+
+// LockHolder identifies the knownfolder process holding a lock, as recorded
+// in the lock file.
+type LockHolder struct {
+	PID     int       `json:"pid"`
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+}
+
+func (h LockHolder) String() string {
+	return fmt.Sprintf("process %v, %q, started %v", h.PID, h.Command, h.Started.Local().Format(time.RFC1123))
+}
+
+// LockedError is returned by AcquireLock when another process holds the lock
+// on Scope. Holder is nil if the lock file doesn't say who.
+type LockedError struct {
+	Scope  string
+	Holder *LockHolder
+}
+
+func (e LockedError) Error() string {
+	if e.Holder == nil {
+		return fmt.Sprintf("Another knownfolder process is changing the folders of %v", e.Scope)
+	}
+	return fmt.Sprintf("Another knownfolder process (%v) is changing the folders of %v", e.Holder, e.Scope)
+}
+
+// Lock is an exclusive lock on changing the folders of a target scope, e.g.
+// "default user", held by a single process at a time, so that concurrent
+// knownfolder runs for the same target can't interleave their changes. It is
+// a lock on a file, which the operating system releases if the process
+// exits without releasing it, e.g. because it was killed.
+type Lock struct {
+	Scope string
+	file  *os.File
+}
+
+// lockFile returns the lock file in dir for scope.
+func lockFile(dir, scope string) string {
+	hash := sha256.Sum256([]byte(scope))
+	return filepath.Join(dir, fmt.Sprintf("lock-%x", hash[:8]))
+}
+
+// AcquireLock takes the lock on scope, whose lock file is in dir, waiting up
+// to timeout for another process to release it; a timeout of 0 doesn't wait
+// at all. The lock file records holder, for LockedErrors of other processes
+// waiting for it.
+func AcquireLock(dir, scope string, holder LockHolder, timeout time.Duration) (*Lock, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	file := lockFile(dir, scope)
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open lock file %v:\n%v", file, err)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("Could not lock %v:\n%v", file, err)
+		}
+		if locked {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			f.Close()
+			return nil, LockedError{Scope: scope, Holder: readLockHolder(file)}
+		}
+		time.Sleep(lockPollInterval)
+	}
+	data, err := json.Marshal(holder)
+	if err == nil {
+		err = f.Truncate(0)
+	}
+	if err == nil {
+		_, err = f.WriteAt(data, 0)
+	}
+	if err != nil {
+		unlockFile(f)
+		f.Close()
+		return nil, fmt.Errorf("Could not write lock file %v:\n%v", file, err)
+	}
+	return &Lock{Scope: scope, file: f}, nil
+}
+
+// readLockHolder returns the holder recorded in a lock file, or nil if it
+// can't be read, e.g. because the holder is just writing it.
+func readLockHolder(file string) *LockHolder {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	holder := &LockHolder{}
+	if json.Unmarshal(data, holder) != nil {
+		return nil
+	}
+	return holder
+}
+
+// Release releases the lock. The lock file is left in place, since removing
+// it could race with another process that has opened it and is waiting for
+// the lock.
+func (l *Lock) Release() error {
+	l.file.Truncate(0)
+	err := unlockFile(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// currentLockHolder returns the LockHolder describing this process, with
+// any password on the command line left out.
+func currentLockHolder() LockHolder {
+	args := append([]string{}, os.Args...)
+	args[0] = filepath.Base(args[0])
+	for i := 1; i < len(args); i++ {
+		switch {
+		case args[i] == "-p" && i+1 < len(args):
+			args[i+1] = "PASSWORD"
+		case strings.HasPrefix(args[i], "-p") && len(args[i]) > 2:
+			args[i] = "-pPASSWORD"
+		}
+	}
+	return LockHolder{PID: os.Getpid(), Command: strings.Join(args, " "), Started: processStarted}
+}
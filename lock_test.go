@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testLockHolder returns a LockHolder for process pid.
+func testLockHolder(pid int) LockHolder {
+	return LockHolder{PID: pid, Command: "knownfolder apply -d folders.yaml", Started: time.Date(2019, 6, 12, 10, 19, 55, 0, time.UTC)}
+}
+
+func TestAcquireLock(t *testing.T) {
+	defer func(interval time.Duration) { lockPollInterval = interval }(lockPollInterval)
+	lockPollInterval = 10 * time.Millisecond
+	dir := t.TempDir()
+	lock, err := AcquireLock(dir, "default user", testLockHolder(4242), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a held lock makes others time out, naming the holder
+	start := time.Now()
+	_, err = AcquireLock(dir, "default user", testLockHolder(4343), 50*time.Millisecond)
+	locked, ok := err.(LockedError)
+	if !ok || locked.Scope != "default user" || locked.Holder == nil || locked.Holder.PID != 4242 || !locked.Holder.Started.Equal(testLockHolder(4242).Started) {
+		t.Fatalf("Acquiring a held lock returned %#v", err)
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("Gave up on a held lock after %v, want 50ms", waited)
+	}
+	if _, err := AcquireLock(dir, "default user", testLockHolder(4343), 0); err == nil {
+		t.Error("Acquired a held lock without waiting")
+	}
+	// other scopes aren't affected
+	other, err := AcquireLock(dir, "user bob", testLockHolder(4343), 0)
+	if err != nil {
+		t.Fatalf("Could not lock another scope: %v", err)
+	}
+	other.Release()
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+	lock, err = AcquireLock(dir, "default user", testLockHolder(4343), 0)
+	if err != nil {
+		t.Fatalf("Could not acquire a released lock: %v", err)
+	}
+	// a waiting process gets the lock once it is released
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		lock.Release()
+	}()
+	waiter, err := AcquireLock(dir, "default user", testLockHolder(4444), 5*time.Second)
+	if err != nil {
+		t.Fatalf("Could not acquire a lock after it was released: %v", err)
+	}
+	if holder := readLockHolder(lockFile(dir, "default user")); holder == nil || holder.PID != 4444 {
+		t.Errorf("Lock file records holder %+v, want process 4444", holder)
+	}
+	waiter.Release()
+}
+
+func TestLockedError(t *testing.T) {
+	holder := testLockHolder(4242)
+	for _, test := range []struct {
+		Err  LockedError
+		Want string
+	}{
+		{LockedError{Scope: "default user"}, "Another knownfolder process is changing the folders of default user"},
+		{LockedError{Scope: "user bob", Holder: &holder}, `Another knownfolder process (process 4242, "knownfolder apply -d folders.yaml", started `},
+	} {
+		if got := test.Err.Error(); !strings.HasPrefix(got, test.Want) {
+			t.Errorf("Error is %q, want %q", got, test.Want)
+		}
+	}
+}
+
+func TestCurrentLockHolder(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"/usr/local/bin/knownfolder", "set", "-u", "bob", "-p", "s3cret", "-psecret2", "Documents", `D:\Docs`}
+	holder := currentLockHolder()
+	if want := `knownfolder set -u bob -p PASSWORD -pPASSWORD Documents D:\Docs`; holder.Command != want {
+		t.Errorf("Command is %q, want %q", holder.Command, want)
+	}
+	if holder.PID != os.Getpid() {
+		t.Errorf("PID is %v, want %v", holder.PID, os.Getpid())
+	}
+}
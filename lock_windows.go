@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	LOCKFILE_FAIL_IMMEDIATELY = 0x00000001
+	LOCKFILE_EXCLUSIVE_LOCK   = 0x00000002
+
+	ERROR_LOCK_VIOLATION syscall.Errno = 33
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+// lockedRange is the byte range of lock files that LockFileEx locks. Locks
+// on Windows are mandatory, so it is well beyond the holder recorded at the
+// start of the file, which other processes need to read.
+const (
+	lockedRangeOffsetHigh = 0x7FFFFFFF
+	lockedRangeLength     = 1
+)
+
+// tryLockFile locks f exclusively with LockFileEx, or returns false if
+// another process holds the lock.
+func tryLockFile(f *os.File) (bool, error) {
+	overlapped := syscall.Overlapped{OffsetHigh: lockedRangeOffsetHigh}
+	r1, _, err := procLockFileEx.Call(
+		f.Fd(),
+		LOCKFILE_EXCLUSIVE_LOCK|LOCKFILE_FAIL_IMMEDIATELY,
+		0,
+		lockedRangeLength,
+		0,
+		uintptr(unsafe.Pointer(&overlapped)),
+	)
+	if r1 != 0 {
+		return true, nil
+	}
+	if err == ERROR_LOCK_VIOLATION {
+		return false, nil
+	}
+	return false, err
+}
+
+func unlockFile(f *os.File) error {
+	overlapped := syscall.Overlapped{OffsetHigh: lockedRangeOffsetHigh}
+	r1, _, err := procUnlockFileEx.Call(
+		f.Fd(),
+		0,
+		lockedRangeLength,
+		0,
+		uintptr(unsafe.Pointer(&overlapped)),
+	)
+	if r1 == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,1401 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	docopt "github.com/docopt/docopt-go"
+)
+
+var usage = `
+knownfolder
+
+knownfolder allows you to get and set known folder locations on Windows.
+
+See https://msdn.microsoft.com/en-us/library/windows/desktop/dd378457(v=vs.85).aspx
+
+  Usage:
+    knownfolder set [-d|-u USERNAME -p PASSWORD|--wine-prefix PREFIX] [--audit-log FILE]
+                    [--hooks FILE] [--lock-timeout DURATION|--no-wait] [--desktop-ini] FOLDER LOCATION
+    knownfolder get [-d|-u USERNAME -p PASSWORD|--wine-prefix PREFIX] FOLDER
+    knownfolder get --image-root ROOT [--profile PROFILE] FOLDER
+    knownfolder list [--aliases] [--software-hive PATH|--image-root ROOT]
+    knownfolder completion SHELL
+    knownfolder serve [-d] --listen ADDR --token-file FILE [--tls-cert CERT --tls-key KEY]
+                      [--audit-log FILE] [--lock-timeout DURATION|--no-wait]
+    knownfolder apply [-d|-u USERNAME -p PASSWORD|--wine-prefix PREFIX] [--audit-log FILE]
+                      [--hooks FILE] [--lock-timeout DURATION|--no-wait] MANIFEST
+    knownfolder apply --image-root ROOT [--profile PROFILE] [--force] [--audit-log FILE]
+                      [--hooks FILE] [--lock-timeout DURATION|--no-wait] MANIFEST
+    knownfolder apply --users FILE [--workers N] [--audit-log FILE] [--hooks FILE]
+                      [--lock-timeout DURATION|--no-wait] MANIFEST
+    knownfolder apply --all-profiles [--include PATTERN]... [--exclude PATTERN]...
+                      [--software-hive PATH|--image-root ROOT [--force]] [--dry-run]
+                      [--audit-log FILE] [--hooks FILE] [--lock-timeout DURATION|--no-wait] MANIFEST
+    knownfolder recover (--rollback|--complete) [-d|-u USERNAME -p PASSWORD|--wine-prefix PREFIX]
+                        [--audit-log FILE] [--hooks FILE] [--lock-timeout DURATION|--no-wait]
+    knownfolder recover (--rollback|--complete) --image-root ROOT [--profile PROFILE] [--force]
+                        [--audit-log FILE] [--hooks FILE] [--lock-timeout DURATION|--no-wait]
+    knownfolder enforce [-d|--wine-prefix PREFIX] [--interval DURATION|--once] [--audit-log FILE]
+                        [--lock-timeout DURATION|--no-wait] MANIFEST
+    knownfolder snapshot [-d|-u USERNAME -p PASSWORD|--wine-prefix PREFIX]
+    knownfolder snapshot --image-root ROOT [--profile PROFILE]
+    knownfolder diff [-d|-u USERNAME -p PASSWORD|--wine-prefix PREFIX] MANIFEST
+    knownfolder diff --image-root ROOT [--profile PROFILE] MANIFEST
+    knownfolder export --format FORMAT [-d] [--pass PASS] [--arch ARCH] [--exe PATH] [--merge FILE]
+                       MANIFEST
+    knownfolder import --format FORMAT [--user-map FILE] [--audit-log FILE] PATH
+    knownfolder users [--software-hive PATH|--image-root ROOT]
+    knownfolder register --name NAME --guid GUID --category CATEGORY [--parent PARENT]
+                         [--relative-path RELPATH] [--parsing-name PARSINGNAME] [--precreate]
+                         [--dry-run]
+    knownfolder unregister [--dry-run] FOLDER
+    knownfolder resolve [-d|-u USERNAME -p PASSWORD|--wine-prefix PREFIX] PATH
+    knownfolder tree [-d|-u USERNAME -p PASSWORD] [--software-hive PATH] [FOLDER]
+    knownfolder library show [-d|-u USERNAME -p PASSWORD] LIBRARY
+    knownfolder library (add-location|remove-location) [-d|-u USERNAME -p PASSWORD] LIBRARY PATH
+    knownfolder library set-default-save [--non-owner] [-d|-u USERNAME -p PASSWORD] LIBRARY PATH
+    knownfolder -h|--help
+    knownfolder --version
+
+  Targets:
+    set          Set a folder location. You need to run this command as the user concerned, for
+                 USER based settings, otherwise -d will apply the setting for the default user.
+    get          Retrieve a folder location. You need to run this command as the user concerned,
+                 for USER based settings, otherwise -d will apply the setting for the default user.
+    list         List all possible values for FOLDER, including custom known folders that
+                 applications have registered, which are marked as discovered. With
+                 --software-hive, discover these from the given SOFTWARE hive file rather than
+                 the registry. With --aliases, list the CSIDL names and values, FOLDERID_
+                 names, environment variables and legacy registry names that are also
+                 accepted for FOLDER, and the folder each one refers to.
+    completion   Output a script which provides tab completion of commands, options and FOLDER
+                 names for the given SHELL.
+    serve        Serve list, get and set as a JSON API over HTTP(S), for the user running the
+                 command, or the default user if -d is given. See README.md for the API.
+    apply        Set all of the folder locations in MANIFEST. With --users, log on as each of the
+                 users listed in FILE, up to N at a time, and apply MANIFEST for each of them,
+                 reporting the outcome for every user. With --all-profiles, apply MANIFEST to
+                 the Default user, which new profiles are copied from, and every existing user
+                 profile, writing the user hives of profiles which aren't logged on directly.
+                 Service, temporary and backup profiles are skipped, as are any not selected
+                 by --include and --exclude, and the reason for each is reported. With
+                 --image-root, apply MANIFEST to the hives of an offline Windows image.
+                 Every change is first written to a journal of the target, or of each user
+                 or profile, so that an apply which is interrupted, e.g. by a reboot, can be
+                 recovered. Journals are kept in the directory given by the
+                 KNOWNFOLDER_JOURNAL_DIR environment variable, by default
+                 %ProgramData%\knownfolder on Windows, or ~/.local/state/knownfolder.
+    recover      Recover from an apply for the given target which was interrupted, using its
+                 journal: with --rollback, set the folders it changed back to their previous
+                 locations, or with --complete, set those it didn't get to. Until then,
+                 knownfolder warns about the interrupted apply, and refuses to apply another
+                 MANIFEST for the same target.
+    enforce      Repeatedly compare folder locations with those in MANIFEST, and set any that
+                 differ, until interrupted. With --once, do this a single time, and exit with a
+                 non-zero exit code if any folder could not be set.
+    snapshot     Output a MANIFEST of the current locations of the folders which can be set,
+                 so that apply can put them back later, or on another target.
+    diff         Compare the folder locations in MANIFEST with the current ones, and list
+                 those which differ, exiting with a non-zero exit code if any do.
+    export       Output the folder locations in MANIFEST in another FORMAT. "powershell" outputs
+                 a script which sets them without needing knownfolder, for the user running the
+                 script, or the default user if -d is given. "unattend" outputs an answer file
+                 which runs "knownfolder set -d" for each folder during the given PASS, or with
+                 --merge, the given answer file with these commands added or updated. The
+                 {{.Username}} of MANIFEST is "Default" for the default user, or in a script
+                 for the user running it, their username, as it runs.
+    import       Output a MANIFEST of the folder locations configured in another FORMAT.
+                 "gpo-fdeploy" reads the Group Policy Folder Redirection settings from PATH,
+                 which may be an fdeploy1.ini or fdeploy.ini file, or a GPO backup directory.
+    users        List the local user profiles, with the SID, account name, state flags and
+                 ProfileImagePath of each, and whether its registry hive is currently loaded.
+                 With --software-hive, read the profiles from the given SOFTWARE hive file
+                 instead, e.g. of an offline Windows image, which also works on other platforms.
+    register     Register a custom known folder, so that applications can look it up by GUID,
+                 and it can be redirected like the built-in folders. A folder in a per user
+                 (peruser) or machine wide (common) CATEGORY is normally located at RELPATH
+                 under the PARENT folder. A virtual folder has no file system location, only
+                 a shell namespace PARSINGNAME. With --precreate, Windows creates the folder
+                 when a user first logs on. Requires administrator privileges.
+    unregister   Remove the registration of a custom known FOLDER. The folder itself and its
+                 contents are left in place.
+    resolve      Find the known folders which most specifically contain PATH, i.e. are at the
+                 deepest location that PATH is in, and output PATH relative to each, and in a
+                 portable form, e.g. {LocalAppData}\Temp\x. Paths are compared case
+                 insensitively, after expanding short (8.3) names and removing \\?\ prefixes.
+    tree         Show the hierarchy of known folders which are defined relative to a parent
+                 folder, or just those below FOLDER, with the current location of each. Folders
+                 which have been redirected away from their parent, and so no longer move along
+                 with it, are flagged. With --software-hive, show the hierarchy defined in the
+                 given SOFTWARE hive file, without locations.
+    library      Show or change the locations which a Library includes, and which of them
+                 files saved to the library are saved in, by default for the library's owner,
+                 or with --non-owner, for other users. PATH may be a file system path, or a
+                 known folder given as {FOLDER}, e.g. "{Documents}".
+
+  Options:
+    -d           Set/get known folder for the default user profile, rather than an existing user.
+    FOLDER       The folder name, as per the Constants shown in
+                 https://msdn.microsoft.com/en-us/library/windows/desktop/dd378457(v=vs.85).aspx
+                 or of a custom folder shown by "knownfolder list", the folder's GUID, or one
+                 of the aliases shown by "knownfolder list --aliases".
+    LOCATION     The full file system path to set the given FOLDER location to. It may refer to
+                 the current location of another folder as {FOLDER}, e.g. "{Profile}\dl".
+    USERNAME     The username of the user you wish to set/get the known folder for, if different
+                 to the user running the knownfolder command.
+    PASSWORD     The password of the user you wish to set/get the known folder for, if different
+                 to the user running the knownfolder command.
+    LIBRARY      A library folder, e.g. DocumentsLibrary, or the path of a .library-ms file.
+    SHELL        One of bash, zsh, fish or powershell.
+    MANIFEST     A YAML file mapping FOLDERs to LOCATIONs, in the form:
+                   variables:
+                     Data: 'D:\Data'
+                   folders:
+                     LocalAppData: '{Data}\AppData\Local'
+                     LocalAppDataLow: '{LocalAppData}Low'
+                 Locations may include {{.Username}}, which is replaced by the name of the user
+                 the manifest is applied for. They may also refer to variables, and to other
+                 folders, which take their location from the manifest if they are in it.
+    --listen ADDR        The address for serve to listen on, e.g. ":8443".
+    --token-file FILE    A file containing the bearer token that serve requires all requests to
+                         present in their Authorization header.
+    --tls-cert CERT      PEM encoded certificate file, if serve should use HTTPS.
+    --tls-key KEY        PEM encoded private key file for the --tls-cert certificate.
+    --interval DURATION  How often enforce checks folder locations, e.g. "90s", "5m", "1h".
+                         [default: 5m]
+    --format FORMAT      The format for export to output ("powershell" or "unattend"), or for
+                         import to read ("gpo-fdeploy").
+    --pass PASS          The configuration pass for unattend commands: "specialize" or
+                         "oobeSystem". [default: specialize]
+    --arch ARCH          The processorArchitecture of unattend components. [default: amd64]
+    --exe PATH           The path to knownfolder in unattend commands. [default: knownfolder.exe]
+    --merge FILE         An existing answer file to merge unattend commands into.
+    --user-map FILE      A YAML file listing the SIDs, most specific first, whose Folder
+                         Redirection targets apply, and optionally a username to replace
+                         %USERNAME% with. Without it, only targets for Everyone are imported.
+    --software-hive PATH  A SOFTWARE registry hive file, e.g.
+                         "Windows\System32\config\SOFTWARE" of a mounted Windows image.
+    --name NAME          The name to register a custom known folder under, e.g. "TaskclusterCache".
+    --guid GUID          The KNOWNFOLDERID of the folder to register, e.g.
+                         "{0A5A3F5E-8C5B-4C1B-9A42-6A4C7F1B2D3E}".
+    --category CATEGORY  One of "peruser", "common", "fixed" or "virtual".
+    --parent PARENT      The FOLDER that RELPATH is relative to, e.g. "LocalAppData".
+    --relative-path RELPATH  The location of the folder under PARENT, e.g. "Taskcluster\Cache".
+    --parsing-name PARSINGNAME  The shell namespace parsing name of a virtual folder.
+    --precreate          Create the folder when a user first logs on.
+    --dry-run            Output the registry changes that register or unregister would make, as
+                         a .reg file, rather than making them, or for apply, the locations that
+                         would be set for each profile.
+    --include PATTERN    Only apply MANIFEST to profiles whose SID, account name or profile path
+                         matches PATTERN, where * matches any text and ? any character, e.g.
+                         "CONTOSO\*". May be given more than once.
+    --exclude PATTERN    Don't apply MANIFEST to profiles matching PATTERN, e.g. "Default".
+                         May be given more than once.
+    --users FILE         A CSV file of usernames and passwords to apply MANIFEST for, with an
+                         optional "username,password" header row.
+    --workers N          The maximum number of users to apply MANIFEST for concurrently.
+                         [default: 4]
+    --image-root ROOT    The directory a Windows image is mounted at, e.g. "/mnt/win", to read
+                         and write the registry hives of, rather than those of the running
+                         system. Paths in the image are on its system drive, e.g. C:, and
+                         LOCATION may also be a path below ROOT.
+    --profile PROFILE    The profile of the image to get or apply folders for, matched as for
+                         --include. Defaults to the Default profile.
+    --force              Write hives of the image even though they have changes which are only
+                         in their transaction logs, e.g. because the image was not shut down
+                         cleanly, which Windows may then apply over the changes written.
+    --wine-prefix PREFIX  Get or set the known folders of the Wine prefix directory PREFIX, e.g.
+                         "$HOME/.wine", by editing its user.reg and system.reg files. Wine must
+                         not be running in the prefix. LOCATION may then also be a Unix path on
+                         one of the prefix's drives.
+    --desktop-ini        Write the name, icon and KNOWNFOLDERID of FOLDER to a desktop.ini file
+                         in LOCATION, so that Explorer shows it like the default location. Any
+                         existing desktop.ini is updated, keeping its other settings.
+    --lock-timeout DURATION  How long to wait for another knownfolder process which is changing
+                         the folders of the same target to finish, e.g. "30s". Only one process
+                         at a time can change the folders of a target, i.e. the current user,
+                         the default user, another USERNAME, a Wine prefix or a Windows image,
+                         so set, apply, recover, enforce and serve wait for any other that is.
+                         [default: 5m]
+    --no-wait            Fail straight away if another knownfolder process is changing the
+                         folders of the same target, rather than waiting for it.
+    --rollback           Set the folders an interrupted apply changed back to where they were.
+    --complete           Set the folders of an interrupted apply that it didn't set.
+    --audit-log FILE     Append a JSON record of every folder location change (successful or
+                         not), or for import, of every folder location imported, to FILE.
+                         Defaults to the value of the KNOWNFOLDER_AUDIT_LOG environment
+                         variable, if set.
+    --hooks FILE         Run the commands configured in the YAML file FILE before and after each
+                         folder location change, and before and after an apply. A command run
+                         before a change which fails prevents it. Defaults to the value of the
+                         KNOWNFOLDER_HOOKS environment variable, if set.
+
+  Examples:
+
+    C:\> knownfolder set RoamingAppData "D:\Users\Pete\AppData\Roaming"
+    C:\> knownfolder list
+    C:\> knownfolder get LocalAppData
+    C:\> knownfolder get CSIDL_APPDATA
+    C:\> knownfolder completion powershell | Out-String | Invoke-Expression
+    C:\> knownfolder --help
+    C:\> knownfolder --version
+`
+
+func main() {
+	// hidden command used by the scripts that "knownfolder completion" outputs
+	if len(os.Args) > 1 && os.Args[1] == "__complete" {
+		// complete the custom known folders registered on this machine too
+		discoverFolders(map[string]interface{}{})
+		folders := make([]string, 0, len(knownfolders))
+		for key := range knownfolders {
+			folders = append(folders, key)
+		}
+		candidates, err := Complete(os.Args[2:], folders)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		for _, candidate := range candidates {
+			fmt.Println(candidate)
+		}
+		return
+	}
+
+	arguments, err := docopt.Parse(usage, nil, true, "knownfolders "+version, false, true)
+	if err != nil {
+		log.Fatalf("Error parsing command line arguments!")
+	}
+
+	for _, command := range []string{"set", "get", "list", "serve", "apply", "recover", "enforce", "snapshot", "diff", "register", "unregister", "resolve", "tree", "library"} {
+		if arguments[command] == true {
+			discoverFolders(arguments)
+			break
+		}
+	}
+
+	switch {
+	case arguments["recover"] == true:
+	case arguments["apply"] == true && arguments["--users"] == nil && arguments["--all-profiles"] != true:
+		// apply refuses to start if its own target has a journal
+		warnIncompleteJournals(journalFile(journalDir(), targetScope(arguments)))
+	default:
+		warnIncompleteJournals("")
+	}
+
+	switch {
+	case arguments["set"]:
+		location := arguments["LOCATION"].(string)
+		folder, ok := resolveFolder(arguments["FOLDER"].(string))
+		if !ok {
+			log.Fatalf(`Unknown folder "%v"`, arguments["FOLDER"])
+		}
+		lock := lockTarget(arguments, lockScope(arguments))
+		defer lock.Release()
+		backend, logoff, err := targetBackend(arguments)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer logoff()
+		entries, err := ExpandReferences([]ManifestEntry{{Folder: folder, Location: location}}, nil, backend.GetFolder)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		location = entries[0].Location
+		warnFollowers(arguments, folder, backend)
+		backend = hookBackend(loadHooks(arguments), targetScope(arguments), targetUsername(arguments, backend), backend)
+		err = auditBackend(arguments, "set", targetScope(arguments), backend).SetFolder(folder, location)
+		if err != nil {
+			log.Fatalf("Could not set folder location %v=%v\n%v", folder, location, err)
+		}
+		if arguments["--desktop-ini"].(bool) {
+			err = updateDesktopINI(folder, location)
+			if err != nil {
+				logoff()
+				log.Fatalf("Could not write desktop.ini in %v:\n%v", location, err)
+			}
+		}
+		fmt.Printf("%v=%v", folder, location)
+	case arguments["get"]:
+		folder, ok := resolveFolder(arguments["FOLDER"].(string))
+		if !ok {
+			log.Fatalf(`Unknown folder "%v"`, arguments["FOLDER"])
+		}
+		backend, logoff, err := targetBackend(arguments)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer logoff()
+		value, err := backend.GetFolder(folder)
+		if err != nil {
+			log.Fatalf("Could not retrieve folder %v:\n%v", folder, err)
+		}
+		fmt.Println(value)
+	case arguments["list"]:
+		var err error
+		if arguments["--aliases"].(bool) {
+			err = ListAliases()
+		} else {
+			err = ListFolders()
+		}
+		if err != nil {
+			log.Fatalf("Could not list folders:\n%v", err)
+		}
+	case arguments["completion"]:
+		script, err := CompletionScript(arguments["SHELL"].(string))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		fmt.Print(script)
+	case arguments["serve"]:
+		backend, logoff, err := targetBackend(arguments)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer logoff()
+		token, err := ReadToken(arguments["--token-file"].(string))
+		if err != nil {
+			log.Fatalf("Could not read token file:\n%v", err)
+		}
+		server := &Server{
+			Backend: auditBackend(arguments, "serve", targetScope(arguments), backend),
+			Token:   token,
+			Lock:    targetLocker(arguments),
+		}
+		certFile, _ := arguments["--tls-cert"].(string)
+		keyFile, _ := arguments["--tls-key"].(string)
+		err = server.ListenAndServe(arguments["--listen"].(string), certFile, keyFile)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	case arguments["apply"] == true && arguments["--all-profiles"] == true:
+		manifest, err := LoadManifest(arguments["MANIFEST"].(string))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		var image *WindowsImage
+		if root, ok := arguments["--image-root"].(string); ok {
+			image, err = OpenWindowsImage(root)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			defer image.Close()
+			image.Force = arguments["--force"].(bool)
+			if !arguments["--dry-run"].(bool) {
+				lock := lockTarget(arguments, lockScope(arguments))
+				defer lock.Release()
+			}
+		}
+		var software RegistryKey
+		if image != nil {
+			software = image.Software.Root()
+		} else if software, err = softwareKey(arguments); err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer software.Close()
+		_, offline := arguments["--software-hive"].(string)
+		if offline && !arguments["--dry-run"].(bool) {
+			log.Fatalf("Profiles of a SOFTWARE hive file can only be applied with --dry-run, use --image-root to write them")
+		}
+		var profiles []Profile
+		if offline || image != nil {
+			profiles, err = ReadProfileList(software)
+		} else {
+			profiles, err = liveProfiles()
+		}
+		if err != nil {
+			log.Fatalf("Could not list profiles:\n%v", err)
+		}
+		defaultProfile, err := ReadDefaultProfile(software)
+		if err != nil {
+			log.Fatalf("Could not find the Default profile:\n%v", err)
+		}
+		descriptions, err := ReadFolderDescriptions(software)
+		if err != nil {
+			log.Fatalf("Could not read folder descriptions:\n%v", err)
+		}
+		filter := ProfileFilter{
+			Include: arguments["--include"].([]string),
+			Exclude: arguments["--exclude"].([]string),
+		}
+		selected, skipped := SelectProfiles(append([]Profile{defaultProfile}, profiles...), filter)
+		getenv, openHive := os.Getenv, openProfileHive
+		switch {
+		case image != nil:
+			getenv = image.Environment(Profile{})
+			openHive = func(p Profile, file string) RegistryKey { return image.OpenProfileHive(p) }
+		case offline:
+			// the environment and user hives are those of another machine
+			getenv = func(string) string { return "" }
+			openHive = func(Profile, string) RegistryKey { return nil }
+		}
+		plans, unplanned, err := PlanProfiles(selected, manifest, descriptions, getenv, openHive)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		skipped = append(skipped, unplanned...)
+		if arguments["--dry-run"].(bool) {
+			err = WriteProfilePlans(os.Stdout, plans, skipped)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			return
+		}
+		timeout, hooks := lockTimeout(arguments), loadHooks(arguments)
+		manifestFile := absolutePath(arguments["MANIFEST"].(string))
+		open := func(plan ProfilePlan) (backend Backend, close func() error, err error) {
+			if image != nil {
+				backend = &ImageBackend{Image: image, Profile: plan.Profile, Descriptions: descriptions}
+				close = func() error { return nil }
+			} else {
+				// lock each live profile while it is written
+				lock, err := AcquireLock(journalDir(), profileLockScope(plan.Profile), currentLockHolder(), timeout)
+				if err != nil {
+					return nil, nil, err
+				}
+				backend, close, err = profileBackend(plan.Profile, plan.Hive, descriptions)
+				if err != nil {
+					lock.Release()
+					return nil, nil, err
+				}
+				unload := close
+				close = func() error {
+					defer lock.Release()
+					return unload()
+				}
+			}
+			scope, username := profileScope(arguments, plan.Profile), ProfileUsername(plan.Profile)
+			backend = hookBackend(hooks, scope, username, backend)
+			return auditBackend(arguments, "apply", scope, backend), close, nil
+		}
+		apply := func(plan ProfilePlan, backend Backend) ([]FolderResult, error) {
+			scope := profileScope(arguments, plan.Profile)
+			return ApplyJournaled(journalFile(journalDir(), scope), scope, profileTargetOptions(arguments, plan.Profile),
+				applyHooks(hooks, ProfileUsername(plan.Profile), manifestFile), backend, plan.Entries)
+		}
+		results := ApplyProfilePlans(plans, open, apply)
+		failed := WriteApplyReport(os.Stdout, results)
+		err = WriteSkippedProfiles(os.Stdout, skipped)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if failed > 0 {
+			software.Close()
+			log.Fatalf("Could not apply %v for %v of %v profiles", arguments["MANIFEST"], failed, len(plans))
+		}
+	case arguments["apply"] == true && arguments["--users"] != nil:
+		manifest, err := LoadManifest(arguments["MANIFEST"].(string))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		users, err := LoadUsers(arguments["--users"].(string))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		workers, err := strconv.Atoi(arguments["--workers"].(string))
+		if err != nil || workers < 1 {
+			log.Fatalf(`Invalid number of workers "%v"`, arguments["--workers"])
+		}
+		timeout, hooks := lockTimeout(arguments), loadHooks(arguments)
+		manifestFile := absolutePath(arguments["MANIFEST"].(string))
+		logon := func(user UserCredentials) (Backend, func(), error) {
+			lock, err := AcquireLock(journalDir(), userLockScope(user.Username), currentLockHolder(), timeout)
+			if err != nil {
+				return nil, nil, err
+			}
+			backend, logoffUser, err := logonBackend(user)
+			if err != nil {
+				lock.Release()
+				return nil, nil, err
+			}
+			logoff := func() {
+				logoffUser()
+				lock.Release()
+			}
+			backend = hookBackend(hooks, "user "+user.Username, user.Username, backend)
+			backend = auditBackend(arguments, "apply", "user "+user.Username, backend)
+			return backend, logoff, nil
+		}
+		apply := func(user UserCredentials, backend Backend, entries []ManifestEntry) ([]FolderResult, error) {
+			scope := "user " + user.Username
+			return ApplyJournaled(journalFile(journalDir(), scope), scope, fmt.Sprintf("-u %q -p PASSWORD", user.Username),
+				applyHooks(hooks, user.Username, manifestFile), backend, entries)
+		}
+		results := ApplyToUsers(users, manifest, workers, logon, apply)
+		if failed := WriteApplyReport(os.Stdout, results); failed > 0 {
+			log.Fatalf("Could not apply %v for %v of %v users", arguments["MANIFEST"], failed, len(users))
+		}
+	case arguments["apply"]:
+		manifest, err := LoadManifest(arguments["MANIFEST"].(string))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		hooks := loadHooks(arguments)
+		lock := lockTarget(arguments, lockScope(arguments))
+		defer lock.Release()
+		backend, logoff, err := targetBackend(arguments)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer logoff()
+		username := targetUsername(arguments, backend)
+		entries, err := manifest.Render(TemplateData{Username: username})
+		if err != nil {
+			logoff()
+			log.Fatalf("%v", err)
+		}
+		entries, err = manifest.Expand(entries, backend.GetFolder)
+		if err != nil {
+			logoff()
+			log.Fatalf("%v", err)
+		}
+		scope := targetScope(arguments)
+		results, err := ApplyJournaled(journalFile(journalDir(), scope), scope, targetOptions(arguments),
+			applyHooks(hooks, username, absolutePath(arguments["MANIFEST"].(string))),
+			auditBackend(arguments, "apply", scope, hookBackend(hooks, scope, username, backend)), entries)
+		if err != nil && results == nil {
+			// the manifest wasn't applied at all
+			logoff()
+			log.Fatalf("%v", err)
+		}
+		if err != nil {
+			log.Printf("%v", err)
+		}
+		failed := 0
+		for _, result := range results {
+			if result.Err != nil {
+				log.Printf("Could not set folder location %v=%v\n%v", result.Folder, result.Location, result.Err)
+				failed++
+				continue
+			}
+			fmt.Printf("%v=%v\n", result.Folder, result.Location)
+		}
+		if failed > 0 {
+			// log off explicitly, since deferred calls don't run on exit
+			logoff()
+			log.Fatalf("Could not set %v of %v folders", failed, len(results))
+		}
+	case arguments["recover"]:
+		lock := lockTarget(arguments, lockScope(arguments))
+		defer lock.Release()
+		file := journalFile(journalDir(), targetScope(arguments))
+		journal, err := LoadJournal(file)
+		if os.IsNotExist(err) {
+			log.Fatalf("There is no interrupted apply for %v to recover", targetScope(arguments))
+		}
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		backend, logoff, err := targetBackend(arguments)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer logoff()
+		backend = hookBackend(loadHooks(arguments), journal.Scope, targetUsername(arguments, backend), backend)
+		backend = auditBackend(arguments, "recover", targetScope(arguments), backend)
+		var results []FolderResult
+		if arguments["--rollback"].(bool) {
+			results = journal.Rollback(backend)
+		} else {
+			results = journal.Apply(backend)
+		}
+		failed := 0
+		for _, result := range results {
+			if result.Err != nil {
+				log.Printf("Could not set folder location %v=%v\n%v", result.Folder, result.Location, result.Err)
+				failed++
+				continue
+			}
+			fmt.Printf("%v=%v\n", result.Folder, result.Location)
+		}
+		if failed > 0 {
+			// keep the journal, so that recover can be run again
+			logoff()
+			log.Fatalf("Could not set %v of %v folders, run recover again once the problem is fixed", failed, len(results))
+		}
+		err = journal.Finish()
+		if err != nil {
+			logoff()
+			log.Fatalf("Could not remove journal %v:\n%v", journal.File, err)
+		}
+	case arguments["enforce"]:
+		manifest, err := LoadManifest(arguments["MANIFEST"].(string))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		interval, err := time.ParseDuration(arguments["--interval"].(string))
+		if err != nil || interval <= 0 {
+			log.Fatalf(`Invalid interval "%v"`, arguments["--interval"])
+		}
+		backend, logoff, err := targetBackend(arguments)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer logoff()
+		backend = auditBackend(arguments, "enforce", targetScope(arguments), backend)
+		enforcer := NewEnforcer(backend, manifest, targetUsername(arguments, backend), interval)
+		enforcer.Lock = targetLocker(arguments)
+		if arguments["--once"].(bool) {
+			_, err := enforcer.Reconcile()
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			return
+		}
+		stop := make(chan struct{})
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			sig := <-signals
+			log.Printf("Received %v, shutting down", sig)
+			close(stop)
+		}()
+		enforcer.Run(stop)
+	case arguments["snapshot"]:
+		backend, logoff, err := targetBackend(arguments)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer logoff()
+		err = WriteManifest(os.Stdout, Snapshot(backend))
+		if err != nil {
+			logoff()
+			log.Fatalf("%v", err)
+		}
+	case arguments["diff"]:
+		manifest, err := LoadManifest(arguments["MANIFEST"].(string))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		backend, logoff, err := targetBackend(arguments)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer logoff()
+		differences, err := DiffManifest(backend, manifest, targetUsername(arguments, backend))
+		if err != nil {
+			logoff()
+			log.Fatalf("%v", err)
+		}
+		if len(differences) > 0 {
+			WriteDifferences(os.Stdout, differences)
+			logoff()
+			os.Exit(1)
+		}
+	case arguments["export"]:
+		file := arguments["MANIFEST"].(string)
+		manifest, err := LoadManifest(file)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		format := arguments["--format"].(string)
+		options := ExportOptions{
+			Manifest:     filepath.Base(file),
+			DefaultUser:  arguments["-d"].(bool),
+			Pass:         arguments["--pass"].(string),
+			Architecture: arguments["--arch"].(string),
+			Executable:   arguments["--exe"].(string),
+		}
+		options.MergeFile, _ = arguments["--merge"].(string)
+		entries, err := manifest.Render(TemplateData{Username: ExportUsername(format, options)})
+		if err == nil {
+			// there is no user to look up the locations of other folders for
+			entries, err = manifest.Expand(entries, func(folder string) (string, error) {
+				return "", fmt.Errorf("Only folders in the manifest can be referred to by exported locations")
+			})
+		}
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		err = Export(os.Stdout, format, entries, options)
+		if err != nil {
+			log.Fatalf("Could not export %v:\n%v", file, err)
+		}
+	case arguments["import"]:
+		if format := arguments["--format"].(string); format != "gpo-fdeploy" {
+			log.Fatalf(`Unsupported import format "%v"`, format)
+		}
+		var userMap *GPOUserMap
+		if file, ok := arguments["--user-map"].(string); ok {
+			userMap, err = LoadGPOUserMap(file)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+		}
+		file, err := FindFdeploy(arguments["PATH"].(string))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		manifest, err := ImportFdeploy(file, userMap)
+		if err != nil {
+			log.Fatalf("Could not import %v:\n%v", file, err)
+		}
+		if audit := auditLog(arguments, "import"); audit != nil {
+			entries, err := manifest.Entries()
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			scope := "every user"
+			if userMap != nil && userMap.Username != "" {
+				scope = "user " + userMap.Username
+			}
+			for _, entry := range entries {
+				err = audit.Record(scope, entry.Folder, "", entry.Location, nil)
+				if err != nil {
+					log.Fatalf("Could not write audit log %v: %v", audit.File, err)
+				}
+			}
+		}
+		err = WriteManifest(os.Stdout, manifest)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	case arguments["register"]:
+		definition := KnownFolderDefinition{
+			Name:      arguments["--name"].(string),
+			PreCreate: arguments["--precreate"].(bool),
+		}
+		definition.GUID, err = ParseGUID(arguments["--guid"].(string))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		definition.Category, err = ParseCategory(arguments["--category"].(string))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if parent, ok := arguments["--parent"].(string); ok {
+			definition.Parent, ok = resolveFolder(parent)
+			if !ok {
+				log.Fatalf(`Unknown parent folder "%v"`, parent)
+			}
+		}
+		definition.RelativePath, _ = arguments["--relative-path"].(string)
+		definition.ParsingName, _ = arguments["--parsing-name"].(string)
+		err = definition.Validate()
+		if err != nil {
+			log.Fatalf("Invalid folder definition:\n%v", err)
+		}
+		writeRegistryChanges(arguments, definition.Registration())
+		if !arguments["--dry-run"].(bool) {
+			fmt.Printf("Registered %v %v\n", definition.Name, definition.GUID.String())
+		}
+	case arguments["unregister"]:
+		name := arguments["FOLDER"].(string)
+		var guid GUID
+		if folder, ok := resolveFolder(name); ok {
+			d := discoveredFolders[folder]
+			if d == nil {
+				log.Fatalf("%v is a built-in folder, so cannot be unregistered", folder)
+			}
+			guid = d.GUID
+		} else if guid, err = ParseGUID(name); err != nil {
+			// unknown folders may still be unregistered by GUID, e.g. offline
+			log.Fatalf(`Unknown folder "%v"`, name)
+		}
+		writeRegistryChanges(arguments, Unregistration(guid))
+		if !arguments["--dry-run"].(bool) {
+			fmt.Printf("Unregistered %v\n", guid.String())
+		}
+	case arguments["resolve"]:
+		backend, logoff, err := targetBackend(arguments)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer logoff()
+		resolver := &PathResolver{Locations: FolderLocations(backend), LongPath: longPathName}
+		matches := resolver.Resolve(arguments["PATH"].(string))
+		if len(matches) == 0 {
+			log.Fatalf("%v is not in any known folder", arguments["PATH"])
+		}
+		err = WritePathMatches(os.Stdout, matches)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	case arguments["tree"]:
+		roots, err := folderTree(arguments)
+		if err != nil {
+			log.Fatalf("Could not read folder hierarchy:\n%v", err)
+		}
+		if name, ok := arguments["FOLDER"].(string); ok {
+			folder, ok := resolveFolder(name)
+			if !ok {
+				log.Fatalf(`Unknown folder "%v"`, name)
+			}
+			node := FindFolderNode(roots, folder)
+			if node == nil {
+				log.Fatalf("Folder %v is not described in the registry", folder)
+			}
+			roots = []*FolderNode{node}
+		}
+		if _, offline := arguments["--software-hive"].(string); !offline {
+			backend, logoff, err := targetBackend(arguments)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			defer logoff()
+			for _, root := range roots {
+				if root.Parent != nil {
+					// locate the parent too, to tell if root is redirected
+					root.Parent.Location, _ = backend.GetFolder(root.Parent.Folder)
+				}
+				root.Locate(backend.GetFolder)
+			}
+		}
+		err = WriteFolderTree(os.Stdout, roots)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	case arguments["library"]:
+		file, logoff := libraryFile(arguments)
+		defer logoff()
+		library, err := LoadLibrary(file)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if arguments["show"].(bool) {
+			err = WriteLibraryLocations(os.Stdout, library)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			return
+		}
+		url, err := LibraryURL(arguments["PATH"].(string))
+		if err == nil {
+			switch {
+			case arguments["add-location"].(bool):
+				err = library.AddLocation(url)
+			case arguments["remove-location"].(bool):
+				err = library.RemoveLocation(url)
+			case arguments["set-default-save"].(bool):
+				err = library.SetDefaultSaveLocation(url, arguments["--non-owner"].(bool))
+			}
+		}
+		if err == nil {
+			err = library.Save(file)
+		}
+		if err != nil {
+			logoff()
+			log.Fatalf("Could not update library %v:\n%v", file, err)
+		}
+		err = WriteLibraryLocations(os.Stdout, library)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	case arguments["users"]:
+		var profiles []Profile
+		if _, offline := arguments["--software-hive"].(string); offline || arguments["--image-root"] != nil {
+			var software RegistryKey
+			software, err = softwareKey(arguments)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			defer software.Close()
+			profiles, err = ReadProfileList(software)
+		} else {
+			profiles, err = liveProfiles()
+		}
+		if err != nil {
+			log.Fatalf("Could not list profiles:\n%v", err)
+		}
+		err = WriteProfiles(os.Stdout, profiles)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+}
+
+// discoverFolders merges the custom known folders registered in the SOFTWARE
+// hive given by --software-hive or of the image given by --image-root, or
+// otherwise the live registry (if there is one), into knownfolders.
+func discoverFolders(arguments map[string]interface{}) {
+	_, hive := arguments["--software-hive"].(string)
+	_, image := arguments["--image-root"].(string)
+	var software RegistryKey
+	var err error
+	if hive || image {
+		software, err = softwareKey(arguments)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	} else {
+		software, err = liveSoftwareKey()
+		if err != nil {
+			log.Printf("Could not discover registered known folders:\n%v", err)
+			return
+		}
+		if software == nil {
+			return
+		}
+	}
+	defer software.Close()
+	descriptions, err := ReadFolderDescriptions(software)
+	if err != nil {
+		log.Printf("Could not discover registered known folders:\n%v", err)
+		return
+	}
+	for _, clash := range MergeFolderDescriptions(descriptions) {
+		log.Print(clash)
+	}
+}
+
+// softwareKey opens the SOFTWARE hive file given by --software-hive or of the
+// image given by --image-root, or otherwise HKLM\SOFTWARE of the live
+// registry. The key must be closed once it is no longer needed.
+func softwareKey(arguments map[string]interface{}) (RegistryKey, error) {
+	if file, ok := arguments["--software-hive"].(string); ok {
+		return openHiveRoot(file)
+	}
+	if root, ok := arguments["--image-root"].(string); ok {
+		image, err := OpenWindowsImage(root)
+		if err != nil {
+			return nil, err
+		}
+		return image.Software.ClosingRoot(), nil
+	}
+	software, err := liveSoftwareKey()
+	if err != nil {
+		return nil, err
+	}
+	if software == nil {
+		return nil, fmt.Errorf("There is no live registry on %v, use --software-hive", runtime.GOOS)
+	}
+	return software, nil
+}
+
+// folderTree returns the hierarchy of known folders described in the SOFTWARE
+// hive given by --software-hive, or otherwise the live registry.
+func folderTree(arguments map[string]interface{}) ([]*FolderNode, error) {
+	software, err := softwareKey(arguments)
+	if err != nil {
+		return nil, err
+	}
+	defer software.Close()
+	descriptions, err := ReadFolderDescriptions(software)
+	if err != nil {
+		return nil, err
+	}
+	return BuildFolderTree(descriptions), nil
+}
+
+// libraryFile returns the path of the .library-ms file given by LIBRARY,
+// which is either the path itself, or a library folder, whose location is
+// retrieved for the user given on the command line. logoff must be called
+// once the file is no longer needed.
+func libraryFile(arguments map[string]interface{}) (file string, logoff func()) {
+	name := arguments["LIBRARY"].(string)
+	if strings.HasSuffix(strings.ToLower(name), ".library-ms") {
+		return name, func() {}
+	}
+	folder, ok := resolveFolder(name)
+	if !ok {
+		log.Fatalf(`Unknown folder "%v"`, name)
+	}
+	backend, logoff, err := targetBackend(arguments)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	file, err = backend.GetFolder(folder)
+	if err != nil {
+		logoff()
+		log.Fatalf("Could not retrieve folder %v:\n%v", folder, err)
+	}
+	if !strings.HasSuffix(strings.ToLower(file), ".library-ms") {
+		logoff()
+		log.Fatalf("%v is not a library (its location is %v)", folder, file)
+	}
+	return file, logoff
+}
+
+// updateDesktopINI writes or merges the desktop.ini file in location, so that
+// Explorer shows it with the name and icon of folder.
+func updateDesktopINI(folder, location string) error {
+	description := discoveredFolders[folder]
+	if description == nil {
+		// the names and icons of built-in folders are only in the registry
+		description = &FolderDescription{GUID: *knownfolders[folder]}
+		if software, err := liveSoftwareKey(); err == nil && software != nil {
+			defer software.Close()
+			descriptions, err := ReadFolderDescriptions(software)
+			if err != nil {
+				return err
+			}
+			for i := range descriptions {
+				if descriptions[i].GUID == description.GUID {
+					description = &descriptions[i]
+				}
+			}
+		}
+	}
+	existing, err := ioutil.ReadFile(filepath.Join(location, "desktop.ini"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return writeDesktopINI(location, NewDesktopINI(*description).Merge(existing))
+}
+
+// warnFollowers logs which folders will move along with folder when it is
+// set, since they are defined relative to it and haven't been redirected.
+// Nothing is logged if the SOFTWARE hive of the target isn't available.
+func warnFollowers(arguments map[string]interface{}, folder string, backend Backend) {
+	software, err := softwareKey(arguments)
+	if err != nil {
+		return
+	}
+	defer software.Close()
+	descriptions, err := ReadFolderDescriptions(software)
+	if err != nil {
+		return
+	}
+	node := FindFolderNode(BuildFolderTree(descriptions), folder)
+	if node == nil {
+		return
+	}
+	node.Locate(backend.GetFolder)
+	if followers := node.Followers(); len(followers) > 0 {
+		log.Printf("Setting %v also moves %v, which are located relative to it", folder, strings.Join(followers, ", "))
+	}
+}
+
+// offlineBackend returns the backend for --wine-prefix or --image-root, which
+// work the same on every platform, with ok false if neither is given. close
+// must be called once the backend is no longer needed.
+func offlineBackend(arguments map[string]interface{}) (backend Backend, close func(), ok bool, err error) {
+	if prefix, ok := arguments["--wine-prefix"].(string); ok {
+		return &WineBackend{Prefix: prefix}, func() {}, true, nil
+	}
+	if root, ok := arguments["--image-root"].(string); ok {
+		profile, _ := arguments["--profile"].(string)
+		force, _ := arguments["--force"].(bool)
+		image, err := NewImageBackend(root, profile, force)
+		if err != nil {
+			return nil, nil, true, err
+		}
+		return image, func() { image.Close() }, true, nil
+	}
+	return nil, nil, false, nil
+}
+
+// writeRegistryChanges makes changes to the registry, or with --dry-run,
+// outputs them as a .reg file.
+func writeRegistryChanges(arguments map[string]interface{}, changes []RegistryChange) {
+	var err error
+	if arguments["--dry-run"].(bool) {
+		err = WriteRegFile(os.Stdout, changes)
+	} else {
+		err = applyRegistryChanges(changes)
+	}
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+// auditLog returns the audit log configured with --audit-log or
+// KNOWNFOLDER_AUDIT_LOG, for command, or nil if there is none.
+func auditLog(arguments map[string]interface{}, command string) *AuditLog {
+	file, _ := arguments["--audit-log"].(string)
+	if file == "" {
+		file = os.Getenv("KNOWNFOLDER_AUDIT_LOG")
+	}
+	if file == "" {
+		return nil
+	}
+	return &AuditLog{File: file, Command: command}
+}
+
+// auditBackend returns backend, whose target is described by scope, wrapped
+// in an AuditedBackend if an audit log has been configured, otherwise
+// backend itself.
+func auditBackend(arguments map[string]interface{}, command, scope string, backend Backend) Backend {
+	audit := auditLog(arguments, command)
+	if audit == nil {
+		return backend
+	}
+	return &AuditedBackend{Backend: backend, Log: audit, Scope: scope}
+}
+
+// loadHooks returns the hooks configured with --hooks or KNOWNFOLDER_HOOKS,
+// or nil if there are none.
+func loadHooks(arguments map[string]interface{}) *Hooks {
+	file, _ := arguments["--hooks"].(string)
+	if file == "" {
+		file = os.Getenv("KNOWNFOLDER_HOOKS")
+	}
+	if file == "" {
+		return nil
+	}
+	hooks, err := LoadHooks(file)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	return hooks
+}
+
+// hookBackend returns backend wrapped in a HookedBackend running hooks for
+// changes to the folders of target, if there are any, otherwise backend
+// itself.
+func hookBackend(hooks *Hooks, target, username string, backend Backend) Backend {
+	if hooks == nil {
+		return backend
+	}
+	return &HookedBackend{Backend: backend, Hooks: hooks, Target: target, Username: username}
+}
+
+// applyHooks returns the ApplyHooks running hooks around an apply of the
+// manifest file manifest for the user username, if there are any hooks,
+// otherwise nil.
+func applyHooks(hooks *Hooks, username, manifest string) *ApplyHooks {
+	if hooks == nil {
+		return nil
+	}
+	return &ApplyHooks{Hooks: hooks, Username: username, Manifest: manifest}
+}
+
+// targetUsername returns the name of the user whose folders backend, as
+// returned by targetBackend, gets and sets, without any domain, e.g.
+// "Default" for -d.
+func targetUsername(arguments map[string]interface{}, backend Backend) string {
+	if image, ok := backend.(*ImageBackend); ok {
+		return ProfileUsername(image.Profile)
+	}
+	if defaultUser, _ := arguments["-d"].(bool); defaultUser {
+		return "Default"
+	}
+	if otherUser, _ := arguments["-u"].(bool); otherUser {
+		return arguments["USERNAME"].(string)
+	}
+	username := currentUsername()
+	return username[strings.LastIndex(username, `\`)+1:]
+}
+
+// targetScope describes the target that targetBackend returns a backend
+// for, e.g. "default user".
+func targetScope(arguments map[string]interface{}) string {
+	if defaultUser, _ := arguments["-d"].(bool); defaultUser {
+		return "default user"
+	}
+	if otherUser, _ := arguments["-u"].(bool); otherUser {
+		return "user " + arguments["USERNAME"].(string)
+	}
+	if prefix, ok := arguments["--wine-prefix"].(string); ok {
+		return "wine prefix " + prefix
+	}
+	if root, ok := arguments["--image-root"].(string); ok {
+		profile, _ := arguments["--profile"].(string)
+		if profile == "" {
+			profile = defaultProfileName
+		}
+		return "image " + root + " profile " + profile
+	}
+	return "current user"
+}
+
+// profileScope describes profile p of the target of --all-profiles, as
+// targetScope does, e.g. "user alice".
+func profileScope(arguments map[string]interface{}, p Profile) string {
+	if root, ok := arguments["--image-root"].(string); ok {
+		return "image " + root + " profile " + ProfileName(p)
+	}
+	if p.SID == "" {
+		return "default user"
+	}
+	return "user " + ProfileUsername(p)
+}
+
+// targetOptions returns the options which select the same target as
+// arguments, for messages telling the user what to run, with any password
+// left as PASSWORD.
+func targetOptions(arguments map[string]interface{}) string {
+	if defaultUser, _ := arguments["-d"].(bool); defaultUser {
+		return "-d"
+	}
+	if otherUser, _ := arguments["-u"].(bool); otherUser {
+		return fmt.Sprintf("-u %q -p PASSWORD", arguments["USERNAME"])
+	}
+	if prefix, ok := arguments["--wine-prefix"].(string); ok {
+		return fmt.Sprintf("--wine-prefix %q", prefix)
+	}
+	if root, ok := arguments["--image-root"].(string); ok {
+		options := fmt.Sprintf("--image-root %q", root)
+		if profile, ok := arguments["--profile"].(string); ok {
+			options += fmt.Sprintf(" --profile %q", profile)
+		}
+		return options
+	}
+	return ""
+}
+
+// profileTargetOptions returns the options which select profile p of the
+// target of --all-profiles on its own, as targetOptions does.
+func profileTargetOptions(arguments map[string]interface{}, p Profile) string {
+	if root, ok := arguments["--image-root"].(string); ok {
+		return fmt.Sprintf("--image-root %q --profile %q", root, ProfileName(p))
+	}
+	if p.SID == "" {
+		return "-d"
+	}
+	return fmt.Sprintf("-u %q -p PASSWORD", ProfileUsername(p))
+}
+
+// lockTimeout returns how long to wait for the lock on a target, given by
+// --lock-timeout, or 0 for --no-wait.
+func lockTimeout(arguments map[string]interface{}) time.Duration {
+	if arguments["--no-wait"].(bool) {
+		return 0
+	}
+	timeout, err := time.ParseDuration(arguments["--lock-timeout"].(string))
+	if err != nil || timeout < 0 {
+		log.Fatalf(`Invalid lock timeout "%v"`, arguments["--lock-timeout"])
+	}
+	return timeout
+}
+
+// lockTarget takes the lock on scope, exiting if another process holds it
+// for longer than lockTimeout. It must be released once the target has been
+// changed; the operating system releases it if the process exits first.
+func lockTarget(arguments map[string]interface{}, scope string) *Lock {
+	lock, err := AcquireLock(journalDir(), scope, currentLockHolder(), lockTimeout(arguments))
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	return lock
+}
+
+// targetLocker returns a function which takes the lock on the target of
+// arguments, waiting up to lockTimeout, and returns a function releasing it,
+// for commands which change the target from time to time.
+func targetLocker(arguments map[string]interface{}) func() (unlock func(), err error) {
+	timeout, scope := lockTimeout(arguments), lockScope(arguments)
+	return func() (func(), error) {
+		lock, err := AcquireLock(journalDir(), scope, currentLockHolder(), timeout)
+		if err != nil {
+			return nil, err
+		}
+		return func() { lock.Release() }, nil
+	}
+}
+
+// lockScope returns the scope of the lock on the target of arguments. Unlike
+// targetScope, it is the same however the target is given, e.g. for the
+// current user and -u with their username, or for every profile of an image.
+func lockScope(arguments map[string]interface{}) string {
+	if defaultUser, _ := arguments["-d"].(bool); defaultUser {
+		return "default user"
+	}
+	if otherUser, _ := arguments["-u"].(bool); otherUser {
+		return userLockScope(arguments["USERNAME"].(string))
+	}
+	if prefix, ok := arguments["--wine-prefix"].(string); ok {
+		return "wine prefix " + absolutePath(prefix)
+	}
+	if root, ok := arguments["--image-root"].(string); ok {
+		return "image " + absolutePath(root)
+	}
+	return userLockScope(currentUsername())
+}
+
+// userLockScope returns the lock scope of a user, ignoring any domain and
+// case.
+func userLockScope(username string) string {
+	return "user " + strings.ToLower(username[strings.LastIndex(username, `\`)+1:])
+}
+
+// profileLockScope returns the lock scope of a live profile, which for the
+// Default profile is that of -d.
+func profileLockScope(p Profile) string {
+	if p.SID == "" {
+		return "default user"
+	}
+	return userLockScope(ProfileUsername(p))
+}
+
+// absolutePath returns path made absolute, if possible.
+func absolutePath(path string) string {
+	if absolute, err := filepath.Abs(path); err == nil {
+		return absolute
+	}
+	return path
+}
+
+// journalDir returns the directory that journals are kept in.
+func journalDir() string {
+	if dir := os.Getenv("KNOWNFOLDER_JOURNAL_DIR"); dir != "" {
+		return dir
+	}
+	return defaultJournalDir()
+}
+
+// warnIncompleteJournals logs a warning for each apply which was interrupted
+// and hasn't been recovered, other than the one journaled in except.
+func warnIncompleteJournals(except string) {
+	journals, err := IncompleteJournals(journalDir())
+	if err != nil {
+		log.Printf("Could not check for interrupted applies:\n%v", err)
+		return
+	}
+	for _, j := range journals {
+		if j.File != except {
+			log.Printf("Warning: %v", IncompleteJournalError{j})
+		}
+	}
+}
+
+func ListFolders() (err error) {
+	keys := make([]string, 0, len(knownfolders))
+	for key := range knownfolders {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		d := discoveredFolders[key]
+		if d == nil {
+			fmt.Println(key)
+			continue
+		}
+		location := d.RelativePath
+		if d.Parent != "" {
+			parent, ok := folderByGUID(d.Parent)
+			if !ok {
+				parent = d.Parent
+			}
+			location = parent + `\` + location
+		}
+		details := []string{d.GUID.String(), d.CategoryName()}
+		if location != "" {
+			details = append(details, location)
+		}
+		fmt.Printf("%v (discovered: %v)\n", key, strings.Join(details, ", "))
+	}
+	return
+}
+
+func ListAliases() (err error) {
+	for _, c := range csidls {
+		fmt.Printf("%v=%v\n", c.Name, c.Folder)
+		fmt.Printf("%v=%v\n", c.Value, c.Folder)
+	}
+	keys := make([]string, 0, len(knownfolders))
+	for key := range knownfolders {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Printf("FOLDERID_%v=%v\n", key, key)
+	}
+	for _, a := range knownfolderAliases {
+		fmt.Printf("%v=%v\n", a.Name, a.Folder)
+		fmt.Printf("FOLDERID_%v=%v\n", a.Name, a.Folder)
+	}
+	for _, a := range nameAliases {
+		fmt.Printf("%v=%v\n", a.Name, a.Folder)
+	}
+	return
+}
@@ -3,10 +3,82 @@
 package main
 
 import (
-	"log"
+	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
+	"syscall"
 )
 
-func main() {
-	log.Printf("knownfolder only runs on Windows (you are running on %v)", runtime.GOOS)
+// errNotWindows is returned for commands that need the live Windows APIs.
+var errNotWindows = fmt.Errorf("knownfolder can only access the live system on Windows (you are running on %v)", runtime.GOOS)
+
+// targetBackend returns a WineBackend for --wine-prefix or an ImageBackend
+// for --image-root, since those are the only targets knownfolder can access
+// on other platforms.
+func targetBackend(arguments map[string]interface{}) (Backend, func(), error) {
+	if backend, close, ok, err := offlineBackend(arguments); ok {
+		return backend, close, err
+	}
+	return nil, nil, errNotWindows
+}
+
+func logonBackend(user UserCredentials) (Backend, func(), error) {
+	return nil, nil, errNotWindows
+}
+
+func liveProfiles() ([]Profile, error) {
+	return nil, fmt.Errorf("knownfolder can only list the profiles of a live system on Windows (you are running on %v), use --software-hive", runtime.GOOS)
+}
+
+// liveSoftwareKey returns nil, since there is no live registry to read.
+func liveSoftwareKey() (RegistryKey, error) {
+	return nil, nil
+}
+
+func applyRegistryChanges(changes []RegistryChange) error {
+	return errNotWindows
+}
+
+// longPathName returns path, since short names can only be expanded on
+// Windows.
+func longPathName(path string) string {
+	return path
+}
+
+func writeDesktopINI(dir string, data []byte) error {
+	return errNotWindows
+}
+
+func openProfileHive(p Profile, file string) RegistryKey {
+	return openProfileHiveFile(file)
+}
+
+func profileBackend(p Profile, file string, descriptions []FolderDescription) (Backend, func() error, error) {
+	return nil, nil, errNotWindows
+}
+
+// defaultJournalDir is $XDG_STATE_HOME/knownfolder, or
+// ~/.local/state/knownfolder if XDG_STATE_HOME isn't set.
+func defaultJournalDir() string {
+	if state := os.Getenv("XDG_STATE_HOME"); state != "" {
+		return filepath.Join(state, "knownfolder")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".local", "state", "knownfolder")
+}
+
+// syncDir syncs the entries of dir to disk, so that a file just renamed into
+// it survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	err = d.Sync()
+	if pathErr, ok := err.(*os.PathError); ok && pathErr.Err == syscall.EINVAL {
+		// the file system can't sync directories
+		return nil
+	}
+	return err
 }
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"unicode/utf16"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares got with the golden file testdata/name, or writes got
+// to it when run with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	file := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(file, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%v differs from golden file %v (run go test -update if the change is intended):\n%s", name, file, got)
+	}
+}
+
+// fakeBackend is a Backend keeping folder locations in memory, which records
+// every SetFolder call in Calls, as "FOLDER=LOCATION". Setting a folder in
+// Errors fails with its error, and OnSet, if not nil, is called before each
+// SetFolder, e.g. to simulate a crash by panicking.
+type fakeBackend struct {
+	mutex   sync.Mutex
+	Folders map[string]string
+	Errors  map[string]error
+	Calls   []string
+	OnSet   func(folder, location string)
+}
+
+func newFakeBackend(folders map[string]string) *fakeBackend {
+	b := &fakeBackend{Folders: map[string]string{}, Errors: map[string]error{}}
+	for folder, location := range folders {
+		b.Folders[folder] = location
+	}
+	return b
+}
+
+func (b *fakeBackend) GetFolder(folder string) (string, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	location, ok := b.Folders[folder]
+	if !ok {
+		return "", fmt.Errorf("Folder %v has no location", folder)
+	}
+	return location, nil
+}
+
+func (b *fakeBackend) SetFolder(folder, location string) error {
+	if b.OnSet != nil {
+		b.OnSet(folder, location)
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.Calls = append(b.Calls, folder+"="+location)
+	if err := b.Errors[folder]; err != nil {
+		return err
+	}
+	b.Folders[folder] = location
+	return nil
+}
+
+// testKey is a registry key for writeTestHive. Its entries are subkeys
+// (testKey) and values: string (REG_SZ), expandString (REG_EXPAND_SZ) and
+// uint32 (REG_DWORD).
+type testKey map[string]interface{}
+
+type expandString string
+
+// writeTestHive writes a minimal regf hive file of root to file, in a single
+// hive bin, and returns file.
+func writeTestHive(t *testing.T, file string, root testKey) string {
+	t.Helper()
+	var cells []byte
+	// cell offsets are relative to the start of the hive bin, whose header
+	// is 0x20 bytes
+	allocate := func(data []byte) uint32 {
+		size := (len(data) + 4 + 7) &^ 7
+		offset := uint32(0x20 + len(cells))
+		cell := make([]byte, size)
+		le.PutUint32(cell, uint32(-int32(size)))
+		copy(cell[4:], data)
+		cells = append(cells, cell...)
+		return offset
+	}
+	set := func(offset uint32, field int, v uint32) {
+		le.PutUint32(cells[int(offset)-0x20+4+field:], v)
+	}
+	addValue := func(name string, value interface{}) uint32 {
+		var valueType uint32
+		var data []byte
+		switch v := value.(type) {
+		case string:
+			valueType, data = REG_SZ, utf16Bytes(v)
+		case expandString:
+			valueType, data = REG_EXPAND_SZ, utf16Bytes(string(v))
+		case uint32:
+			valueType, data = REG_DWORD, make([]byte, 4)
+			le.PutUint32(data, v)
+		default:
+			t.Fatalf("Unsupported test hive value %v=%#v", name, value)
+		}
+		vk := make([]byte, 0x14+len(name))
+		copy(vk, "vk")
+		le.PutUint16(vk[2:], uint16(len(name)))
+		if len(data) <= 4 {
+			le.PutUint32(vk[4:], uint32(len(data))|0x80000000)
+			copy(vk[8:12], data)
+		} else {
+			le.PutUint32(vk[4:], uint32(len(data)))
+			le.PutUint32(vk[8:], allocate(data))
+		}
+		le.PutUint32(vk[0xC:], valueType)
+		le.PutUint16(vk[0x10:], valueCompressedName)
+		copy(vk[0x14:], name)
+		return allocate(vk)
+	}
+	var addKey func(name string, key testKey, parent uint32, flags uint16) uint32
+	addKey = func(name string, key testKey, parent uint32, flags uint16) uint32 {
+		nk := make([]byte, 0x4C+len(name))
+		copy(nk, "nk")
+		le.PutUint16(nk[2:], flags|keyCompressedName)
+		le.PutUint32(nk[0x10:], parent)
+		for _, field := range []int{0x1C, 0x20, 0x28, 0x2C, 0x30} {
+			le.PutUint32(nk[field:], noCell)
+		}
+		le.PutUint16(nk[0x48:], uint16(len(name)))
+		copy(nk[0x4C:], name)
+		offset := allocate(nk)
+		names := make([]string, 0, len(key))
+		for n := range key {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		subkeys, values := []uint32{}, []uint32{}
+		for _, n := range names {
+			if subkey, ok := key[n].(testKey); ok {
+				subkeys = append(subkeys, addKey(n, subkey, offset, 0))
+			} else {
+				values = append(values, addValue(n, key[n]))
+			}
+		}
+		if len(subkeys) > 0 {
+			li := make([]byte, 4+4*len(subkeys))
+			copy(li, "li")
+			le.PutUint16(li[2:], uint16(len(subkeys)))
+			for i, subkey := range subkeys {
+				le.PutUint32(li[4+4*i:], subkey)
+			}
+			set(offset, 0x14, uint32(len(subkeys)))
+			set(offset, 0x1C, allocate(li))
+		}
+		if len(values) > 0 {
+			list := make([]byte, 4*len(values))
+			for i, value := range values {
+				le.PutUint32(list[4*i:], value)
+			}
+			set(offset, 0x24, uint32(len(values)))
+			set(offset, 0x28, allocate(list))
+		}
+		return offset
+	}
+	// KEY_HIVE_ENTRY | KEY_NO_DELETE
+	rootOffset := addKey("ROOT", root, 0, 0x0C)
+	binSize := (0x20 + len(cells) + 0xFFF) &^ 0xFFF
+	bin := make([]byte, binSize)
+	copy(bin, "hbin")
+	le.PutUint32(bin[8:], uint32(binSize))
+	copy(bin[0x20:], cells)
+	if free := binSize - 0x20 - len(cells); free > 0 {
+		le.PutUint32(bin[0x20+len(cells):], uint32(free))
+	}
+	base := make([]byte, hiveBinsOffset)
+	copy(base, "regf")
+	for i, v := range []uint32{1, 1} {
+		le.PutUint32(base[4+4*i:], v)
+	}
+	for i, v := range []uint32{1, 5, 0, 1, rootOffset, uint32(binSize), 1} {
+		le.PutUint32(base[0x14+4*i:], v)
+	}
+	checksum := uint32(0)
+	for i := 0; i < 0x1FC; i += 4 {
+		checksum ^= le.Uint32(base[i:])
+	}
+	le.PutUint32(base[0x1FC:], checksum)
+	if err := ioutil.WriteFile(file, append(base, bin...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func utf16Bytes(s string) []byte {
+	units := utf16.Encode([]rune(s + "\x00"))
+	data := make([]byte, 2*len(units))
+	for i, u := range units {
+		le.PutUint16(data[2*i:], u)
+	}
+	return data
+}
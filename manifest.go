@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"text/template"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Manifest describes a desired set of known folder locations, e.g.
+//
+//	folders:
+//	  LocalAppData: 'D:\AppData\Local'
+//	  RoamingAppData: 'D:\AppData\Roaming'
+//
+// Folders may be given by any name accepted on the command line, including
+// aliases. Locations may refer to other folders, whether or not they are in
+// the manifest, and to variables, e.g.
+//
+//	variables:
+//	  Data: 'D:\Data'
+//	folders:
+//	  Profile: '{Data}\Profile'
+//	  Downloads: '{Profile}\dl'
+//	  LocalAppDataLow: '{LocalAppData}Low'
+type Manifest struct {
+	Variables map[string]string `yaml:"variables,omitempty"`
+	Folders   map[string]string `yaml:"folders"`
+}
+
+// ManifestEntry is a single folder location of a Manifest, with the folder
+// name resolved to its knownfolders name.
+type ManifestEntry struct {
+	Folder   string
+	Location string
+}
+
+// LoadManifest reads the YAML manifest file at the given path.
+func LoadManifest(file string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	m := new(Manifest)
+	err = yaml.UnmarshalStrict(data, m)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse manifest %v:\n%v", file, err)
+	}
+	if err = m.validate(); err != nil {
+		return nil, fmt.Errorf("Invalid manifest %v:\n%v", file, err)
+	}
+	return m, nil
+}
+
+// validate checks the folders and variables of the manifest, and that the
+// references between them can be expanded, without looking up the locations
+// of any other folders.
+func (m *Manifest) validate() error {
+	entries, err := m.Entries()
+	if err != nil {
+		return err
+	}
+	for name := range m.Variables {
+		if !referencePattern.MatchString("{" + name + "}") {
+			return fmt.Errorf(`Invalid variable name "%v"`, name)
+		}
+		if folder, ok := resolveFolder(name); ok {
+			return fmt.Errorf(`Variable "%v" has the same name as folder %v`, name, folder)
+		}
+	}
+	_, err = m.Expand(entries, func(folder string) (string, error) { return "", nil })
+	return err
+}
+
+// Entries returns the manifest folder locations, sorted by folder name. It
+// is an error for a folder to be unknown, or listed twice (e.g. via an
+// alias).
+func (m *Manifest) Entries() ([]ManifestEntry, error) {
+	entries := make([]ManifestEntry, 0, len(m.Folders))
+	seen := map[string]string{}
+	for name, location := range m.Folders {
+		folder, ok := resolveFolder(name)
+		if !ok {
+			return nil, UnknownFolderError(name)
+		}
+		if other, exists := seen[folder]; exists {
+			return nil, fmt.Errorf(`Folder %v is listed twice, as "%v" and "%v"`, folder, other, name)
+		}
+		if location == "" {
+			return nil, fmt.Errorf(`No location given for folder "%v"`, name)
+		}
+		seen[folder] = name
+		entries = append(entries, ManifestEntry{Folder: folder, Location: location})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Folder < entries[j].Folder })
+	return entries, nil
+}
+
+// TemplateData is available to manifest locations as Go template fields,
+// e.g. 'D:\tasks\{{.Username}}\AppData'.
+type TemplateData struct {
+	Username string
+}
+
+// Render returns the manifest entries, with each location executed as a Go
+// template against data.
+func (m *Manifest) Render(data TemplateData) ([]ManifestEntry, error) {
+	entries, err := m.Entries()
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		t, err := template.New(entry.Folder).Option("missingkey=error").Parse(entry.Location)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid location template for %v: %v", entry.Folder, err)
+		}
+		var b bytes.Buffer
+		if err := t.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("Could not expand location template for %v: %v", entry.Folder, err)
+		}
+		entries[i].Location = b.String()
+	}
+	return entries, nil
+}
+
+// Expand returns entries with their references to other folders and
+// manifest variables expanded, in dependency order. lookup is used for
+// folders that aren't in entries.
+func (m *Manifest) Expand(entries []ManifestEntry, lookup FolderLookup) ([]ManifestEntry, error) {
+	return ExpandReferences(entries, m.Variables, lookup)
+}
+
+// WriteManifest writes manifest to w in YAML format.
+func WriteManifest(w io.Writer, manifest *Manifest) error {
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// profileListKey is the key of the SOFTWARE hive listing local user profiles,
+// with a subkey per profile, named after the profile owner's SID.
+const profileListKey = `Microsoft\Windows NT\CurrentVersion\ProfileList`
+
+// Profile is a local user profile, as recorded in the ProfileList registry
+// key.
+type Profile struct {
+	SID string
+	// Account is the DOMAIN\name of the profile owner, or "" if it could not
+	// be resolved.
+	Account string
+	// Path is the ProfileImagePath of the profile, with any environment
+	// variables unexpanded.
+	Path string
+	// State is the profile State value, see profileStates.
+	State uint32
+	// Loaded reports whether the profile's user hive is currently loaded,
+	// and is nil if this isn't known, e.g. when reading an offline hive.
+	Loaded *bool
+}
+
+// profileStates are the documented bits of the State value of a
+// ProfileList entry.
+var profileStates = []struct {
+	Bit  uint32
+	Name string
+}{
+	{0x00001, "PROFILE_MANDATORY"},
+	{0x00002, "PROFILE_USE_CACHE"},
+	{0x00004, "PROFILE_NEW_LOCAL"},
+	{0x00008, "PROFILE_NEW_CENTRAL"},
+	{0x00010, "PROFILE_UPDATE_CENTRAL"},
+	{0x00020, "PROFILE_DELETE_CACHE"},
+	{0x00040, "PROFILE_UPGRADE"},
+	{0x00080, "PROFILE_GUEST_USER"},
+	{0x00100, "PROFILE_ADMIN_USER"},
+	{0x00200, "DEFAULT_NET_READY"},
+	{0x00400, "PROFILE_SLOW_LINK"},
+	{0x00800, "PROFILE_TEMP_ASSIGNED"},
+	{0x08000, "PROFILE_PARTLY_LOADED"},
+	{0x10000, "PROFILE_BACKUP_EXISTS"},
+	{0x20000, "PROFILE_THIS_IS_BAK"},
+}
+
+// wellKnownAccounts are the accounts of SIDs which have profiles on every
+// installation, so can be resolved even offline.
+var wellKnownAccounts = map[string]string{
+	"S-1-5-18": `NT AUTHORITY\SYSTEM`,
+	"S-1-5-19": `NT AUTHORITY\LOCAL SERVICE`,
+	"S-1-5-20": `NT AUTHORITY\NETWORK SERVICE`,
+}
+
+// ReadProfileList returns the profiles listed under the ProfileList key of
+// software, the root of a SOFTWARE hive. Accounts are only resolved for well
+// known SIDs.
+func ReadProfileList(software RegistryKey) ([]Profile, error) {
+	list, err := software.OpenKey(profileListKey)
+	if err != nil {
+		return nil, err
+	}
+	defer list.Close()
+	sids, err := list.SubkeyNames()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(sids)
+	profiles := make([]Profile, 0, len(sids))
+	for _, sid := range sids {
+		profile, err := readProfile(list, sid)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read profile %v:\n%v", sid, err)
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+func readProfile(list RegistryKey, sid string) (profile Profile, err error) {
+	key, err := list.OpenKey(sid)
+	if err != nil {
+		return
+	}
+	defer key.Close()
+	profile = Profile{
+		SID:     sid,
+		Account: wellKnownAccounts[profileSID(sid)],
+	}
+	// both values are missing from partially created profiles
+	profile.Path, err = stringValue(key, "ProfileImagePath")
+	if err != nil && !isRegistryNotFound(err) {
+		return
+	}
+	profile.State, err = dwordValue(key, "State")
+	if err != nil && !isRegistryNotFound(err) {
+		return
+	}
+	return profile, nil
+}
+
+// profileSID returns the SID of a ProfileList subkey name, which has a ".bak"
+// suffix for backup copies of a profile's entry.
+func profileSID(name string) string {
+	return strings.TrimSuffix(name, ".bak")
+}
+
+// ProfileStateNames returns the names of the bits set in a profile State
+// value, with any undocumented bits in hex.
+func ProfileStateNames(state uint32) []string {
+	names := []string{}
+	for _, s := range profileStates {
+		if state&s.Bit != 0 {
+			names = append(names, s.Name)
+			state &^= s.Bit
+		}
+	}
+	if state != 0 {
+		names = append(names, fmt.Sprintf("0x%x", state))
+	}
+	return names
+}
+
+// WriteProfiles writes profiles to w as a table.
+func WriteProfiles(w io.Writer, profiles []Profile) error {
+	t := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(t, "SID\tACCOUNT\tLOADED\tSTATE\tPROFILEIMAGEPATH")
+	for _, p := range profiles {
+		account := p.Account
+		if account == "" {
+			account = "-"
+		}
+		loaded := "-"
+		if p.Loaded != nil {
+			loaded = "no"
+			if *p.Loaded {
+				loaded = "yes"
+			}
+		}
+		state := fmt.Sprintf("0x%x", p.State)
+		if names := ProfileStateNames(p.State); len(names) > 0 {
+			state += " (" + strings.Join(names, ",") + ")"
+		}
+		fmt.Fprintf(t, "%v\t%v\t%v\t%v\t%v\n", p.SID, account, loaded, state, p.Path)
+	}
+	return t.Flush()
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestReadProfileList(t *testing.T) {
+	file := writeTestHive(t, filepath.Join(t.TempDir(), "SOFTWARE"), testKey{
+		"Microsoft": testKey{"Windows NT": testKey{"CurrentVersion": testKey{"ProfileList": testKey{
+			"Default":                 expandString(`%SystemDrive%\Users\Default`),
+			"S-1-5-18":                testKey{"ProfileImagePath": expandString(`%systemroot%\system32\config\systemprofile`), "State": uint32(0)},
+			"S-1-5-20.bak":            testKey{"ProfileImagePath": expandString(`C:\Windows\ServiceProfiles\NetworkService`), "State": uint32(0x20000)},
+			"S-1-5-21-1-2-3-1001":     testKey{"ProfileImagePath": expandString(`C:\Users\alice`), "State": uint32(0x100)},
+			"S-1-5-21-1-2-3-1003":     testKey{"ProfileImagePath": expandString(`C:\Users\TEMP`), "State": uint32(0x40800)},
+			"S-1-5-21-1-2-3-1004":     testKey{},
+			"S-1-5-21-1-2-3-1001.tmp": testKey{"ProfileImagePath": expandString(`C:\Users\alice.tmp`)},
+		}}}},
+	})
+	software, err := openHiveRoot(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer software.Close()
+	profiles, err := ReadProfileList(software)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Profile{
+		{SID: "S-1-5-18", Account: `NT AUTHORITY\SYSTEM`, Path: `%systemroot%\system32\config\systemprofile`},
+		{SID: "S-1-5-20.bak", Account: `NT AUTHORITY\NETWORK SERVICE`, Path: `C:\Windows\ServiceProfiles\NetworkService`, State: 0x20000},
+		{SID: "S-1-5-21-1-2-3-1001", Path: `C:\Users\alice`, State: 0x100},
+		{SID: "S-1-5-21-1-2-3-1001.tmp", Path: `C:\Users\alice.tmp`},
+		{SID: "S-1-5-21-1-2-3-1003", Path: `C:\Users\TEMP`, State: 0x40800},
+		// partially created
+		{SID: "S-1-5-21-1-2-3-1004"},
+	}
+	if !reflect.DeepEqual(profiles, want) {
+		t.Errorf("Read profiles\n%+v\nwant\n%+v", profiles, want)
+	}
+	var b bytes.Buffer
+	loaded, unloaded := true, false
+	profiles[2].Loaded, profiles[4].Loaded = &loaded, &unloaded
+	if err := WriteProfiles(&b, profiles); err != nil {
+		t.Fatal(err)
+	}
+	table := `SID                      ACCOUNT                       LOADED  STATE                                    PROFILEIMAGEPATH
+S-1-5-18                 NT AUTHORITY\SYSTEM           -       0x0                                      %systemroot%\system32\config\systemprofile
+S-1-5-20.bak             NT AUTHORITY\NETWORK SERVICE  -       0x20000 (PROFILE_THIS_IS_BAK)            C:\Windows\ServiceProfiles\NetworkService
+S-1-5-21-1-2-3-1001      -                             yes     0x100 (PROFILE_ADMIN_USER)               C:\Users\alice
+S-1-5-21-1-2-3-1001.tmp  -                             -       0x0                                      C:\Users\alice.tmp
+S-1-5-21-1-2-3-1003      -                             no      0x40800 (PROFILE_TEMP_ASSIGNED,0x40000)  C:\Users\TEMP
+S-1-5-21-1-2-3-1004      -                             -       0x0
+`
+	// the table is padded to the last column, even where it is empty
+	got := regexp.MustCompile(` +\n`).ReplaceAllString(b.String(), "\n")
+	if got != table {
+		t.Errorf("Wrote\n%v\nwant\n%v", got, table)
+	}
+}
+
+func TestReadProfileListErrors(t *testing.T) {
+	file := writeTestHive(t, filepath.Join(t.TempDir(), "SOFTWARE"), testKey{
+		"Microsoft": testKey{"Windows NT": testKey{"CurrentVersion": testKey{"ProfileList": testKey{
+			"S-1-5-21-1-2-3-1001": testKey{"ProfileImagePath": expandString(`C:\Users\alice`), "State": "0"},
+		}}}},
+	})
+	software, err := openHiveRoot(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer software.Close()
+	if _, err := ReadProfileList(software); err == nil {
+		t.Error("Read a profile whose State isn't a DWORD")
+	}
+	empty, err := openHiveRoot(writeTestHive(t, filepath.Join(t.TempDir(), "SOFTWARE"), testKey{"Microsoft": testKey{}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer empty.Close()
+	if _, err := ReadProfileList(empty); err == nil {
+		t.Error("Read profiles of a hive without a ProfileList")
+	}
+}
+
+func TestProfileStateNames(t *testing.T) {
+	for _, test := range []struct {
+		State uint32
+		Want  []string
+	}{
+		{0, []string{}},
+		{0x101, []string{"PROFILE_MANDATORY", "PROFILE_ADMIN_USER"}},
+		{0x800 | 0x80000000, []string{"PROFILE_TEMP_ASSIGNED", "0x80000000"}},
+	} {
+		if got := ProfileStateNames(test.State); !reflect.DeepEqual(got, test.Want) {
+			t.Errorf("ProfileStateNames(0x%x) is %q, want %q", test.State, got, test.Want)
+		}
+	}
+}
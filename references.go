@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// referencePattern matches references to other folders and manifest
+// variables in locations, e.g. {LocalAppData}. Other text in braces, such as
+// the GUIDs in shell namespace paths, doesn't match and is left alone.
+var referencePattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// FolderLookup returns the current location of a folder, e.g.
+// Backend.GetFolder.
+type FolderLookup func(folder string) (string, error)
+
+// reference is a folder or variable that a location can refer to.
+type reference struct {
+	Name     string
+	Variable bool
+}
+
+// expansion is the state of a single ExpandReferences call.
+type expansion struct {
+	locations map[string]string
+	variables map[string]string
+	lookup    FolderLookup
+	values    map[reference]string
+	active    map[reference]bool
+	order     []string
+}
+
+// ExpandReferences replaces the {Name} references in the locations of
+// entries with the location of the named folder, or the value of the named
+// manifest variable, which may contain references too. A variable hides a
+// folder of the same name. Folders which are in entries have the location
+// given there (itself expanded), and any others are looked up. The expanded
+// entries are returned in dependency order, i.e. each folder after those it
+// refers to, and otherwise in the order given.
+//
+// Cycles and references to unknown names are errors, which give the chain of
+// references that leads to them, e.g. "Downloads -> Profile -> Downloads".
+func ExpandReferences(entries []ManifestEntry, variables map[string]string, lookup FolderLookup) ([]ManifestEntry, error) {
+	x := &expansion{
+		locations: map[string]string{},
+		variables: variables,
+		lookup:    lookup,
+		values:    map[reference]string{},
+		active:    map[reference]bool{},
+	}
+	for _, entry := range entries {
+		x.locations[entry.Folder] = entry.Location
+	}
+	for _, entry := range entries {
+		if _, err := x.expand(reference{Name: entry.Folder}, nil); err != nil {
+			return nil, err
+		}
+	}
+	expanded := make([]ManifestEntry, len(x.order))
+	for i, folder := range x.order {
+		expanded[i] = ManifestEntry{Folder: folder, Location: x.values[reference{Name: folder}]}
+	}
+	return expanded, nil
+}
+
+// expand returns the value of r, where chain is the references followed to
+// get to it.
+func (x *expansion) expand(r reference, chain []string) (string, error) {
+	if value, done := x.values[r]; done {
+		return value, nil
+	}
+	chain = append(append([]string{}, chain...), r.Name)
+	if x.active[r] {
+		return "", fmt.Errorf("Cycle in location references: %v", strings.Join(chain, " -> "))
+	}
+	var template string
+	if r.Variable {
+		template = x.variables[r.Name]
+	} else if location, ok := x.locations[r.Name]; ok {
+		template = location
+	} else {
+		value, err := x.lookup(r.Name)
+		if err != nil {
+			return "", fmt.Errorf("Could not retrieve location of %v (via %v):\n%v", r.Name, strings.Join(chain, " -> "), err)
+		}
+		x.values[r] = value
+		return value, nil
+	}
+	x.active[r] = true
+	var b strings.Builder
+	last := 0
+	for _, m := range referencePattern.FindAllStringSubmatchIndex(template, -1) {
+		b.WriteString(template[last:m[0]])
+		last = m[1]
+		name := template[m[2]:m[3]]
+		next, ok := x.resolve(name)
+		if !ok {
+			return "", fmt.Errorf(`Unknown folder or variable "%v" in location references: %v -> %v`, name, strings.Join(chain, " -> "), name)
+		}
+		value, err := x.expand(next, chain)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(value)
+	}
+	b.WriteString(template[last:])
+	x.active[r] = false
+	x.values[r] = b.String()
+	if !r.Variable {
+		x.order = append(x.order, r.Name)
+	}
+	return b.String(), nil
+}
+
+// resolve returns what the name in a reference refers to.
+func (x *expansion) resolve(name string) (reference, bool) {
+	if _, ok := x.variables[name]; ok {
+		return reference{Name: name, Variable: true}, true
+	}
+	folder, ok := resolveFolder(name)
+	return reference{Name: folder}, ok
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandReferences(t *testing.T) {
+	lookup := newFakeBackend(map[string]string{
+		"Profile":      `C:\Users\pete`,
+		"LocalAppData": `C:\Users\pete\AppData\Local`,
+	}).GetFolder
+	for _, test := range []struct {
+		Name      string
+		Entries   []ManifestEntry
+		Variables map[string]string
+		Want      []ManifestEntry
+	}{
+		{
+			Name:    "looked up folder",
+			Entries: []ManifestEntry{{"Documents", `{Profile}\Docs`}},
+			Want:    []ManifestEntry{{"Documents", `C:\Users\pete\Docs`}},
+		},
+		{
+			Name:    "no references",
+			Entries: []ManifestEntry{{"Documents", `D:\Docs`}},
+			Want:    []ManifestEntry{{"Documents", `D:\Docs`}},
+		},
+		{
+			Name: "dependency order",
+			Entries: []ManifestEntry{
+				{"Music", `{Documents}\Music`},
+				{"Documents", `{Profile}\Docs`},
+				{"Videos", `D:\Videos`},
+			},
+			Want: []ManifestEntry{
+				{"Documents", `C:\Users\pete\Docs`},
+				{"Music", `C:\Users\pete\Docs\Music`},
+				{"Videos", `D:\Videos`},
+			},
+		},
+		{
+			Name: "manifest location rather than current one",
+			Entries: []ManifestEntry{
+				{"Profile", `D:\pete`},
+				{"Documents", `{Profile}\Docs`},
+			},
+			Want: []ManifestEntry{
+				{"Profile", `D:\pete`},
+				{"Documents", `D:\pete\Docs`},
+			},
+		},
+		{
+			Name:      "variables, which refer to folders and each other",
+			Entries:   []ManifestEntry{{"Documents", `{Data}\Docs`}},
+			Variables: map[string]string{"Data": `{Drive}\{Profile}`, "Drive": `D:`},
+			Want:      []ManifestEntry{{"Documents", `D:\C:\Users\pete\Docs`}},
+		},
+		{
+			Name:      "variable hides folder",
+			Entries:   []ManifestEntry{{"Documents", `{Profile}\Docs`}},
+			Variables: map[string]string{"Profile": `E:\pete`},
+			Want:      []ManifestEntry{{"Documents", `E:\pete\Docs`}},
+		},
+		{
+			Name:    "GUID and FOLDERID_ references",
+			Entries: []ManifestEntry{{"Documents", `{FOLDERID_LocalAppData}\{F1B32785-6FBA-4FCF-9D55-7B8E7F157091}`}},
+			Want:    []ManifestEntry{{"Documents", `C:\Users\pete\AppData\Local\{F1B32785-6FBA-4FCF-9D55-7B8E7F157091}`}},
+		},
+		{
+			Name:    "shell namespace path",
+			Entries: []ManifestEntry{{"Documents", `::{20D04FE0-3AEA-1069-A2D8-08002B30309D}\{ not a reference }`}},
+			Want:    []ManifestEntry{{"Documents", `::{20D04FE0-3AEA-1069-A2D8-08002B30309D}\{ not a reference }`}},
+		},
+	} {
+		got, err := ExpandReferences(test.Entries, test.Variables, lookup)
+		if err != nil {
+			t.Errorf("%v: %v", test.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.Want) {
+			t.Errorf("%v: expanded to %+v, want %+v", test.Name, got, test.Want)
+		}
+	}
+}
+
+func TestExpandReferencesErrors(t *testing.T) {
+	lookup := newFakeBackend(map[string]string{"Profile": `C:\Users\pete`}).GetFolder
+	for _, test := range []struct {
+		Name      string
+		Entries   []ManifestEntry
+		Variables map[string]string
+		Err       string
+	}{
+		{
+			Name:    "cycle",
+			Entries: []ManifestEntry{{"Downloads", `{Profile}\Downloads`}, {"Profile", `{Downloads}\..`}},
+			Err:     "Cycle in location references: Downloads -> Profile -> Downloads",
+		},
+		{
+			Name:    "self reference",
+			Entries: []ManifestEntry{{"Documents", `{Documents}\x`}},
+			Err:     "Cycle in location references: Documents -> Documents",
+		},
+		{
+			Name:      "variable cycle",
+			Entries:   []ManifestEntry{{"Documents", `{A}`}},
+			Variables: map[string]string{"A": `{B}`, "B": `{A}`},
+			Err:       "Cycle in location references: Documents -> A -> B -> A",
+		},
+		{
+			Name:    "unknown name",
+			Entries: []ManifestEntry{{"Music", `{Documents}`}, {"Documents", `{Nowhere}\Docs`}},
+			Err:     `Unknown folder or variable "Nowhere" in location references: Music -> Documents -> Nowhere`,
+		},
+		{
+			Name:    "lookup failure",
+			Entries: []ManifestEntry{{"Documents", `{Music}\Docs`}},
+			Err:     "Could not retrieve location of Music (via Documents -> Music):\nFolder Music has no location",
+		},
+	} {
+		_, err := ExpandReferences(test.Entries, test.Variables, lookup)
+		if err == nil || err.Error() != test.Err {
+			t.Errorf("%v: returned %v, want %v", test.Name, err, test.Err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// folderDescriptionsRoot is the full path of the FolderDescriptions key.
+const folderDescriptionsRoot = `HKEY_LOCAL_MACHINE\SOFTWARE\` + folderDescriptionsKey
+
+// KnownFolderDefinition describes a custom known folder to register, with the
+// same semantics as the Windows KNOWNFOLDER_DEFINITION structure.
+type KnownFolderDefinition struct {
+	GUID     GUID
+	Name     string
+	Category uint32
+	// Parent is the knownfolders name of the folder RelativePath is
+	// relative to, or "" for none.
+	Parent       string
+	RelativePath string
+	// ParsingName is the shell namespace parsing name, required for virtual
+	// folders.
+	ParsingName string
+	// PreCreate requests that the folder is created when the user first logs
+	// on (KFDF_PRECREATE).
+	PreCreate bool
+}
+
+// ParseCategory returns the KF_CATEGORY value with the given name, e.g.
+// "peruser".
+func ParseCategory(name string) (uint32, error) {
+	for category, n := range folderCategories {
+		if strings.EqualFold(n, name) {
+			return category, nil
+		}
+	}
+	return 0, fmt.Errorf(`Invalid category "%v" - must be one of virtual, fixed, common, peruser`, name)
+}
+
+// Validate checks that d is a definition Windows would accept, and that its
+// name and GUID are not already in use by a folder in knownfolders.
+func (d *KnownFolderDefinition) Validate() error {
+	if d.GUID == (GUID{}) {
+		return fmt.Errorf("A GUID is required")
+	}
+	if existing, ok := folderByGUID(d.GUID.String()); ok {
+		return fmt.Errorf("GUID %v is already used by folder %v", d.GUID.String(), existing)
+	}
+	if d.Name == "" {
+		return fmt.Errorf("A name is required")
+	}
+	if strings.ContainsAny(d.Name, `\/`) {
+		return fmt.Errorf(`Name "%v" must not contain slashes or backslashes`, d.Name)
+	}
+	if existing, ok := resolveFolder(d.Name); ok {
+		return fmt.Errorf(`Name "%v" is already used by folder %v`, d.Name, existing)
+	}
+	if _, ok := folderCategories[d.Category]; !ok {
+		return fmt.Errorf("Invalid category %v", d.Category)
+	}
+	if d.Category == KF_CATEGORY_VIRTUAL {
+		if d.Parent != "" || d.RelativePath != "" {
+			return fmt.Errorf("Virtual folders have no file system location, so cannot have a parent or relative path")
+		}
+		if d.ParsingName == "" {
+			return fmt.Errorf("Virtual folders require a parsing name")
+		}
+		if d.PreCreate {
+			return fmt.Errorf("Virtual folders cannot be precreated")
+		}
+		return nil
+	}
+	if (d.Parent == "") != (d.RelativePath == "") {
+		return fmt.Errorf("A parent and relative path must be given together")
+	}
+	if d.Parent == "" {
+		return nil
+	}
+	if knownfolders[d.Parent] == nil {
+		return UnknownFolderError(d.Parent)
+	}
+	return validateRelativePath(d.RelativePath)
+}
+
+// validateRelativePath checks that path is a relative Windows path which
+// stays within its parent folder.
+func validateRelativePath(path string) error {
+	if strings.HasPrefix(path, `\`) || strings.HasPrefix(path, "/") || strings.Contains(path, ":") {
+		return fmt.Errorf(`Relative path "%v" must not be absolute`, path)
+	}
+	for _, element := range strings.FieldsFunc(path, func(r rune) bool { return r == '\\' || r == '/' }) {
+		if element == "." || element == ".." {
+			return fmt.Errorf(`Relative path "%v" must not contain "." or ".." elements`, path)
+		}
+		if strings.ContainsAny(element, `<>"|?*`) {
+			return fmt.Errorf(`Relative path "%v" contains characters not allowed in file names`, path)
+		}
+	}
+	return nil
+}
+
+// RegistryChange is a change to a single registry key: either its deletion,
+// with all of its subkeys, or the creation of the key (if need be) and the
+// setting of Values.
+type RegistryChange struct {
+	Key    string
+	Delete bool
+	Values []RegistryValue
+}
+
+// RegistryValue is a registry value to set.
+type RegistryValue struct {
+	Name string
+	Type uint32
+	Data []byte
+}
+
+func stringRegistryValue(name, value string) RegistryValue {
+	units := utf16.Encode([]rune(value + "\x00"))
+	data := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(data[2*i:], u)
+	}
+	return RegistryValue{Name: name, Type: REG_SZ, Data: data}
+}
+
+func dwordRegistryValue(name string, value uint32) RegistryValue {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, value)
+	return RegistryValue{Name: name, Type: REG_DWORD, Data: data}
+}
+
+// Registration returns the registry changes that register d, as
+// IKnownFolderManager::RegisterFolder would make them.
+func (d *KnownFolderDefinition) Registration() []RegistryChange {
+	values := []RegistryValue{
+		stringRegistryValue("Name", d.Name),
+		dwordRegistryValue("Category", d.Category),
+	}
+	if d.Parent != "" {
+		values = append(values,
+			stringRegistryValue("ParentFolder", knownfolders[d.Parent].String()),
+			stringRegistryValue("RelativePath", d.RelativePath),
+		)
+	}
+	if d.ParsingName != "" {
+		values = append(values, stringRegistryValue("ParsingName", d.ParsingName))
+	}
+	if d.PreCreate {
+		values = append(values, dwordRegistryValue("PreCreate", 1))
+	}
+	return []RegistryChange{{
+		Key:    folderDescriptionsRoot + `\` + d.GUID.String(),
+		Values: values,
+	}}
+}
+
+// Unregistration returns the registry changes that unregister the folder
+// with the given GUID.
+func Unregistration(guid GUID) []RegistryChange {
+	return []RegistryChange{{
+		Key:    folderDescriptionsRoot + `\` + guid.String(),
+		Delete: true,
+	}}
+}
+
+// WriteRegFile writes changes to w in the format of a .reg file, as
+// imported by regedit or "reg import".
+func WriteRegFile(w io.Writer, changes []RegistryChange) error {
+	_, err := fmt.Fprint(w, "Windows Registry Editor Version 5.00\r\n")
+	for _, c := range changes {
+		if err != nil {
+			return err
+		}
+		if c.Delete {
+			_, err = fmt.Fprintf(w, "\r\n[-%v]\r\n", c.Key)
+			continue
+		}
+		_, err = fmt.Fprintf(w, "\r\n[%v]\r\n", c.Key)
+		for _, v := range c.Values {
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(w, "%v=%v\r\n", regFileString(v.Name), regFileData(v))
+		}
+	}
+	return err
+}
+
+func regFileString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func regFileData(v RegistryValue) string {
+	switch v.Type {
+	case REG_SZ:
+		s := decodeUTF16(v.Data, binary.LittleEndian)
+		return regFileString(strings.TrimSuffix(s, "\x00"))
+	case REG_DWORD:
+		return fmt.Sprintf("dword:%08x", binary.LittleEndian.Uint32(v.Data))
+	}
+	hex := make([]string, len(v.Data))
+	for i, b := range v.Data {
+		hex[i] = fmt.Sprintf("%02x", b)
+	}
+	if v.Type == REG_BINARY {
+		return "hex:" + strings.Join(hex, ",")
+	}
+	return fmt.Sprintf("hex(%x):%v", v.Type, strings.Join(hex, ","))
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestValidateDefinition(t *testing.T) {
+	restoreKnownFolders(t)
+	MergeFolderDescriptions([]FolderDescription{
+		{GUID: testGUID(t, "{1A2B3C4D-0000-0000-0000-000000000001}"), Name: "AcmeData", Category: KF_CATEGORY_PERUSER},
+	})
+	guid := testGUID(t, "{A1B2C3D4-1111-2222-3333-444455556666}")
+	for _, test := range []struct {
+		Name       string
+		Definition KnownFolderDefinition
+		Err        string
+	}{
+		{"peruser", KnownFolderDefinition{GUID: guid, Name: "AcmeCache", Category: KF_CATEGORY_PERUSER}, ""},
+		{"relative", KnownFolderDefinition{GUID: guid, Name: "AcmeCache", Category: KF_CATEGORY_PERUSER, Parent: "LocalAppData", RelativePath: `Acme\Cache`, PreCreate: true}, ""},
+		{"relative to registered", KnownFolderDefinition{GUID: guid, Name: "AcmeCache", Category: KF_CATEGORY_COMMON, Parent: "AcmeData", RelativePath: "Cache"}, ""},
+		{"virtual", KnownFolderDefinition{GUID: guid, Name: "AcmeView", Category: KF_CATEGORY_VIRTUAL, ParsingName: "::{A1B2C3D4-1111-2222-3333-444455556666}"}, ""},
+
+		{"no GUID", KnownFolderDefinition{Name: "AcmeCache", Category: KF_CATEGORY_PERUSER}, "A GUID is required"},
+		{"built-in GUID", KnownFolderDefinition{GUID: *knownfolders["Documents"], Name: "AcmeCache", Category: KF_CATEGORY_PERUSER}, "GUID {FDD39AD0-238F-46AF-ADB4-6C85480369C7} is already used by folder Documents"},
+		{"registered GUID", KnownFolderDefinition{GUID: *knownfolders["AcmeData"], Name: "AcmeCache", Category: KF_CATEGORY_PERUSER}, "is already used by folder AcmeData"},
+		{"no name", KnownFolderDefinition{GUID: guid, Category: KF_CATEGORY_PERUSER}, "A name is required"},
+		{"backslash in name", KnownFolderDefinition{GUID: guid, Name: `Acme\Cache`, Category: KF_CATEGORY_PERUSER}, "must not contain slashes"},
+		{"slash in name", KnownFolderDefinition{GUID: guid, Name: "Acme/Cache", Category: KF_CATEGORY_PERUSER}, "must not contain slashes"},
+		{"built-in name", KnownFolderDefinition{GUID: guid, Name: "Documents", Category: KF_CATEGORY_PERUSER}, `Name "Documents" is already used by folder Documents`},
+		{"alias", KnownFolderDefinition{GUID: guid, Name: "personal", Category: KF_CATEGORY_PERUSER}, `Name "personal" is already used by folder Documents`},
+		{"registered name", KnownFolderDefinition{GUID: guid, Name: "AcmeData", Category: KF_CATEGORY_PERUSER}, `Name "AcmeData" is already used by folder AcmeData`},
+		{"no category", KnownFolderDefinition{GUID: guid, Name: "AcmeCache"}, "Invalid category 0"},
+		{"unknown category", KnownFolderDefinition{GUID: guid, Name: "AcmeCache", Category: 5}, "Invalid category 5"},
+
+		{"virtual without parsing name", KnownFolderDefinition{GUID: guid, Name: "AcmeView", Category: KF_CATEGORY_VIRTUAL}, "Virtual folders require a parsing name"},
+		{"virtual with parent", KnownFolderDefinition{GUID: guid, Name: "AcmeView", Category: KF_CATEGORY_VIRTUAL, ParsingName: "::{x}", Parent: "Documents", RelativePath: "View"}, "cannot have a parent or relative path"},
+		{"virtual with relative path", KnownFolderDefinition{GUID: guid, Name: "AcmeView", Category: KF_CATEGORY_VIRTUAL, ParsingName: "::{x}", RelativePath: "View"}, "cannot have a parent or relative path"},
+		{"virtual precreated", KnownFolderDefinition{GUID: guid, Name: "AcmeView", Category: KF_CATEGORY_VIRTUAL, ParsingName: "::{x}", PreCreate: true}, "Virtual folders cannot be precreated"},
+
+		{"parent without relative path", KnownFolderDefinition{GUID: guid, Name: "AcmeCache", Category: KF_CATEGORY_PERUSER, Parent: "LocalAppData"}, "must be given together"},
+		{"relative path without parent", KnownFolderDefinition{GUID: guid, Name: "AcmeCache", Category: KF_CATEGORY_PERUSER, RelativePath: "Cache"}, "must be given together"},
+		{"unknown parent", KnownFolderDefinition{GUID: guid, Name: "AcmeCache", Category: KF_CATEGORY_PERUSER, Parent: "NoSuchFolder", RelativePath: "Cache"}, "NoSuchFolder"},
+	} {
+		err := test.Definition.Validate()
+		switch {
+		case test.Err == "" && err != nil:
+			t.Errorf("%v: %v", test.Name, err)
+		case test.Err != "" && (err == nil || !strings.Contains(err.Error(), test.Err)):
+			t.Errorf("%v: returned %v, want %v", test.Name, err, test.Err)
+		}
+	}
+}
+
+func TestValidateRelativePath(t *testing.T) {
+	for _, test := range []struct {
+		Path string
+		Err  string
+	}{
+		{`Cache`, ""},
+		{`Acme\Cache\v2`, ""},
+		{`Acme/Cache`, ""},
+		{`Acme.Cache\.hidden`, ""},
+		{`\Acme`, "must not be absolute"},
+		{`/Acme`, "must not be absolute"},
+		{`C:\Acme`, "must not be absolute"},
+		{`C:Acme`, "must not be absolute"},
+		{`\\server\share`, "must not be absolute"},
+		{`..\Acme`, `must not contain "." or ".." elements`},
+		{`Acme\..\..\Windows`, `must not contain "." or ".." elements`},
+		{`Acme\.\Cache`, `must not contain "." or ".." elements`},
+		{`Acme/../Cache`, `must not contain "." or ".." elements`},
+		{`Acme\Ca*che`, "contains characters not allowed"},
+		{`Acme\<Cache>`, "contains characters not allowed"},
+		{`Acme\"Cache"`, "contains characters not allowed"},
+		{`Acme|Cache`, "contains characters not allowed"},
+		{`Acme?`, "contains characters not allowed"},
+	} {
+		err := validateRelativePath(test.Path)
+		switch {
+		case test.Err == "" && err != nil:
+			t.Errorf("validateRelativePath(%q): %v", test.Path, err)
+		case test.Err != "" && (err == nil || !strings.Contains(err.Error(), test.Err)):
+			t.Errorf("validateRelativePath(%q) returned %v, want %v", test.Path, err, test.Err)
+		}
+	}
+}
+
+func TestParseCategory(t *testing.T) {
+	for name, want := range map[string]uint32{"virtual": KF_CATEGORY_VIRTUAL, "Fixed": KF_CATEGORY_FIXED, "COMMON": KF_CATEGORY_COMMON, "peruser": KF_CATEGORY_PERUSER} {
+		if got, err := ParseCategory(name); err != nil || got != want {
+			t.Errorf("ParseCategory(%q) is %v (%v), want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseCategory("per-user"); err == nil {
+		t.Error("Parsed an invalid category")
+	}
+}
+
+func TestWriteRegFile(t *testing.T) {
+	guid := testGUID(t, "{A1B2C3D4-1111-2222-3333-444455556666}")
+	for _, test := range []struct {
+		Golden  string
+		Changes []RegistryChange
+	}{
+		{"register.reg", (&KnownFolderDefinition{GUID: guid, Name: "AcmeCache", Category: KF_CATEGORY_PERUSER}).Registration()},
+		{"register-relative.reg", (&KnownFolderDefinition{
+			GUID:         guid,
+			Name:         `Acme "Cache"`,
+			Category:     KF_CATEGORY_COMMON,
+			Parent:       "ProgramData",
+			RelativePath: `Acme\Cache`,
+			PreCreate:    true,
+		}).Registration()},
+		{"register-virtual.reg", (&KnownFolderDefinition{
+			GUID:        guid,
+			Name:        "AcmeView",
+			Category:    KF_CATEGORY_VIRTUAL,
+			ParsingName: `::{20D04FE0-3AEA-1069-A2D8-08002B30309D}\::{A1B2C3D4-1111-2222-3333-444455556666}`,
+		}).Registration()},
+		{"unregister.reg", Unregistration(guid)},
+		{"registry-values.reg", []RegistryChange{{
+			Key: `HKEY_CURRENT_USER\Software\Acme`,
+			Values: []RegistryValue{
+				{Name: "Binary", Type: REG_BINARY, Data: []byte{0x01, 0xab}},
+				{Name: "Expand", Type: REG_EXPAND_SZ, Data: []byte{'%', 0, 0, 0}},
+				{Name: `Quote"Back\slash`, Type: REG_DWORD, Data: []byte{0x78, 0x56, 0x34, 0x12}},
+			},
+		}}},
+	} {
+		var b bytes.Buffer
+		if err := WriteRegFile(&b, test.Changes); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(strings.ReplaceAll(b.String(), "\r\n", ""), "\n") {
+			t.Errorf("%v has lines not ending in CRLF", test.Golden)
+		}
+		checkGolden(t, test.Golden, b.Bytes())
+	}
+}
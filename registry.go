@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Registry value types
+const (
+	REG_NONE             = 0
+	REG_SZ               = 1
+	REG_EXPAND_SZ        = 2
+	REG_BINARY           = 3
+	REG_DWORD            = 4
+	REG_DWORD_BIG_ENDIAN = 5
+	REG_MULTI_SZ         = 7
+	REG_QWORD            = 11
+)
+
+// RegistryKey is an open registry key, either in the live registry of the
+// machine knownfolder is running on, or in an offline hive file. Paths are
+// backslash separated and relative to the key, and names are matched case
+// insensitively, as they are by Windows.
+type RegistryKey interface {
+	OpenKey(path string) (RegistryKey, error)
+	SubkeyNames() ([]string, error)
+	// Value returns the type and raw data of the named value, or of the
+	// default value if name is "".
+	Value(name string) (valueType uint32, data []byte, err error)
+	Close() error
+}
+
+// RegistryNotFoundError is returned when a registry key or value does not
+// exist.
+type RegistryNotFoundError string
+
+func (e RegistryNotFoundError) Error() string {
+	return fmt.Sprintf(`Registry key or value "%v" does not exist`, string(e))
+}
+
+func isRegistryNotFound(err error) bool {
+	_, ok := err.(RegistryNotFoundError)
+	return ok
+}
+
+// stringValue returns the named REG_SZ or REG_EXPAND_SZ value of key. Any
+// environment variables in REG_EXPAND_SZ values are left unexpanded.
+func stringValue(key RegistryKey, name string) (string, error) {
+	valueType, data, err := key.Value(name)
+	if err != nil {
+		return "", err
+	}
+	if valueType != REG_SZ && valueType != REG_EXPAND_SZ {
+		return "", fmt.Errorf("Registry value %v has type %v, not REG_SZ or REG_EXPAND_SZ", name, valueType)
+	}
+	s := decodeUTF16(data, binary.LittleEndian)
+	if i := strings.IndexRune(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return s, nil
+}
+
+// dwordValue returns the named REG_DWORD value of key.
+func dwordValue(key RegistryKey, name string) (uint32, error) {
+	valueType, data, err := key.Value(name)
+	if err != nil {
+		return 0, err
+	}
+	if valueType != REG_DWORD || len(data) < 4 {
+		return 0, fmt.Errorf("Registry value %v has type %v, not REG_DWORD", name, valueType)
+	}
+	return binary.LittleEndian.Uint32(data), nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// not defined by package syscall
+const (
+	ERROR_NO_MORE_ITEMS    syscall.Errno = 259
+	ERROR_NOT_ALL_ASSIGNED syscall.Errno = 1300
+	SE_PRIVILEGE_ENABLED                 = 0x00000002
+)
+
+// liveKey is a RegistryKey in the registry of the machine knownfolder is
+// running on.
+type liveKey struct {
+	handle syscall.Handle
+	path   string
+}
+
+// openLiveKey opens path under the predefined key root (e.g.
+// syscall.HKEY_LOCAL_MACHINE) for reading. name is used in error messages.
+func openLiveKey(root syscall.Handle, name, path string) (RegistryKey, error) {
+	return (&liveKey{handle: root, path: name}).OpenKey(path)
+}
+
+func (k *liveKey) OpenKey(path string) (RegistryKey, error) {
+	full := k.path + `\` + strings.Trim(path, `\`)
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	var h syscall.Handle
+	err = syscall.RegOpenKeyEx(k.handle, p, 0, syscall.KEY_READ, &h)
+	if err == syscall.ERROR_FILE_NOT_FOUND {
+		return nil, RegistryNotFoundError(full)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &liveKey{handle: h, path: full}, nil
+}
+
+func (k *liveKey) SubkeyNames() ([]string, error) {
+	var count, maxLen uint32
+	err := syscall.RegQueryInfoKey(k.handle, nil, nil, nil, &count, &maxLen, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, count)
+	buf := make([]uint16, maxLen+1)
+	for i := uint32(0); ; i++ {
+		n := uint32(len(buf))
+		err := syscall.RegEnumKeyEx(k.handle, i, &buf[0], &n, nil, nil, nil, nil)
+		if err == ERROR_NO_MORE_ITEMS {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, syscall.UTF16ToString(buf[:n]))
+	}
+	return names, nil
+}
+
+func (k *liveKey) Value(name string) (valueType uint32, data []byte, err error) {
+	p, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return
+	}
+	var size uint32
+	for {
+		data = make([]byte, size+2)
+		size = uint32(len(data))
+		err = syscall.RegQueryValueEx(k.handle, p, nil, &valueType, &data[0], &size)
+		// the value may grow between calls
+		if err != syscall.ERROR_MORE_DATA {
+			break
+		}
+	}
+	if err == syscall.ERROR_FILE_NOT_FOUND {
+		return 0, nil, RegistryNotFoundError(k.path + `\` + name)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	return valueType, data[:size], nil
+}
+
+func (k *liveKey) Close() error {
+	return syscall.RegCloseKey(k.handle)
+}
+
+var (
+	procRegCreateKeyExW = advapi32.NewProc("RegCreateKeyExW")
+	procRegSetValueExW  = advapi32.NewProc("RegSetValueExW")
+	procRegDeleteTreeW  = advapi32.NewProc("RegDeleteTreeW")
+	procRegLoadKeyW     = advapi32.NewProc("RegLoadKeyW")
+	procRegUnLoadKeyW   = advapi32.NewProc("RegUnLoadKeyW")
+
+	procLookupPrivilegeValueW = advapi32.NewProc("LookupPrivilegeValueW")
+	procAdjustTokenPrivileges = advapi32.NewProc("AdjustTokenPrivileges")
+)
+
+var predefinedKeys = map[string]syscall.Handle{
+	"HKEY_CLASSES_ROOT":  syscall.HKEY_CLASSES_ROOT,
+	"HKEY_CURRENT_USER":  syscall.HKEY_CURRENT_USER,
+	"HKEY_LOCAL_MACHINE": syscall.HKEY_LOCAL_MACHINE,
+	"HKEY_USERS":         syscall.HKEY_USERS,
+}
+
+// applyRegistryChanges makes changes to the live registry.
+func applyRegistryChanges(changes []RegistryChange) error {
+	for _, c := range changes {
+		parts := strings.SplitN(c.Key, `\`, 2)
+		root, ok := predefinedKeys[parts[0]]
+		if !ok || len(parts) < 2 {
+			return fmt.Errorf("Invalid registry key %v", c.Key)
+		}
+		subkey, err := syscall.UTF16PtrFromString(parts[1])
+		if err != nil {
+			return err
+		}
+		if c.Delete {
+			r1, _, _ := procRegDeleteTreeW.Call(uintptr(root), uintptr(unsafe.Pointer(subkey)))
+			if r1 != 0 && syscall.Errno(r1) != syscall.ERROR_FILE_NOT_FOUND {
+				return fmt.Errorf("Could not delete %v: %v", c.Key, syscall.Errno(r1))
+			}
+			continue
+		}
+		var key syscall.Handle
+		r1, _, _ := procRegCreateKeyExW.Call(
+			uintptr(root),
+			uintptr(unsafe.Pointer(subkey)),
+			0,
+			0,
+			0,
+			uintptr(syscall.KEY_WRITE),
+			0,
+			uintptr(unsafe.Pointer(&key)),
+			0,
+		)
+		if r1 != 0 {
+			return fmt.Errorf("Could not create %v: %v", c.Key, syscall.Errno(r1))
+		}
+		err = setRegistryValues(key, c.Values)
+		syscall.RegCloseKey(key)
+		if err != nil {
+			return fmt.Errorf("Could not set values of %v: %v", c.Key, err)
+		}
+	}
+	return nil
+}
+
+func setRegistryValues(key syscall.Handle, values []RegistryValue) error {
+	for _, v := range values {
+		name, err := syscall.UTF16PtrFromString(v.Name)
+		if err != nil {
+			return err
+		}
+		var data uintptr
+		if len(v.Data) > 0 {
+			data = uintptr(unsafe.Pointer(&v.Data[0]))
+		}
+		r1, _, _ := procRegSetValueExW.Call(
+			uintptr(key),
+			uintptr(unsafe.Pointer(name)),
+			0,
+			uintptr(v.Type),
+			data,
+			uintptr(len(v.Data)),
+		)
+		if r1 != 0 {
+			return fmt.Errorf("%v: %v", v.Name, syscall.Errno(r1))
+		}
+	}
+	return nil
+}
+
+// tokenPrivileges is a TOKEN_PRIVILEGES structure with a single privilege.
+type tokenPrivileges struct {
+	PrivilegeCount uint32
+	LowPart        uint32
+	HighPart       int32
+	Attributes     uint32
+}
+
+// enablePrivilege enables the named privilege, e.g. "SeRestorePrivilege", in
+// the access token of the knownfolder process.
+func enablePrivilege(name string) error {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return err
+	}
+	var token syscall.Token
+	err = syscall.OpenProcessToken(process, syscall.TOKEN_ADJUST_PRIVILEGES|syscall.TOKEN_QUERY, &token)
+	if err != nil {
+		return err
+	}
+	defer token.Close()
+	n, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	privileges := tokenPrivileges{PrivilegeCount: 1, Attributes: SE_PRIVILEGE_ENABLED}
+	r1, _, e1 := procLookupPrivilegeValueW.Call(0, uintptr(unsafe.Pointer(n)), uintptr(unsafe.Pointer(&privileges.LowPart)))
+	if r1 == 0 {
+		return fmt.Errorf("Could not look up privilege %v: %v", name, e1)
+	}
+	r1, _, e1 = procAdjustTokenPrivileges.Call(uintptr(token), 0, uintptr(unsafe.Pointer(&privileges)), 0, 0, 0)
+	// AdjustTokenPrivileges succeeds even if the privilege isn't held
+	if r1 == 0 || e1 == ERROR_NOT_ALL_ASSIGNED {
+		return fmt.Errorf("Could not enable privilege %v: %v", name, e1)
+	}
+	return nil
+}
+
+// loadUserHive loads the user hive file as HKEY_USERS\name, and returns a
+// function to unload it again. Requires administrator privileges.
+func loadUserHive(name, file string) (unload func() error, err error) {
+	for _, privilege := range []string{"SeBackupPrivilege", "SeRestorePrivilege"} {
+		if err := enablePrivilege(privilege); err != nil {
+			return nil, err
+		}
+	}
+	key, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := syscall.UTF16PtrFromString(file)
+	if err != nil {
+		return nil, err
+	}
+	r1, _, _ := procRegLoadKeyW.Call(uintptr(syscall.HKEY_USERS), uintptr(unsafe.Pointer(key)), uintptr(unsafe.Pointer(f)))
+	if r1 != 0 {
+		return nil, fmt.Errorf("Could not load hive %v: %v", file, syscall.Errno(r1))
+	}
+	return func() error {
+		r1, _, _ := procRegUnLoadKeyW.Call(uintptr(syscall.HKEY_USERS), uintptr(unsafe.Pointer(key)))
+		if r1 != 0 {
+			return fmt.Errorf("Could not unload hive %v: %v", file, syscall.Errno(r1))
+		}
+		return nil
+	}, nil
+}
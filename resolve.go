@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// FolderLocation is the location of a known folder.
+type FolderLocation struct {
+	Folder   string
+	Location string
+}
+
+// PathMatch is a known folder containing a path.
+type PathMatch struct {
+	FolderLocation
+	// Relative is the path relative to the folder location, "" if it is
+	// the folder itself.
+	Relative string
+}
+
+// Portable returns the path in terms of the folder, e.g.
+// {LocalAppData}\Temp\x.
+func (m PathMatch) Portable() string {
+	if m.Relative == "" {
+		return "{" + m.Folder + "}"
+	}
+	return "{" + m.Folder + `}\` + m.Relative
+}
+
+// PathResolver finds the known folders containing Windows paths.
+type PathResolver struct {
+	Locations []FolderLocation
+	// LongPath, if set, expands any 8.3 short names in a path, e.g.
+	// C:\PROGRA~1 to C:\Program Files. It is applied to both the path being
+	// resolved and folder locations.
+	LongPath func(path string) string
+}
+
+// Resolve returns the most specific folders containing path, i.e. those
+// with the longest locations. There is more than one match if several
+// folders share a location, and none if no folder contains path. Paths are
+// compared case insensitively, ignoring \\?\ prefixes, and after resolving
+// . and .. elements.
+func (r *PathResolver) Resolve(path string) []PathMatch {
+	target := r.split(path)
+	var matches []PathMatch
+	longest := -1
+	for _, l := range r.Locations {
+		location := r.split(l.Location)
+		if len(location) == 0 || len(location) > len(target) || len(location) < longest {
+			continue
+		}
+		if !sameElements(location, target[:len(location)]) {
+			continue
+		}
+		if len(location) > longest {
+			longest = len(location)
+			matches = nil
+		}
+		matches = append(matches, PathMatch{
+			FolderLocation: l,
+			// keep the original spelling of the relative part
+			Relative: strings.Join(target[len(location):], `\`),
+		})
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Folder < matches[j].Folder })
+	return matches
+}
+
+func (r *PathResolver) split(path string) []string {
+	path = normalizeWindowsPath(path)
+	if r.LongPath != nil {
+		path = normalizeWindowsPath(r.LongPath(path))
+	}
+	return splitWindowsPath(path)
+}
+
+func sameElements(a, b []string) bool {
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeWindowsPath returns path with forward slashes replaced by
+// backslashes, the \\?\ (or \??\) prefix of Win32 file namespace paths
+// removed, and . and .. elements resolved.
+func normalizeWindowsPath(path string) string {
+	path = strings.Replace(path, "/", `\`, -1)
+	for _, prefix := range []string{`\\?\`, `\??\`} {
+		if strings.HasPrefix(path, prefix) {
+			path = path[len(prefix):]
+			if len(path) >= 4 && strings.EqualFold(path[:4], `UNC\`) {
+				path = `\\` + path[4:]
+			}
+			break
+		}
+	}
+	return strings.Join(splitWindowsPath(path), `\`)
+}
+
+// splitWindowsPath splits path into its root (e.g. "C:" or
+// "\\server\share") and the remaining elements, resolving . and ..
+// elements and dropping empty ones.
+func splitWindowsPath(path string) []string {
+	root := ""
+	rest := path
+	if strings.HasPrefix(path, `\\`) {
+		parts := strings.SplitN(path[2:], `\`, 3)
+		if len(parts) >= 2 {
+			root = `\\` + parts[0] + `\` + parts[1]
+			rest = ""
+			if len(parts) == 3 {
+				rest = parts[2]
+			}
+		}
+	} else if len(path) >= 2 && path[1] == ':' {
+		root = strings.ToUpper(path[:2])
+		rest = path[2:]
+	}
+	elements := []string{}
+	if root != "" {
+		elements = append(elements, root)
+	}
+	for _, e := range strings.Split(rest, `\`) {
+		switch e {
+		case "", ".":
+		case "..":
+			if len(elements) > 1 || (len(elements) == 1 && root == "") {
+				elements = elements[:len(elements)-1]
+			}
+		default:
+			// Windows ignores trailing dots and spaces in file names
+			if trimmed := strings.TrimRight(e, ". "); trimmed != "" {
+				e = trimmed
+			}
+			elements = append(elements, e)
+		}
+	}
+	return elements
+}
+
+// WritePathMatches writes matches to w as a table.
+func WritePathMatches(w io.Writer, matches []PathMatch) error {
+	t := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(t, "FOLDER\tLOCATION\tRELATIVE\tPORTABLE")
+	for _, m := range matches {
+		relative := m.Relative
+		if relative == "" {
+			relative = "."
+		}
+		fmt.Fprintf(t, "%v\t%v\t%v\t%v\n", m.Folder, m.Location, relative, m.Portable())
+	}
+	return t.Flush()
+}
+
+// FolderLocations returns the locations of all of the known folders which
+// have one for backend. Virtual folders, and folders which cannot be
+// retrieved, are left out.
+func FolderLocations(backend Backend) []FolderLocation {
+	names := make([]string, 0, len(knownfolders))
+	for name := range knownfolders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var locations []FolderLocation
+	for _, name := range names {
+		location, err := backend.GetFolder(name)
+		if err != nil || location == "" {
+			continue
+		}
+		locations = append(locations, FolderLocation{Folder: name, Location: location})
+	}
+	return locations
+}
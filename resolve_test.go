@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitWindowsPath(t *testing.T) {
+	for _, test := range []struct {
+		Path string
+		Want []string
+	}{
+		{`C:\Users\pete`, []string{"C:", "Users", "pete"}},
+		{`c:\Users\\pete\`, []string{"C:", "Users", "pete"}},
+		{`C:\Users\.\pete\..\jane`, []string{"C:", "Users", "jane"}},
+		// .. can't go above the root
+		{`C:\..\..\Windows`, []string{"C:", "Windows"}},
+		{`\\server\share\docs\..\music`, []string{`\\server\share`, "music"}},
+		{`\\server\share`, []string{`\\server\share`}},
+		{`\\server\share\..`, []string{`\\server\share`}},
+		// trailing dots and spaces are ignored, as by Windows
+		{`C:\Users\pete. \Documents..`, []string{"C:", "Users", "pete", "Documents"}},
+		{`C:\...`, []string{"C:", "..."}},
+		{`relative\path\..\..\..`, []string{}},
+		{``, []string{}},
+	} {
+		if got := splitWindowsPath(test.Path); !reflect.DeepEqual(got, test.Want) {
+			t.Errorf("splitWindowsPath(%q) is %q, want %q", test.Path, got, test.Want)
+		}
+	}
+}
+
+func TestNormalizeWindowsPath(t *testing.T) {
+	for _, test := range []struct {
+		Path string
+		Want string
+	}{
+		{`C:/Users/pete/`, `C:\Users\pete`},
+		{`\\?\C:\Users\pete`, `C:\Users\pete`},
+		{`\??\C:\Users\pete`, `C:\Users\pete`},
+		{`\\?\UNC\server\share\docs`, `\\server\share\docs`},
+		{`\\?\unc\server\share`, `\\server\share`},
+		{`//server/share/docs/../music`, `\\server\share\music`},
+	} {
+		if got := normalizeWindowsPath(test.Path); got != test.Want {
+			t.Errorf("normalizeWindowsPath(%q) is %q, want %q", test.Path, got, test.Want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	resolver := &PathResolver{
+		Locations: []FolderLocation{
+			{"Profile", `C:\Users\pete`},
+			{"Documents", `C:\Users\pete\Documents`},
+			{"Personal", `C:\Users\pete\Documents`},
+			{"LocalAppData", `C:\Users\pete\AppData\Local`},
+			{"ProgramFiles", `C:\PROGRA~1`},
+			{"Music", `\\nas\music`},
+			{"Empty", ``},
+		},
+		LongPath: func(path string) string {
+			return strings.Replace(path, `PROGRA~1`, `Program Files`, 1)
+		},
+	}
+	profile := FolderLocation{"Profile", `C:\Users\pete`}
+	documents := FolderLocation{"Documents", `C:\Users\pete\Documents`}
+	personal := FolderLocation{"Personal", `C:\Users\pete\Documents`}
+	for _, test := range []struct {
+		Path string
+		Want []PathMatch
+	}{
+		{`C:\Users\pete\Documents\Report.docx`, []PathMatch{{documents, "Report.docx"}, {personal, "Report.docx"}}},
+		{`c:\users\PETE\documents`, []PathMatch{{documents, ""}, {personal, ""}}},
+		{`C:\Users\pete\Desktop\Mixed Case.txt`, []PathMatch{{profile, `Desktop\Mixed Case.txt`}}},
+		{`\\?\C:\Users\pete\AppData\Local\Temp\x`, []PathMatch{{FolderLocation{"LocalAppData", `C:\Users\pete\AppData\Local`}, `Temp\x`}}},
+		{`C:\Users\pete\Documents\..\Music`, []PathMatch{{profile, "Music"}}},
+		{`C:\Program Files\Go`, []PathMatch{{FolderLocation{"ProgramFiles", `C:\PROGRA~1`}, "Go"}}},
+		{`C:\PROGRA~1\Go`, []PathMatch{{FolderLocation{"ProgramFiles", `C:\PROGRA~1`}, "Go"}}},
+		{`\\NAS\Music\album`, []PathMatch{{FolderLocation{"Music", `\\nas\music`}, "album"}}},
+		// a folder doesn't contain a sibling with the same prefix
+		{`C:\Users\peter`, nil},
+		{`D:\`, nil},
+	} {
+		if got := resolver.Resolve(test.Path); !reflect.DeepEqual(got, test.Want) {
+			t.Errorf("Resolve(%q) is %+v, want %+v", test.Path, got, test.Want)
+		}
+	}
+}
+
+func TestPathMatchPortable(t *testing.T) {
+	for _, test := range []struct {
+		Match PathMatch
+		Want  string
+	}{
+		{PathMatch{FolderLocation{"Documents", `C:\Docs`}, ""}, "{Documents}"},
+		{PathMatch{FolderLocation{"LocalAppData", `C:\Local`}, `Temp\x`}, `{LocalAppData}\Temp\x`},
+	} {
+		if got := test.Match.Portable(); got != test.Want {
+			t.Errorf("%+v is %q, want %q", test.Match, got, test.Want)
+		}
+	}
+}
+
+func TestWritePathMatches(t *testing.T) {
+	var b bytes.Buffer
+	err := WritePathMatches(&b, []PathMatch{
+		{FolderLocation{"Documents", `C:\Docs`}, ""},
+		{FolderLocation{"LocalAppData", `C:\Local`}, `Temp\x`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"FOLDER        LOCATION  RELATIVE  PORTABLE\n" +
+		"Documents     C:\\Docs   .         {Documents}\n" +
+		"LocalAppData  C:\\Local  Temp\\x    {LocalAppData}\\Temp\\x\n"
+	if b.String() != want {
+		t.Errorf("Table is\n%v\nwant\n%v", b.String(), want)
+	}
+}
+
+func TestFolderLocations(t *testing.T) {
+	backend := newFakeBackend(map[string]string{
+		"Documents":    `C:\Docs`,
+		"Music":        "",
+		"LocalAppData": `C:\Local`,
+	})
+	want := []FolderLocation{{"Documents", `C:\Docs`}, {"LocalAppData", `C:\Local`}}
+	if got := FolderLocations(backend); !reflect.DeepEqual(got, want) {
+		t.Errorf("FolderLocations returned %+v, want %+v", got, want)
+	}
+}
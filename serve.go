@@ -0,0 +1,199 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxRequestBody is the largest request body the server reads, which is far
+// more than any folder location needs.
+const maxRequestBody = 64 << 10
+
+// Timeouts of the server's connections, so that slow or idle clients can't
+// hold them open. Responses have no timeout, since setting a folder may wait
+// for the lock on the target for as long as --lock-timeout.
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = 30 * time.Second
+	idleTimeout       = 2 * time.Minute
+)
+
+// Server exposes list, get and set over HTTP:
+//
+//	GET /folders           list all known folders (without locations)
+//	GET /folders/FOLDER    get the location of FOLDER
+//	PUT /folders/FOLDER    set the location of FOLDER to the "location"
+//	                       property of the JSON request body
+//
+// FOLDER may be any name accepted on the command line, including aliases.
+// Every request must carry an "Authorization: Bearer TOKEN" header.
+type Server struct {
+	Backend Backend
+	Token   string
+	// Lock, if set, is called before each folder is set to lock the target
+	// against other knownfolder processes, and returns a function which
+	// unlocks it again
+	Lock func() (unlock func(), err error)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type setRequest struct {
+	Location string `json:"location"`
+}
+
+// ReadToken reads a bearer token from the given file, ignoring leading and
+// trailing white space.
+func ReadToken(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("Token file %v is empty", file)
+	}
+	return token, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="knownfolder"`)
+		writeJSON(w, http.StatusUnauthorized, errorResponse{"Missing or invalid bearer token"})
+		return
+	}
+	switch {
+	case r.URL.Path == "/folders":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		s.list(w)
+	case strings.HasPrefix(r.URL.Path, "/folders/"):
+		name := strings.TrimPrefix(r.URL.Path, "/folders/")
+		switch r.Method {
+		case http.MethodGet:
+			s.get(w, name)
+		case http.MethodPut:
+			s.set(w, r, name)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPut)
+		}
+	default:
+		writeJSON(w, http.StatusNotFound, errorResponse{"Not found"})
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+func (s *Server) list(w http.ResponseWriter) {
+	folders := make([]Folder, 0, len(knownfolders))
+	for name, guid := range knownfolders {
+		folders = append(folders, Folder{Name: name, GUID: guid.String()})
+	}
+	sort.Slice(folders, func(i, j int) bool { return folders[i].Name < folders[j].Name })
+	writeJSON(w, http.StatusOK, folders)
+}
+
+func (s *Server) get(w http.ResponseWriter, name string) {
+	folder, err := LookupFolder(s.Backend, name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, folder)
+}
+
+func (s *Server) set(w http.ResponseWriter, r *http.Request, name string) {
+	var body setRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	if err := decoder.Decode(&body); err != nil || body.Location == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{`Request body must be a JSON object with a non-empty "location" property`})
+		return
+	}
+	canonical, ok := resolveFolder(name)
+	if !ok {
+		writeError(w, UnknownFolderError(name))
+		return
+	}
+	if s.Lock != nil {
+		unlock, err := s.Lock()
+		if err != nil {
+			log.Printf("Could not set folder location %v=%v: %v", canonical, body.Location, err)
+			writeError(w, err)
+			return
+		}
+		defer unlock()
+	}
+	if err := s.Backend.SetFolder(canonical, body.Location); err != nil {
+		log.Printf("Could not set folder location %v=%v: %v", canonical, body.Location, err)
+		writeError(w, err)
+		return
+	}
+	log.Printf("%v=%v (requested by %v)", canonical, body.Location, r.RemoteAddr)
+	writeJSON(w, http.StatusOK, Folder{
+		Name:     canonical,
+		GUID:     knownfolders[canonical].String(),
+		Location: body.Location,
+	})
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeJSON(w, http.StatusMethodNotAllowed, errorResponse{"Method not allowed"})
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch err.(type) {
+	case UnknownFolderError:
+		status = http.StatusNotFound
+	case LockedError:
+		status = http.StatusConflict
+	}
+	writeJSON(w, status, errorResponse{err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Could not write response: %v", err)
+	}
+}
+
+// ListenAndServe serves s on addr, using TLS if certFile is not empty.
+func (s *Server) ListenAndServe(addr, certFile, keyFile string) error {
+	server := s.httpServer(addr)
+	if certFile != "" {
+		return server.ListenAndServeTLS(certFile, keyFile)
+	}
+	return server.ListenAndServe()
+}
+
+// httpServer returns the http.Server serving s on addr.
+func (s *Server) httpServer(addr string) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+}
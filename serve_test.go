@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	backend := newFakeBackend(map[string]string{"Documents": `C:\Users\pete\Documents`})
+	backend.Errors["Music"] = errors.New("Access is denied.")
+	server := httptest.NewServer(&Server{Backend: backend, Token: "s3cret"})
+	defer server.Close()
+
+	for _, test := range []struct {
+		Name     string
+		Method   string
+		Path     string
+		Token    string
+		Body     string
+		Status   int
+		Response string
+	}{
+		{"no token", "GET", "/folders/Documents", "", "", http.StatusUnauthorized, "Missing or invalid bearer token"},
+		{"wrong token", "GET", "/folders/Documents", "guess", "", http.StatusUnauthorized, "Missing or invalid bearer token"},
+		{"list", "GET", "/folders", "s3cret", "", http.StatusOK, `"name":"Documents","guid":"{FDD39AD0-238F-46AF-ADB4-6C85480369C7}"`},
+		{"get", "GET", "/folders/Documents", "s3cret", "", http.StatusOK, `"location":"C:\\Users\\pete\\Documents"`},
+		{"get alias", "GET", "/folders/CSIDL_PERSONAL", "s3cret", "", http.StatusOK, `"name":"Documents"`},
+		{"get unknown", "GET", "/folders/Nope", "s3cret", "", http.StatusNotFound, `Unknown folder \"Nope\"`},
+		{"get unset", "GET", "/folders/Videos", "s3cret", "", http.StatusInternalServerError, "Folder Videos has no location"},
+		{"bad path", "GET", "/nope", "s3cret", "", http.StatusNotFound, "Not found"},
+		{"bad method", "DELETE", "/folders/Documents", "s3cret", "", http.StatusMethodNotAllowed, "Method not allowed"},
+		{"put invalid", "PUT", "/folders/Documents", "s3cret", `D:\Docs`, http.StatusBadRequest, `non-empty \"location\" property`},
+		{"put empty", "PUT", "/folders/Documents", "s3cret", `{"location":""}`, http.StatusBadRequest, `non-empty \"location\" property`},
+		{"put too large", "PUT", "/folders/Documents", "s3cret", `{"location":"` + strings.Repeat("x", maxRequestBody) + `"}`, http.StatusBadRequest, `non-empty \"location\" property`},
+		{"put unknown", "PUT", "/folders/Nope", "s3cret", `{"location":"D:\\Nope"}`, http.StatusNotFound, `Unknown folder \"Nope\"`},
+		{"put failing", "PUT", "/folders/Music", "s3cret", `{"location":"D:\\Music"}`, http.StatusInternalServerError, "Access is denied."},
+		{"put", "PUT", "/folders/CSIDL_PERSONAL", "s3cret", `{"location":"D:\\Docs"}`, http.StatusOK, `{"name":"Documents","guid":"{FDD39AD0-238F-46AF-ADB4-6C85480369C7}","location":"D:\\Docs"}`},
+	} {
+		request, err := http.NewRequest(test.Method, server.URL+test.Path, strings.NewReader(test.Body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.Token != "" {
+			request.Header.Set("Authorization", "Bearer "+test.Token)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body json.RawMessage
+		err = json.NewDecoder(response.Body).Decode(&body)
+		response.Body.Close()
+		if err != nil {
+			t.Errorf("%v: invalid JSON response: %v", test.Name, err)
+			continue
+		}
+		if response.StatusCode != test.Status || !strings.Contains(string(body), test.Response) {
+			t.Errorf("%v: got %v %s, want %v containing %s", test.Name, response.StatusCode, body, test.Status, test.Response)
+		}
+	}
+	if location := backend.Folders["Documents"]; location != `D:\Docs` {
+		t.Errorf("Documents is %v after PUT, want D:\\Docs", location)
+	}
+	if len(backend.Calls) != 2 {
+		t.Errorf("Backend was called to set %v, want only Music and Documents", backend.Calls)
+	}
+}
+
+func TestServerLock(t *testing.T) {
+	backend := newFakeBackend(nil)
+	locked := false
+	var lockErr error
+	backend.OnSet = func(folder, location string) {
+		if !locked {
+			t.Errorf("Set %v without holding the lock", folder)
+		}
+	}
+	server := httptest.NewServer(&Server{
+		Backend: backend,
+		Token:   "s3cret",
+		Lock: func() (func(), error) {
+			if lockErr != nil {
+				return nil, lockErr
+			}
+			locked = true
+			return func() { locked = false }, nil
+		},
+	})
+	defer server.Close()
+	put := func() (int, string) {
+		request, err := http.NewRequest("PUT", server.URL+"/folders/Documents", strings.NewReader(`{"location":"D:\\Docs"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("Authorization", "Bearer s3cret")
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		var body errorResponse
+		json.NewDecoder(response.Body).Decode(&body)
+		return response.StatusCode, body.Error
+	}
+	if status, _ := put(); status != http.StatusOK || locked {
+		t.Errorf("PUT returned %v, and left the lock held: %v", status, locked)
+	}
+	lockErr = LockedError{Scope: "current user"}
+	if status, message := put(); status != http.StatusConflict || message != lockErr.Error() {
+		t.Errorf("PUT of a locked target returned %v %q", status, message)
+	}
+	if len(backend.Calls) != 1 {
+		t.Errorf("Set %q, want only the PUT which got the lock", backend.Calls)
+	}
+}
+
+func TestServerTimeouts(t *testing.T) {
+	server := (&Server{Backend: newFakeBackend(nil), Token: "s3cret"}).httpServer("127.0.0.1:0")
+	if server.ReadHeaderTimeout != readHeaderTimeout || server.ReadTimeout != readTimeout || server.IdleTimeout != idleTimeout {
+		t.Errorf("Server has timeouts %v, %v and %v", server.ReadHeaderTimeout, server.ReadTimeout, server.IdleTimeout)
+	}
+	// a client which never finishes its headers is disconnected
+	server.ReadHeaderTimeout = 50 * time.Millisecond
+	l, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(l)
+	defer server.Close()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("GET /folders HTTP/1.1\r\nHost: localhost\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if _, err := ioutil.ReadAll(conn); err != nil {
+		t.Errorf("Connection wasn't closed after the header timeout: %v", err)
+	}
+}
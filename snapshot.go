@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Snapshot returns a Manifest of the current locations of the known folders
+// of backend, which can be applied later, or to another target, to put them
+// back where they are now. Fixed and virtual folders, whose locations can't
+// be set, are left out.
+func Snapshot(backend Backend) *Manifest {
+	manifest := &Manifest{Folders: map[string]string{}}
+	for _, l := range FolderLocations(backend) {
+		if d, ok := describeFolder(l.Folder, nil); ok && d.Category != KF_CATEGORY_PERUSER && d.Category != KF_CATEGORY_COMMON {
+			continue
+		}
+		manifest.Folders[l.Folder] = l.Location
+	}
+	return manifest
+}
+
+// FolderDifference is a folder whose current location differs from the one
+// a manifest gives it.
+type FolderDifference struct {
+	Folder string
+	// Current is the current location of the folder, or "" if it couldn't
+	// be retrieved, in which case Err is why not
+	Current string
+	Err     error
+	// Location is the location the manifest gives the folder
+	Location string
+}
+
+// DiffManifest compares the locations of the folders in manifest, rendered
+// for the user username, with their current locations for backend, and
+// returns those which differ, in dependency order.
+func DiffManifest(backend Backend, manifest *Manifest, username string) ([]FolderDifference, error) {
+	entries, err := manifest.Render(TemplateData{Username: username})
+	if err != nil {
+		return nil, err
+	}
+	entries, err = manifest.Expand(entries, backend.GetFolder)
+	if err != nil {
+		return nil, err
+	}
+	var differences []FolderDifference
+	for _, entry := range entries {
+		current, err := backend.GetFolder(entry.Folder)
+		if err == nil && samePath(current, entry.Location) {
+			continue
+		}
+		differences = append(differences, FolderDifference{Folder: entry.Folder, Current: current, Err: err, Location: entry.Location})
+	}
+	return differences, nil
+}
+
+// WriteDifferences writes differences to w as a table.
+func WriteDifferences(w io.Writer, differences []FolderDifference) error {
+	t := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(t, "FOLDER\tCURRENT\tMANIFEST")
+	for _, d := range differences {
+		current := d.Current
+		if d.Err != nil {
+			current = fmt.Sprintf("<%v>", d.Err)
+		}
+		fmt.Fprintf(t, "%v\t%v\t%v\n", d.Folder, current, d.Location)
+	}
+	return t.Flush()
+}
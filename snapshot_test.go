@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	backend := newFakeBackend(map[string]string{
+		"Documents":          `D:\Docs`,
+		"PublicDocuments":    `C:\Users\Public\Documents`,
+		"Profile":            `C:\Users\pete`,
+		"Windows":            `C:\Windows`,
+		"ControlPanelFolder": "",
+		"Music":              "",
+	})
+	want := map[string]string{
+		"Documents":       `D:\Docs`,
+		"PublicDocuments": `C:\Users\Public\Documents`,
+	}
+	got := Snapshot(backend)
+	if !reflect.DeepEqual(got.Folders, want) || len(got.Variables) != 0 {
+		t.Errorf("Snapshot is %+v, want folders %v", got, want)
+	}
+}
+
+func TestDiffManifest(t *testing.T) {
+	backend := newFakeBackend(map[string]string{
+		"Profile":   `C:\Users\pete`,
+		"Documents": `c:\users\PETE\Docs\`,
+		"Downloads": `D:\pete`,
+		"Music":     `C:\Users\pete\Music`,
+	})
+	manifest := &Manifest{Folders: map[string]string{
+		"Documents": `{Profile}\Docs`,
+		"Downloads": `D:\{{.Username}}`,
+		"Music":     `{Documents}\Music`,
+		"Videos":    `D:\Videos`,
+	}}
+	got, err := DiffManifest(backend, manifest, "pete")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FolderDifference{
+		{Folder: "Music", Current: `C:\Users\pete\Music`, Location: `C:\Users\pete\Docs\Music`},
+		{Folder: "Videos", Err: errors.New("Folder Videos has no location"), Location: `D:\Videos`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Differences are %+v, want %+v", got, want)
+	}
+	if len(backend.Calls) != 0 {
+		t.Errorf("DiffManifest set folders: %v", backend.Calls)
+	}
+	manifest.Folders["Downloads"] = `D:\{{.Nobody}}`
+	if _, err := DiffManifest(backend, manifest, "pete"); err == nil {
+		t.Error("Expected an error for a manifest that can't be rendered")
+	}
+}
+
+func TestWriteDifferences(t *testing.T) {
+	var b bytes.Buffer
+	err := WriteDifferences(&b, []FolderDifference{
+		{Folder: "Music", Current: `C:\Music`, Location: `D:\Music`},
+		{Folder: "Videos", Err: errors.New("no location"), Location: `D:\Videos`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"FOLDER  CURRENT        MANIFEST\n" +
+		"Music   C:\\Music       D:\\Music\n" +
+		"Videos  <no location>  D:\\Videos\n"
+	if b.String() != want {
+		t.Errorf("Table is\n%v\nwant\n%v", b.String(), want)
+	}
+}
+
+func TestSnapshotAndDiffImage(t *testing.T) {
+	root := testImage(t)
+	backend := newTestImageBackend(t, root, "alice")
+	snapshot := Snapshot(backend)
+	want := map[string]string{
+		"Documents":       `D:\alice\Docs`,
+		"Downloads":       `C:\Users\alice\Downloads`,
+		"PublicDocuments": `C:\Users\Public\Documents`,
+	}
+	if !reflect.DeepEqual(snapshot.Folders, want) {
+		t.Fatalf("Snapshot of the image is %v, want %v", snapshot.Folders, want)
+	}
+	if differences, err := DiffManifest(backend, snapshot, "alice"); err != nil || len(differences) != 0 {
+		t.Fatalf("Image differs from its own snapshot: %+v (%v)", differences, err)
+	}
+	if err := backend.SetFolder("Downloads", `E:\dl`); err != nil {
+		t.Fatal(err)
+	}
+	differences, err := DiffManifest(newTestImageBackend(t, root, "alice"), snapshot, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDifferences := []FolderDifference{{Folder: "Downloads", Current: `E:\dl`, Location: `C:\Users\alice\Downloads`}}
+	if !reflect.DeepEqual(differences, wantDifferences) {
+		t.Errorf("Differences are %+v, want %+v", differences, wantDifferences)
+	}
+}
@@ -0,0 +1,8 @@
+# bash completion for knownfolder
+# Install with: knownfolder completion bash > /etc/bash_completion.d/knownfolder
+
+_knownfolder() {
+    local IFS=$'\n'
+    COMPREPLY=($(knownfolder __complete $((COMP_CWORD - 1)) "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _knownfolder knownfolder knownfolder.exe
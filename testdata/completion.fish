@@ -0,0 +1,9 @@
+# fish completion for knownfolder
+# Install with: knownfolder completion fish > ~/.config/fish/completions/knownfolder.fish
+
+function __knownfolder_complete
+    set -l words (commandline -opc)
+    knownfolder __complete (math (count $words) - 1) $words[2..-1] (commandline -ct) 2>/dev/null
+end
+
+complete -c knownfolder -c knownfolder.exe -f -a '(__knownfolder_complete)'
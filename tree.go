@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// FolderNode is a known folder in the hierarchy of folders that are defined
+// relative to a parent folder, e.g. Startup under Programs under
+// StartMenu under RoamingAppData under Profile.
+type FolderNode struct {
+	Folder       string
+	RelativePath string
+	// Location is the current location of the folder, "" if not known.
+	Location string
+	Parent   *FolderNode
+	Children []*FolderNode
+}
+
+// BuildFolderTree returns the roots of the hierarchy of the folders in
+// descriptions, sorted by name, as are the children of each folder. Folders
+// are named as in knownfolders where possible. A folder whose parent isn't
+// described is a root, as are the folders in a cycle of parents, which a
+// broken registration can leave.
+func BuildFolderTree(descriptions []FolderDescription) []*FolderNode {
+	nodes := map[string]*FolderNode{}
+	for _, d := range descriptions {
+		guid := d.GUID.String()
+		name, ok := folderByGUID(guid)
+		if !ok {
+			name = d.Name
+		}
+		nodes[guid] = &FolderNode{Folder: name, RelativePath: d.RelativePath}
+	}
+	parents := map[*FolderNode]*FolderNode{}
+	for _, d := range descriptions {
+		node := nodes[d.GUID.String()]
+		if parent, ok := nodes[strings.ToUpper("{"+strings.Trim(d.Parent, "{}")+"}")]; d.Parent != "" && ok {
+			parents[node] = parent
+		}
+	}
+	roots := []*FolderNode{}
+	for _, d := range descriptions {
+		node := nodes[d.GUID.String()]
+		parent, ok := parents[node]
+		if !ok || inParentCycle(node, parents) {
+			roots = append(roots, node)
+			continue
+		}
+		node.Parent = parent
+		parent.Children = append(parent.Children, node)
+	}
+	sortFolderNodes(roots)
+	return roots
+}
+
+// inParentCycle reports whether following the parents of node leads back to
+// it, including when it is its own parent.
+func inParentCycle(node *FolderNode, parents map[*FolderNode]*FolderNode) bool {
+	seen := map[*FolderNode]bool{}
+	for n := parents[node]; n != nil && !seen[n]; n = parents[n] {
+		if n == node {
+			return true
+		}
+		seen[n] = true
+	}
+	return false
+}
+
+func sortFolderNodes(nodes []*FolderNode) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Folder < nodes[j].Folder })
+	for _, n := range nodes {
+		sortFolderNodes(n.Children)
+	}
+}
+
+// FindFolderNode returns the node of folder in the trees below roots, or nil.
+func FindFolderNode(roots []*FolderNode, folder string) *FolderNode {
+	for _, n := range roots {
+		if n.Folder == folder {
+			return n
+		}
+		if found := FindFolderNode(n.Children, folder); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Locate sets the Location of n and every folder below it with lookup.
+// Folders whose location can't be looked up are left with none.
+func (n *FolderNode) Locate(lookup FolderLookup) {
+	if location, err := lookup(n.Folder); err == nil {
+		n.Location = location
+	}
+	for _, child := range n.Children {
+		child.Locate(lookup)
+	}
+}
+
+// DefaultLocation returns where n would be if it hadn't been redirected,
+// i.e. its relative path under its parent's location, or "" if that isn't
+// known.
+func (n *FolderNode) DefaultLocation() string {
+	if n.Parent == nil || n.Parent.Location == "" || n.RelativePath == "" {
+		return ""
+	}
+	return strings.TrimRight(n.Parent.Location, `\`) + `\` + n.RelativePath
+}
+
+// Redirected reports whether n has been moved away from its default
+// location under its parent, so that it no longer moves along with it.
+func (n *FolderNode) Redirected() bool {
+	def := n.DefaultLocation()
+	if def == "" || n.Location == "" {
+		return false
+	}
+	return !strings.EqualFold(normalizeWindowsPath(def), normalizeWindowsPath(n.Location))
+}
+
+// Followers returns the names of the folders below n that move along with
+// it if it is redirected, i.e. those that haven't themselves been
+// redirected away from their parents, in tree order.
+func (n *FolderNode) Followers() []string {
+	followers := []string{}
+	for _, child := range n.Children {
+		if child.Redirected() {
+			continue
+		}
+		followers = append(followers, child.Folder)
+		followers = append(followers, child.Followers()...)
+	}
+	return followers
+}
+
+// WriteFolderTree writes the trees below roots to w, one folder per line,
+// indented by depth, with its location. Folders without a known location
+// are shown relative to their parent, e.g. {Profile}\Desktop.
+func WriteFolderTree(w io.Writer, roots []*FolderNode) error {
+	for _, n := range roots {
+		if err := writeFolderNode(w, n, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFolderNode(w io.Writer, n *FolderNode, depth int) error {
+	line := strings.Repeat("  ", depth) + n.Folder
+	switch {
+	case n.Location != "":
+		line += "  " + n.Location
+		if n.Redirected() {
+			line += "  (redirected from " + n.DefaultLocation() + ")"
+		}
+	case n.Parent != nil && n.RelativePath != "":
+		line += "  {" + n.Parent.Folder + `}\` + n.RelativePath
+	}
+	if _, err := fmt.Fprintln(w, line); err != nil {
+		return err
+	}
+	for _, child := range n.Children {
+		if err := writeFolderNode(w, child, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"reflect"
+	"testing"
+)
+
+// testFolderDescriptions describes Startup under Programs under StartMenu
+// under RoamingAppData under Profile, Documents under Profile with a
+// registered folder below it, and a registered folder whose parent isn't
+// described. The parent GUIDs are written the ways the registry may have
+// them.
+func testFolderDescriptions(t *testing.T) []FolderDescription {
+	return []FolderDescription{
+		{GUID: *knownfolders["Startup"], Name: "Startup", Parent: "{A77F5D77-2E2B-44C3-A6A2-ABA601054A51}", RelativePath: "Startup"},
+		{GUID: *knownfolders["Programs"], Name: "Programs", Parent: "{625b53c3-ab48-4ec1-ba1f-a1ef4146fc19}", RelativePath: "Programs"},
+		{GUID: *knownfolders["StartMenu"], Name: "Start Menu", Parent: "3EB685DB-65F9-4CF6-A03A-E3EF65729F3D", RelativePath: `Microsoft\Windows\Start Menu`},
+		{GUID: *knownfolders["RoamingAppData"], Name: "AppData", Parent: "{5E6C858F-0E22-4760-9AFE-EA3317B67173}", RelativePath: `AppData\Roaming`},
+		{GUID: *knownfolders["Profile"], Name: "Profile"},
+		{GUID: *knownfolders["Documents"], Name: "Personal", Parent: "{5E6C858F-0E22-4760-9AFE-EA3317B67173}", RelativePath: "Documents"},
+		{GUID: testGUID(t, "{1A2B3C4D-0000-0000-0000-000000000001}"), Name: "AcmeData", Parent: "{FDD39AD0-238F-46AF-ADB4-6C85480369C7}", RelativePath: "Acme"},
+		{GUID: testGUID(t, "{1A2B3C4D-0000-0000-0000-000000000002}"), Name: "AcmeOrphan", Parent: "{1A2B3C4D-0000-0000-0000-0000000000FF}", RelativePath: "Orphan"},
+	}
+}
+
+func writeTestTree(t *testing.T, roots []*FolderNode) string {
+	var b bytes.Buffer
+	if err := WriteFolderTree(&b, roots); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestBuildFolderTree(t *testing.T) {
+	roots := BuildFolderTree(testFolderDescriptions(t))
+	want := "AcmeOrphan\n" +
+		"Profile\n" +
+		"  Documents  {Profile}\\Documents\n" +
+		"    AcmeData  {Documents}\\Acme\n" +
+		"  RoamingAppData  {Profile}\\AppData\\Roaming\n" +
+		"    StartMenu  {RoamingAppData}\\Microsoft\\Windows\\Start Menu\n" +
+		"      Programs  {StartMenu}\\Programs\n" +
+		"        Startup  {Programs}\\Startup\n"
+	if got := writeTestTree(t, roots); got != want {
+		t.Errorf("Built\n%v\nwant\n%v", got, want)
+	}
+	if n := FindFolderNode(roots, "Programs"); n == nil || n.Parent.Folder != "StartMenu" {
+		t.Errorf("Found Programs as %+v", n)
+	}
+	if n := FindFolderNode(roots, "Music"); n != nil {
+		t.Errorf("Found undescribed Music as %+v", n)
+	}
+}
+
+func TestBuildFolderTreeCycles(t *testing.T) {
+	roots := BuildFolderTree([]FolderDescription{
+		{GUID: testGUID(t, "{1A2B3C4D-0000-0000-0000-000000000001}"), Name: "AcmeA", Parent: "{1A2B3C4D-0000-0000-0000-000000000002}", RelativePath: "A"},
+		{GUID: testGUID(t, "{1A2B3C4D-0000-0000-0000-000000000002}"), Name: "AcmeB", Parent: "{1A2B3C4D-0000-0000-0000-000000000001}", RelativePath: "B"},
+		{GUID: testGUID(t, "{1A2B3C4D-0000-0000-0000-000000000003}"), Name: "AcmeC", Parent: "{1A2B3C4D-0000-0000-0000-000000000001}", RelativePath: "C"},
+		{GUID: testGUID(t, "{1A2B3C4D-0000-0000-0000-000000000004}"), Name: "AcmeSelf", Parent: "{1A2B3C4D-0000-0000-0000-000000000004}", RelativePath: "Self"},
+	})
+	want := "AcmeA\n" +
+		"  AcmeC  {AcmeA}\\C\n" +
+		"AcmeB\n" +
+		"AcmeSelf\n"
+	if got := writeTestTree(t, roots); got != want {
+		t.Errorf("Built\n%v\nwant\n%v", got, want)
+	}
+	for _, n := range roots {
+		n.Locate(func(folder string) (string, error) { return `D:\` + folder, nil })
+		n.Followers()
+	}
+	if n := FindFolderNode(roots, "AcmeC"); n == nil || n.Location != `D:\AcmeC` || !n.Redirected() {
+		t.Errorf("Located AcmeC as %+v", n)
+	}
+}
+
+func TestFolderTreeLocations(t *testing.T) {
+	roots := BuildFolderTree(testFolderDescriptions(t))
+	locations := map[string]string{
+		"Profile":        `C:\Users\bob`,
+		"RoamingAppData": `C:\Users\bob\AppData\Roaming`,
+		"StartMenu":      `D:\Start Menu`,
+		"Programs":       `D:\Start Menu\Programs`,
+		"Startup":        `D:\Start Menu\Programs\Startup`,
+		// the same as the default, but for case and the trailing backslash
+		"Documents": `c:\users\BOB\documents\`,
+	}
+	profile := FindFolderNode(roots, "Profile")
+	profile.Locate(func(folder string) (string, error) {
+		if location, ok := locations[folder]; ok {
+			return location, nil
+		}
+		return "", errors.New("The system cannot find the file specified.")
+	})
+	for _, test := range []struct {
+		Folder     string
+		Default    string
+		Redirected bool
+	}{
+		{"Profile", "", false},
+		{"Documents", `C:\Users\bob\Documents`, false},
+		{"AcmeData", `c:\users\BOB\documents\Acme`, false},
+		{"RoamingAppData", `C:\Users\bob\AppData\Roaming`, false},
+		{"StartMenu", `C:\Users\bob\AppData\Roaming\Microsoft\Windows\Start Menu`, true},
+		{"Programs", `D:\Start Menu\Programs`, false},
+	} {
+		n := FindFolderNode(roots, test.Folder)
+		if def := n.DefaultLocation(); def != test.Default {
+			t.Errorf("Default location of %v is %q, want %q", test.Folder, def, test.Default)
+		}
+		if redirected := n.Redirected(); redirected != test.Redirected {
+			t.Errorf("%v is redirected %v, want %v", test.Folder, redirected, test.Redirected)
+		}
+	}
+	for _, test := range []struct {
+		Folder string
+		Want   []string
+	}{
+		// StartMenu has moved away, taking Programs and Startup along
+		{"Profile", []string{"Documents", "AcmeData", "RoamingAppData"}},
+		{"StartMenu", []string{"Programs", "Startup"}},
+		{"Startup", []string{}},
+	} {
+		if followers := FindFolderNode(roots, test.Folder).Followers(); !reflect.DeepEqual(followers, test.Want) {
+			t.Errorf("Followers of %v are %q, want %q", test.Folder, followers, test.Want)
+		}
+	}
+	want := "Profile  C:\\Users\\bob\n" +
+		"  Documents  c:\\users\\BOB\\documents\\\n" +
+		"    AcmeData  {Documents}\\Acme\n" +
+		"  RoamingAppData  C:\\Users\\bob\\AppData\\Roaming\n" +
+		"    StartMenu  D:\\Start Menu  (redirected from C:\\Users\\bob\\AppData\\Roaming\\Microsoft\\Windows\\Start Menu)\n" +
+		"      Programs  D:\\Start Menu\\Programs\n" +
+		"        Startup  D:\\Start Menu\\Programs\\Startup\n"
+	if got := writeTestTree(t, []*FolderNode{profile}); got != want {
+		t.Errorf("Wrote\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestWarnFollowers(t *testing.T) {
+	root := testImage(t)
+	output, flags := log.Writer(), log.Flags()
+	defer log.SetOutput(output)
+	defer log.SetFlags(flags)
+	log.SetFlags(0)
+	for _, test := range []struct {
+		Profile string
+		Want    string
+	}{
+		{"Default", "Setting Profile also moves Documents, Downloads, which are located relative to it\n"},
+		// alice's Documents are redirected, so stay where they are
+		{"alice", "Setting Profile also moves Downloads, which are located relative to it\n"},
+	} {
+		arguments := map[string]interface{}{"--image-root": root, "--profile": test.Profile}
+		backend, close, _, err := offlineBackend(arguments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		log.SetOutput(&b)
+		warnFollowers(arguments, "Profile", backend)
+		close()
+		if got := b.String(); got != test.Want {
+			t.Errorf("%v: logged %q, want %q", test.Profile, got, test.Want)
+		}
+	}
+}
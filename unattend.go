@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// unattendPasses maps each configuration pass that export can target to the
+// component and elements used to run commands during that pass.
+var unattendPasses = map[string]struct {
+	Component string
+	List      string
+	Command   string
+	Field     string
+}{
+	// https://docs.microsoft.com/en-us/windows-hardware/customize/desktop/unattend/microsoft-windows-deployment-runsynchronous-runsynchronouscommand
+	"specialize": {"Microsoft-Windows-Deployment", "RunSynchronous", "RunSynchronousCommand", "Path"},
+	// https://docs.microsoft.com/en-us/windows-hardware/customize/desktop/unattend/microsoft-windows-shell-setup-firstlogoncommands-synchronouscommand
+	"oobeSystem": {"Microsoft-Windows-Shell-Setup", "FirstLogonCommands", "SynchronousCommand", "CommandLine"},
+}
+
+const unattendSkeleton = `<?xml version="1.0" encoding="utf-8"?>
+<unattend xmlns="urn:schemas-microsoft-com:unattend">
+</unattend>
+`
+
+// unattendDescriptionPrefix marks the commands that export generates, so that
+// they can be found again when merging into an existing answer file.
+const unattendDescriptionPrefix = "knownfolder: set "
+
+// ExportUnattend writes an answer file which runs "knownfolder set -d" for
+// each manifest entry during the configured pass. If options.MergeFile is
+// set, the commands are merged into that answer file: commands previously
+// generated for the same folders are updated where they are, keeping their
+// Order, and new commands are appended, numbered after any existing ones.
+func ExportUnattend(w io.Writer, entries []ManifestEntry, options ExportOptions) error {
+	var source io.Reader = strings.NewReader(unattendSkeleton)
+	if options.MergeFile != "" {
+		f, err := os.Open(options.MergeFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		source = f
+	}
+	doc, err := parseXML(source)
+	if err != nil {
+		return err
+	}
+	err = mergeUnattend(doc, entries, options)
+	if err != nil {
+		return err
+	}
+	return writeXML(w, doc)
+}
+
+func mergeUnattend(doc *xmlNode, entries []ManifestEntry, options ExportOptions) error {
+	pass, ok := unattendPasses[options.Pass]
+	if !ok {
+		return fmt.Errorf(`Unsupported configuration pass "%v" - must be specialize or oobeSystem`, options.Pass)
+	}
+	unattend := doc.element("unattend")
+	if unattend == nil {
+		return fmt.Errorf("No <unattend> element found")
+	}
+
+	var settings *xmlNode
+	for _, s := range unattend.elements("settings") {
+		if s.attr("pass") == options.Pass {
+			settings = s
+			break
+		}
+	}
+	if settings == nil {
+		settings = newXMLElement("settings", "pass", options.Pass)
+		unattend.appendElement(settings)
+	}
+
+	var component *xmlNode
+	for _, c := range settings.elements("component") {
+		if c.attr("name") == pass.Component && c.attr("processorArchitecture") == options.Architecture {
+			component = c
+			break
+		}
+	}
+	if component == nil {
+		component = newXMLElement("component",
+			"name", pass.Component,
+			"processorArchitecture", options.Architecture,
+			"publicKeyToken", "31bf3856ad364e35",
+			"language", "neutral",
+			"versionScope", "nonSxS",
+			"xmlns:wcm", "http://schemas.microsoft.com/WMIConfig/2002/State",
+			"xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance",
+		)
+		settings.appendElement(component)
+	}
+
+	list := component.element(pass.List)
+	if list == nil {
+		list = newXMLElement(pass.List)
+		component.appendElement(list)
+	}
+
+	existing := map[string]*xmlNode{}
+	maxOrder := 0
+	for _, command := range list.elements(pass.Command) {
+		if order, err := strconv.Atoi(command.childText("Order")); err == nil && order > maxOrder {
+			maxOrder = order
+		}
+		if description := command.childText("Description"); strings.HasPrefix(description, unattendDescriptionPrefix) {
+			existing[strings.TrimPrefix(description, unattendDescriptionPrefix)] = command
+		}
+	}
+
+	for _, entry := range entries {
+		commandLine := strings.Join([]string{
+			windowsQuoteArg(options.Executable),
+			"set",
+			"-d",
+			entry.Folder,
+			windowsQuoteArg(entry.Location),
+		}, " ")
+		if command, ok := existing[entry.Folder]; ok {
+			if field := command.element(pass.Field); field != nil {
+				field.setText(commandLine)
+			} else {
+				command.appendElement(newXMLTextElement(pass.Field, commandLine))
+			}
+			continue
+		}
+		maxOrder++
+		command := newXMLElement(pass.Command, "wcm:action", "add")
+		list.appendElement(command)
+		command.appendElement(newXMLTextElement("Order", strconv.Itoa(maxOrder)))
+		command.appendElement(newXMLTextElement(pass.Field, commandLine))
+		command.appendElement(newXMLTextElement("Description", unattendDescriptionPrefix+entry.Folder))
+	}
+	return nil
+}
+
+// windowsQuoteArg quotes s, if necessary, so that it is parsed as a single
+// argument by CommandLineToArgvW (and the C runtime), in the same way as
+// syscall.EscapeArg does on Windows.
+func windowsQuoteArg(s string) string {
+	if s == "" {
+		return `""`
+	}
+	if !strings.ContainsAny(s, " \t\"") {
+		return s
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	slashes := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			slashes++
+		case '"':
+			// backslashes preceding a quote must be doubled, and the quote
+			// itself escaped
+			b.WriteString(strings.Repeat(`\`, slashes+1))
+			slashes = 0
+		default:
+			slashes = 0
+		}
+		b.WriteByte(s[i])
+	}
+	// backslashes preceding the closing quote must be doubled
+	b.WriteString(strings.Repeat(`\`, slashes))
+	b.WriteByte('"')
+	return b.String()
+}
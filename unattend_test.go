@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportUnattend(t *testing.T) {
+	for _, test := range []struct {
+		Golden  string
+		Options ExportOptions
+	}{
+		{"unattend-specialize.xml", ExportOptions{Pass: "specialize", Architecture: "amd64", Executable: "knownfolder.exe"}},
+		{"unattend-oobesystem.xml", ExportOptions{Pass: "oobeSystem", Architecture: "x86", Executable: `C:\Program Files\knownfolder\knownfolder.exe`}},
+		// updates the generated command for Documents in place, keeps
+		// the others, and numbers the new ones after the highest Order
+		{"unattend-merged.xml", ExportOptions{Pass: "specialize", Architecture: "amd64", Executable: "knownfolder.exe", MergeFile: filepath.Join("testdata", "unattend-merge.xml")}},
+	} {
+		var b bytes.Buffer
+		if err := Export(&b, "unattend", exportEntries, test.Options); err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, test.Golden, b.Bytes())
+	}
+}
+
+func TestExportUnattendUnknownPass(t *testing.T) {
+	var b bytes.Buffer
+	if err := Export(&b, "unattend", exportEntries, ExportOptions{Pass: "windowsPE", Architecture: "amd64"}); err == nil {
+		t.Errorf("Exporting for the windowsPE pass succeeded")
+	}
+}
+
+func TestWindowsQuoteArg(t *testing.T) {
+	for _, test := range []struct {
+		Arg    string
+		Quoted string
+	}{
+		{``, `""`},
+		{`D:\Docs`, `D:\Docs`},
+		{`D:\My Docs`, `"D:\My Docs"`},
+		{"D:\\tab\there", "\"D:\\tab\there\""},
+		{`D:\My Docs\`, `"D:\My Docs\\"`},
+		{`D:\"quoted"`, `"D:\\\"quoted\""`},
+		{`D:\a\\"b`, `"D:\a\\\\\"b"`},
+		{`D:\O'Brien`, `D:\O'Brien`},
+		{`D:\%USERNAME%`, `D:\%USERNAME%`},
+	} {
+		if quoted := windowsQuoteArg(test.Arg); quoted != test.Quoted {
+			t.Errorf("windowsQuoteArg(%q) = %q, want %q", test.Arg, quoted, test.Quoted)
+		}
+	}
+}
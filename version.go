@@ -0,0 +1,3 @@
+package main
+
+var version = "1.1.0"
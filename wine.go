@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// shellFoldersKey is the key alongside userShellFoldersKey which has the
+// same locations with environment variables expanded, for older
+// applications. Both are relative to HKEY_CURRENT_USER for per user folders,
+// and HKEY_LOCAL_MACHINE for common ones.
+const shellFoldersKey = `Software\Microsoft\Windows\CurrentVersion\Explorer\Shell Folders`
+
+// shellFolderValues are the legacy value names under which folders are
+// stored in the Shell Folders keys, and whether they are common folders,
+// stored in system.reg. Where there is more than one name, Wine and Windows
+// differ, and the first is used unless the other is already present. Other
+// folders are stored in user.reg under their KNOWNFOLDERID.
+var shellFolderValues = []struct {
+	Folder string
+	Names  []string
+	Common bool
+}{
+	{"AdminTools", []string{"Administrative Tools"}, false},
+	{"CDBurning", []string{"CD Burning"}, false},
+	{"Cookies", []string{"Cookies"}, false},
+	{"Desktop", []string{"Desktop"}, false},
+	{"Documents", []string{"Personal"}, false},
+	{"Favorites", []string{"Favorites"}, false},
+	{"Fonts", []string{"Fonts"}, false},
+	{"History", []string{"History"}, false},
+	{"InternetCache", []string{"Cache"}, false},
+	{"LocalAppData", []string{"Local AppData"}, false},
+	{"Music", []string{"My Music"}, false},
+	{"NetHood", []string{"NetHood"}, false},
+	{"Pictures", []string{"My Pictures"}, false},
+	{"PrintHood", []string{"PrintHood"}, false},
+	{"Programs", []string{"Programs"}, false},
+	{"Recent", []string{"Recent"}, false},
+	{"RoamingAppData", []string{"AppData"}, false},
+	{"SendTo", []string{"SendTo"}, false},
+	{"StartMenu", []string{"Start Menu"}, false},
+	{"Startup", []string{"Startup"}, false},
+	{"Templates", []string{"Templates"}, false},
+	{"Videos", []string{"My Videos", "My Video"}, false},
+
+	{"CommonAdminTools", []string{"Common Administrative Tools"}, true},
+	{"CommonOEMLinks", []string{"OEM Links"}, true},
+	{"CommonPrograms", []string{"Common Programs"}, true},
+	{"CommonStartMenu", []string{"Common Start Menu"}, true},
+	{"CommonStartup", []string{"Common Startup"}, true},
+	{"CommonTemplates", []string{"Common Templates"}, true},
+	{"ProgramData", []string{"Common AppData"}, true},
+	{"PublicDesktop", []string{"Common Desktop"}, true},
+	{"PublicDocuments", []string{"Common Documents"}, true},
+	{"PublicMusic", []string{"CommonMusic"}, true},
+	{"PublicPictures", []string{"CommonPictures"}, true},
+	{"PublicVideos", []string{"CommonVideo"}, true},
+}
+
+// WineBackend gets and sets the known folder locations of a Wine prefix, by
+// reading and writing its user.reg and system.reg files directly. Wine must
+// not be running in the prefix (see "wineserver -k"), since it writes these
+// files from memory when it exits. Locations are Windows paths, on the
+// drives that the prefix maps to directories in its dosdevices directory.
+type WineBackend struct {
+	Prefix string
+	// Now returns the time that changed keys are recorded as modified at,
+	// time.Now if nil
+	Now func() time.Time
+}
+
+// registryFile returns the registry file of the prefix that folder is
+// stored in, and the value names it may be stored under.
+func (b *WineBackend) registryFile(folder string) (file string, names []string) {
+	for _, v := range shellFolderValues {
+		if v.Folder == folder {
+			file = "user.reg"
+			if v.Common {
+				file = "system.reg"
+			}
+			return filepath.Join(b.Prefix, file), v.Names
+		}
+	}
+	return filepath.Join(b.Prefix, "user.reg"), []string{knownfolders[folder].String()}
+}
+
+func (b *WineBackend) GetFolder(folder string) (string, error) {
+	file, names := b.registryFile(folder)
+	r, err := loadWineRegistry(file)
+	if err != nil {
+		return "", err
+	}
+	for _, key := range []string{userShellFoldersKey, shellFoldersKey} {
+		for _, name := range names {
+			location, err := r.stringValue(key, name)
+			if _, notFound := err.(RegistryNotFoundError); notFound {
+				continue
+			}
+			if err != nil {
+				return "", err
+			}
+			env, err := b.Environment()
+			if err != nil {
+				return "", err
+			}
+			return expandWindowsEnv(location, env), nil
+		}
+	}
+	return "", fmt.Errorf("Folder %v is not set in %v", folder, file)
+}
+
+// SetFolder sets the location of folder, which may also be given as an
+// absolute Unix path, which is translated to a Windows path on the drive
+// whose directory contains it.
+func (b *WineBackend) SetFolder(folder, location string) error {
+	if strings.HasPrefix(location, "/") {
+		var err error
+		location, err = b.WindowsPath(location)
+		if err != nil {
+			return err
+		}
+	}
+	env, err := b.Environment()
+	if err != nil {
+		return err
+	}
+	expanded := expandWindowsEnv(location, env)
+	if _, err := b.UnixPath(expanded); err != nil {
+		return err
+	}
+	file, names := b.registryFile(folder)
+	r, err := loadWineRegistry(file)
+	if err != nil {
+		return err
+	}
+	name := names[0]
+	for _, n := range names[1:] {
+		if _, err := r.stringValue(userShellFoldersKey, n); err == nil {
+			name = n
+		}
+	}
+	now := time.Now()
+	if b.Now != nil {
+		now = b.Now()
+	}
+	r.setStringValue(userShellFoldersKey, name, location, true, now)
+	r.setStringValue(shellFoldersKey, name, expanded, false, now)
+	return replaceFile(file, r.bytes())
+}
+
+// Environment returns a function which looks up the environment variables
+// that folder locations of the prefix may refer to, as Wine sets them: those
+// of the system and the user in the registry, as well as SystemRoot,
+// SystemDrive, USERPROFILE and USERNAME.
+func (b *WineBackend) Environment() (func(string) string, error) {
+	system, err := loadWineRegistry(filepath.Join(b.Prefix, "system.reg"))
+	if err != nil {
+		return nil, err
+	}
+	user, err := loadWineRegistry(filepath.Join(b.Prefix, "user.reg"))
+	if err != nil {
+		return nil, err
+	}
+	variables := map[string]string{}
+	if root, err := system.stringValue(`Software\Microsoft\Windows NT\CurrentVersion`, "SystemRoot"); err == nil {
+		variables["SYSTEMROOT"] = root
+		variables["WINDIR"] = root
+		variables["SYSTEMDRIVE"] = root[:strings.Index(root+`\`, `\`)]
+	}
+	if profile, err := system.stringValue(`Software\`+profileListKey+`\`+user.sid(), "ProfileImagePath"); err == nil {
+		variables["USERPROFILE"] = profile
+		variables["USERNAME"] = profile[strings.LastIndex(profile, `\`)+1:]
+	}
+	getenv := func(name string) string { return variables[strings.ToUpper(name)] }
+	for _, values := range []map[string]string{
+		system.values(`System\CurrentControlSet\Control\Session Manager\Environment`),
+		user.values("Environment"),
+	} {
+		for name, value := range values {
+			variables[strings.ToUpper(name)] = expandWindowsEnv(value, getenv)
+		}
+	}
+	return getenv, nil
+}
+
+// Drives returns the drives of the prefix, e.g. "C:", and the directory each
+// is mapped to by its dosdevices symlink.
+func (b *WineBackend) Drives() (map[string]string, error) {
+	dir := filepath.Join(b.Prefix, "dosdevices")
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	drives := map[string]string{}
+	for _, e := range entries {
+		name := e.Name()
+		if len(name) != 2 || name[1] != ':' || e.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		target, err := os.Readlink(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(dir, target)
+		}
+		drives[strings.ToUpper(name)] = filepath.Clean(target)
+	}
+	return drives, nil
+}
+
+// WindowsPath returns the Windows path of the absolute Unix path, on the
+// drive mapped to the directory most specifically containing it.
+func (b *WineBackend) WindowsPath(path string) (string, error) {
+	drives, err := b.Drives()
+	if err != nil {
+		return "", err
+	}
+	path = filepath.Clean(path)
+	drive, longest := "", -1
+	for d, dir := range drives {
+		if (path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/")) && len(dir) > longest {
+			drive, longest = d, len(dir)
+		}
+	}
+	if drive == "" {
+		return "", fmt.Errorf("%v is not on any drive of Wine prefix %v", path, b.Prefix)
+	}
+	relative := strings.TrimPrefix(strings.TrimPrefix(path, drives[drive]), "/")
+	return drive + `\` + strings.Replace(relative, "/", `\`, -1), nil
+}
+
+// UnixPath returns the Unix path of the Windows path, which must be on a
+// drive of the prefix.
+func (b *WineBackend) UnixPath(path string) (string, error) {
+	drives, err := b.Drives()
+	if err != nil {
+		return "", err
+	}
+	path = normalizeWindowsPath(path)
+	if len(path) < 2 || path[1] != ':' {
+		return "", fmt.Errorf("%v is not on a drive of Wine prefix %v", path, b.Prefix)
+	}
+	dir, ok := drives[strings.ToUpper(path[:2])]
+	if !ok {
+		return "", fmt.Errorf("Drive %v is not mapped in Wine prefix %v", path[:2], b.Prefix)
+	}
+	return filepath.Join(dir, strings.Replace(path[2:], `\`, "/", -1)), nil
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testWinePrefix returns a Wine prefix with the user.reg and system.reg of
+// testdata/wine, for pete, whose Documents are in the profile, and the
+// directory data, which drives D: and E: are mapped to as well as the
+// relative C: and Z: of every prefix. E: is mapped to data/media, within D:.
+func testWinePrefix(t *testing.T) (prefix, data string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Drive symlinks like c: can't be created on Windows")
+	}
+	root := t.TempDir()
+	prefix, data = filepath.Join(root, "prefix"), filepath.Join(root, "data")
+	for _, dir := range []string{filepath.Join(prefix, "dosdevices"), filepath.Join(prefix, "drive_c"), filepath.Join(data, "media")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"user.reg", "system.reg"} {
+		content, err := ioutil.ReadFile(filepath.Join("testdata", "wine", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(prefix, file), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range map[string]string{
+		"c:":   "../drive_c",
+		"d:":   data,
+		"e:":   filepath.Join(data, "media"),
+		"z:":   "/",
+		"d::":  "/dev/sr0",
+		"com1": "/dev/ttyS0",
+	} {
+		if err := os.Symlink(target, filepath.Join(prefix, "dosdevices", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return prefix, data
+}
+
+func TestWineDrives(t *testing.T) {
+	prefix, data := testWinePrefix(t)
+	// a drive that isn't a symlink isn't mapped
+	if err := os.Mkdir(filepath.Join(prefix, "dosdevices", "f:"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	drives, err := (&WineBackend{Prefix: prefix}).Drives()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"C:": filepath.Join(prefix, "drive_c"),
+		"D:": data,
+		"E:": filepath.Join(data, "media"),
+		"Z:": "/",
+	}
+	if !reflect.DeepEqual(drives, want) {
+		t.Errorf("Drives are %q, want %q", drives, want)
+	}
+	if _, err := (&WineBackend{Prefix: data}).Drives(); !os.IsNotExist(err) {
+		t.Errorf("Drives of a directory without dosdevices returned %v", err)
+	}
+}
+
+func TestWinePaths(t *testing.T) {
+	prefix, data := testWinePrefix(t)
+	b := &WineBackend{Prefix: prefix}
+	for _, test := range []struct {
+		Unix    string
+		Windows string
+	}{
+		{filepath.Join(prefix, "drive_c", "users", "pete"), `C:\users\pete`},
+		{filepath.Join(prefix, "drive_c"), `C:\`},
+		{filepath.Join(data, "music"), `D:\music`},
+		// E: is more specific than D:
+		{filepath.Join(data, "media", "films"), `E:\films`},
+		{"/usr/share", `Z:\usr\share`},
+		{"/", `Z:\`},
+	} {
+		if got, err := b.WindowsPath(test.Unix); err != nil || got != test.Windows {
+			t.Errorf("Windows path of %v is %q (%v), want %q", test.Unix, got, err, test.Windows)
+		}
+		if got, err := b.UnixPath(test.Windows); err != nil || got != test.Unix {
+			t.Errorf("Unix path of %v is %q (%v), want %q", test.Windows, got, err, test.Unix)
+		}
+	}
+	// drive directories contain only the paths below them, not those that
+	// merely start with the same name
+	if got, err := b.WindowsPath(prefix + "/drive_cx/a/../b/"); err != nil || got != `Z:`+strings.Replace(prefix, "/", `\`, -1)+`\drive_cx\b` {
+		t.Errorf("Windows path beside C: is %q (%v)", got, err)
+	}
+	if got, err := b.UnixPath(`c:/users/pete/../Public`); err != nil || got != filepath.Join(prefix, "drive_c", "users", "Public") {
+		t.Errorf("Unix path of a lower case drive is %q (%v)", got, err)
+	}
+	for _, test := range []struct {
+		Path string
+		Err  string
+	}{
+		{`Q:\Docs`, "Drive Q: is not mapped in Wine prefix " + prefix},
+		{`\\server\share\Docs`, `\\server\share\Docs is not on a drive of Wine prefix ` + prefix},
+		{`Docs`, "Docs is not on a drive of Wine prefix " + prefix},
+	} {
+		if _, err := b.UnixPath(test.Path); err == nil || err.Error() != test.Err {
+			t.Errorf("Unix path of %v returned %v, want %v", test.Path, err, test.Err)
+		}
+	}
+	if err := os.Remove(filepath.Join(prefix, "dosdevices", "z:")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.WindowsPath("/usr/share"); err == nil || err.Error() != "/usr/share is not on any drive of Wine prefix "+prefix {
+		t.Errorf("Windows path of a path on no drive returned %v", err)
+	}
+}
+
+func TestWineEnvironment(t *testing.T) {
+	prefix, _ := testWinePrefix(t)
+	env, err := (&WineBackend{Prefix: prefix}).Environment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"USERPROFILE": `C:\users\pete`,
+		"USERNAME":    "pete",
+		"SystemRoot":  `C:\windows`,
+		"windir":      `C:\windows`,
+		"SystemDrive": "C:",
+		"ProgramData": `C:\ProgramData`,
+		"PUBLIC":      `C:\users\Public`,
+		"TEMP":        `C:\users\pete\Temp`,
+		"HOME":        "",
+	} {
+		if got := env(name); got != want {
+			t.Errorf("%%%v%% is %q, want %q", name, got, want)
+		}
+	}
+}
+
+// readWineValue returns the value name of key in the registry file of
+// prefix, failing the test if it can't be read.
+func readWineValue(t *testing.T, prefix, file, key, name string) string {
+	t.Helper()
+	r, err := loadWineRegistry(filepath.Join(prefix, file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := r.stringValue(key, name)
+	if err != nil {
+		t.Errorf("%v: %v", file, err)
+	}
+	return value
+}
+
+func TestWineBackendGetFolder(t *testing.T) {
+	prefix, _ := testWinePrefix(t)
+	b := &WineBackend{Prefix: prefix}
+	for folder, want := range map[string]string{
+		"Documents": `C:\users\pete\Documents`,
+		// only in Shell Folders
+		"Desktop":         `C:\users\pete\Desktop`,
+		"PublicDocuments": `C:\users\Public\Documents`,
+	} {
+		if got, err := b.GetFolder(folder); err != nil || got != want {
+			t.Errorf("%v is %q (%v), want %q", folder, got, err, want)
+		}
+	}
+	if _, err := b.GetFolder("Music"); err == nil || err.Error() != "Folder Music is not set in "+filepath.Join(prefix, "user.reg") {
+		t.Errorf("Getting a folder that isn't set returned %v", err)
+	}
+}
+
+func TestWineBackendSetFolder(t *testing.T) {
+	prefix, data := testWinePrefix(t)
+	b := &WineBackend{Prefix: prefix, Now: func() time.Time { return time.Unix(1600000000, 0) }}
+	for _, test := range []struct {
+		Folder   string
+		Location string
+		Want     string
+	}{
+		{"Documents", `%USERPROFILE%\My Documents`, `C:\users\pete\My Documents`},
+		{"Videos", `D:\videos`, `D:\videos`},
+		{"Pictures", filepath.Join(data, "media", "pictures"), `E:\pictures`},
+		{"Downloads", `c:\downloads`, `c:\downloads`},
+		{"PublicMusic", `%PUBLIC%\Music`, `C:\users\Public\Music`},
+	} {
+		if err := b.SetFolder(test.Folder, test.Location); err != nil {
+			t.Errorf("Setting %v: %v", test.Folder, err)
+			continue
+		}
+		if got, err := b.GetFolder(test.Folder); err != nil || got != test.Want {
+			t.Errorf("%v was set to %q (%v), want %q", test.Folder, got, err, test.Want)
+		}
+	}
+	// the unexpanded location is kept for Windows, and the expanded one
+	// given to older applications, in user.reg for per user folders and
+	// system.reg for common ones
+	for _, test := range []struct {
+		File, Key, Name, Want string
+	}{
+		{"user.reg", userShellFoldersKey, "Personal", `%USERPROFILE%\My Documents`},
+		{"user.reg", shellFoldersKey, "Personal", `C:\users\pete\My Documents`},
+		{"user.reg", userShellFoldersKey, "My Videos", `D:\videos`},
+		{"user.reg", userShellFoldersKey, "My Pictures", `E:\pictures`},
+		{"user.reg", userShellFoldersKey, "{374DE290-123F-4565-9164-39C4925E467B}", `c:\downloads`},
+		{"system.reg", userShellFoldersKey, "CommonMusic", `%PUBLIC%\Music`},
+		{"system.reg", shellFoldersKey, "CommonMusic", `C:\users\Public\Music`},
+	} {
+		if got := readWineValue(t, prefix, test.File, test.Key, test.Name); got != test.Want {
+			t.Errorf("%v of %v in %v is %q, want %q", test.Name, test.Key, test.File, got, test.Want)
+		}
+	}
+	for _, file := range []string{"user.reg", "system.reg"} {
+		content, err := ioutil.ReadFile(filepath.Join(prefix, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, filepath.Join("wine", "set-"+file), content)
+	}
+}
+
+func TestWineBackendSetFolderName(t *testing.T) {
+	prefix, _ := testWinePrefix(t)
+	b := &WineBackend{Prefix: prefix}
+	// Windows stores Videos as My Video, which is kept where it's present
+	r, err := loadWineRegistry(filepath.Join(prefix, "user.reg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.setStringValue(userShellFoldersKey, "My Video", `%USERPROFILE%\Videos`, true, time.Now())
+	if err := replaceFile(filepath.Join(prefix, "user.reg"), r.bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetFolder("Videos", `D:\videos`); err != nil {
+		t.Fatal(err)
+	}
+	if got := readWineValue(t, prefix, "user.reg", shellFoldersKey, "My Video"); got != `D:\videos` {
+		t.Errorf("My Video is %q", got)
+	}
+	if r, err := loadWineRegistry(filepath.Join(prefix, "user.reg")); err != nil || len(r.values(userShellFoldersKey)) != 2 {
+		t.Errorf("User Shell Folders are %q (%v), want Personal and My Video", r.values(userShellFoldersKey), err)
+	}
+}
+
+func TestWineBackendSetFolderErrors(t *testing.T) {
+	prefix, _ := testWinePrefix(t)
+	b := &WineBackend{Prefix: prefix}
+	for _, test := range []struct {
+		Location string
+		Err      string
+	}{
+		{`Q:\Docs`, "Drive Q: is not mapped in Wine prefix " + prefix},
+		// undefined variables are left as they are, as on Windows
+		{`%HOMEDRIVE%\Docs`, `%HOMEDRIVE%\Docs is not on a drive of Wine prefix ` + prefix},
+		{`\\server\docs`, `\\server\docs is not on a drive of Wine prefix ` + prefix},
+	} {
+		if err := b.SetFolder("Documents", test.Location); err == nil || err.Error() != test.Err {
+			t.Errorf("Setting Documents to %v returned %v, want %v", test.Location, err, test.Err)
+		}
+	}
+	for _, file := range []string{"user.reg", "system.reg"} {
+		got, err := ioutil.ReadFile(filepath.Join(prefix, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := ioutil.ReadFile(filepath.Join("testdata", "wine", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("Failed sets changed %v:\n%s", file, got)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// wineRegistry is a registry file of a Wine prefix, i.e. user.reg (keys
+// relative to HKEY_CURRENT_USER) or system.reg (relative to
+// HKEY_LOCAL_MACHINE), in Wine's text format:
+//
+//	WINE REGISTRY Version 2
+//	;; All keys relative to \\User\\S-1-5-21-0-0-0-1000
+//
+//	[Software\\Microsoft\\Windows\\CurrentVersion\\Explorer\\User Shell Folders] 1571592349
+//	#time=1d58771a9b8b0a2
+//	"Personal"=str(2):"%USERPROFILE%\\Documents"
+//
+// Key and value names are matched case insensitively, as Wine does. Only
+// string values are interpreted; everything else is written back as it was
+// read.
+type wineRegistry struct {
+	// Header is the lines before the first key
+	Header []string
+	Keys   []*wineKey
+}
+
+type wineKey struct {
+	// Name is the path of the key, unescaped
+	Name string
+	// Header is the key line as written, e.g. "[Software\\Wine] 1571592349"
+	Header string
+	Lines  []wineLine
+}
+
+// wineLine is a value of a key, or any other line, such as a #time= or
+// blank line, which has no Name and is kept verbatim in Raw. Values whose
+// data is continued over several lines have them all in Raw.
+type wineLine struct {
+	Name  string
+	Value bool
+	Raw   string
+}
+
+// parse returns the name and the data of a value line, i.e. what follows
+// the = after the name. The default value, written as @, has name "".
+func (line wineLine) parse() (name, data string, err error) {
+	if strings.HasPrefix(line.Raw, "@=") {
+		return "", line.Raw[2:], nil
+	}
+	name, rest, err := unescapeWineString(line.Raw[1:], '"')
+	if err != nil {
+		return "", "", err
+	}
+	if !strings.HasPrefix(rest, `"=`) {
+		return "", "", fmt.Errorf(`Value name not followed by =`)
+	}
+	return name, rest[2:], nil
+}
+
+// parseWineRegistry parses the content of a Wine registry file.
+func parseWineRegistry(data []byte) (*wineRegistry, error) {
+	text := strings.Replace(string(data), "\r\n", "\n", -1)
+	lines := strings.Split(text, "\n")
+	if !strings.HasPrefix(lines[0], "WINE REGISTRY Version 2") {
+		return nil, fmt.Errorf("Not a Wine registry file: it doesn't start with WINE REGISTRY Version 2")
+	}
+	r := &wineRegistry{}
+	var key *wineKey
+	for i := 0; i < len(lines); i++ {
+		raw := lines[i]
+		if strings.HasPrefix(raw, "[") {
+			name, rest, err := unescapeWineString(raw[1:], ']')
+			if err != nil {
+				return nil, fmt.Errorf("Line %v: %v", i+1, err)
+			}
+			if !strings.HasPrefix(rest, "]") {
+				return nil, fmt.Errorf("Line %v: key name not terminated by ]", i+1)
+			}
+			key = &wineKey{Name: name, Header: raw}
+			r.Keys = append(r.Keys, key)
+			continue
+		}
+		if key == nil {
+			r.Header = append(r.Header, raw)
+			continue
+		}
+		line := wineLine{Raw: raw, Value: strings.HasPrefix(raw, `"`) || strings.HasPrefix(raw, "@=")}
+		if line.Value {
+			name, data, err := line.parse()
+			if err != nil {
+				return nil, fmt.Errorf("Line %v: %v", i+1, err)
+			}
+			line.Name = name
+			// binary data is continued on the next line after a trailing \
+			for strings.HasPrefix(data, "hex") && strings.HasSuffix(lines[i], `\`) && i+1 < len(lines) {
+				i++
+				line.Raw += "\n" + lines[i]
+			}
+		}
+		key.Lines = append(key.Lines, line)
+	}
+	return r, nil
+}
+
+// loadWineRegistry reads the Wine registry file at the given path.
+func loadWineRegistry(file string) (*wineRegistry, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	r, err := parseWineRegistry(data)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse Wine registry file %v:\n%v", file, err)
+	}
+	return r, nil
+}
+
+// key returns the key with the given path, or nil.
+func (r *wineRegistry) key(name string) *wineKey {
+	for _, k := range r.Keys {
+		if strings.EqualFold(k.Name, name) {
+			return k
+		}
+	}
+	return nil
+}
+
+// sid returns the SID of the user whose user.reg this is, from the comment
+// that Wine writes at the top of the file, or "" if there isn't one.
+func (r *wineRegistry) sid() string {
+	const prefix = `;; All keys relative to \\User\\`
+	for _, line := range r.Header {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimSpace(line[len(prefix):])
+		}
+	}
+	return ""
+}
+
+// stringValue returns the value of a REG_SZ or REG_EXPAND_SZ value of key.
+func (r *wineRegistry) stringValue(key, name string) (string, error) {
+	k := r.key(key)
+	if k == nil {
+		return "", RegistryNotFoundError(key)
+	}
+	for _, line := range k.Lines {
+		if !line.Value || !strings.EqualFold(line.Name, name) {
+			continue
+		}
+		_, data, err := line.parse()
+		if err != nil {
+			return "", err
+		}
+		for _, prefix := range []string{`str(2):"`, `"`} {
+			if strings.HasPrefix(data, prefix) {
+				value, _, err := unescapeWineString(data[len(prefix):], '"')
+				return value, err
+			}
+		}
+		return "", fmt.Errorf("Registry value %v of %v is not a string", name, key)
+	}
+	return "", RegistryNotFoundError(key + `\` + name)
+}
+
+// values returns the string values of key, e.g. for environment variables.
+// Values of other types are left out.
+func (r *wineRegistry) values(key string) map[string]string {
+	values := map[string]string{}
+	if k := r.key(key); k != nil {
+		for _, line := range k.Lines {
+			if line.Name == "" {
+				continue
+			}
+			if value, err := r.stringValue(key, line.Name); err == nil {
+				values[line.Name] = value
+			}
+		}
+	}
+	return values
+}
+
+// setStringValue sets a value of key, as REG_EXPAND_SZ if expand is set,
+// otherwise REG_SZ, replacing any existing value of the same name. The key
+// is added at the end of the file if there is none, and its modification
+// time updated, as Wine would.
+func (r *wineRegistry) setStringValue(key, name, value string, expand bool, now time.Time) {
+	data := `"` + escapeWineString(value, `"`) + `"`
+	if expand {
+		data = "str(2):" + data
+	}
+	line := wineLine{Name: name, Value: true, Raw: `"` + escapeWineString(name, `"`) + `"=` + data}
+	modified := fmt.Sprintf("#time=%x", fileTime(now))
+	k := r.key(key)
+	if k == nil {
+		// Wine separates keys with a blank line, and ends the file with a
+		// newline
+		if len(r.Keys) > 0 {
+			last := r.Keys[len(r.Keys)-1]
+			if n := len(last.Lines); n == 0 || last.Lines[n-1].Raw != "" {
+				last.Lines = append(last.Lines, wineLine{})
+			}
+		}
+		k = &wineKey{Name: key, Lines: []wineLine{{Raw: modified}, {}}}
+		r.Keys = append(r.Keys, k)
+	}
+	k.Header = "[" + escapeWineString(k.Name, "[]") + "] " + strconv.FormatInt(now.Unix(), 10)
+	for i, l := range k.Lines {
+		if strings.HasPrefix(l.Raw, "#time=") {
+			k.Lines[i].Raw = modified
+		}
+	}
+	insert := 0
+	for i, l := range k.Lines {
+		if l.Value && strings.EqualFold(l.Name, name) {
+			k.Lines[i] = line
+			return
+		}
+		if l.Value || strings.HasPrefix(l.Raw, "#") {
+			insert = i + 1
+		}
+	}
+	k.Lines = append(k.Lines[:insert], append([]wineLine{line}, k.Lines[insert:]...)...)
+}
+
+// bytes returns the content of the registry file.
+func (r *wineRegistry) bytes() []byte {
+	lines := append([]string{}, r.Header...)
+	for _, k := range r.Keys {
+		lines = append(lines, k.Header)
+		for _, line := range k.Lines {
+			lines = append(lines, line.Raw)
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// fileTime returns t as a Windows FILETIME, the number of 100ns intervals
+// since 1601, as Wine records the modification times of keys.
+func fileTime(t time.Time) uint64 {
+	return uint64(t.UnixNano()/100) + 116444736000000000
+}
+
+// wineEscapes are the C escapes that Wine uses for control characters.
+var wineEscapes = map[rune]rune{'a': 7, 'b': 8, 'e': 27, 'f': 12, 'n': 10, 'r': 13, 't': 9, 'v': 11}
+
+// unescapeWineString reads a string escaped as Wine does, up to the
+// unescaped delimiter delim, and returns it along with the rest of s from
+// the delimiter on. Non-ASCII characters may be escaped as \x followed by
+// up to four hex digits of a UTF-16 code unit, or given as UTF-8.
+func unescapeWineString(s string, delim rune) (value, rest string, err error) {
+	var units []uint16
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if c == delim {
+			return string(utf16.Decode(units)), string(runes[i:]), nil
+		}
+		if c != '\\' || i+1 == len(runes) {
+			units = append(units, utf16.Encode([]rune{c})...)
+			continue
+		}
+		i++
+		c = runes[i]
+		switch {
+		case c == 'x':
+			n, digits := 0, 0
+			for digits < 4 && i+1 < len(runes) && strings.ContainsRune("0123456789abcdefABCDEF", runes[i+1]) {
+				d, _ := strconv.ParseUint(string(runes[i+1]), 16, 8)
+				n = n*16 + int(d)
+				digits++
+				i++
+			}
+			units = append(units, uint16(n))
+		case c >= '0' && c <= '7':
+			n := int(c - '0')
+			for digits := 1; digits < 3 && i+1 < len(runes) && runes[i+1] >= '0' && runes[i+1] <= '7'; digits++ {
+				i++
+				n = n*8 + int(runes[i]-'0')
+			}
+			units = append(units, uint16(n))
+		case wineEscapes[c] != 0:
+			units = append(units, uint16(wineEscapes[c]))
+		default:
+			units = append(units, utf16.Encode([]rune{c})...)
+		}
+	}
+	return "", "", fmt.Errorf("Missing closing %c", delim)
+}
+
+// escapeWineString escapes s as Wine does for a string ending with one of
+// delims: backslashes and delimiters are escaped with a backslash, control
+// characters as C or octal escapes, and non-ASCII characters as \x escapes.
+func escapeWineString(s string, delims string) string {
+	var b strings.Builder
+	units := utf16.Encode([]rune(s))
+	for i, u := range units {
+		switch {
+		case u > 127:
+			if i+1 < len(units) && units[i+1] < 128 && strings.ContainsRune("0123456789abcdefABCDEF", rune(units[i+1])) {
+				fmt.Fprintf(&b, `\x%04x`, u)
+			} else {
+				fmt.Fprintf(&b, `\x%x`, u)
+			}
+		case u < 32:
+			escaped := false
+			for e, c := range wineEscapes {
+				if c == rune(u) {
+					b.WriteRune('\\')
+					b.WriteRune(e)
+					escaped = true
+				}
+			}
+			if !escaped {
+				fmt.Fprintf(&b, `\%03o`, u)
+			}
+		default:
+			if rune(u) == '\\' || strings.ContainsRune(delims, rune(u)) {
+				b.WriteRune('\\')
+			}
+			b.WriteRune(rune(u))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testUserReg = `WINE REGISTRY Version 2
+;; All keys relative to \\User\\S-1-5-21-0-0-0-1000
+
+#arch=win64
+
+[Environment] 1571592349
+#time=1d58771a9b8b0a2
+"TEMP"=str(2):"%USERPROFILE%\\Temp"
+"Count"=dword:00000001
+
+[Software\\Microsoft\\Windows\\CurrentVersion\\Explorer\\User Shell Folders] 1571592349
+#time=1d58771a9b8b0a2
+"Personal"=str(2):"%USERPROFILE%\\Documents"
+@="default"
+
+[Software\\Wine\\Drivers] 1571592349
+#time=1d58771a9b8b0a2
+"Blob"=hex:01,02,03,\
+  04,05
+"Caf\x00e9"="M\x00fcnchen \"quoted\""
+"Tab"="a\tb\033c"
+`
+
+// mustParseWineRegistry returns the registry of text, failing the test if
+// it can't be parsed.
+func mustParseWineRegistry(t *testing.T, text string) *wineRegistry {
+	t.Helper()
+	r, err := parseWineRegistry([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestWineRegistryRoundTrip(t *testing.T) {
+	for _, text := range []string{
+		testUserReg,
+		"WINE REGISTRY Version 2\n",
+		"WINE REGISTRY Version 2\n\n[Key\\[1\\]] 1\n\"a\"=\"b\"",
+	} {
+		if got := string(mustParseWineRegistry(t, text).bytes()); got != text {
+			t.Errorf("%q was written back as %q", text, got)
+		}
+	}
+	// Wine writes LF, so files edited on Windows are written back with it
+	crlf := strings.Replace(testUserReg, "\n", "\r\n", -1)
+	if got := string(mustParseWineRegistry(t, crlf).bytes()); got != testUserReg {
+		t.Errorf("CRLF file was written back as %q", got)
+	}
+}
+
+func TestParseWineRegistry(t *testing.T) {
+	r := mustParseWineRegistry(t, testUserReg)
+	if got := r.sid(); got != "S-1-5-21-0-0-0-1000" {
+		t.Errorf("SID is %q", got)
+	}
+	names := []string{}
+	for _, k := range r.Keys {
+		names = append(names, k.Name)
+	}
+	want := []string{
+		"Environment",
+		`Software\Microsoft\Windows\CurrentVersion\Explorer\User Shell Folders`,
+		`Software\Wine\Drivers`,
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Keys are %q, want %q", names, want)
+	}
+	blob := r.key(`software\wine\drivers`).Lines[1]
+	if blob.Name != "Blob" || blob.Raw != "\"Blob\"=hex:01,02,03,\\\n  04,05" {
+		t.Errorf("Continued value is %+v", blob)
+	}
+	for _, test := range []struct {
+		Key, Name, Want string
+	}{
+		{"Environment", "temp", `%USERPROFILE%\Temp`},
+		{`Software\Microsoft\Windows\CurrentVersion\Explorer\User Shell Folders`, "Personal", `%USERPROFILE%\Documents`},
+		{`Software\Microsoft\Windows\CurrentVersion\Explorer\User Shell Folders`, "", "default"},
+		{`Software\Wine\Drivers`, "Café", `München "quoted"`},
+		{`Software\Wine\Drivers`, "Tab", "a\tb\x1bc"},
+	} {
+		got, err := r.stringValue(test.Key, test.Name)
+		if err != nil || got != test.Want {
+			t.Errorf("Value %v of %v is %q, %v, want %q", test.Name, test.Key, got, err, test.Want)
+		}
+	}
+	for _, test := range []struct {
+		Key, Name, Err string
+	}{
+		{"Environment", "Count", "Registry value Count of Environment is not a string"},
+		{"Environment", "Missing", RegistryNotFoundError(`Environment\Missing`).Error()},
+		{"Missing", "TEMP", RegistryNotFoundError("Missing").Error()},
+	} {
+		if _, err := r.stringValue(test.Key, test.Name); err == nil || err.Error() != test.Err {
+			t.Errorf("Value %v of %v returned %v, want %v", test.Name, test.Key, err, test.Err)
+		}
+	}
+	if got := r.values("Environment"); !reflect.DeepEqual(got, map[string]string{"TEMP": `%USERPROFILE%\Temp`}) {
+		t.Errorf("Environment is %q", got)
+	}
+}
+
+func TestParseWineRegistryErrors(t *testing.T) {
+	for _, test := range []struct {
+		Text string
+		Err  string
+	}{
+		{"REGEDIT4\n", "Not a Wine registry file: it doesn't start with WINE REGISTRY Version 2"},
+		{"WINE REGISTRY Version 2\n[Key\n", "Line 2: Missing closing ]"},
+		{"WINE REGISTRY Version 2\n[Key]\n\"a\n", `Line 3: Missing closing "`},
+		{"WINE REGISTRY Version 2\n[Key]\n\"a\" = \"b\"\n", "Line 3: Value name not followed by ="},
+	} {
+		if _, err := parseWineRegistry([]byte(test.Text)); err == nil || err.Error() != test.Err {
+			t.Errorf("Parsing %q returned %v, want %v", test.Text, err, test.Err)
+		}
+	}
+}
+
+func TestWineRegistrySetStringValue(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	const folders = `Software\Microsoft\Windows\CurrentVersion\Explorer\User Shell Folders`
+	for _, test := range []struct {
+		Name   string
+		Text   string
+		Key    string
+		Value  string
+		Data   string
+		Expand bool
+		Want   string
+	}{
+		{
+			Name: "replace",
+			Text: testUserReg,
+			Key:  folders, Value: "personal", Data: `D:\Docs`, Expand: true,
+			Want: strings.Replace(testUserReg,
+				"[Software\\\\Microsoft\\\\Windows\\\\CurrentVersion\\\\Explorer\\\\User Shell Folders] 1571592349\n#time=1d58771a9b8b0a2\n\"Personal\"=str(2):\"%USERPROFILE%\\\\Documents\"",
+				"[Software\\\\Microsoft\\\\Windows\\\\CurrentVersion\\\\Explorer\\\\User Shell Folders] 1600000000\n#time=1d689c921a68000\n\"personal\"=str(2):\"D:\\\\Docs\"", 1),
+		},
+		{
+			Name: "add after last value",
+			Text: "WINE REGISTRY Version 2\n\n[Key] 1\n#time=1\n\"a\"=\"1\"\n\n[Other] 1\n",
+			Key:  "key", Value: "Münster", Data: "é",
+			Want: "WINE REGISTRY Version 2\n\n[Key] 1600000000\n#time=1d689c921a68000\n\"a\"=\"1\"\n\"M\\xfcnster\"=\"\\xe9\"\n\n[Other] 1\n",
+		},
+		{
+			Name: "new key",
+			Text: "WINE REGISTRY Version 2\n\n[Key] 1\n#time=1\n",
+			Key:  `New\[Key]`, Value: "a", Data: `%X%\y`, Expand: true,
+			Want: "WINE REGISTRY Version 2\n\n[Key] 1\n#time=1\n\n[New\\\\\\[Key\\]] 1600000000\n#time=1d689c921a68000\n\"a\"=str(2):\"%X%\\\\y\"\n",
+		},
+		{
+			Name: "new key after key without values",
+			Text: "WINE REGISTRY Version 2\n\n[Key] 1",
+			Key:  "New", Value: "a", Data: "b",
+			Want: "WINE REGISTRY Version 2\n\n[Key] 1\n\n[New] 1600000000\n#time=1d689c921a68000\n\"a\"=\"b\"\n",
+		},
+	} {
+		r := mustParseWineRegistry(t, test.Text)
+		r.setStringValue(test.Key, test.Value, test.Data, test.Expand, now)
+		got := string(r.bytes())
+		if got != test.Want {
+			t.Errorf("%v: registry is\n%v\nwant\n%v", test.Name, got, test.Want)
+			continue
+		}
+		// what was written reads back as it was set
+		if value, err := mustParseWineRegistry(t, got).stringValue(test.Key, test.Value); err != nil || value != test.Data {
+			t.Errorf("%v: value reads back as %q, %v", test.Name, value, err)
+		}
+	}
+}
+
+func TestWineStringEscaping(t *testing.T) {
+	for _, test := range []struct {
+		Value   string
+		Escaped string
+	}{
+		{`C:\users\pete`, `C:\\users\\pete`},
+		{`say "hi"`, `say \"hi\"`},
+		{"é", `\xe9`},
+		// a \x escape followed by a hex digit is padded, so that the digit
+		// isn't read as part of it
+		{"éa", `\x00e9a`},
+		{"é1", `\x00e9` + "1"},
+		{"ég", `\xe9g`},
+		{"日本", `\x65e5\x672c`},
+		{"😀", `\xd83d\xde00`},
+		{"\t\n\x1b", `\t\n\e`},
+		{"\x01", `\001`},
+		{"\x017", `\0017`},
+	} {
+		if got := escapeWineString(test.Value, `"`); got != test.Escaped {
+			t.Errorf("%q is escaped as %q, want %q", test.Value, got, test.Escaped)
+		}
+		value, rest, err := unescapeWineString(test.Escaped+`"=`, '"')
+		if err != nil || value != test.Value || rest != `"=` {
+			t.Errorf("%q is unescaped as %q, %q, %v, want %q", test.Escaped, value, rest, err, test.Value)
+		}
+	}
+}
+
+func TestUnescapeWineString(t *testing.T) {
+	for _, test := range []struct {
+		Escaped string
+		Want    string
+	}{
+		// Wine reads up to four hex digits
+		{`\x41BCDE`, "\u41bcDE"},
+		{`\x41`, "A"},
+		{`\x`, "\x00"},
+		{`\101\60`, "A0"},
+		{`\q`, "q"},
+		// UTF-8 is accepted as well as escapes
+		{`München`, "München"},
+	} {
+		value, _, err := unescapeWineString(test.Escaped+`"`, '"')
+		if err != nil || value != test.Want {
+			t.Errorf("%q is unescaped as %q, %v, want %q", test.Escaped, value, err, test.Want)
+		}
+	}
+}
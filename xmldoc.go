@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+const utf8BOM = "\ufeff"
+
+// xmlNode is a node of a minimal XML document tree, used for editing files
+// which we must write back with everything we don't understand intact.
+// encoding/xml can't be used for this directly, since its encoder rewrites
+// namespace prefixes. Names are kept exactly as written in the source, so
+// prefixed names such as "wcm:action" have Space "wcm".
+//
+// An element node has a non-empty Start.Name.Local; any other node holds a
+// single xml.CharData, xml.Comment, xml.ProcInst or xml.Directive in Token.
+type xmlNode struct {
+	Start    xml.StartElement
+	Token    xml.Token
+	Children []*xmlNode
+	// indent is the white space which precedes the element on its line, and
+	// unit the white space added per level of nesting in the document
+	indent string
+	unit   string
+	// crlf is set on the root node of a document with CRLF line endings,
+	// which the decoder turns into LF, so that writeXML can restore them
+	crlf bool
+}
+
+// parseXML reads a whole document, returning a root node whose children are
+// the top level nodes of the document.
+func parseXML(r io.Reader) (*xmlNode, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	root := &xmlNode{crlf: bytes.Contains(data, []byte("\r\n"))}
+	// encoding/xml doesn't accept a byte order mark, so keep it as a leading
+	// text node, which writeXML outputs unchanged
+	if bytes.HasPrefix(data, []byte(utf8BOM)) {
+		root.Children = append(root.Children, &xmlNode{Token: xml.CharData(utf8BOM)})
+		data = data[len(utf8BOM):]
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// some Windows tools label UTF-8 documents "UTF8"
+		if strings.EqualFold(charset, "utf8") {
+			return input, nil
+		}
+		return nil, fmt.Errorf("Unsupported XML encoding %v", charset)
+	}
+	stack := []*xmlNode{root}
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		parent := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{Start: t.Copy()}
+			if n := len(parent.Children); n > 0 {
+				if data, ok := parent.Children[n-1].Token.(xml.CharData); ok && len(bytes.TrimSpace(data)) == 0 {
+					if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+						node.indent = string(data[i+1:])
+					}
+				}
+			}
+			parent.Children = append(parent.Children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) == 1 || qualifiedName(parent.Start.Name) != qualifiedName(t.Name) {
+				return nil, fmt.Errorf("Unexpected closing tag </%v>", qualifiedName(t.Name))
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			parent.Children = append(parent.Children, &xmlNode{Token: xml.CopyToken(token)})
+		}
+	}
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("Unclosed element <%v>", qualifiedName(stack[len(stack)-1].Start.Name))
+	}
+	root.setUnit(root.detectUnit())
+	return root, nil
+}
+
+// detectUnit returns the indentation used per level of nesting below n,
+// defaulting to two spaces.
+func (n *xmlNode) detectUnit() string {
+	for _, child := range n.Children {
+		if !child.isElement() {
+			continue
+		}
+		if n.isElement() && len(child.indent) > len(n.indent) && strings.HasPrefix(child.indent, n.indent) {
+			return child.indent[len(n.indent):]
+		}
+		if unit := child.detectUnit(); unit != "" {
+			return unit
+		}
+	}
+	if n.isElement() {
+		return ""
+	}
+	return "  "
+}
+
+func (n *xmlNode) setUnit(unit string) {
+	n.unit = unit
+	for _, child := range n.Children {
+		child.setUnit(unit)
+	}
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+func (n *xmlNode) isElement() bool {
+	return n.Start.Name.Local != ""
+}
+
+// attr returns the value of the named attribute (e.g. "pass" or
+// "wcm:action"), or "" if the element doesn't have it.
+func (n *xmlNode) attr(name string) string {
+	for _, a := range n.Start.Attr {
+		if qualifiedName(a.Name) == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (n *xmlNode) setAttr(name, value string) {
+	for i, a := range n.Start.Attr {
+		if qualifiedName(a.Name) == name {
+			n.Start.Attr[i].Value = value
+			return
+		}
+	}
+	attr := xml.Attr{Value: value}
+	if i := strings.Index(name, ":"); i >= 0 {
+		attr.Name = xml.Name{Space: name[:i], Local: name[i+1:]}
+	} else {
+		attr.Name = xml.Name{Local: name}
+	}
+	n.Start.Attr = append(n.Start.Attr, attr)
+}
+
+// elements returns the child elements with the given local name, ignoring
+// any namespace prefix, or all child elements if local is "".
+func (n *xmlNode) elements(local string) (elements []*xmlNode) {
+	for _, child := range n.Children {
+		if child.isElement() && (local == "" || child.Start.Name.Local == local) {
+			elements = append(elements, child)
+		}
+	}
+	return
+}
+
+// element returns the first child element with the given local name, or nil.
+func (n *xmlNode) element(local string) *xmlNode {
+	if elements := n.elements(local); len(elements) > 0 {
+		return elements[0]
+	}
+	return nil
+}
+
+// text returns the concatenated character data directly inside n.
+func (n *xmlNode) text() string {
+	var b strings.Builder
+	for _, child := range n.Children {
+		if data, ok := child.Token.(xml.CharData); ok {
+			b.Write(data)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// setText replaces all children of n with the given text.
+func (n *xmlNode) setText(text string) {
+	n.Children = []*xmlNode{{Token: xml.CharData(text)}}
+}
+
+// childText returns the text of the first child element with the given local
+// name, or "" if there is none.
+func (n *xmlNode) childText(local string) string {
+	if child := n.element(local); child != nil {
+		return child.text()
+	}
+	return ""
+}
+
+// appendElement adds child as the last child element of n, indented in line
+// with its siblings. Since the indentation is fixed when child is added, any
+// children of child should be added after it.
+func (n *xmlNode) appendElement(child *xmlNode) {
+	child.indent = n.indent + n.unit
+	if elements := n.elements(""); len(elements) > 0 {
+		child.indent = elements[len(elements)-1].indent
+	}
+	child.setUnit(n.unit)
+	// drop trailing white space before the closing tag, and reinstate it
+	// after the new child
+	for len(n.Children) > 0 {
+		last := n.Children[len(n.Children)-1]
+		data, ok := last.Token.(xml.CharData)
+		if !ok || len(bytes.TrimSpace(data)) > 0 {
+			break
+		}
+		n.Children = n.Children[:len(n.Children)-1]
+	}
+	n.Children = append(n.Children,
+		&xmlNode{Token: xml.CharData("\n" + child.indent)},
+		child,
+		&xmlNode{Token: xml.CharData("\n" + n.indent)},
+	)
+}
+
+// insertElementBefore adds child to n just before its existing child
+// element before, indented in line with it.
+func (n *xmlNode) insertElementBefore(child, before *xmlNode) {
+	child.indent = before.indent
+	child.setUnit(n.unit)
+	for i, c := range n.Children {
+		if c == before {
+			n.Children = append(n.Children[:i], append([]*xmlNode{child, {Token: xml.CharData("\n" + before.indent)}}, n.Children[i:]...)...)
+			return
+		}
+	}
+	n.appendElement(child)
+}
+
+// removeElement removes child from n, along with the white space which
+// precedes it on its line.
+func (n *xmlNode) removeElement(child *xmlNode) {
+	for i, c := range n.Children {
+		if c != child {
+			continue
+		}
+		start := i
+		if i > 0 {
+			if data, ok := n.Children[i-1].Token.(xml.CharData); ok && len(bytes.TrimSpace(data)) == 0 {
+				start = i - 1
+			}
+		}
+		n.Children = append(n.Children[:start], n.Children[i+1:]...)
+		return
+	}
+}
+
+// newXMLElement returns an element with the given (possibly prefixed) name
+// and attributes, given as name/value pairs.
+func newXMLElement(name string, attrs ...string) *xmlNode {
+	node := &xmlNode{}
+	if i := strings.Index(name, ":"); i >= 0 {
+		node.Start.Name = xml.Name{Space: name[:i], Local: name[i+1:]}
+	} else {
+		node.Start.Name = xml.Name{Local: name}
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		node.setAttr(attrs[i], attrs[i+1])
+	}
+	return node
+}
+
+// newXMLTextElement returns an element containing only the given text.
+func newXMLTextElement(name, text string) *xmlNode {
+	node := newXMLElement(name)
+	node.setText(text)
+	return node
+}
+
+// writeXML writes the children of root as an XML document.
+func writeXML(w io.Writer, root *xmlNode) error {
+	var b bytes.Buffer
+	for _, child := range root.Children {
+		child.write(&b)
+	}
+	data := b.Bytes()
+	if root.crlf {
+		data = bytes.Replace(data, []byte("\n"), []byte("\r\n"), -1)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func (n *xmlNode) write(b *bytes.Buffer) {
+	switch t := n.Token.(type) {
+	case xml.CharData:
+		escapeXML(b, string(t), false)
+		return
+	case xml.Comment:
+		b.WriteString("<!--")
+		b.Write(t)
+		b.WriteString("-->")
+		return
+	case xml.ProcInst:
+		b.WriteString("<?" + t.Target)
+		if len(t.Inst) > 0 {
+			b.WriteString(" ")
+			b.Write(t.Inst)
+		}
+		b.WriteString("?>")
+		return
+	case xml.Directive:
+		b.WriteString("<!")
+		b.Write(t)
+		b.WriteString(">")
+		return
+	}
+	b.WriteString("<" + qualifiedName(n.Start.Name))
+	for _, a := range n.Start.Attr {
+		b.WriteString(" " + qualifiedName(a.Name) + `="`)
+		escapeXML(b, a.Value, true)
+		b.WriteString(`"`)
+	}
+	if len(n.Children) == 0 {
+		b.WriteString(" />")
+		return
+	}
+	b.WriteString(">")
+	for _, child := range n.Children {
+		child.write(b)
+	}
+	b.WriteString("</" + qualifiedName(n.Start.Name) + ">")
+}
+
+// escapeXML writes s with XML special characters escaped. Unlike
+// xml.EscapeText, white space in character data is written as is, so that
+// the indentation of the document is preserved.
+func escapeXML(b *bytes.Buffer, s string, attr bool) {
+	for _, r := range s {
+		switch {
+		case r == '&':
+			b.WriteString("&amp;")
+		case r == '<':
+			b.WriteString("&lt;")
+		case r == '>':
+			b.WriteString("&gt;")
+		case r == '"' && attr:
+			b.WriteString("&quot;")
+		case (r == '\n' || r == '\r' || r == '\t') && attr:
+			fmt.Fprintf(b, "&#x%X;", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
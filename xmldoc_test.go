@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// roundTripXML returns the document of root, failing the test if it can't be
+// written.
+func roundTripXML(t *testing.T, root *xmlNode) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := writeXML(&b, root); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+// mustParseXML returns the root of document, failing the test if it can't be
+// parsed.
+func mustParseXML(t *testing.T, document string) *xmlNode {
+	t.Helper()
+	root, err := parseXML(strings.NewReader(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	for _, document := range []string{
+		"<a />",
+		utf8BOM + `<?xml version="1.0" encoding="utf-8"?>` + "\r\n<a>\r\n  <b>text</b>\r\n</a>\r\n",
+		`<?xml version="1.0" encoding="UTF8"?><a />`,
+		"<!DOCTYPE a>\n<!-- a comment -->\n<a x=\"1\" y=\"&quot;quoted&quot; &amp; &lt;escaped&gt;\">\n\t<b />\n</a>",
+		`<unattend xmlns="urn:schemas-microsoft-com:unattend" xmlns:wcm="http://schemas.microsoft.com/WMIConfig/2002/State">` +
+			`<settings pass="oobeSystem"><wcm:x wcm:action="add">1 &amp; 2</wcm:x></settings></unattend>`,
+		"<a>  white\n\tspace  </a>",
+	} {
+		if got := roundTripXML(t, mustParseXML(t, document)); got != document {
+			t.Errorf("%q was written back as %q", document, got)
+		}
+	}
+}
+
+func TestParseXMLErrors(t *testing.T) {
+	for _, test := range []struct {
+		Document string
+		Err      string
+	}{
+		{"<a><b></a>", "Unexpected closing tag </a>"},
+		{"<a><b></b>", "Unclosed element <a>"},
+		{`<?xml version="1.0" encoding="UTF-16"?><a />`, "Unsupported XML encoding UTF-16"},
+	} {
+		_, err := parseXML(strings.NewReader(test.Document))
+		if err == nil || !strings.Contains(err.Error(), test.Err) {
+			t.Errorf("Parsing %q returned %v, want %v", test.Document, err, test.Err)
+		}
+	}
+}
+
+func TestXMLDetectUnit(t *testing.T) {
+	for _, test := range []struct {
+		Document string
+		Want     string
+	}{
+		{"<a>\n  <b />\n</a>", "  "},
+		{"<a>\n    <b>\n        <c />\n    </b>\n</a>", "    "},
+		{"<a>\n\t<b />\n</a>", "\t"},
+		// indentation is found below unindented elements too
+		{"<a><b>\n\t<c />\n</b></a>", "\t"},
+		{"<a><b /></a>", "  "},
+	} {
+		if got := mustParseXML(t, test.Document).unit; got != test.Want {
+			t.Errorf("%q has unit %q, want %q", test.Document, got, test.Want)
+		}
+	}
+}
+
+func TestXMLEdit(t *testing.T) {
+	const document = "<a>\n    <b x=\"1\">\n        <c>one</c>\n    </b>\n    <d />\n</a>\n"
+	for _, test := range []struct {
+		Name string
+		Edit func(root *xmlNode)
+		Want string
+	}{
+		{
+			Name: "append to element with children",
+			Edit: func(root *xmlNode) {
+				root.element("a").element("b").appendElement(newXMLTextElement("c", "two & three"))
+			},
+			Want: "<a>\n    <b x=\"1\">\n        <c>one</c>\n        <c>two &amp; three</c>\n    </b>\n    <d />\n</a>\n",
+		},
+		{
+			Name: "append to empty element",
+			Edit: func(root *xmlNode) {
+				d := root.element("a").element("d")
+				e := newXMLElement("ns:e", "ns:action", "add", "y", "a\nb")
+				d.appendElement(e)
+				e.appendElement(newXMLTextElement("f", "g"))
+			},
+			Want: "<a>\n    <b x=\"1\">\n        <c>one</c>\n    </b>\n    <d>\n        <ns:e ns:action=\"add\" y=\"a&#xA;b\">\n            <f>g</f>\n        </ns:e>\n    </d>\n</a>\n",
+		},
+		{
+			Name: "insert before",
+			Edit: func(root *xmlNode) {
+				a := root.element("a")
+				a.insertElementBefore(newXMLElement("e"), a.element("d"))
+			},
+			Want: "<a>\n    <b x=\"1\">\n        <c>one</c>\n    </b>\n    <e />\n    <d />\n</a>\n",
+		},
+		{
+			Name: "remove",
+			Edit: func(root *xmlNode) {
+				a := root.element("a")
+				a.removeElement(a.element("b"))
+			},
+			Want: "<a>\n    <d />\n</a>\n",
+		},
+		{
+			Name: "set attributes and text",
+			Edit: func(root *xmlNode) {
+				b := root.element("a").element("b")
+				b.setAttr("x", "<2>")
+				b.setAttr("wcm:keyValue", "k")
+				b.element("c").setText("1")
+			},
+			Want: "<a>\n    <b x=\"&lt;2&gt;\" wcm:keyValue=\"k\">\n        <c>1</c>\n    </b>\n    <d />\n</a>\n",
+		},
+	} {
+		root := mustParseXML(t, document)
+		test.Edit(root)
+		if got := roundTripXML(t, root); got != test.Want {
+			t.Errorf("%v: document is\n%v\nwant\n%v", test.Name, got, test.Want)
+		}
+	}
+}
+
+func TestXMLAccessors(t *testing.T) {
+	root := mustParseXML(t, `<a xmlns:wcm="urn:wcm"><wcm:b wcm:action="add" pass="1"> text </wcm:b><c /><b>2</b></a>`)
+	a := root.element("a")
+	if got := len(a.elements("b")); got != 2 {
+		t.Errorf("a has %v b elements, want 2", got)
+	}
+	if got := len(a.elements("")); got != 3 {
+		t.Errorf("a has %v elements, want 3", got)
+	}
+	b := a.element("b")
+	for _, test := range []struct{ Got, Want string }{
+		{b.attr("wcm:action"), "add"},
+		{b.attr("action"), ""},
+		{b.attr("pass"), "1"},
+		{b.text(), "text"},
+		{a.childText("b"), "text"},
+		{a.childText("missing"), ""},
+		{qualifiedName(b.Start.Name), "wcm:b"},
+	} {
+		if test.Got != test.Want {
+			t.Errorf("Got %q, want %q", test.Got, test.Want)
+		}
+	}
+	if a.element("missing") != nil {
+		t.Error("Found missing element")
+	}
+}
+
+func TestXMLEditKeepsCRLF(t *testing.T) {
+	root := mustParseXML(t, "<a>\r\n  <b />\r\n</a>\r\n")
+	root.element("a").appendElement(newXMLElement("c"))
+	want := "<a>\r\n  <b />\r\n  <c />\r\n</a>\r\n"
+	if got := roundTripXML(t, root); got != want {
+		t.Errorf("Document is %q, want %q", got, want)
+	}
+}